@@ -4,18 +4,27 @@ import (
 	"time"
 )
 
+// Array passes the slice v as the value of an Array(T) column or as an array query parameter:
+// the slices of ints, floats, strings and time.Time bound to {ids:Array(UInt64)}, {tags:Array(String)}
+// or {days:Array(Date)} are sent as the array literals of the parameters, the elements quoted and escaped
+// by the driver, e.g.
+//
+//	db.Query("SELECT * FROM example WHERE id IN {ids:Array(UInt64)}", sql.Named("ids", clickhouse.Array([]uint64{1, 2, 3})))
 func Array(v interface{}) interface{} {
 	return v
 }
 
+// ArrayFixedString passes the slice v as the value of an Array(FixedString(len)) column.
 func ArrayFixedString(len int, v interface{}) interface{} {
 	return v
 }
 
+// ArrayDate passes the dates v as the value of an Array(Date) column.
 func ArrayDate(v []time.Time) interface{} {
 	return v
 }
 
+// ArrayDateTime passes the times v as the value of an Array(DateTime) column.
 func ArrayDateTime(v []time.Time) interface{} {
 	return v
 }
@@ -0,0 +1,78 @@
+package clickhouse
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Balancer selects the host to dial.
+// Pick is called with attempt 0, 1, ... up to len(hosts)-1 until a connection is established
+// and returns the index of the host to dial on that attempt. The attempt is picked once the dials
+// of the previous attempts have failed, with parallel_dial the attempts of a batch dialed at once
+// are picked together.
+// Custom balancers must be registered with RegisterBalancer and selected with the balancer DSN parameter
+type Balancer interface {
+	Pick(hosts []string, attempt int) (index int, err error)
+}
+
+var (
+	balancerLock     sync.RWMutex
+	balancerRegistry map[string]Balancer
+)
+
+// RegisterBalancer registers a custom balancer under the name.
+func RegisterBalancer(name string, b Balancer) {
+	balancerLock.Lock()
+	if balancerRegistry == nil {
+		balancerRegistry = make(map[string]Balancer)
+	}
+	balancerRegistry[name] = b
+	balancerLock.Unlock()
+}
+
+// DeregisterBalancer removes the balancer registered under the name.
+func DeregisterBalancer(name string) {
+	balancerLock.Lock()
+	if balancerRegistry != nil {
+		delete(balancerRegistry, name)
+	}
+	balancerLock.Unlock()
+}
+
+func getBalancer(name string) (b Balancer) {
+	balancerLock.RLock()
+	b = balancerRegistry[name]
+	balancerLock.RUnlock()
+	return
+}
+
+// strategyBalancer implements the built-in open strategies for a single dial.
+type strategyBalancer struct {
+	strategy openStrategy
+	weights  []int
+	ident    int
+	order    []int
+}
+
+func (b *strategyBalancer) Pick(hosts []string, attempt int) (int, error) {
+	if b.order == nil {
+		b.order = hostOrder(b.strategy, hosts, b.weights, b.ident)
+	}
+	return b.order[attempt], nil
+}
+
+// pickHosts asks the balancer for the hosts to be dialed on the attempts from attempt up to attempt+n-1.
+func pickHosts(b Balancer, hosts []string, attempt, n int) ([]int, error) {
+	order := make([]int, 0, n)
+	for ; n > 0 && attempt < len(hosts); attempt, n = attempt+1, n-1 {
+		num, err := b.Pick(hosts, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if num < 0 || num >= len(hosts) {
+			return nil, fmt.Errorf("balancer picked host %d out of %d", num, len(hosts))
+		}
+		order = append(order, num)
+	}
+	return order, nil
+}
@@ -0,0 +1,87 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reverseBalancer struct{}
+
+func (reverseBalancer) Pick(hosts []string, attempt int) (int, error) {
+	return len(hosts) - 1 - attempt, nil
+}
+
+func Test_RegisterBalancer(t *testing.T) {
+	RegisterBalancer("reverse", reverseBalancer{})
+	defer DeregisterBalancer("reverse")
+	hosts := []string{"127.0.0.1:1", "127.0.0.1:2"}
+	defer breaker.success(hosts[0])
+	defer breaker.success(hosts[1])
+	_, err := dial(context.Background(), connOptions{
+		hosts:        hosts,
+		balancer:     getBalancer("reverse"),
+		balancerName: "reverse",
+		logf:         func(string, ...interface{}) {},
+	})
+	var dialErr *DialError
+	if assert.True(t, errors.As(err, &dialErr)) {
+		assert.Equal(t, "reverse", dialErr.Strategy)
+		if assert.Len(t, dialErr.Hosts, 2) {
+			assert.Equal(t, hosts[1], dialErr.Hosts[0].Host)
+			assert.Equal(t, hosts[0], dialErr.Hosts[1].Host)
+		}
+	}
+	_, err = Open("tcp://127.0.0.1:9000?balancer=unknown")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no balancer registered")
+	}
+}
+
+func Test_pickHosts(t *testing.T) {
+	order, err := pickHosts(&strategyBalancer{strategy: connOpenInOrder}, make([]string, 3), 0, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{0, 1, 2}, order)
+	}
+	order, err = pickHosts(&strategyBalancer{strategy: connOpenInOrder}, make([]string, 3), 2, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{2}, order)
+	}
+	_, err = pickHosts(balancerFunc(func([]string, int) (int, error) { return 5, nil }), make([]string, 3), 0, 3)
+	assert.Error(t, err)
+}
+
+func Test_BalancerPickAfterDial(t *testing.T) {
+	var events []string
+	RegisterDial(func(network, address string, timeout time.Duration, config *tls.Config) (net.Conn, error) {
+		events = append(events, "dial "+address)
+		return nil, errors.New("connection refused")
+	})
+	defer DeregisterDial()
+	hosts := []string{"127.0.0.1:1", "127.0.0.1:2"}
+	defer breaker.success(hosts[0])
+	defer breaker.success(hosts[1])
+	_, err := dial(context.Background(), connOptions{
+		hosts: hosts,
+		balancer: balancerFunc(func(hosts []string, attempt int) (int, error) {
+			events = append(events, fmt.Sprintf("pick %d", attempt))
+			return attempt, nil
+		}),
+		logf: func(string, ...interface{}) {},
+	})
+	assert.Error(t, err)
+	// the balancer picks the next host once the previous one failed
+	assert.Equal(t, []string{"pick 0", "dial 127.0.0.1:1", "pick 1", "dial 127.0.0.1:2"}, events)
+}
+
+type balancerFunc func(hosts []string, attempt int) (int, error)
+
+func (f balancerFunc) Pick(hosts []string, attempt int) (int, error) {
+	return f(hosts, attempt)
+}
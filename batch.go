@@ -0,0 +1,249 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/c3mb0/clickhouse-go/lib/data"
+)
+
+// Batch is the block of the rows of an INSERT, sent to the server at once by Send.
+// The rows are appended as a whole with Append, or column by column with the appenders of Column.
+// The values are checked against the types of the columns as they are appended.
+// Once the columns hold the same number of rows and the block reaches its maximum size (see WithBatchBlockSize),
+// the block is sent to the server and a new one is started, the rows of the sent blocks can't be aborted.
+type Batch interface {
+	// Append appends a row, the values are in the order of the columns of the INSERT.
+	// A row failing to be appended is not appended to any column, the batch is kept usable.
+	Append(values ...interface{}) error
+	// Column returns the appender of the column idx.
+	Column(idx int) BatchColumn
+	// Send sends the rows not sent yet to the server and ends the INSERT, all the columns have to hold the same number of rows.
+	Send() error
+	// RowsAffected returns the rows inserted by Send, counted by the client as the rows of the blocks sent:
+	// the server doesn't report the written rows to the revision of the protocol of the driver.
+	RowsAffected() int64
+	// Abort gives up the rows not sent yet and releases the block. If no block was sent, the INSERT is ended
+	// without any row and the connection is kept for the following queries, otherwise the connection is closed
+	// to end the INSERT without its last blocks. Abort is not a rollback: the INSERTs are not atomic across
+	// their blocks, the blocks already sent (see WithBatchBlockSize) may have been inserted by the server
+	// and closing the connection doesn't remove them.
+	Abort() error
+}
+
+// BatchColumn appends the values of a column of a Batch.
+type BatchColumn interface {
+	Append(values ...interface{}) error
+}
+
+// PrepareBatch sends the INSERT query and returns the batch of its rows, no other query can be run
+// on the connection until the batch is sent or aborted.
+//
+//	batch, err := connect.PrepareBatch(ctx, "INSERT INTO example (id, name)")
+//	for i := 0; i < 1000; i++ {
+//		if err := batch.Append(uint32(i), fmt.Sprintf("name_%d", i)); err != nil {
+//			return err
+//		}
+//	}
+//	err = batch.Send()
+func (ch *clickhouse) PrepareBatch(ctx context.Context, query string) (Batch, error) {
+	ch.logf("[prepare batch] %s", query)
+	switch {
+	case ch.conn.isClosed():
+		return nil, driver.ErrBadConn
+	case ch.block != nil:
+		return nil, ErrLimitDataRequestInTx
+	case !isInsert(query):
+		return nil, fmt.Errorf("clickhouse: PrepareBatch expects an INSERT query")
+	}
+	if _, err := ch.insert(ctx, query); err != nil {
+		return nil, err
+	}
+	ch.block.Reserve()
+	b := &batch{
+		ch:      ch,
+		block:   ch.block,
+		counts:  make([]int, len(ch.block.Columns)),
+		maxRows: ch.blockSize,
+	}
+	if size, ok := ctx.Value(batchBlockSizeKey).(batchBlockSize); ok {
+		if size.rows > 0 {
+			b.maxRows = size.rows
+		}
+		b.maxBytes = size.bytes
+	}
+	return b, nil
+}
+
+// WithBatchBlockSize bounds the blocks of the batches prepared with the context: a block holding maxBlockRows rows,
+// or maxBlockBytes bytes of values, is sent to the server as the following rows are appended to a new block.
+// The rows are bounded by block_size of the DSN if maxBlockRows is 0, the bytes are not bounded if maxBlockBytes is 0.
+// The values of the columns written at once, e.g. Map or Tuple, are not counted by maxBlockBytes.
+func WithBatchBlockSize(ctx context.Context, maxBlockRows, maxBlockBytes int) context.Context {
+	return context.WithValue(ctx, batchBlockSizeKey, batchBlockSize{rows: maxBlockRows, bytes: maxBlockBytes})
+}
+
+type batchBlockSize struct {
+	rows, bytes int
+}
+
+type batch struct {
+	ch       *clickhouse
+	block    *data.Block
+	counts   []int // the number of the values appended to the columns
+	maxRows  int   // the maximum rows of a block, 0 if not bounded
+	maxBytes int   // the maximum bytes of a block, 0 if not bounded
+	flushed  bool  // a full block was sent to the server
+	rows     int64 // the rows of the blocks sent
+	sent     bool
+}
+
+func (b *batch) Append(values ...interface{}) error {
+	switch {
+	case b.sent:
+		return ErrBatchSent
+	case len(values) != len(b.block.Columns):
+		return fmt.Errorf("clickhouse: expected %d values (columns: %v), got %d", len(b.block.Columns), b.block.ColumnNames(), len(values))
+	}
+	marks := make([]data.ColumnMark, len(values))
+	for idx, v := range values {
+		marks[idx] = b.block.Mark(idx)
+		if err := b.append(idx, v); err != nil {
+			// the values of the row appended to the preceding columns are dropped, the columns keep the same rows
+			for prev := 0; prev < idx; prev++ {
+				b.block.Truncate(prev, marks[prev])
+				b.counts[prev]--
+			}
+			return err
+		}
+	}
+	return b.flushFull()
+}
+
+func (b *batch) Column(idx int) BatchColumn {
+	return &batchColumn{batch: b, idx: idx}
+}
+
+// append appends the value to the column idx, the value is converted as the arguments of Exec are.
+// A value failing to be appended is dropped, e.g. the elements of an Array written before the offending one.
+func (b *batch) append(idx int, v interface{}) error {
+	switch {
+	case b.sent:
+		return ErrBatchSent
+	case idx < 0 || idx >= len(b.block.Columns):
+		return fmt.Errorf("clickhouse: column %d is out of the %d columns of the batch", idx, len(b.block.Columns))
+	}
+	value := driver.NamedValue{Ordinal: idx + 1, Value: v}
+	if err := b.ch.CheckNamedValue(&value); err != nil {
+		return fmt.Errorf("clickhouse: column %s: %v", b.block.Columns[idx].Name(), err)
+	}
+	mark := b.block.Mark(idx)
+	if err := b.block.AppendColumn(idx, value.Value); err != nil {
+		b.block.Truncate(idx, mark)
+		return fmt.Errorf("clickhouse: column %s: %v", b.block.Columns[idx].Name(), err)
+	}
+	b.counts[idx]++
+	return nil
+}
+
+// flushFull sends the block to the server if it is full and all the columns hold the same number of rows.
+func (b *batch) flushFull() error {
+	if len(b.counts) == 0 {
+		return nil
+	}
+	rows := b.counts[0]
+	for _, count := range b.counts {
+		if count != rows {
+			return nil
+		}
+	}
+	if rows == 0 || ((b.maxRows == 0 || rows < b.maxRows) && (b.maxBytes == 0 || b.block.Size() < b.maxBytes)) {
+		return nil
+	}
+	b.block.NumRows = uint64(rows)
+	b.ch.logf("[batch] flush block: rows=%d", rows)
+	if err := b.ch.writeBlock(b.block, ""); err != nil {
+		return err
+	}
+	for idx := range b.counts {
+		b.counts[idx] = 0
+	}
+	b.rows += int64(rows)
+	b.flushed = true
+	return b.ch.encoder.Flush()
+}
+
+func (b *batch) Send() error {
+	if b.sent {
+		return ErrBatchSent
+	}
+	for idx, count := range b.counts {
+		if count != b.counts[0] {
+			return fmt.Errorf("clickhouse: column %s holds %d rows, column %s holds %d",
+				b.block.Columns[idx].Name(), count, b.block.Columns[0].Name(), b.counts[0])
+		}
+	}
+	b.sent = true
+	defer func() {
+		b.block.Reset()
+		b.ch.block = nil
+	}()
+	if len(b.counts) != 0 {
+		b.block.NumRows = uint64(b.counts[0])
+	}
+	b.ch.logf("[batch] send: rows=%d", b.block.NumRows)
+	rows := b.block.NumRows
+	if err := b.ch.writeBlock(b.block, ""); err != nil {
+		return err
+	}
+	b.rows += int64(rows)
+	// the empty block marks the end of the data
+	if err := b.ch.writeBlock(&data.Block{}, ""); err != nil {
+		return err
+	}
+	if err := b.ch.encoder.Flush(); err != nil {
+		return err
+	}
+	return b.ch.process()
+}
+
+func (b *batch) RowsAffected() int64 {
+	return b.rows
+}
+
+func (b *batch) Abort() error {
+	if b.sent {
+		return ErrBatchSent
+	}
+	b.sent = true
+	b.block.Reset()
+	b.ch.block = nil
+	if b.flushed {
+		b.ch.logf("[batch] abort: close the connection, blocks were sent")
+		return b.ch.conn.Close()
+	}
+	b.ch.logf("[batch] abort")
+	// the empty block ends the data of the INSERT, no row is inserted
+	if err := b.ch.writeBlock(&data.Block{}, ""); err != nil {
+		return err
+	}
+	if err := b.ch.encoder.Flush(); err != nil {
+		return err
+	}
+	return b.ch.process()
+}
+
+type batchColumn struct {
+	batch *batch
+	idx   int
+}
+
+func (column *batchColumn) Append(values ...interface{}) error {
+	for _, v := range values {
+		if err := column.batch.append(column.idx, v); err != nil {
+			return err
+		}
+	}
+	return column.batch.flushFull()
+}
@@ -0,0 +1,232 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Batch(t *testing.T) {
+	var (
+		sent     bytes.Buffer
+		received bytes.Buffer
+		block    = &data.Block{NumColumns: 3}
+	)
+	for _, chType := range []string{"UInt32", "Nullable(String)", "Array(Int8)"} {
+		c, err := column.Factory("column_"+chType, chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		block.Columns = append(block.Columns, c)
+	}
+	binary.NewEncoder(&received).Uvarint(protocol.ServerEndOfStream)
+	ch := &clickhouse{
+		logf:    func(string, ...interface{}) {},
+		conn:    &connect{},
+		block:   block,
+		encoder: binary.NewEncoder(&sent),
+		decoder: binary.NewDecoder(&received),
+	}
+	block.Reserve()
+	batch := &batch{ch: ch, block: block, counts: make([]int, len(block.Columns))}
+
+	a := "a"
+	if assert.NoError(t, batch.Append(uint32(1), &a, []int8{1, 2})) {
+		assert.NoError(t, batch.Append(uint32(2), nil, []int8{}))
+	}
+	assert.Error(t, batch.Append(uint32(3), "b"))
+	// the rows failing to be appended, e.g. by an element of their Array, are dropped from all the columns
+	assert.Error(t, batch.Append(uint32(9), "x", "not an array"))
+	assert.Error(t, batch.Append(uint32(9), "x", []interface{}{int8(9), "not an int8"}))
+	if err := batch.Column(0).Append("3"); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "column_UInt32")
+	}
+	assert.Error(t, batch.Column(3).Append(uint32(3)))
+	if assert.NoError(t, batch.Column(0).Append(uint32(3), uint32(4))) {
+		// the columns hold 4, 2 and 2 rows
+		assert.Error(t, batch.Send())
+		assert.NoError(t, batch.Column(1).Append("c", nil))
+		assert.NoError(t, batch.Column(2).Append([]int8{3}, []int8{4, 5}))
+	}
+	if assert.NoError(t, batch.Send()) {
+		decoder := binary.NewDecoder(&sent)
+		if packet, err := decoder.Uvarint(); assert.NoError(t, err) && assert.Equal(t, uint64(protocol.ClientData), packet) {
+			decoder.String()
+			var block data.Block
+			if assert.NoError(t, block.Read(&data.ServerInfo{}, decoder)) {
+				assert.Equal(t, uint64(4), block.NumRows)
+				assert.Equal(t, [][]interface{}{
+					{uint32(1), uint32(2), uint32(3), uint32(4)},
+					{"a", nil, "c", nil},
+					{[]int8{1, 2}, []int8{}, []int8{3}, []int8{4, 5}},
+				}, block.Values)
+			}
+		}
+		assert.Nil(t, ch.block)
+	}
+	assert.Equal(t, ErrBatchSent, batch.Append(uint32(5), nil, []int8{}))
+	assert.Equal(t, ErrBatchSent, batch.Send())
+}
+
+func Test_BatchBlockSize(t *testing.T) {
+	newBatch := func(sent *bytes.Buffer, maxRows, maxBytes int) *batch {
+		var received bytes.Buffer
+		binary.NewEncoder(&received).Uvarint(protocol.ServerEndOfStream)
+		block := &data.Block{NumColumns: 2}
+		for _, chType := range []string{"UInt32", "String"} {
+			c, err := column.Factory("column_"+chType, chType, time.UTC)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			block.Columns = append(block.Columns, c)
+		}
+		ch := &clickhouse{
+			logf:    func(string, ...interface{}) {},
+			conn:    &connect{},
+			block:   block,
+			encoder: binary.NewEncoder(sent),
+			decoder: binary.NewDecoder(&received),
+		}
+		block.Reserve()
+		return &batch{ch: ch, block: block, counts: make([]int, len(block.Columns)), maxRows: maxRows, maxBytes: maxBytes}
+	}
+	// sentRows returns the rows of the data packets, up to the empty block ending the data
+	sentRows := func(sent *bytes.Buffer) (rows []uint64) {
+		decoder := binary.NewDecoder(sent)
+		for {
+			packet, err := decoder.Uvarint()
+			if !assert.NoError(t, err) || !assert.Equal(t, uint64(protocol.ClientData), packet) {
+				return rows
+			}
+			decoder.String()
+			var block data.Block
+			if !assert.NoError(t, block.Read(&data.ServerInfo{}, decoder)) || block.NumColumns == 0 {
+				return rows
+			}
+			if block.NumRows != 0 {
+				assert.Len(t, block.Values[0], int(block.NumRows))
+			}
+			rows = append(rows, block.NumRows)
+		}
+	}
+	{
+		var sent bytes.Buffer
+		batch := newBatch(&sent, 3, 0)
+		for i := 0; i < 8; i++ {
+			if !assert.NoError(t, batch.Append(uint32(i), "value")) {
+				return
+			}
+		}
+		// the blocks are sent as they are full
+		assert.NotZero(t, sent.Len())
+		// the rows of the blocks sent so far
+		assert.Equal(t, int64(6), batch.RowsAffected())
+		if assert.NoError(t, batch.Send()) {
+			assert.Equal(t, []uint64{3, 3, 2}, sentRows(&sent))
+			assert.Equal(t, int64(8), batch.RowsAffected())
+		}
+	}
+	{
+		var sent bytes.Buffer
+		batch := newBatch(&sent, 3, 0)
+		assert.NoError(t, batch.Column(0).Append(uint32(1), uint32(2), uint32(3), uint32(4), uint32(5)))
+		// the block is sent once the columns hold the same number of rows
+		assert.Zero(t, sent.Len())
+		assert.NoError(t, batch.Column(1).Append("a", "b", "c", "d", "e"))
+		assert.NotZero(t, sent.Len())
+		if assert.NoError(t, batch.Append(uint32(6), "f")) && assert.NoError(t, batch.Send()) {
+			assert.Equal(t, []uint64{5, 1}, sentRows(&sent))
+		}
+	}
+	{
+		var sent bytes.Buffer
+		// a row is 4 bytes of UInt32 and 2 bytes of String
+		batch := newBatch(&sent, 0, 12)
+		for i := 0; i < 5; i++ {
+			if !assert.NoError(t, batch.Append(uint32(i), "v")) {
+				return
+			}
+		}
+		if assert.NoError(t, batch.Send()) {
+			assert.Equal(t, []uint64{2, 2, 1}, sentRows(&sent))
+			assert.Equal(t, int64(5), batch.RowsAffected())
+		}
+	}
+}
+
+func Test_BatchAbort(t *testing.T) {
+	newBatch := func(stub *stubConn) (*clickhouse, *batch) {
+		conn := newStubConnect(t, stub, connOptions{})
+		block := &data.Block{NumColumns: 2}
+		for _, chType := range []string{"UInt32", "String"} {
+			c, err := column.Factory("column_"+chType, chType, time.UTC)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			block.Columns = append(block.Columns, c)
+		}
+		ch := &clickhouse{
+			logf:     func(string, ...interface{}) {},
+			conn:     conn,
+			block:    block,
+			settings: &querySettings{},
+			encoder:  binary.NewEncoder(conn),
+			decoder:  binary.NewDecoder(conn),
+		}
+		block.Reserve()
+		return ch, &batch{ch: ch, block: block, counts: make([]int, len(block.Columns)), maxRows: 3}
+	}
+	{
+		var received bytes.Buffer
+		// the end of the INSERT, then of the following query
+		binary.NewEncoder(&received).Uvarint(protocol.ServerEndOfStream)
+		binary.NewEncoder(&received).Uvarint(protocol.ServerEndOfStream)
+		stub := &stubConn{data: received.Bytes(), err: io.EOF}
+		ch, batch := newBatch(stub)
+		assert.NoError(t, batch.Append(uint32(1), "a"))
+		assert.NoError(t, batch.Column(0).Append(uint32(2)))
+		if assert.NoError(t, batch.Abort()) {
+			// only the empty block ending the data is sent
+			decoder := binary.NewDecoder(bytes.NewReader(stub.written))
+			if packet, err := decoder.Uvarint(); assert.NoError(t, err) && assert.Equal(t, uint64(protocol.ClientData), packet) {
+				decoder.String()
+				var block data.Block
+				if assert.NoError(t, block.Read(&data.ServerInfo{}, decoder)) {
+					assert.Zero(t, block.NumColumns)
+					assert.Zero(t, block.NumRows)
+				}
+			}
+			assert.Nil(t, ch.block)
+			assert.False(t, ch.conn.isClosed())
+		}
+		assert.Equal(t, ErrBatchSent, batch.Append(uint32(3), "c"))
+		assert.Equal(t, ErrBatchSent, batch.Abort())
+		// the connection runs the next query
+		stub.written = nil
+		if _, err := ch.ExecContext(context.Background(), "SELECT 1", nil); assert.NoError(t, err) {
+			assert.Contains(t, string(stub.written), "SELECT 1")
+		}
+	}
+	{
+		stub := &stubConn{err: io.EOF}
+		ch, batch := newBatch(stub)
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, batch.Append(uint32(i), "value"))
+		}
+		// the rows of the sent block would be inserted, the connection is closed
+		written := len(stub.written)
+		if assert.NoError(t, batch.Abort()) {
+			assert.Len(t, stub.written, written)
+			assert.True(t, ch.conn.isClosed())
+			assert.Nil(t, ch.block)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package clickhouse
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,6 +41,8 @@ var (
 	logOutput   io.Writer = os.Stdout
 	hostname, _           = os.Hostname()
 	poolInit    sync.Once
+	// verifyBlocksWarning logs once that verify_blocks can't verify the uncompressed blocks
+	verifyBlocksWarning sync.Once
 )
 
 func init() {
@@ -64,6 +67,28 @@ func (d *bootstrap) Open(dsn string) (driver.Conn, error) {
 	return Open(dsn)
 }
 
+// OpenConnector implements driver.DriverContext so that database/sql passes
+// the caller's context down to the connection dialing.
+func (d *bootstrap) OpenConnector(dsn string) (driver.Connector, error) {
+	return &connector{
+		dsn:    dsn,
+		driver: d,
+	}, nil
+}
+
+type connector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return openConn(ctx, c.dsn)
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
 // SetLogOutput allows to change output of the default logger
 func SetLogOutput(output io.Writer) {
 	logOutput = output
@@ -71,37 +96,89 @@ func SetLogOutput(output io.Writer) {
 
 // Open the connection
 func Open(dsn string) (driver.Conn, error) {
-	clickhouse, err := open(dsn)
+	return openConn(context.Background(), dsn)
+}
+
+// openConn opens the connection of the DSN: over the HTTP interface for the http:// and https:// DSNs,
+// over the native protocol otherwise.
+func openConn(ctx context.Context, dsn string) (driver.Conn, error) {
+	if strings.HasPrefix(dsn, "http://") || strings.HasPrefix(dsn, "https://") {
+		conn, err := openHTTP(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	clickhouse, err := open(ctx, dsn)
 	if err != nil {
 		return nil, err
 	}
+	return clickhouse, nil
+}
+
+// hostWeightRe matches the weight suffix of the DSN host, e.g. tcp://host1:9000*3
+var hostWeightRe = regexp.MustCompile(`^([^:/?#]+://[^/?#*]*)\*([^/?#]*)`)
 
-	return clickhouse, err
+// splitHostWeight splits the optional weight suffix from the host, unweighted hosts have weight 1.
+func splitHostWeight(host string) (string, int, error) {
+	idx := strings.LastIndex(host, "*")
+	if idx == -1 {
+		return host, 1, nil
+	}
+	weight, err := strconv.Atoi(host[idx+1:])
+	if err != nil || weight < 1 {
+		return "", 0, fmt.Errorf("invalid weight of host %s", host)
+	}
+	return host[:idx], weight, nil
 }
 
-func open(dsn string) (*clickhouse, error) {
+func open(ctx context.Context, dsn string) (*clickhouse, error) {
+	hostWeight := 1
+	if match := hostWeightRe.FindStringSubmatch(dsn); match != nil {
+		var err error
+		if _, hostWeight, err = splitHostWeight(match[0]); err != nil {
+			return nil, err
+		}
+		dsn = match[1] + dsn[len(match[0]):]
+	}
 	url, err := url.Parse(dsn)
 	if err != nil {
 		return nil, err
 	}
 	var (
 		hosts            = []string{url.Host}
+		weights          = []int{hostWeight}
 		query            = url.Query()
 		secure           = false
 		skipVerify       = false
+		verifyOCSP       = false
+		allowMissingOCSP = false
 		tlsConfigName    = query.Get("tls_config")
 		noDelay          = true
+		strictSocketOpts = false
+		strictDeadlines  = false
 		compress         = false
 		database         = query.Get("database")
 		username         = query.Get("username")
 		password         = query.Get("password")
 		blockSize        = 1000000
 		connTimeout      = DefaultConnTimeout
+		totalDialTimeout time.Duration
 		readTimeout      = DefaultReadTimeout
 		writeTimeout     = DefaultWriteTimeout
+		keepAlive        time.Duration
+		heartbeat        time.Duration
+		idleTimeout      time.Duration
+		dialRetries      int
+		readBufferSize   int
+		dialRetryBackoff time.Duration
 		connOpenStrategy = connOpenRandom
+		parallelDial     = 1
 		poolSize         = 100
 	)
+	if url.Scheme == "unix" {
+		hosts = []string{unixSocketPrefix + url.Path}
+	}
 	if len(database) == 0 {
 		database = DefaultDatabase
 	}
@@ -111,29 +188,72 @@ func open(dsn string) (*clickhouse, error) {
 	if v, err := strconv.ParseBool(query.Get("no_delay")); err == nil {
 		noDelay = v
 	}
+	if v, err := strconv.ParseBool(query.Get("strict_socket_options")); err == nil {
+		strictSocketOpts = v
+	}
+	if v, err := strconv.ParseBool(query.Get("strict_deadlines")); err == nil {
+		strictDeadlines = v
+	}
 	tlsConfig := getTLSConfigClone(tlsConfigName)
 	if tlsConfigName != "" && tlsConfig == nil {
 		return nil, fmt.Errorf("invalid tls_config - no config registered under name %s", tlsConfigName)
 	}
-	secure = tlsConfig != nil
+	if tlsConfig, err = loadTLSFiles(tlsConfig, query.Get("tls_cert"), query.Get("tls_key"), query.Get("tls_key_password"), query.Get("tls_ca")); err != nil {
+		return nil, err
+	}
+	tlsMinVersion, err := parseTLSVersion(query.Get("tls_min_version"))
+	if err != nil {
+		return nil, err
+	}
+	tlsFingerprints, err := parseFingerprints(query.Get("tls_cert_fingerprint"))
+	if err != nil {
+		return nil, err
+	}
+	secure = tlsConfig != nil || len(tlsFingerprints) != 0
 	if v, err := strconv.ParseBool(query.Get("secure")); err == nil {
 		secure = v
 	}
 	if v, err := strconv.ParseBool(query.Get("skip_verify")); err == nil {
 		skipVerify = v
 	}
+	switch v := query.Get("verify_ocsp"); v {
+	case "lenient":
+		verifyOCSP, allowMissingOCSP = true, true
+	default:
+		if b, err := strconv.ParseBool(v); err == nil {
+			verifyOCSP = b
+		}
+	}
 	if duration, err := strconv.ParseFloat(query.Get("timeout"), 64); err == nil {
 		connTimeout = time.Duration(duration * float64(time.Second))
 	}
+	if duration, err := strconv.ParseFloat(query.Get("total_connect_timeout"), 64); err == nil {
+		totalDialTimeout = time.Duration(duration * float64(time.Second))
+	}
 	if duration, err := strconv.ParseFloat(query.Get("read_timeout"), 64); err == nil {
 		readTimeout = time.Duration(duration * float64(time.Second))
 	}
 	if duration, err := strconv.ParseFloat(query.Get("write_timeout"), 64); err == nil {
 		writeTimeout = time.Duration(duration * float64(time.Second))
 	}
+	if duration, err := time.ParseDuration(query.Get("keep_alive")); err == nil {
+		keepAlive = duration
+	}
+	if duration, err := time.ParseDuration(query.Get("heartbeat")); err == nil {
+		heartbeat = duration
+	}
+	if duration, err := time.ParseDuration(query.Get("idle_timeout")); err == nil {
+		idleTimeout = duration
+	}
 	if size, err := strconv.ParseInt(query.Get("block_size"), 10, 64); err == nil {
 		blockSize = int(size)
 	}
+	if size, err := strconv.ParseInt(query.Get("read_buffer_size"), 10, 64); err == nil {
+		if size != 0 && size < minReadBufferSize {
+			return nil, fmt.Errorf("invalid read_buffer_size - must be at least %d bytes", minReadBufferSize)
+		}
+		readBufferSize = int(size)
+	}
 	if size, err := strconv.ParseInt(query.Get("pool_size"), 10, 64); err == nil {
 		poolSize = int(size)
 	}
@@ -143,7 +263,12 @@ func open(dsn string) (*clickhouse, error) {
 	if altHosts := strings.Split(query.Get("alt_hosts"), ","); len(altHosts) != 0 {
 		for _, host := range altHosts {
 			if len(host) != 0 {
+				host, weight, err := splitHostWeight(host)
+				if err != nil {
+					return nil, err
+				}
 				hosts = append(hosts, host)
+				weights = append(weights, weight)
 			}
 		}
 	}
@@ -154,6 +279,23 @@ func open(dsn string) (*clickhouse, error) {
 		connOpenStrategy = connOpenInOrder
 	case "time_random":
 		connOpenStrategy = connOpenTimeRandom
+	case "least_conn":
+		connOpenStrategy = connOpenLeastConn
+	}
+	balancerName := query.Get("balancer")
+	balancer := getBalancer(balancerName)
+	if balancerName != "" && balancer == nil {
+		return nil, fmt.Errorf("invalid balancer - no balancer registered under name %s", balancerName)
+	}
+
+	if v, err := strconv.ParseInt(query.Get("parallel_dial"), 10, 64); err == nil {
+		parallelDial = int(v)
+	}
+	if v, err := strconv.ParseInt(query.Get("dial_retries"), 10, 64); err == nil {
+		dialRetries = int(v)
+	}
+	if duration, err := time.ParseDuration(query.Get("dial_retry_backoff")); err == nil {
+		dialRetryBackoff = duration
 	}
 
 	settings, err := makeQuerySettings(query)
@@ -161,53 +303,184 @@ func open(dsn string) (*clickhouse, error) {
 		return nil, err
 	}
 
-	if v, err := strconv.ParseBool(query.Get("compress")); err == nil {
-		compress = v
+	compressMethod := binary.CompressionMethodByte(binary.LZ4)
+	switch v := query.Get("compress"); strings.ToLower(v) {
+	case "":
+	case "lz4":
+		compress = true
+	case "zstd":
+		compress, compressMethod = true, binary.ZSTD
+	default:
+		if compress, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid compress - must be lz4, zstd or a boolean value, got %q", v)
+		}
+	}
+	var compressLevel int
+	if v := query.Get("compress_level"); v != "" {
+		if compressLevel, err = strconv.Atoi(v); err != nil || compressLevel < 0 {
+			return nil, fmt.Errorf("invalid compress_level - must be a non-negative integer, got %q", v)
+		}
+	}
+	if compressMethod == binary.ZSTD {
+		if err := binary.CheckZSTDLevel(compressLevel); err != nil {
+			return nil, fmt.Errorf("invalid compress_level - %v", err)
+		}
+	}
+	var compressBlockSize int
+	if v := query.Get("compress_block_size"); v != "" {
+		if compressBlockSize, err = strconv.Atoi(v); err != nil || compressBlockSize < 0 {
+			return nil, fmt.Errorf("invalid compress_block_size - must be a non-negative integer, got %q", v)
+		}
+	}
+	var retryOnCodes []int32
+	if v := query.Get("retry_on_codes"); v != "" {
+		for _, code := range strings.Split(v, ",") {
+			c, err := strconv.ParseInt(strings.TrimSpace(code), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retry_on_codes - must be a comma separated list of error codes, got %q", v)
+			}
+			retryOnCodes = append(retryOnCodes, int32(c))
+		}
+	}
+	retryAttempts := 1
+	if v := query.Get("retry_attempts"); v != "" {
+		if retryAttempts, err = strconv.Atoi(v); err != nil || retryAttempts < 0 {
+			return nil, fmt.Errorf("invalid retry_attempts - must be a non-negative integer, got %q", v)
+		}
+	}
+	if compress && compressMethod == binary.ZSTD {
+		// the server compresses the blocks it sends by the method of the setting
+		overrides := map[string]interface{}{"network_compression_method": "ZSTD"}
+		if compressLevel != 0 {
+			overrides["network_zstd_compression_level"] = compressLevel
+		}
+		if settings, err = settings.with(overrides); err != nil {
+			return nil, err
+		}
+	}
+	allowExperimental, _ := strconv.ParseBool(query.Get("allow_experimental"))
+	allowMultiStatements, _ := strconv.ParseBool(query.Get("allow_multi_statements"))
+	traceStatement, err := parseTraceStatement(query.Get("trace_statement"))
+	if err != nil {
+		return nil, err
 	}
+	clientInfo, err := parseClientInfo(query.Get("client_name"), query.Get("client_version"))
+	if err != nil {
+		return nil, err
+	}
+	// the settings of the DSN have been checked
+	maxQuerySize, _ := strconv.ParseUint(query.Get("max_query_size"), 10, 64)
 
 	var (
 		ch = clickhouse{
-			logf:      func(string, ...interface{}) {},
-			settings:  settings,
-			compress:  compress,
-			blockSize: blockSize,
+			logf:                 func(string, ...interface{}) {},
+			settings:             settings,
+			compress:             compress,
+			compressMethod:       compressMethod,
+			compressLevel:        compressLevel,
+			compressBlockSize:    compressBlockSize,
+			retryOnCodes:         retryOnCodes,
+			retryAttempts:        retryAttempts,
+			maxQuerySize:         maxQuerySize,
+			tracer:               getTracer(),
+			traceStatement:       traceStatement,
+			blockSize:            blockSize,
+			allowExperimental:    allowExperimental,
+			allowMultiStatements: allowMultiStatements,
+			quotaKey:             query.Get("quota_key"),
+			database:             database,
+			ClientInfo:           clientInfo,
+			dsnCredentials:       credentials{username: username, password: password},
 			ServerInfo: data.ServerInfo{
 				Timezone: time.Local,
 			},
 		}
 		logger = log.New(logOutput, "[clickhouse]", 0)
 	)
-	if debug, err := strconv.ParseBool(url.Query().Get("debug")); err == nil && debug {
+	debug, _ := strconv.ParseBool(url.Query().Get("debug"))
+	if debug {
 		ch.logf = logger.Printf
 	}
+	if custom := getLogger(); custom != nil {
+		// the registered logger receives the debug logs too
+		ch.logger, ch.logf = custom, loggerLogf(custom)
+	}
+	if verifyBlocks, _ := strconv.ParseBool(query.Get("verify_blocks")); verifyBlocks && !compress {
+		// the checksums of the blocks are those of their compressed frames, the server sends none without compression
+		verifyBlocksWarning.Do(func() {
+			logEvent(ch.logger, logger.Printf, LogWarn, "[verify blocks] the server sends no checksums of the uncompressed blocks, set compress to verify them")
+		})
+	}
+	// the connection opened with the credentials of a context is authenticated as their user
+	ch.credentials = contextCredentials(ctx, ch.dsnCredentials)
 	ch.logf("host(s)=%s, database=%s, username=%s",
 		strings.Join(hosts, ", "),
 		database,
-		username,
+		ch.credentials.username,
 	)
 	options := connOptions{
-		secure:       secure,
-		tlsConfig:    tlsConfig,
-		skipVerify:   skipVerify,
-		hosts:        hosts,
-		connTimeout:  connTimeout,
-		readTimeout:  readTimeout,
-		writeTimeout: writeTimeout,
-		noDelay:      noDelay,
-		openStrategy: connOpenStrategy,
-		logf:         ch.logf,
-	}
-	if ch.conn, err = dial(options); err != nil {
-		return nil, err
+		secure:              secure,
+		tlsConfig:           tlsConfig,
+		tlsServerName:       query.Get("tls_server_name"),
+		tlsMinVersion:       tlsMinVersion,
+		tlsFingerprints:     tlsFingerprints,
+		verifyOCSP:          verifyOCSP,
+		allowMissingOCSP:    allowMissingOCSP,
+		skipVerify:          skipVerify,
+		hosts:               hosts,
+		socks5:              query.Get("socks5"),
+		weights:             weights,
+		connTimeout:         connTimeout,
+		totalDialTimeout:    totalDialTimeout,
+		readTimeout:         readTimeout,
+		writeTimeout:        writeTimeout,
+		strictDeadlines:     strictDeadlines,
+		noDelay:             noDelay,
+		strictSocketOptions: strictSocketOpts,
+		keepAlive:           keepAlive,
+		heartbeat:           heartbeat,
+		idleTimeout:         idleTimeout,
+		openStrategy:        connOpenStrategy,
+		balancer:            balancer,
+		balancerName:        balancerName,
+		parallelDial:        parallelDial,
+		dialRetries:         dialRetries,
+		readBufferSize:      readBufferSize,
+		dialRetryBackoff:    dialRetryBackoff,
+		logf:                ch.logf,
+		logger:              ch.logger,
+		metrics:             getMetrics(),
+		dumpPackets:         debug,
 	}
-	logger.SetPrefix(fmt.Sprintf("[clickhouse][connect=%d]", ch.conn.ident))
-	ch.buffer = bufio.NewWriter(ch.conn)
-
-	ch.decoder = binary.NewDecoderWithCompress(ch.conn)
-	ch.encoder = binary.NewEncoderWithCompress(ch.buffer)
+	ch.reconnect = func(ctx context.Context) error {
+		conn, err := dial(ctx, options)
+		if err != nil {
+			return &connectionError{err: err}
+		}
+		logger.SetPrefix(fmt.Sprintf("[clickhouse][connect=%d]", conn.ident))
+		ch.conn = conn
+		ch.decoder = binary.NewDecoderWithCompress(conn)
+		if ch.encoder, err = binary.NewEncoderWithCompressMethod(conn, ch.compressMethod, ch.compressLevel, ch.compressBlockSize); err != nil {
+			conn.Close()
+			return fmt.Errorf("invalid compress_level - %v", err)
+		}
+		ch.decoder.OnCompressedBlock(ch.blockDecompressed)
+		if metrics := options.metrics; metrics != nil {
+			ch.encoder.OnCompressedBlock(metrics.BlockCompressed)
+		}
 
-	if err := ch.hello(database, username, password); err != nil {
-		ch.conn.Close()
+		if err := ch.hello(database, ch.credentials.username, ch.credentials.password); err != nil {
+			conn.Close()
+			if _, ok := err.(*Exception); ok {
+				return err
+			}
+			return &connectionError{err: err}
+		}
+		ch.currentDatabase = database
+		conn.startHeartbeat(options.heartbeat)
+		return nil
+	}
+	if err := ch.reconnect(ctx); err != nil {
 		return nil, err
 	}
 	return &ch, nil
@@ -216,7 +489,7 @@ func open(dsn string) (*clickhouse, error) {
 func (ch *clickhouse) hello(database, username, password string) error {
 	ch.logf("[hello] -> %s", ch.ClientInfo)
 	{
-		ch.encoder.Uvarint(protocol.ClientHello)
+		ch.writePacket(protocol.ClientHello)
 		if err := ch.ClientInfo.Write(ch.encoder); err != nil {
 			return err
 		}
@@ -231,13 +504,17 @@ func (ch *clickhouse) hello(database, username, password string) error {
 
 	}
 	{
-		packet, err := ch.decoder.Uvarint()
+		packet, err := ch.readPacket()
 		if err != nil {
 			return err
 		}
 		switch packet {
 		case protocol.ServerException:
-			return ch.exception()
+			err := ch.exception()
+			if e, ok := err.(*Exception); ok {
+				e.auth = isAuthCode(e.Code)
+			}
+			return err
 		case protocol.ServerHello:
 			if err := ch.ServerInfo.Read(ch.decoder); err != nil {
 				return err
@@ -249,6 +526,16 @@ func (ch *clickhouse) hello(database, username, password string) error {
 			return fmt.Errorf("[hello] unexpected packet [%d] from server", packet)
 		}
 	}
+	if ch.conn.dump != nil {
+		ch.conn.dump.endRead()
+	}
+	ch.conn.serverVersion = ServerVersion{
+		Name:     ch.ServerInfo.Name,
+		Major:    ch.ServerInfo.MajorVersion,
+		Minor:    ch.ServerInfo.MinorVersion,
+		Revision: ch.ServerInfo.Revision,
+	}
+	ch.conn.serverTimezone = ch.ServerInfo.Timezone
 	ch.logf("[hello] <- %s", ch.ServerInfo)
 	return nil
 }
@@ -1,10 +1,17 @@
 package clickhouse
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_bootstrap_Open(t *testing.T) {
@@ -94,3 +101,110 @@ func Test_now(t *testing.T) {
 		})
 	}
 }
+
+func Test_splitHostWeight(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		weight  int
+		wantErr bool
+	}{
+		{host: "host1:9000", want: "host1:9000", weight: 1},
+		{host: "host1:9000*3", want: "host1:9000", weight: 3},
+		{host: "unix:///var/run/clickhouse.sock*2", want: "unix:///var/run/clickhouse.sock", weight: 2},
+		{host: "host1:9000*0", wantErr: true},
+		{host: "host1:9000*x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, weight, err := splitHostWeight(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("splitHostWeight() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want || weight != tt.weight {
+				t.Errorf("splitHostWeight() = %s, %d, want %s, %d", got, weight, tt.want, tt.weight)
+			}
+		})
+	}
+	if _, err := Open("tcp://127.0.0.1:9000*abc"); err == nil || !strings.Contains(err.Error(), "invalid weight") {
+		t.Errorf("Open() error = %v, want invalid weight", err)
+	}
+}
+
+func Test_OpenInvalidReadBufferSize(t *testing.T) {
+	if _, err := Open("tcp://127.0.0.1:9000?read_buffer_size=8"); err == nil || !strings.Contains(err.Error(), "read_buffer_size") {
+		t.Errorf("Open() error = %v, want invalid read_buffer_size", err)
+	}
+}
+
+func Test_OpenCompress(t *testing.T) {
+	for _, value := range []string{"lz4", "LZ4", "1", "true", "zstd", "zstd&compress_level=9", "lz4&compress_block_size=65536"} {
+		if _, err := Open("tcp://127.0.0.1:9000?compress=" + value); err != nil && strings.Contains(err.Error(), "compress") {
+			t.Errorf("Open() compress=%s error = %v", value, err)
+		}
+	}
+	if _, err := Open("tcp://127.0.0.1:9000?compress=brotli"); err == nil || !strings.Contains(err.Error(), "invalid compress") {
+		t.Errorf("Open() error = %v, want invalid compress", err)
+	}
+	if _, err := Open("tcp://127.0.0.1:9000?compress=zstd&compress_level=high"); err == nil || !strings.Contains(err.Error(), "invalid compress_level") {
+		t.Errorf("Open() error = %v, want invalid compress_level", err)
+	}
+	if _, err := Open("tcp://127.0.0.1:9000?compress=zstd&compress_level=23"); err == nil || err.Error() != "invalid compress_level - must be between 0 and 22, got 23" {
+		t.Errorf("Open() error = %v, want invalid compress_level - must be between 0 and 22, got 23", err)
+	}
+	if _, err := Open("tcp://127.0.0.1:9000?compress=1&compress_block_size=1MB"); err == nil || !strings.Contains(err.Error(), "invalid compress_block_size") {
+		t.Errorf("Open() error = %v, want invalid compress_block_size", err)
+	}
+}
+
+func Test_OpenRetry(t *testing.T) {
+	if _, err := Open("tcp://127.0.0.1:9000?retry_on_codes=159,%20202&retry_attempts=2"); err != nil && strings.Contains(err.Error(), "retry") {
+		t.Errorf("Open() error = %v", err)
+	}
+	for param, want := range map[string]string{
+		"retry_on_codes=159,timeout": "invalid retry_on_codes",
+		"retry_attempts=-1":          "invalid retry_attempts",
+	} {
+		if _, err := Open("tcp://127.0.0.1:9000?" + param); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Open() %s error = %v, want %s", param, err, want)
+		}
+	}
+}
+
+func Test_ServerVersion(t *testing.T) {
+	server := newHelloServer(t, data.ServerInfo{
+		Name:         "ClickHouse",
+		MajorVersion: 22,
+		MinorVersion: 3,
+		Revision:     protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE,
+	}, "Europe/Berlin")
+	defer server.close()
+	db, err := sql.Open("clickhouse", "tcp://"+server.addr+"?timeout=1&read_timeout=1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	err = conn.Raw(func(driverConn interface{}) error {
+		ch := driverConn.(interface {
+			ServerVersion() ServerVersion
+			ServerTimezone() *time.Location
+		})
+		assert.Equal(t, ServerVersion{
+			Name:     "ClickHouse",
+			Major:    22,
+			Minor:    3,
+			Revision: protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE,
+		}, ch.ServerVersion())
+		// the patch is not sent to the revision of the driver
+		assert.Equal(t, "ClickHouse 22.3 (revision 54058)", ch.ServerVersion().String())
+		assert.Equal(t, "Europe/Berlin", ch.ServerTimezone().String())
+		return nil
+	})
+	assert.NoError(t, err)
+}
@@ -1,8 +1,8 @@
 package clickhouse
 
 import (
-	"bufio"
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
@@ -11,6 +11,7 @@ import (
 	"reflect"
 	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/binary"
@@ -35,6 +36,22 @@ type ExternalTable struct {
 var (
 	ErrInsertInNotBatchMode = errors.New("insert statement supported only in the batch mode (use begin/commit)")
 	ErrLimitDataRequestInTx = errors.New("data request has already been prepared in transaction")
+	ErrBatchSent            = errors.New("clickhouse: the batch has already been sent or aborted")
+	// ErrChecksumMismatch is returned when a compressed block of the server got corrupted on the way,
+	// the connection is closed since the rest of the response can't be trusted either.
+	ErrChecksumMismatch = binary.ErrChecksumMismatch
+	// ErrConnectionFailed is matched by errors.Is with the errors of the connections that couldn't be established:
+	// none of the servers could be dialed (see DialError), or the handshake with the server broke off.
+	ErrConnectionFailed = errors.New("clickhouse: connection failed")
+	// ErrAuthFailed is matched by errors.Is with the *Exception of the server rejecting the credentials
+	// of the DSN when the connection is established.
+	ErrAuthFailed = errors.New("clickhouse: authentication failed")
+	// ErrTruncatedResult is matched by errors.Is, as io.ErrUnexpectedEOF, with the error of the rows whose connection
+	// was closed, once some of them had been returned, before the server ended their stream: the rows read are not
+	// all the rows of the results.
+	ErrTruncatedResult = errors.New("clickhouse: the connection was closed before the end of the results")
+	// ErrQueryTooLarge is returned, wrapped with the sizes, for a query longer than max_query_size, it isn't sent.
+	ErrQueryTooLarge = errors.New("clickhouse: query too large")
 )
 
 var (
@@ -50,15 +67,63 @@ type clickhouse struct {
 	logf          logger
 	conn          *connect
 	block         *data.Block
-	buffer        *bufio.Writer
 	decoder       *binary.Decoder
 	encoder       *binary.Encoder
 	settings      *querySettings
 	compress      bool
 	blockSize     int
 	inTransaction bool
+	// allowExperimental enables the experimental column types, e.g. Object('json'), see checkExperimental
+	allowExperimental bool
+	// allowMultiStatements makes ExecContext run the statements of a query separated by semicolons one after the other
+	allowMultiStatements bool
+	// onProgress is called with the progress of the query, see WithProgress
+	onProgress func(Progress)
+	// onProfileInfo is called with the profile info of the query, see WithProfileInfo
+	onProfileInfo func(ProfileInfo)
+	// columnar makes the blocks of the query read into the slices of the types of their columns, see WithColumnarResults
+	columnar bool
+	// location is the location of the DateTime values of the query, the timezone of the server if nil, see WithTimezone
+	location *time.Location
+	// stats are the statistics of the query reported to onQueryStats, nil unless the query has a callback, see WithQueryStats
+	stats        *queryStats
+	onQueryStats func(QueryStats)
+	queryState   int32  // queryIdle, queryReading or queryCanceled, accessed atomically
+	cancelErr    error  // the error of the context of the canceled query, set before queryCanceled
+	sessionID    string // the session the connection belongs to, see WithSessionID
+	// database is the database of the DSN, currentDatabase the database the connection is switched to, see WithDatabase
+	database        string
+	currentDatabase string
+	// credentials are the credentials of the hello of the connection, dsnCredentials those of the DSN, see WithCredentials
+	credentials    credentials
+	dsnCredentials credentials
+	quotaKey       string // the quota key of the DSN, see WithQuotaKey
+	// compressMethod is the method compressing the blocks sent, LZ4 or ZSTD at compressLevel (its default level if 0)
+	compressMethod binary.CompressionMethodByte
+	compressLevel  int
+	// compressBlockSize is the size in bytes of the blocks compressed, see binary.NewCompressWriterMethod
+	compressBlockSize int
+	// retryOnCodes are the codes of the exceptions retried retryAttempts times on a fresh connection, see retryQuery
+	retryOnCodes  []int32
+	retryAttempts int
+	// maxQuerySize is the max_query_size of the DSN, the queries longer are failed by checkQuerySize
+	maxQuerySize uint64
+	// logger receives the structured logs, nil if none is registered: they are printed by logf, see logEvent
+	logger Logger
+	// tracer starts the spans of the queries, nil if none is registered; traceStatement is trace_statement of the DSN
+	tracer         Tracer
+	traceStatement string
+	// reconnect replaces the connection by a fresh one to the servers of the DSN
+	reconnect func(ctx context.Context) error
 }
 
+// the states of the query of the connection, see cancel
+const (
+	queryIdle     int32 = iota
+	queryReading        // the query has been sent and its packets are read, it can be canceled by the Cancel packet
+	queryCanceled       // the Cancel packet has been sent, the packets left are read until the end of the stream
+)
+
 func (ch *clickhouse) Prepare(query string) (driver.Stmt, error) {
 	return ch.prepareContext(context.Background(), query)
 }
@@ -70,7 +135,7 @@ func (ch *clickhouse) PrepareContext(ctx context.Context, query string) (driver.
 func (ch *clickhouse) prepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	ch.logf("[prepare] %s", query)
 	switch {
-	case ch.conn.closed:
+	case ch.conn.isClosed():
 		return nil, driver.ErrBadConn
 	case ch.block != nil:
 		return nil, ErrLimitDataRequestInTx
@@ -121,7 +186,7 @@ func (ch *clickhouse) beginTx(ctx context.Context, opts txOptions) (*clickhouse,
 	switch {
 	case ch.inTransaction:
 		return nil, sql.ErrTxDone
-	case ch.conn.closed:
+	case ch.conn.isClosed():
 		return nil, driver.ErrBadConn
 	}
 	if finish := ch.watchCancel(ctx); finish != nil {
@@ -144,7 +209,7 @@ func (ch *clickhouse) Commit() error {
 	switch {
 	case !ch.inTransaction:
 		return sql.ErrTxDone
-	case ch.conn.closed:
+	case ch.conn.isClosed():
 		return driver.ErrBadConn
 	}
 	if ch.block != nil {
@@ -172,12 +237,17 @@ func (ch *clickhouse) Rollback() error {
 		ch.block.Reset()
 	}
 	ch.block = nil
-	ch.buffer = nil
 	ch.inTransaction = false
 	return ch.conn.Close()
 }
 
 func (ch *clickhouse) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+// checkNamedValue converts the value to the types the columns write, the values of the other types are rejected
+// by the columns as they are written.
+func checkNamedValue(nv *driver.NamedValue) error {
 	switch nv.Value.(type) {
 	case ExternalTable, column.IP, column.UUID:
 		return nil
@@ -235,13 +305,81 @@ func (ch *clickhouse) CheckNamedValue(nv *driver.NamedValue) error {
 	return nil
 }
 
+// RemoteHost returns the address of the server the connection is established to.
+func (ch *clickhouse) RemoteHost() string {
+	return ch.conn.RemoteAddr().String()
+}
+
+// ServerVersion is the name and the version of the server sent by its hello.
+// The servers send the patch of their version to the clients of the protocol revision 54401 or later only,
+// the revision of the driver is older: Patch is left zero and String reports the revision instead.
+type ServerVersion struct {
+	Name     string
+	Major    uint64
+	Minor    uint64
+	Patch    uint64
+	Revision uint64
+}
+
+func (v ServerVersion) String() string {
+	if v.Patch == 0 {
+		return fmt.Sprintf("%s %d.%d (revision %d)", v.Name, v.Major, v.Minor, v.Revision)
+	}
+	return fmt.Sprintf("%s %d.%d.%d", v.Name, v.Major, v.Minor, v.Patch)
+}
+
+// ServerVersion returns the version of the server sent by its hello.
+func (ch *clickhouse) ServerVersion() ServerVersion {
+	return ch.conn.serverVersion
+}
+
+// ServerTimezone returns the timezone of the server sent by its hello, the DateTime values of the server
+// are decoded in it. It is time.Local if the server is older than the revision 54058, which sends no timezone.
+func (ch *clickhouse) ServerTimezone() *time.Location {
+	return ch.conn.serverTimezone
+}
+
+// TLSConnectionState returns the state negotiated by the TLS handshake, such as the protocol version,
+// the cipher suite and the peer certificates. The boolean is false if the connection is not secure.
+func (ch *clickhouse) TLSConnectionState() (tls.ConnectionState, bool) {
+	if ch.conn.tlsState == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *ch.conn.tlsState, true
+}
+
+// IsValid implements driver.Validator, so that database/sql discards the connection before reuse
+// if it is closed or has been idle for longer than idle_timeout.
+func (ch *clickhouse) IsValid() bool {
+	if ch.conn.IsExpired() {
+		ch.logf("[connect] idle for longer than %s, closing", ch.conn.idleTimeout)
+		ch.conn.Close()
+	}
+	return !ch.conn.isClosed()
+}
+
+// IOError returns the read or write error that broke the connection, nil if there was none.
+// The driver reports it as driver.ErrBadConn, so that database/sql retries on another connection;
+// a timeout can be told from other errors with errors.Is(err, os.ErrDeadlineExceeded).
+func (ch *clickhouse) IOError() error {
+	return ch.conn.ioErr
+}
+
 func (ch *clickhouse) Close() error {
 	ch.block = nil
 	return ch.conn.Close()
 }
 
+// CloseGracefully closes the connection once the read or write in progress is done,
+// but waits no longer than the timeout.
+func (ch *clickhouse) CloseGracefully(timeout time.Duration) error {
+	return ch.conn.CloseGracefully(timeout)
+}
+
 func (ch *clickhouse) process() error {
-	packet, err := ch.decoder.Uvarint()
+	defer ch.conn.release()
+	defer ch.reportQueryStats()
+	packet, err := ch.readPacket()
 	if err != nil {
 		return err
 	}
@@ -259,16 +397,16 @@ func (ch *clickhouse) process() error {
 				return err
 			}
 			ch.logf("[process] <- progress: rows=%d, bytes=%d, total rows=%d",
-				progress.rows,
-				progress.bytes,
-				progress.totalRows,
+				progress.Rows,
+				progress.Bytes,
+				progress.TotalRows,
 			)
 		case protocol.ServerProfileInfo:
 			profileInfo, err := ch.profileInfo()
 			if err != nil {
 				return err
 			}
-			ch.logf("[process] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.rows, profileInfo.bytes, profileInfo.blocks)
+			ch.logf("[process] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.Rows, profileInfo.Bytes, profileInfo.Blocks)
 		case protocol.ServerData:
 			block, err := ch.readBlock()
 			if err != nil {
@@ -282,16 +420,62 @@ func (ch *clickhouse) process() error {
 			ch.conn.Close()
 			return fmt.Errorf("[process] unexpected packet [%d] from server", packet)
 		}
-		if packet, err = ch.decoder.Uvarint(); err != nil {
+		if packet, err = ch.readPacket(); err != nil {
 			return err
 		}
 	}
 }
 
-func (ch *clickhouse) cancel() error {
+// readPacket reads the type of the next packet of the server.
+func (ch *clickhouse) readPacket() (uint64, error) {
+	packet, err := ch.decoder.Uvarint()
+	if err == nil && ch.conn.dump != nil {
+		ch.conn.dump.beginRead(packet)
+	}
+	return packet, err
+}
+
+// writePacket writes the type of the packet of the client about to be written.
+func (ch *clickhouse) writePacket(packet uint64) error {
+	if ch.conn.dump != nil {
+		ch.conn.dump.beginWrite(packet)
+	}
+	return ch.encoder.Uvarint(packet)
+}
+
+// beginReading marks the query sent as cancelable by the Cancel packet until endReading is called.
+func (ch *clickhouse) beginReading() {
+	atomic.StoreInt32(&ch.queryState, queryReading)
+}
+
+// endReading marks the packets of the query as read, it returns the error of the context if the query was canceled.
+func (ch *clickhouse) endReading() error {
+	if atomic.SwapInt32(&ch.queryState, queryIdle) == queryCanceled {
+		return ch.cancelErr
+	}
+	return nil
+}
+
+// cancel cancels the query of the connection for the cause, the error of its context. The packets of a query
+// being read are still read after the Cancel packet, until the server ends the stream: the connection is kept.
+// The connection is closed otherwise, e.g. while the rows of an INSERT are written.
+func (ch *clickhouse) cancel(cause error) error {
+	ch.cancelErr = cause
+	if atomic.CompareAndSwapInt32(&ch.queryState, queryReading, queryCanceled) {
+		ch.logf("[cancel request] the packets left are drained")
+		ch.Lock()
+		err := ch.writePacket(protocol.ClientCancel)
+		if err == nil {
+			err = ch.encoder.Flush()
+		}
+		ch.Unlock()
+		if err == nil {
+			return nil
+		}
+	}
 	ch.logf("[cancel request]")
 	// even if we fail to write the cancel, we still need to close
-	err := ch.encoder.Uvarint(protocol.ClientCancel)
+	err := ch.writePacket(protocol.ClientCancel)
 	if err == nil {
 		err = ch.encoder.Flush()
 	}
@@ -308,7 +492,7 @@ func (ch *clickhouse) watchCancel(ctx context.Context) func() {
 		go func() {
 			select {
 			case <-done:
-				ch.cancel()
+				ch.cancel(ctx.Err())
 				finished <- struct{}{}
 				ch.logf("[cancel] <- done")
 			case <-finished:
@@ -327,15 +511,16 @@ func (ch *clickhouse) watchCancel(ctx context.Context) func() {
 
 func (ch *clickhouse) ExecContext(ctx context.Context, query string,
 	args []driver.NamedValue) (driver.Result, error) {
+	if ch.allowMultiStatements {
+		if statements := splitStatements(query); len(statements) > 1 {
+			return ch.execStatements(ctx, statements, args)
+		}
+	}
 	finish := ch.watchCancel(ctx)
 	defer finish()
 	stmt, err := ch.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	dargs := make([]driver.Value, len(args))
-	for i, nv := range args {
-		dargs[i] = nv.Value
-	}
-	return stmt.Exec(dargs)
+	return stmt.(driver.StmtExecContext).ExecContext(ctx, args)
 }
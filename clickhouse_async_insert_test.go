@@ -0,0 +1,69 @@
+//go:build integration
+// +build integration
+
+package clickhouse_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AsyncInsert(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_async_insert (
+				id   UInt32,
+				wait UInt8
+			) Engine=MergeTree ORDER BY id
+		`
+		dml = `INSERT INTO clickhouse_test_async_insert (id, wait) VALUES (?, ?)`
+	)
+	connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer connect.Close()
+	if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_async_insert"); !assert.NoError(t, err) {
+		return
+	}
+	if _, err := connect.Exec(ddl); !assert.NoError(t, err) {
+		return
+	}
+	for wait, flag := range map[bool]uint8{true: 1, false: 0} {
+		ctx := clickhouse.WithAsyncInsert(context.Background(), wait)
+		tx, err := connect.Begin()
+		if !assert.NoError(t, err) {
+			return
+		}
+		stmt, err := tx.PrepareContext(ctx, dml)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for i := 0; i < 10; i++ {
+			if _, err := stmt.Exec(uint32(i), flag); !assert.NoError(t, err) {
+				return
+			}
+		}
+		if !assert.NoError(t, tx.Commit()) {
+			return
+		}
+	}
+	count := func(wait uint8) (count uint64) {
+		err := connect.QueryRow("SELECT count() FROM clickhouse_test_async_insert WHERE wait = ?", wait).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+	// the commit returned once the rows were written
+	assert.Equal(t, uint64(10), count(1))
+	// the rows are eventually written, once the server flushes its buffer
+	deadline := time.Now().Add(10 * time.Second)
+	for count(0) != 10 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, uint64(10), count(0))
+}
@@ -0,0 +1,161 @@
+package clickhouse_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go"
+	"github.com/stretchr/testify/assert"
+)
+
+const batchDDL = `
+	CREATE TABLE clickhouse_test_batch (
+		id    UInt32,
+		name  String,
+		tags  Array(String),
+		score Nullable(Float64)
+	) Engine=Memory
+`
+
+func prepareBatchTable(tb testing.TB) clickhouse.Clickhouse {
+	connect, err := clickhouse.OpenDirect("tcp://127.0.0.1:9000?debug=false")
+	if err != nil {
+		tb.Skip(err)
+	}
+	for _, query := range []string{"DROP TABLE IF EXISTS clickhouse_test_batch", batchDDL} {
+		if _, err := connect.Begin(); !assert.NoError(tb, err) {
+			tb.FailNow()
+		}
+		stmt, err := connect.Prepare(query)
+		if !assert.NoError(tb, err) {
+			tb.FailNow()
+		}
+		if _, err := stmt.Exec([]driver.Value{}); !assert.NoError(tb, err) {
+			tb.FailNow()
+		}
+		if !assert.NoError(tb, connect.Commit()) {
+			tb.FailNow()
+		}
+	}
+	return connect
+}
+
+func Test_PrepareBatch(t *testing.T) {
+	connect := prepareBatchTable(t)
+	defer connect.Close()
+	if batch, err := connect.PrepareBatch(context.Background(), "INSERT INTO clickhouse_test_batch (id, name, tags, score)"); assert.NoError(t, err) {
+		for i := 0; i < 10; i++ {
+			var score interface{}
+			if i%2 == 0 {
+				score = float64(i) / 2
+			}
+			if !assert.NoError(t, batch.Append(uint32(i), fmt.Sprintf("name_%d", i), []string{"a", "b"}, score)) {
+				return
+			}
+		}
+		if err := batch.Append(uint32(10), 10, []string{}, nil); assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "name")
+		}
+		// the column by column appends
+		for i := 11; i < 20; i++ {
+			if !assert.NoError(t, batch.Column(1).Append(fmt.Sprintf("name_%d", i))) {
+				return
+			}
+		}
+		for i := 11; i < 20; i++ {
+			assert.NoError(t, batch.Column(0).Append(uint32(i)))
+			assert.NoError(t, batch.Column(2).Append([]string{}))
+			assert.NoError(t, batch.Column(3).Append(nil))
+		}
+		// the id of the failed row is appended to the first column
+		assert.Error(t, batch.Send())
+		assert.NoError(t, batch.Column(1).Append("name_10"))
+		assert.NoError(t, batch.Column(2).Append([]string{}))
+		assert.NoError(t, batch.Column(3).Append(nil))
+		assert.NoError(t, batch.Send())
+	}
+	if _, err := connect.Begin(); assert.NoError(t, err) {
+		if stmt, err := connect.Prepare("SELECT COUNT() FROM clickhouse_test_batch"); assert.NoError(t, err) {
+			if rows, err := stmt.Query([]driver.Value{}); assert.NoError(t, err) {
+				dest := make([]driver.Value, 1)
+				if assert.NoError(t, rows.Next(dest)) {
+					assert.Equal(t, uint64(20), dest[0])
+				}
+				rows.Close()
+			}
+		}
+		assert.NoError(t, connect.Commit())
+	}
+}
+
+func Test_PrepareBatchBlockSize(t *testing.T) {
+	connect := prepareBatchTable(t)
+	defer connect.Close()
+	ctx := clickhouse.WithBatchBlockSize(context.Background(), 7, 0)
+	if batch, err := connect.PrepareBatch(ctx, "INSERT INTO clickhouse_test_batch (id, name, tags, score)"); assert.NoError(t, err) {
+		for i := 0; i < 100; i++ {
+			if !assert.NoError(t, batch.Append(uint32(i), fmt.Sprintf("name_%d", i), []string{}, nil)) {
+				return
+			}
+		}
+		assert.NoError(t, batch.Send())
+	}
+	// the rows of the 15 blocks sent by the batch
+	if _, err := connect.Begin(); assert.NoError(t, err) {
+		if stmt, err := connect.Prepare("SELECT COUNT(), uniqExact(id) FROM clickhouse_test_batch"); assert.NoError(t, err) {
+			if rows, err := stmt.Query([]driver.Value{}); assert.NoError(t, err) {
+				dest := make([]driver.Value, 2)
+				if assert.NoError(t, rows.Next(dest)) {
+					assert.Equal(t, []driver.Value{uint64(100), uint64(100)}, dest)
+				}
+				rows.Close()
+			}
+		}
+		assert.NoError(t, connect.Commit())
+	}
+}
+
+func Benchmark_PrepareBatch(b *testing.B) {
+	connect := prepareBatchTable(b)
+	defer connect.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		batch, err := connect.PrepareBatch(context.Background(), "INSERT INTO clickhouse_test_batch (id, name, tags, score)")
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 10000; j++ {
+			if err := batch.Append(uint32(j), "name", []string{"a"}, float64(j)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := batch.Send(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_StmtExecInsert(b *testing.B) {
+	connect := prepareBatchTable(b)
+	defer connect.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := connect.Begin(); err != nil {
+			b.Fatal(err)
+		}
+		stmt, err := connect.Prepare("INSERT INTO clickhouse_test_batch (id, name, tags, score) VALUES (?, ?, ?, ?)")
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 10000; j++ {
+			if _, err := stmt.Exec([]driver.Value{uint32(j), "name", []string{"a"}, float64(j)}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := connect.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// cancelServer answers the queries of the connection as a server running them until they are canceled:
+// the query is answered by the head, then by the response once the Cancel packet is received,
+// the next query by the end of the stream.
+func cancelServer(t *testing.T, server net.Conn, head, response []byte) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, 64*1024)
+		// the query, the Cancel packet, the next query
+		for i, answer := range [][]byte{head, response, {protocol.ServerEndOfStream}} {
+			n, err := server.Read(buf)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if i == 1 {
+				assert.Equal(t, []byte{protocol.ClientCancel}, buf[:n])
+			}
+			if len(answer) == 0 {
+				continue
+			}
+			if _, err := server.Write(answer); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	return done
+}
+
+func newCancelClickhouse(t *testing.T, client net.Conn) *clickhouse {
+	conn := newStubConnect(t, client, connOptions{})
+	return &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+}
+
+func Test_CancelExec(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	done := cancelServer(t, server, nil, []byte{protocol.ServerEndOfStream})
+	ch := newCancelClickhouse(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := ch.ExecContext(ctx, "SELECT sleep(3)", nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	// the connection is drained and kept
+	if assert.False(t, ch.conn.isClosed()) {
+		_, err := ch.ExecContext(context.Background(), "SELECT 1", nil)
+		assert.NoError(t, err)
+	}
+	<-done
+}
+
+func Test_CancelRows(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	c, err := column.Factory("value", "UInt8", time.UTC)
+	if !assert.NoError(t, err) {
+		return
+	}
+	header := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	if !assert.NoError(t, header.Write(&data.ServerInfo{}, encoder)) {
+		return
+	}
+	client, server := net.Pipe()
+	defer client.Close()
+	// the server sends the header of the results, then the end of the stream once the query is canceled
+	done := cancelServer(t, server, buf.Bytes(), []byte{protocol.ServerEndOfStream})
+	ch := newCancelClickhouse(t, client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stmt, err := ch.PrepareContext(ctx, "SELECT sleep(3)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	assert.Equal(t, context.Canceled, rows.Next(make([]driver.Value, 1)))
+	assert.NoError(t, rows.Close())
+	if assert.False(t, ch.conn.isClosed()) {
+		_, err := ch.ExecContext(context.Background(), "SELECT 1", nil)
+		assert.NoError(t, err)
+	}
+	<-done
+}
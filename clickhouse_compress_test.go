@@ -152,3 +152,102 @@ func Test_Compress(t *testing.T) {
 		}
 	}
 }
+
+func Benchmark_CompressWideResult(b *testing.B) {
+	const query = `
+		SELECT
+			number
+			, toString(number)
+			, repeat('ClickHouse', 10)
+			, [number, number * 2, number * 3]
+			, toDateTime(number)
+			, number / 3
+		FROM system.numbers
+		LIMIT 100000
+	`
+	for _, compress := range []string{"0", "lz4"} {
+		b.Run("compress="+compress, func(b *testing.B) {
+			connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?compress="+compress)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer connect.Close()
+			if err := connect.Ping(); err != nil {
+				b.Skip(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rows, err := connect.Query(query)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var (
+					number  uint64
+					str     string
+					repeat  string
+					numbers []uint64
+					date    time.Time
+					third   float64
+				)
+				for rows.Next() {
+					if err := rows.Scan(&number, &str, &repeat, &numbers, &date, &third); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if err := rows.Close(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Test_CompressZSTD(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_compress_zstd (
+				id   UInt64,
+				text String
+			) Engine=Memory
+		`
+		dml   = "INSERT INTO clickhouse_test_compress_zstd (id, text) VALUES (?, ?)"
+		query = "SELECT id, text FROM clickhouse_test_compress_zstd ORDER BY id"
+		// the rows span several compressed blocks of the inserts and of the results
+		total = 50000
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=false&compress=zstd&compress_level=5"); assert.NoError(t, err) && assert.NoError(t, connect.Ping()) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_compress_zstd"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < total; i++ {
+							if _, err := stmt.Exec(uint64(i), fmt.Sprintf("the text of the row %d", i)); !assert.NoError(t, err) {
+								return
+							}
+						}
+					}
+					if !assert.NoError(t, tx.Commit()) {
+						return
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var (
+						count int
+						id    uint64
+						text  string
+					)
+					for rows.Next() {
+						if assert.NoError(t, rows.Scan(&id, &text)) {
+							assert.Equal(t, uint64(count), id)
+							assert.Equal(t, fmt.Sprintf("the text of the row %d", count), text)
+						}
+						count++
+					}
+					if assert.NoError(t, rows.Err()) {
+						assert.Equal(t, total, count)
+					}
+				}
+			}
+		}
+	}
+}
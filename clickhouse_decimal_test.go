@@ -3,6 +3,8 @@ package clickhouse_test
 import (
 	"database/sql"
 	"github.com/stretchr/testify/assert"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -95,3 +97,79 @@ func Test_Decimal(t *testing.T) {
 		}
 	}
 }
+
+func Test_Decimal256(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_decimal256 (
+				id               UInt8,
+				decimal          Decimal(76, 10),
+				decimalNullable  Nullable(Decimal(76, 10))
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_decimal256 (
+				id,
+				decimal,
+				decimalNullable
+			) VALUES (
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				decimal,
+				decimalNullable
+			FROM clickhouse_test_decimal256
+			ORDER BY id
+		`
+	)
+	maxValue, _ := new(big.Int).SetString(strings.Repeat("9", 76), 10)
+	values := []*big.Int{
+		big.NewInt(165500000000),
+		big.NewInt(-165500000000),
+		maxValue,
+		new(big.Int).Neg(maxValue),
+	}
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_decimal256"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i, v := range values {
+							var nullable interface{}
+							if i%2 == 0 {
+								nullable = v
+							}
+							if _, err := stmt.Exec(uint8(i), v, nullable); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var decimal, decimalNullable *big.Int
+						if err := rows.Scan(&decimal, &decimalNullable); assert.NoError(t, err) {
+							assert.Equal(t, values[i].String(), decimal.String())
+							if i%2 == 0 {
+								if assert.NotNil(t, decimalNullable) {
+									assert.Equal(t, values[i].String(), decimalNullable.String())
+								}
+							} else {
+								assert.Nil(t, decimalNullable)
+							}
+						}
+					}
+					assert.Equal(t, len(values), i)
+				}
+			}
+		}
+	}
+}
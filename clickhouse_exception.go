@@ -5,18 +5,51 @@ import (
 	"strings"
 )
 
+// Exception is the error of a query failed on the server, the Code is the one of the ErrorCodes of ClickHouse,
+// e.g. 159 for TIMEOUT_EXCEEDED. The server may send the exception that caused it, it is the Nested one
+// and errors.As/errors.Is reach it through Unwrap.
 type Exception struct {
 	Code       int32
 	Name       string
 	Message    string
 	StackTrace string
-	nested     error
+	Nested     *Exception
+	auth       bool // the server rejected the credentials of the hello, see ErrAuthFailed
+}
+
+// the codes of the exceptions of the server rejecting the credentials of the hello
+const (
+	errUnknownUser          int32 = 192
+	errWrongPassword        int32 = 193
+	errRequiredPassword     int32 = 194
+	errAuthenticationFailed int32 = 516
+)
+
+func isAuthCode(code int32) bool {
+	switch code {
+	case errUnknownUser, errWrongPassword, errRequiredPassword, errAuthenticationFailed:
+		return true
+	}
+	return false
 }
 
 func (e *Exception) Error() string {
 	return fmt.Sprintf("code: %d, message: %s", e.Code, e.Message)
 }
 
+// Is reports whether the exception is ErrAuthFailed, the server rejected the credentials of the connection.
+func (e *Exception) Is(target error) bool {
+	return e.auth && target == ErrAuthFailed
+}
+
+// Unwrap returns the nested exception, nil if the server didn't send one.
+func (e *Exception) Unwrap() error {
+	if e.Nested == nil {
+		return nil
+	}
+	return e.Nested
+}
+
 func (ch *clickhouse) exception() error {
 	var (
 		e         Exception
@@ -40,7 +73,17 @@ func (ch *clickhouse) exception() error {
 		return err
 	}
 	if hasNested {
-		e.nested = ch.exception()
+		err = ch.exception()
+		nested, ok := err.(*Exception)
+		if !ok {
+			// the stream broke off in the nested exception
+			return err
+		}
+		e.Nested = nested
+	}
+	if ch.conn != nil && ch.conn.dump != nil {
+		// the exception ends the response
+		ch.conn.dump.endRead()
 	}
 	return &e
 }
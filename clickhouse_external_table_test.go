@@ -0,0 +1,77 @@
+package clickhouse_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go"
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithExternalTableJoin(t *testing.T) {
+	const ddl = `
+		CREATE TABLE clickhouse_test_external_table (
+			id   UInt64,
+			name String
+		) Engine=Memory
+	`
+	db, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skip(err)
+	}
+	for _, query := range []string{"DROP TABLE IF EXISTS clickhouse_test_external_table", ddl, "INSERT INTO clickhouse_test_external_table SELECT number, concat('name_', toString(number)) FROM system.numbers LIMIT 100"} {
+		if _, err := db.Exec(query); !assert.NoError(t, err) {
+			return
+		}
+	}
+	// the scores of the client, in the Native format: 2 columns, 2 rows
+	var scores bytes.Buffer
+	{
+		encoder := binary.NewEncoder(&scores)
+		encoder.Uvarint(2)
+		encoder.Uvarint(2)
+		encoder.String("id")
+		encoder.String("UInt64")
+		encoder.UInt64(7)
+		encoder.UInt64(42)
+		encoder.String("score")
+		encoder.String("Float64")
+		encoder.Float64(0.5)
+		encoder.Float64(1.5)
+	}
+	ctx := clickhouse.WithExternalTable(context.Background(), "ids", "id UInt64", [][]driver.Value{{uint64(7)}, {uint64(42)}, {uint64(1000)}})
+	ctx = clickhouse.WithExternalTable(ctx, "scores", "id UInt64, score Float64", &scores)
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.name, s.score
+		FROM clickhouse_test_external_table AS t
+		INNER JOIN scores AS s ON t.id = s.id
+		WHERE t.id IN ids
+		ORDER BY t.id
+	`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var (
+			name  string
+			score float64
+		)
+		if assert.NoError(t, rows.Scan(&name, &score)) {
+			results = append(results, fmt.Sprintf("%s=%v", name, score))
+		}
+	}
+	if assert.NoError(t, rows.Err()) {
+		assert.Equal(t, []string{"name_7=0.5", "name_42=1.5"}, results)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build http
+// +build http
+
+package clickhouse_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the tests of the HTTP interface run with the tag http against a server listening on 8123: go test -tags http
+
+func Test_HTTPInsertSelect(t *testing.T) {
+	const ddl = `
+		CREATE TABLE clickhouse_test_http (
+			id    UInt64,
+			name  Nullable(String),
+			tag   LowCardinality(String),
+			day   Date,
+			time  DateTime,
+			score Float64
+		) Engine=Memory
+	`
+	db, err := sql.Open("clickhouse", "http://127.0.0.1:8123?debug=false&compress=true")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	for _, query := range []string{"DROP TABLE IF EXISTS clickhouse_test_http", ddl} {
+		if _, err := db.Exec(query); !assert.NoError(t, err) {
+			return
+		}
+	}
+	now := time.Now().Truncate(time.Second)
+	tx, err := db.Begin()
+	if !assert.NoError(t, err) {
+		return
+	}
+	stmt, err := tx.Prepare("INSERT INTO clickhouse_test_http (id, name, tag, day, time, score) VALUES (?, ?, ?, ?, ?, ?)")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := 0; i < 100; i++ {
+		var name interface{}
+		if i%2 == 0 {
+			name = "name"
+		}
+		if _, err := stmt.Exec(uint64(i), name, "tag", now, now, float64(i)/2); !assert.NoError(t, err) {
+			return
+		}
+	}
+	if !assert.NoError(t, tx.Commit()) {
+		return
+	}
+	var (
+		count int
+		names int
+		sum   float64
+		last  time.Time
+	)
+	if err := db.QueryRow("SELECT count(), count(name), sum(score), max(time) FROM clickhouse_test_http WHERE id >= ? OR id IN {ids:Array(UInt64)}", 0, sql.Named("ids", []uint64{1, 2})).Scan(&count, &names, &sum, &last); assert.NoError(t, err) {
+		assert.Equal(t, 100, count)
+		assert.Equal(t, 50, names)
+		assert.Equal(t, float64(2475), sum)
+		assert.True(t, now.Equal(last))
+	}
+	rows, err := db.Query("SELECT id, name, tag FROM clickhouse_test_http WHERE id < 2 ORDER BY id")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	var results []string
+	for rows.Next() {
+		var (
+			id   uint64
+			name sql.NullString
+			tag  string
+		)
+		if assert.NoError(t, rows.Scan(&id, &name, &tag)) {
+			results = append(results, name.String+tag)
+		}
+	}
+	if assert.NoError(t, rows.Err()) {
+		assert.Equal(t, []string{"nametag", "tag"}, results)
+	}
+}
@@ -0,0 +1,82 @@
+package clickhouse_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go"
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeNativeFile writes the rows (id UInt32, name String) in the Native format: the number of the columns
+// and of the rows, then each column by its name, its type and its values.
+func writeNativeFile(t *testing.T, rows int) string {
+	file, err := ioutil.TempFile("", "clickhouse_insert_reader_*.native")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+	encoder := binary.NewEncoder(file)
+	encoder.Uvarint(2)
+	encoder.Uvarint(uint64(rows))
+	encoder.String("id")
+	encoder.String("UInt32")
+	for i := 0; i < rows; i++ {
+		encoder.UInt32(uint32(i))
+	}
+	encoder.String("name")
+	encoder.String("String")
+	for i := 0; i < rows; i++ {
+		encoder.String(fmt.Sprintf("name_%d", i))
+	}
+	return file.Name()
+}
+
+func Test_InsertReaderNative(t *testing.T) {
+	const ddl = `
+		CREATE TABLE clickhouse_test_insert_reader (
+			id   UInt32,
+			name String
+		) Engine=Memory
+	`
+	connect, err := clickhouse.OpenDirect("tcp://127.0.0.1:9000?debug=false")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer connect.Close()
+	db, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	for _, query := range []string{"DROP TABLE IF EXISTS clickhouse_test_insert_reader", ddl} {
+		if _, err := db.Exec(query); !assert.NoError(t, err) {
+			return
+		}
+	}
+	path := writeNativeFile(t, 100)
+	defer os.Remove(path)
+	file, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+	if assert.NoError(t, connect.InsertReader(context.Background(), "clickhouse_test_insert_reader", "Native", file)) {
+		var (
+			count int
+			name  string
+		)
+		if assert.NoError(t, db.QueryRow("SELECT count(), any(name) FROM clickhouse_test_insert_reader WHERE id = 42").Scan(&count, &name)) {
+			assert.Equal(t, 1, count)
+			assert.Equal(t, "name_42", name)
+		}
+	}
+	// the connection is reused once the data is inserted
+	assert.NoError(t, connect.InsertReader(context.Background(), "clickhouse_test_insert_reader", "TSV", strings.NewReader("1000\tname_1000\n")))
+}
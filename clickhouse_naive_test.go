@@ -1,9 +1,12 @@
 package clickhouse
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/c3mb0/clickhouse-go/lib/binary"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,6 +17,12 @@ func Test_Naive_Result(t *testing.T) {
 			assert.Equal(t, int64(0), rows)
 		}
 	}
+	insert := insertResult{rows: 42}
+	if _, err := insert.LastInsertId(); assert.Error(t, err) {
+		if rows, err := insert.RowsAffected(); assert.NoError(t, err) {
+			assert.Equal(t, int64(42), rows)
+		}
+	}
 }
 
 func Test_Naive_Exception(t *testing.T) {
@@ -25,3 +34,41 @@ func Test_Naive_Exception(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf("code: %d, message: %s", exception.Code, exception.Message), exception.Error())
 	}
 }
+
+func Test_Naive_NestedException(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	for i, code := range []int32{159, 209} {
+		encoder.Int32(code)
+		encoder.String("DB::Exception")
+		encoder.String(fmt.Sprintf("DB::Exception: message %d ", i))
+		encoder.String("stack trace")
+		encoder.Bool(i == 0)
+	}
+	stream := buf.Bytes()
+	ch := &clickhouse{decoder: binary.NewDecoder(bytes.NewReader(stream))}
+	err := ch.exception()
+	var exception *Exception
+	if assert.True(t, errors.As(err, &exception)) {
+		assert.Equal(t, int32(159), exception.Code)
+		assert.Equal(t, "DB::Exception", exception.Name)
+		assert.Equal(t, "message 0", exception.Message)
+		assert.Equal(t, "stack trace", exception.StackTrace)
+		assert.Equal(t, "code: 159, message: message 0", err.Error())
+		if assert.NotNil(t, exception.Nested) {
+			assert.Equal(t, int32(209), exception.Nested.Code)
+			assert.Equal(t, "message 1", exception.Nested.Message)
+			assert.Nil(t, exception.Nested.Nested)
+			assert.Equal(t, exception.Nested, errors.Unwrap(err))
+			assert.Nil(t, errors.Unwrap(exception.Nested))
+		}
+	}
+	// the stream broke off in the nested exception
+	ch = &clickhouse{decoder: binary.NewDecoder(bytes.NewReader(stream[:len(stream)-5]))}
+	if err := ch.exception(); assert.Error(t, err) {
+		_, ok := err.(*Exception)
+		assert.False(t, ok, err)
+	}
+}
@@ -2,6 +2,7 @@ package clickhouse_test
 
 import (
 	"database/sql"
+	"math/big"
 	"testing"
 	"time"
 
@@ -314,3 +315,93 @@ func Test_Nullable(t *testing.T) {
 		}
 	}
 }
+
+func Test_NullableInterleaved(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_nullable_interleaved (
+				id         UInt8,
+				uint8      Nullable(UInt8),
+				int128     Nullable(Int128),
+				decimal256 Nullable(Decimal(76, 2)),
+				int32Array Array(Nullable(Int32))
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_nullable_interleaved (
+				id,
+				uint8,
+				int128,
+				decimal256,
+				int32Array
+			) VALUES (
+				?,
+				?,
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				uint8,
+				int128,
+				decimal256,
+				int32Array
+			FROM clickhouse_test_nullable_interleaved
+			ORDER BY id
+		`
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_nullable_interleaved"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 6; i++ {
+							var (
+								value  = int32(i)
+								small  interface{}
+								int128 interface{}
+								array  = []*int32{&value, nil}
+							)
+							if i%2 == 0 {
+								small, int128 = sql.NullInt64{Int64: int64(i), Valid: true}, big.NewInt(int64(-i))
+							}
+							if _, err := stmt.Exec(uint8(i), small, int128, int128, array); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							small      sql.NullInt64
+							int128     *big.Int
+							decimal256 *big.Int
+							int32Array []*int32
+						)
+						if err := rows.Scan(&small, &int128, &decimal256, &int32Array); assert.NoError(t, err) {
+							if i%2 == 0 {
+								assert.Equal(t, sql.NullInt64{Int64: int64(i), Valid: true}, small)
+								assert.Equal(t, big.NewInt(int64(-i)), int128)
+								assert.Equal(t, big.NewInt(int64(-i)), decimal256)
+							} else {
+								assert.False(t, small.Valid)
+								assert.Nil(t, int128)
+								assert.Nil(t, decimal256)
+							}
+							value := int32(i)
+							assert.Equal(t, []*int32{&value, nil}, int32Array)
+						}
+					}
+					assert.Equal(t, 6, i)
+				}
+			}
+		}
+	}
+}
@@ -3,26 +3,84 @@ package clickhouse
 import (
 	"context"
 	"database/sql/driver"
+	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/protocol"
 )
 
+// Ping implements driver.Pinger, database/sql discards the connection if the ping fails with driver.ErrBadConn.
+// The Pong of the server is awaited until the deadline of the context, if it is sooner than the read timeout,
+// the connection is closed and the error of the context returned if it is done before.
 func (ch *clickhouse) Ping(ctx context.Context) error {
 	return ch.ping(ctx)
 }
 
 func (ch *clickhouse) ping(ctx context.Context) error {
-	if ch.conn.closed {
+	if ch.conn.isClosed() {
 		return driver.ErrBadConn
 	}
+	if err := ctx.Err(); err != nil {
+		// the connection is not used
+		return err
+	}
 	ch.logf("-> ping")
-	finish := ch.watchCancel(ctx)
-	defer finish()
-	if err := ch.encoder.Uvarint(protocol.ClientPing); err != nil {
+	ch.conn.acquire()
+	if deadline, ok := ctx.Deadline(); ok {
+		// restored by the release of the connection
+		if timeout := time.Until(deadline); ch.conn.readTimeout == 0 || timeout < ch.conn.readTimeout {
+			ch.conn.overrideReadTimeout(timeout)
+		}
+	}
+	// reports whether the context closed the connection, once its watch is stopped
+	stopWatch := func() bool { return false }
+	if done := ctx.Done(); done != nil {
+		// a Ping has no Cancel packet, the connection is closed if the context is done before the Pong
+		finished, closed := make(chan struct{}), make(chan bool, 1)
+		go func() {
+			select {
+			case <-done:
+				ch.conn.Close()
+				closed <- true
+			case <-finished:
+				closed <- false
+			}
+		}()
+		stopWatch = func() bool {
+			close(finished)
+			return <-closed
+		}
+	}
+	if err := ch.writePacket(protocol.ClientPing); err != nil {
+		ch.conn.release()
+		if stopWatch() {
+			return ctx.Err()
+		}
 		return err
 	}
 	if err := ch.encoder.Flush(); err != nil {
+		ch.conn.release()
+		if stopWatch() {
+			return ctx.Err()
+		}
 		return err
 	}
-	return ch.process()
+	err := ch.process()
+	if stopWatch() {
+		// even if the Pong was read, the connection is closed
+		ch.logf("[ping] %v", ctx.Err())
+		return ctx.Err()
+	}
+	if err != nil {
+		ch.logf("[ping] %v", err)
+		ch.conn.Close()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			// the read timeout overridden by the deadline expired before the context
+			return context.DeadlineExceeded
+		}
+		return driver.ErrBadConn
+	}
+	return nil
 }
@@ -0,0 +1,112 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// deadlinesConn records all the read deadlines set on the stub.
+type deadlinesConn struct {
+	*stubConn
+	readDeadlines []time.Time
+}
+
+func (c *deadlinesConn) SetReadDeadline(t time.Time) error {
+	c.readDeadlines = append(c.readDeadlines, t)
+	return nil
+}
+
+func Test_Ping(t *testing.T) {
+	stub := &stubConn{data: []byte{protocol.ServerPong}, err: io.EOF}
+	deadlines := &deadlinesConn{stubConn: stub}
+	conn := newStubConnect(t, deadlines, connOptions{})
+	ch := &clickhouse{
+		conn:    conn,
+		logf:    func(string, ...interface{}) {},
+		decoder: binary.NewDecoder(conn),
+		encoder: binary.NewEncoder(conn),
+	}
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if assert.NoError(t, ch.Ping(ctx)) {
+		assert.Equal(t, []byte{protocol.ClientPing}, stub.written)
+		// the pong is awaited until the deadline of the context, then the read timeout is restored
+		if assert.Len(t, deadlines.readDeadlines, 2) {
+			assert.WithinDuration(t, deadline, deadlines.readDeadlines[0], time.Second)
+			assert.True(t, deadlines.readDeadlines[1].IsZero())
+		}
+		assert.Equal(t, time.Duration(0), conn.readTimeout)
+	}
+	// the connection is not used once the context is done
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, ch.Ping(canceled))
+	assert.Len(t, stub.written, 1)
+
+	// the ping fails if the connection is closed by the server
+	assert.Equal(t, driver.ErrBadConn, ch.Ping(context.Background()))
+	assert.True(t, conn.isClosed())
+}
+
+func Test_PingTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	// the server reads the ping but never answers it
+	go io.Copy(ioutil.Discard, server)
+	conn := newStubConnect(t, client, connOptions{})
+	ch := &clickhouse{
+		conn:    conn,
+		logf:    func(string, ...interface{}) {},
+		decoder: binary.NewDecoder(conn),
+		encoder: binary.NewEncoder(conn),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, ch.Ping(ctx))
+	assert.True(t, time.Since(start) < time.Second)
+	assert.True(t, conn.isClosed())
+}
+
+// cancelConn cancels a context once the Pong is read.
+type cancelConn struct {
+	*stubConn
+	cancel context.CancelFunc
+}
+
+func (c *cancelConn) Read(b []byte) (int, error) {
+	n, err := c.stubConn.Read(b)
+	c.cancel()
+	return n, err
+}
+
+func Test_PingCancelledAtPong(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		stub := &stubConn{data: []byte{protocol.ServerPong}, err: io.EOF}
+		conn := newStubConnect(t, &cancelConn{stubConn: stub, cancel: cancel}, connOptions{})
+		ch := &clickhouse{
+			conn:    conn,
+			logf:    func(string, ...interface{}) {},
+			decoder: binary.NewDecoder(conn),
+			encoder: binary.NewEncoder(conn),
+		}
+		// the ping succeeds only if the connection is left open
+		if err := ch.Ping(ctx); conn.isClosed() {
+			assert.Equal(t, context.Canceled, err)
+		} else {
+			assert.NoError(t, err)
+		}
+		cancel()
+	}
+}
@@ -1,37 +1,52 @@
 package clickhouse
 
-type profileInfo struct {
-	rows                      uint64
-	bytes                     uint64
-	blocks                    uint64
-	appliedLimit              bool
-	rowsBeforeLimit           uint64
-	calculatedRowsBeforeLimit bool
+import "context"
+
+// ProfileInfo is the summary of a finished query sent by the server.
+// RowsBeforeLimit is the number of the rows the query would have returned without its LIMIT, at least:
+// it is the exact count only if CalculatedRowsBeforeLimit and the query was not run on several shards.
+type ProfileInfo struct {
+	Rows                      uint64
+	Blocks                    uint64
+	Bytes                     uint64
+	AppliedLimit              bool
+	RowsBeforeLimit           uint64
+	CalculatedRowsBeforeLimit bool
+}
+
+// WithProfileInfo calls fn with the profile info the server sends once the query run with the context is done,
+// before the end of its results. fn is called on the goroutine decoding the results, as WithProgress.
+func WithProfileInfo(ctx context.Context, fn func(ProfileInfo)) context.Context {
+	return context.WithValue(ctx, profileInfoKey, fn)
 }
 
-func (ch *clickhouse) profileInfo() (*profileInfo, error) {
+// profileInfo reads the profile info packet and reports it to the callback of the query, if any.
+func (ch *clickhouse) profileInfo() (*ProfileInfo, error) {
 	var (
-		p   profileInfo
+		p   ProfileInfo
 		err error
 	)
-	if p.rows, err = ch.decoder.Uvarint(); err != nil {
+	if p.Rows, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
-	if p.blocks, err = ch.decoder.Uvarint(); err != nil {
+	if p.Blocks, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
-	if p.bytes, err = ch.decoder.Uvarint(); err != nil {
+	if p.Bytes, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
 
-	if p.appliedLimit, err = ch.decoder.Bool(); err != nil {
+	if p.AppliedLimit, err = ch.decoder.Bool(); err != nil {
 		return nil, err
 	}
-	if p.rowsBeforeLimit, err = ch.decoder.Uvarint(); err != nil {
+	if p.RowsBeforeLimit, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
-	if p.calculatedRowsBeforeLimit, err = ch.decoder.Bool(); err != nil {
+	if p.CalculatedRowsBeforeLimit, err = ch.decoder.Bool(); err != nil {
 		return nil, err
 	}
+	if ch.onProfileInfo != nil {
+		ch.onProfileInfo(p)
+	}
 	return &p, nil
 }
@@ -0,0 +1,95 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithProfileInfo(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	c, err := column.Factory("value", "UInt8", time.UTC)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the header of the results, the block of the rows, the profile info and the end of the stream
+	for _, values := range [][]driver.Value{nil, {uint8(1)}, {uint8(2)}} {
+		block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+		block.Reserve()
+		for _, v := range values {
+			if !assert.NoError(t, block.AppendRow([]driver.Value{v})) {
+				return
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		if !assert.NoError(t, block.Write(&data.ServerInfo{}, encoder)) {
+			return
+		}
+	}
+	encoder.Uvarint(protocol.ServerProfileInfo)
+	encoder.Uvarint(2)    // rows
+	encoder.Uvarint(2)    // blocks
+	encoder.Uvarint(16)   // bytes
+	encoder.Bool(true)    // applied limit
+	encoder.Uvarint(1000) // rows before limit
+	encoder.Bool(true)    // calculated rows before limit
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	profileInfo := make(chan ProfileInfo, 1)
+	ctx := WithProfileInfo(context.Background(), func(p ProfileInfo) {
+		profileInfo <- p
+	})
+	stmt, err := ch.PrepareContext(ctx, "SELECT value FROM example LIMIT 2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	for _, expected := range []uint8{1, 2} {
+		if assert.NoError(t, rows.Next(dest)) {
+			assert.Equal(t, expected, dest[0])
+		}
+	}
+	if assert.Equal(t, io.EOF, rows.Next(dest)) {
+		// the profile info is reported before the end of the results
+		select {
+		case p := <-profileInfo:
+			assert.Equal(t, ProfileInfo{
+				Rows:                      2,
+				Blocks:                    2,
+				Bytes:                     16,
+				AppliedLimit:              true,
+				RowsBeforeLimit:           1000,
+				CalculatedRowsBeforeLimit: true,
+			}, p)
+		default:
+			t.Error("the profile info is not reported")
+		}
+	}
+}
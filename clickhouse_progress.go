@@ -1,26 +1,56 @@
 package clickhouse
 
-type progress struct {
-	rows      uint64
-	bytes     uint64
-	totalRows uint64
+import (
+	"context"
+
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+)
+
+// Progress is the progress of a query sent by the server: the rows and bytes read (and written) since the previous
+// progress of the query, and the total rows to read as estimated so far.
+// The written rows and bytes are only sent to the clients of the protocol revision 54420 or later, they are zero otherwise.
+type Progress struct {
+	Rows         uint64
+	Bytes        uint64
+	TotalRows    uint64
+	WrittenRows  uint64
+	WrittenBytes uint64
+}
+
+// WithProgress calls fn with each progress the server sends while running the query run with the context.
+// fn is called on the goroutine decoding the results, the packets of the query are not read until it returns:
+// it should not block, e.g. hand the progress over to a buffered channel without waiting, or drop it.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey, fn)
 }
 
-func (ch *clickhouse) progress() (*progress, error) {
+// progress reads the progress packet and reports it to the callback of the query, if any.
+func (ch *clickhouse) progress() (*Progress, error) {
 	var (
-		p   progress
+		p   Progress
 		err error
 	)
-	if p.rows, err = ch.decoder.Uvarint(); err != nil {
+	if p.Rows, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
-	if p.bytes, err = ch.decoder.Uvarint(); err != nil {
+	if p.Bytes, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
 
-	if p.totalRows, err = ch.decoder.Uvarint(); err != nil {
+	if p.TotalRows, err = ch.decoder.Uvarint(); err != nil {
 		return nil, err
 	}
-
+	if revision := ch.ServerInfo.Revision; revision >= protocol.DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO && data.ClickHouseRevision >= protocol.DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO {
+		if p.WrittenRows, err = ch.decoder.Uvarint(); err != nil {
+			return nil, err
+		}
+		if p.WrittenBytes, err = ch.decoder.Uvarint(); err != nil {
+			return nil, err
+		}
+	}
+	if ch.onProgress != nil {
+		ch.onProgress(p)
+	}
 	return &p, nil
 }
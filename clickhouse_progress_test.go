@@ -0,0 +1,108 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeProgress returns the progress packet as sent by the server to the clients of the current revision.
+func encodeProgress(encoder *binary.Encoder, rows, bytes, totalRows uint64) {
+	encoder.Uvarint(protocol.ServerProgress)
+	encoder.Uvarint(rows)
+	encoder.Uvarint(bytes)
+	encoder.Uvarint(totalRows)
+}
+
+func Test_WithProgress(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	encodeProgress(encoder, 1, 10, 3)
+	encodeProgress(encoder, 2, 20, 3)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	var progress []Progress
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		// the server of a later revision sends the written rows and bytes to the clients of the revision 54420 or later
+		ServerInfo: data.ServerInfo{Revision: 54451},
+		conn:       conn,
+		logf:       func(string, ...interface{}) {},
+		settings:   &querySettings{},
+		decoder:    binary.NewDecoder(conn),
+		encoder:    binary.NewEncoder(conn),
+	}
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		progress = append(progress, p)
+	})
+	if _, err := ch.ExecContext(ctx, "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Equal(t, []Progress{
+			{Rows: 1, Bytes: 10, TotalRows: 3},
+			{Rows: 2, Bytes: 20, TotalRows: 3},
+		}, progress)
+	}
+	// the callback is the one of the query
+	stub.data = []byte{protocol.ServerProgress, 1, 1, 1, protocol.ServerEndOfStream}
+	if _, err := ch.ExecContext(context.Background(), "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Len(t, progress, 2)
+	}
+}
+
+func Test_RowsProgress(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	c, err := column.Factory("value", "String", time.UTC)
+	if !assert.NoError(t, err) {
+		return
+	}
+	encodeProgress(encoder, 1, 10, 2)
+	block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	if !assert.NoError(t, block.AppendRow([]driver.Value{"a"})) {
+		return
+	}
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	if !assert.NoError(t, block.Write(&data.ServerInfo{}, encoder)) {
+		return
+	}
+	encodeProgress(encoder, 1, 10, 2)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	progress := make(chan Progress, 10)
+	rows := &rows{
+		ch: &clickhouse{
+			logf:       func(string, ...interface{}) {},
+			conn:       &connect{},
+			decoder:    binary.NewDecoder(&buf),
+			onProgress: func(p Progress) { progress <- p },
+		},
+		finish:       func() {},
+		stream:       make(chan *data.Block, 50),
+		columns:      []string{"value"},
+		blockColumns: []column.Column{c},
+	}
+	go rows.receiveData()
+	dest := make([]driver.Value, 1)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, "a", dest[0])
+		// the progress preceding the block is reported before its rows are scanned
+		assert.Equal(t, Progress{Rows: 1, Bytes: 10, TotalRows: 2}, <-progress)
+	}
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	assert.Equal(t, Progress{Rows: 1, Bytes: 10, TotalRows: 2}, <-progress)
+	assert.NoError(t, rows.Close())
+}
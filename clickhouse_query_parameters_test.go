@@ -0,0 +1,90 @@
+package clickhouse_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ArrayQueryParameters(t *testing.T) {
+	const ddl = `
+		CREATE TABLE clickhouse_test_array_parameters (
+			id   UInt64,
+			name String,
+			day  Date
+		) Engine=Memory
+	`
+	db, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skip(err)
+	}
+	for _, query := range []string{"DROP TABLE IF EXISTS clickhouse_test_array_parameters", ddl, "INSERT INTO clickhouse_test_array_parameters SELECT number, concat('name_', toString(number)), toDate('2021-01-01') + number FROM system.numbers LIMIT 100"} {
+		if _, err := db.Exec(query); !assert.NoError(t, err) {
+			return
+		}
+	}
+	for _, test := range []struct {
+		query    string
+		arg      interface{}
+		expected []uint64
+	}{
+		{"SELECT id FROM clickhouse_test_array_parameters WHERE id IN {ids:Array(UInt64)} ORDER BY id", clickhouse.Array([]uint64{42, 7, 1000}), []uint64{7, 42}},
+		{"SELECT id FROM clickhouse_test_array_parameters WHERE name IN {ids:Array(String)} ORDER BY id", clickhouse.Array([]string{"name_3", "name_5", "name_5' OR 1 = 1 --"}), []uint64{3, 5}},
+		{"SELECT id FROM clickhouse_test_array_parameters WHERE day IN {ids:Array(Date)} ORDER BY id", clickhouse.Array([]time.Time{time.Date(2021, 1, 11, 0, 0, 0, 0, time.UTC)}), []uint64{10}},
+		{"SELECT id FROM clickhouse_test_array_parameters WHERE id IN {ids:Array(UInt64)}", clickhouse.Array([]uint64{}), nil},
+	} {
+		rows, err := db.Query(test.query, sql.Named("ids", test.arg))
+		if !assert.NoError(t, err, test.query) {
+			continue
+		}
+		var ids []uint64
+		for rows.Next() {
+			var id uint64
+			if assert.NoError(t, rows.Scan(&id)) {
+				ids = append(ids, id)
+			}
+		}
+		if assert.NoError(t, rows.Err()) {
+			assert.Equal(t, test.expected, ids, test.query)
+		}
+		rows.Close()
+	}
+}
+
+func Test_NativeQueryParameters(t *testing.T) {
+	db, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skip(err)
+	}
+	// the parameters are bound by the driver, the server of any revision runs the query
+	var (
+		name     string
+		none     sql.NullString
+		precise  time.Time
+		count    uint64
+		dateTime = time.Date(2021, 3, 4, 5, 6, 7, 8000000, time.UTC)
+	)
+	row := db.QueryRow("SELECT {name:String}, {none:Nullable(String)}, {precise:DateTime64(3, 'UTC')}, count() FROM system.{table:Identifier}",
+		sql.Named("name", "a\tb\\c'd"),
+		sql.Named("none", nil),
+		sql.Named("precise", dateTime),
+		sql.Named("table", "one"),
+	)
+	if assert.NoError(t, row.Scan(&name, &none, &precise, &count)) {
+		assert.Equal(t, "a\tb\\c'd", name)
+		assert.False(t, none.Valid)
+		assert.Equal(t, dateTime, precise.UTC())
+		assert.Equal(t, uint64(1), count)
+	}
+}
@@ -0,0 +1,62 @@
+package clickhouse
+
+import (
+	"context"
+	"time"
+)
+
+// QueryStats are the statistics of the results of a query measured by the client, unlike ProfileInfo sent by the server.
+// Bytes is the count of the bytes read from the connection for the query, the packets other than the blocks included.
+// DecodeTime is the time spent decoding the blocks, the time waiting for their bytes included.
+// CompressedBytes and UncompressedBytes are the sizes of the compressed blocks read, they are zero unless compress is set.
+type QueryStats struct {
+	Rows              uint64
+	Blocks            uint64
+	Bytes             uint64
+	CompressedBytes   uint64
+	UncompressedBytes uint64
+	DecodeTime        time.Duration
+}
+
+// WithQueryStats calls fn with the statistics of the query run with the context once its results end,
+// whether they are read to the end or not. fn is called on the goroutine decoding the results, as WithProgress.
+func WithQueryStats(ctx context.Context, fn func(QueryStats)) context.Context {
+	return context.WithValue(ctx, queryStatsKey, fn)
+}
+
+// queryStats collects the statistics of the query reported to onQueryStats.
+type queryStats struct {
+	QueryStats
+	bytesBefore uint64 // the bytes read by the connection before the query
+}
+
+// startQueryStats starts collecting the statistics of the query if the context has a callback.
+func (ch *clickhouse) startQueryStats(ctx context.Context) {
+	ch.stats = nil
+	if fn, ok := ctx.Value(queryStatsKey).(func(QueryStats)); ok {
+		ch.stats = &queryStats{bytesBefore: ch.conn.received}
+		ch.onQueryStats = fn
+	}
+}
+
+// blockDecompressed is the hook of the decoder called once a compressed block is read.
+func (ch *clickhouse) blockDecompressed(size, compressedSize int) {
+	if ch.stats != nil {
+		ch.stats.CompressedBytes += uint64(compressedSize)
+		ch.stats.UncompressedBytes += uint64(size)
+	}
+	if ch.conn != nil && ch.conn.metrics != nil {
+		ch.conn.metrics.BlockDecompressed(size, compressedSize)
+	}
+}
+
+// reportQueryStats calls the callback of the query with its statistics once its results end.
+func (ch *clickhouse) reportQueryStats() {
+	if ch.stats == nil {
+		return
+	}
+	stats := ch.stats.QueryStats
+	stats.Bytes = ch.conn.received - ch.stats.bytesBefore
+	ch.stats = nil
+	ch.onQueryStats(stats)
+}
@@ -0,0 +1,101 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithQueryStats(t *testing.T) {
+	var (
+		buf        bytes.Buffer
+		encoder    = binary.NewEncoderWithCompress(&buf)
+		compressed int
+	)
+	encoder.OnCompressedBlock(func(_, compressedSize int) {
+		compressed += compressedSize
+	})
+	c, err := column.Factory("value", "String", time.UTC)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the header of the results, the blocks of the rows and the end of the stream
+	for _, values := range [][]driver.Value{nil, {"a", "b"}, {"c"}} {
+		block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+		block.Reserve()
+		for _, v := range values {
+			if !assert.NoError(t, block.AppendRow([]driver.Value{v})) {
+				return
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		encoder.SelectCompress(true)
+		if !assert.NoError(t, block.Write(&data.ServerInfo{}, encoder)) {
+			return
+		}
+		encoder.SelectCompress(false)
+	}
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		compress: true,
+		decoder:  binary.NewDecoderWithCompress(conn),
+		encoder:  binary.NewEncoderWithCompress(conn),
+	}
+	ch.decoder.OnCompressedBlock(ch.blockDecompressed)
+	queryStats := make(chan QueryStats, 1)
+	ctx := WithQueryStats(context.Background(), func(s QueryStats) {
+		queryStats <- s
+	})
+	stmt, err := ch.PrepareContext(ctx, "SELECT value FROM example")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	for _, expected := range []string{"a", "b", "c"} {
+		if assert.NoError(t, rows.Next(dest)) {
+			assert.Equal(t, expected, dest[0])
+		}
+	}
+	if assert.Equal(t, io.EOF, rows.Next(dest)) {
+		// the stats are reported before the end of the results
+		select {
+		case s := <-queryStats:
+			assert.Equal(t, uint64(3), s.Rows)
+			assert.Equal(t, uint64(3), s.Blocks)
+			assert.Equal(t, uint64(buf.Len()), s.Bytes)
+			assert.Equal(t, uint64(compressed), s.CompressedBytes)
+			assert.True(t, s.UncompressedBytes > 0)
+			assert.True(t, s.DecodeTime > 0)
+		default:
+			t.Error("the query stats are not reported")
+		}
+	}
+
+	// the stats are not collected for the queries without the callback
+	stub.data = []byte{protocol.ServerEndOfStream}
+	if _, err := ch.ExecContext(context.Background(), "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Nil(t, ch.stats)
+		assert.Len(t, queryStats, 0)
+	}
+}
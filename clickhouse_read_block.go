@@ -1,6 +1,10 @@
 package clickhouse
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/column"
 	"github.com/c3mb0/clickhouse-go/lib/data"
 )
 
@@ -10,10 +14,47 @@ func (ch *clickhouse) readBlock() (*data.Block, error) {
 	}
 
 	ch.decoder.SelectCompress(ch.compress)
-	var block data.Block
+	var (
+		block data.Block
+		begin time.Time
+	)
+	if ch.stats != nil {
+		begin = time.Now()
+	}
+	block.Columnar, block.Location = ch.columnar, ch.location
 	if err := block.Read(&ch.ServerInfo, ch.decoder); err != nil {
+		if err == ErrChecksumMismatch {
+			ch.conn.Close()
+		}
 		return nil, err
 	}
 	ch.decoder.SelectCompress(false)
+	if ch.stats != nil {
+		ch.stats.DecodeTime += time.Since(begin)
+		ch.stats.Blocks++
+		ch.stats.Rows += block.NumRows
+	}
+	if metrics := ch.conn.metrics; metrics != nil && block.NumRows != 0 {
+		metrics.RowsRead(int(block.NumRows))
+	}
+	if err := ch.checkExperimental(&block); err != nil {
+		// the rest of the response would still be sent, the connection can't be reused
+		ch.conn.Close()
+		return nil, err
+	}
 	return &block, nil
 }
+
+// checkExperimental rejects the experimental column types of the block unless allow_experimental is set in the DSN:
+// their wire format may change with the version of the server.
+func (ch *clickhouse) checkExperimental(block *data.Block) error {
+	if ch.allowExperimental {
+		return nil
+	}
+	for _, c := range block.Columns {
+		if _, ok := c.(*column.JSON); ok {
+			return fmt.Errorf("clickhouse: column %s of the experimental type %s requires allow_experimental=true in the DSN", c.Name(), c.CHType())
+		}
+	}
+	return nil
+}
@@ -7,9 +7,14 @@ import (
 	"github.com/c3mb0/clickhouse-go/lib/protocol"
 )
 
-func (ch *clickhouse) readMeta() (*data.Block, error) {
+func (ch *clickhouse) readMeta() (_ *data.Block, err error) {
+	defer func() {
+		if err != nil {
+			ch.conn.release()
+		}
+	}()
 	for {
-		packet, err := ch.decoder.Uvarint()
+		packet, err := ch.readPacket()
 		if err != nil {
 			return nil, err
 		}
@@ -24,16 +29,16 @@ func (ch *clickhouse) readMeta() (*data.Block, error) {
 				return nil, err
 			}
 			ch.logf("[read meta] <- progress: rows=%d, bytes=%d, total rows=%d",
-				progress.rows,
-				progress.bytes,
-				progress.totalRows,
+				progress.Rows,
+				progress.Bytes,
+				progress.TotalRows,
 			)
 		case protocol.ServerProfileInfo:
 			profileInfo, err := ch.profileInfo()
 			if err != nil {
 				return nil, err
 			}
-			ch.logf("[read meta] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.rows, profileInfo.bytes, profileInfo.blocks)
+			ch.logf("[read meta] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.Rows, profileInfo.Bytes, profileInfo.Blocks)
 		case protocol.ServerData:
 			block, err := ch.readBlock()
 			if err != nil {
@@ -44,6 +49,7 @@ func (ch *clickhouse) readMeta() (*data.Block, error) {
 		case protocol.ServerEndOfStream:
 			_, err := ch.readBlock()
 			ch.logf("[process] <- end of stream")
+			ch.conn.release()
 			return nil, err
 		default:
 			ch.conn.Close()
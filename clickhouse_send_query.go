@@ -2,24 +2,72 @@ package clickhouse
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/data"
 	"github.com/c3mb0/clickhouse-go/lib/protocol"
 )
 
-func (ch *clickhouse) sendQuery(ctx context.Context, query string, externalTables []ExternalTable) error {
-	ch.logf("[send query] %s", query)
-	if err := ch.encoder.Uvarint(protocol.ClientQuery); err != nil {
+func (ch *clickhouse) sendQuery(ctx context.Context, query string, externalTables []ExternalTable) (err error) {
+	ch.logf("[send query] server=%d, %s", ch.conn.server, query)
+	if err := ch.checkCredentials(ctx); err != nil {
 		return err
 	}
-	var queryID string
-	queryIDValue := ctx.Value(queryIDKey)
-	if queryIDValue != nil {
-		if queryIdStr, ok := queryIDValue.(string); ok {
-			queryID = queryIdStr
+	if s, ok := ctx.Value(sessionKey).(session); ok {
+		if err := ch.joinSession(s); err != nil {
+			return err
+		}
+	}
+	if err := ch.switchDatabase(ctx); err != nil {
+		return err
+	}
+	ch.conn.acquire()
+	defer func() {
+		if err != nil {
+			ch.conn.release()
+		}
+	}()
+	settings := ch.settings
+	overrides, _ := ctx.Value(querySettingsKey).(map[string]interface{})
+	if overrides != nil {
+		// the settings are checked before the query is written
+		if settings, err = settings.with(overrides); err != nil {
+			return err
 		}
 	}
-	if err := ch.encoder.String(queryID); err != nil {
+	if err := ch.checkQuerySize(query, overrides); err != nil {
+		return err
+	}
+	contextTables, err := ch.contextTables(ctx)
+	if err != nil {
+		return err
+	}
+	ch.onProgress, _ = ctx.Value(progressKey).(func(Progress))
+	ch.onProfileInfo, _ = ctx.Value(profileInfoKey).(func(ProfileInfo))
+	ch.columnar, _ = ctx.Value(columnarKey).(bool)
+	ch.location, _ = ctx.Value(timezoneKey).(*time.Location)
+	ch.startQueryStats(ctx)
+	if timeout, ok := ctx.Value(readTimeoutKey).(time.Duration); ok {
+		ch.conn.overrideReadTimeout(timeout)
+	}
+	if err := ch.writePacket(protocol.ClientQuery); err != nil {
+		return err
+	}
+	id, err := queryID(ctx)
+	if err != nil {
+		return err
+	}
+	ch.logf("[send query] query_id=%s", id)
+	if fn, ok := ctx.Value(queryIDFuncKey).(func(string)); ok {
+		fn(id)
+	}
+	if span, ok := ctx.Value(querySpanKey).(Span); ok {
+		span.SetAttribute("net.peer.name", ch.conn.host)
+		span.SetAttribute("db.clickhouse.query_id", id)
+	}
+	if err := ch.encoder.String(id); err != nil {
 		return err
 	}
 	{ // client info
@@ -35,13 +83,17 @@ func (ch *clickhouse) sendQuery(ctx context.Context, query string, externalTable
 		return err
 	}
 	if ch.ServerInfo.Revision >= protocol.DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO {
-		ch.encoder.String("")
+		quotaKey := ch.quotaKey
+		if key, ok := ctx.Value(quotaKeyKey).(string); ok {
+			quotaKey = key
+		}
+		ch.encoder.String(quotaKey)
 	}
 
 	// the settings are written as list of contiguous name-value pairs, finished with empty name
-	if !ch.settings.IsEmpty() {
-		ch.logf("[query settings] %s", ch.settings.settingsStr)
-		if err := ch.settings.Serialize(ch.encoder); err != nil {
+	if !settings.IsEmpty() {
+		ch.logf("[query settings] %s", settings.settingsStr)
+		if err := settings.Serialize(ch.encoder); err != nil {
 			return err
 		}
 	}
@@ -59,14 +111,43 @@ func (ch *clickhouse) sendQuery(ctx context.Context, query string, externalTable
 	if err := ch.encoder.Uvarint(compress); err != nil {
 		return err
 	}
-	if err := ch.encoder.String(query); err != nil {
+	if data, ok := ctx.Value(queryDataKey).(*queryData); ok {
+		if err := ch.writeQueryData(query, data); err != nil {
+			return err
+		}
+	} else if err := ch.encoder.String(query); err != nil {
 		return err
 	}
 	if err := ch.sendExternalTables(externalTables); err != nil {
 		return err
 	}
+	if err := ch.sendContextTables(contextTables); err != nil {
+		return err
+	}
 	if err := ch.writeBlock(&data.Block{}, ""); err != nil {
 		return err
 	}
-	return ch.encoder.Flush()
+	if err := ch.encoder.Flush(); err != nil {
+		return err
+	}
+	if metrics := ch.conn.metrics; metrics != nil {
+		metrics.QueryExecuted()
+	}
+	return nil
+}
+
+// checkQuerySize fails the query longer than the max_query_size of the DSN, or of the settings of the query,
+// before it is sent: the server would reject it by a syntax error at the point it cut the query off.
+// The size is not checked if max_query_size is not set.
+func (ch *clickhouse) checkQuerySize(query string, overrides map[string]interface{}) error {
+	limit := ch.maxQuerySize
+	if v, ok := overrides["max_query_size"]; ok {
+		if n, err := strconv.ParseUint(fmt.Sprint(v), 10, 64); err == nil {
+			limit = n
+		}
+	}
+	if limit != 0 && uint64(len(query)) > limit {
+		return fmt.Errorf("%w: the query is %d bytes, max_query_size is %d", ErrQueryTooLarge, len(query), limit)
+	}
+	return nil
 }
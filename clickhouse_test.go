@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"net"
 	"strings"
 	"testing"
@@ -43,7 +45,10 @@ func Test_RegisterTLSConfig(t *testing.T) {
 
 	connect, err = sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&tls_config="+tlsName)
 	assert.NoError(t, err)
-	assert.EqualError(t, connect.Ping(), "tls: first record does not look like a TLS handshake")
+	// the TLS failure is the error of the host reported by the *clickhouse.DialError
+	if err := connect.Ping(); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "tls: first record does not look like a TLS handshake")
+	}
 
 	clickhouse.DeregisterTLSConfig(tlsName)
 
@@ -1219,7 +1224,7 @@ func Test_IP(t *testing.T) {
 							if err := rows.Scan(&oldIPv4, &oldIPv6, &v4, &v6); assert.NoError(t, err) {
 								assert.Equal(t, net.IP(oldIPv4), ipv4)
 								assert.Equal(t, net.IP(oldIPv6), ipv6)
-								assert.Equal(t, v4, ipv4)
+								assert.Equal(t, v4, ipv4.To4())
 								assert.Equal(t, v6, ipv6)
 							}
 						}
@@ -1237,7 +1242,7 @@ func Test_Context_Timeout(t *testing.T) {
 			defer cancel()
 			if row := connect.QueryRowContext(ctx, "SELECT 1, sleep(2)"); assert.NotNil(t, row) {
 				var a, b int
-				assert.Equal(t, driver.ErrBadConn, row.Scan(&a, &b))
+				assert.Equal(t, context.DeadlineExceeded, row.Scan(&a, &b))
 			}
 		}
 		{
@@ -1253,6 +1258,29 @@ func Test_Context_Timeout(t *testing.T) {
 	}
 }
 
+func Test_CancelQuery(t *testing.T) {
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		conn, err := connect.Conn(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		// the queries are run on the same connection
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = conn.ExecContext(ctx, "SELECT sleepEachRow(0.1) FROM numbers(100) SETTINGS max_block_size = 1")
+		assert.Equal(t, context.DeadlineExceeded, err)
+		// the server stops the query once it is canceled
+		assert.True(t, time.Since(start) < 5*time.Second)
+		// the connection of the canceled query is still used
+		var value int
+		if assert.NoError(t, conn.QueryRowContext(context.Background(), "SELECT 42").Scan(&value)) {
+			assert.Equal(t, 42, value)
+		}
+	}
+}
+
 func Test_Ping_Context_Timeout(t *testing.T) {
 	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) && assert.NoError(t, connect.Ping()) {
 		{
@@ -1267,7 +1295,7 @@ func Test_Ping_Context_Timeout(t *testing.T) {
 			defer cancel()
 			if row := connect.QueryRowContext(ctx, "SELECT 1, sleep(2)"); assert.NotNil(t, row) {
 				var a, b int
-				assert.Equal(t, driver.ErrBadConn, row.Scan(&a, &b))
+				assert.Equal(t, context.DeadlineExceeded, row.Scan(&a, &b))
 			}
 		}
 		{
@@ -1444,6 +1472,65 @@ func TestArrayArrayT(t *testing.T) {
 	}
 }
 
+func Test_ArrayArrayEmpty(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_array_array_empty (
+				Float64 Array(Array(Float64)),
+				String  Array(Array(Array(String)))
+			) Engine=Memory
+		`
+		dml = `
+			INSERT INTO clickhouse_test_array_array_empty (Float64, String) VALUES (?, ?)
+		`
+		query = `
+			SELECT
+				Float64,
+				String
+			FROM clickhouse_test_array_array_empty
+		`
+	)
+	items := []struct {
+		Float64 [][]float64
+		String  [][][]string
+	}{
+		{[][]float64{{1.5, 2}, {}}, [][][]string{{}, {{}}}},
+		{[][]float64{}, [][][]string{}},
+		{[][]float64{{}, {3}}, [][][]string{{{"a", "b"}, {}}, {{"c"}}}},
+	}
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) && assert.NoError(t, connect.Ping()) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_array_array_empty"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for _, item := range items {
+							if _, err := stmt.Exec(item.Float64, item.String); !assert.NoError(t, err) {
+								return
+							}
+						}
+					}
+					if assert.NoError(t, tx.Commit()) {
+						if rows, err := connect.Query(query); assert.NoError(t, err) {
+							var i int
+							for ; rows.Next(); i++ {
+								var (
+									float64Values [][]float64
+									stringValues  [][][]string
+								)
+								if err := rows.Scan(&float64Values, &stringValues); assert.NoError(t, err) {
+									assert.Equal(t, items[i].Float64, float64Values)
+									assert.Equal(t, items[i].String, stringValues)
+								}
+							}
+							assert.Equal(t, len(items), i)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
 func Test_LikeQuery(t *testing.T) {
 	const (
 		ddl = `
@@ -1565,3 +1652,673 @@ func Test_RegisterDial(t *testing.T) {
 	}
 	clickhouse.DeregisterDial()
 }
+
+func Test_Map(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_map (
+				id          UInt8,
+				map         Map(String, UInt64),
+				mapNullable Map(String, Nullable(String)),
+				mapArray    Array(Map(String, Array(Int32)))
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_map (
+				id,
+				map,
+				mapNullable,
+				mapArray
+			) VALUES (
+				?,
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				map,
+				mapNullable,
+				mapArray
+			FROM clickhouse_test_map
+			ORDER BY id
+		`
+	)
+	value := "value"
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&allow_experimental_map_type=1"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_map"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							if _, err := stmt.Exec(
+								uint8(i),
+								map[string]uint64{"a": uint64(i), "b": 2},
+								map[string]*string{"a": &value, "b": nil},
+								[]map[string][]int32{{"a": {int32(i)}}, {}},
+							); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							m         map[string]uint64
+							mNullable map[string]*string
+							mArray    []map[string][]int32
+						)
+						if err := rows.Scan(&m, &mNullable, &mArray); assert.NoError(t, err) {
+							assert.Equal(t, map[string]uint64{"a": uint64(i), "b": 2}, m)
+							assert.Equal(t, map[string]*string{"a": &value, "b": nil}, mNullable)
+							assert.Equal(t, []map[string][]int32{{"a": {int32(i)}}, {}}, mArray)
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_Geo(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_geo (
+				id           UInt8,
+				point        Point,
+				ring         Ring,
+				polygon      Polygon,
+				multiPolygon MultiPolygon
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_geo (
+				id,
+				point,
+				ring,
+				polygon,
+				multiPolygon
+			) VALUES (
+				?,
+				?,
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				point,
+				ring,
+				polygon,
+				multiPolygon
+			FROM clickhouse_test_geo
+			ORDER BY id
+		`
+	)
+	var (
+		point        = [2]float64{13.404954, 52.520008}
+		ring         = [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+		polygon      = [][][2]float64{ring, {{1.1, 1.1}, {2.2, 1.1}, {2.2, 2.2}}}
+		multiPolygon = [][][][2]float64{polygon, {{{-0.1, 0.3}, {0.7, -0.000001}, {1e-10, 1e10}}}}
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&allow_experimental_geo_types=1"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_geo"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							if _, err := stmt.Exec(uint8(i), point, ring, polygon, multiPolygon); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							p  [2]float64
+							r  [][2]float64
+							pl [][][2]float64
+							mp [][][][2]float64
+						)
+						if err := rows.Scan(&p, &r, &pl, &mp); assert.NoError(t, err) {
+							assert.Equal(t, point, p)
+							assert.Equal(t, ring, r)
+							assert.Equal(t, polygon, pl)
+							assert.Equal(t, multiPolygon, mp)
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_LowCardinality(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_low_cardinality (
+				id                     UInt8,
+				lowCardinality         LowCardinality(String),
+				lowCardinalityNullable LowCardinality(Nullable(String))
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_low_cardinality (
+				id,
+				lowCardinality,
+				lowCardinalityNullable
+			) VALUES (
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				lowCardinality,
+				lowCardinalityNullable
+			FROM clickhouse_test_low_cardinality
+			ORDER BY id
+		`
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_low_cardinality"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							var nullable interface{}
+							if i%3 != 0 {
+								nullable = fmt.Sprintf("value_%d", i%2)
+							}
+							if _, err := stmt.Exec(uint8(i), fmt.Sprintf("value_%d", i%2), nullable); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							lowCardinality         string
+							lowCardinalityNullable *string
+						)
+						if err := rows.Scan(&lowCardinality, &lowCardinalityNullable); assert.NoError(t, err) {
+							assert.Equal(t, fmt.Sprintf("value_%d", i%2), lowCardinality)
+							if i%3 != 0 {
+								if assert.NotNil(t, lowCardinalityNullable) {
+									assert.Equal(t, fmt.Sprintf("value_%d", i%2), *lowCardinalityNullable)
+								}
+							} else {
+								assert.Nil(t, lowCardinalityNullable)
+							}
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_BigInt(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_big_int (
+				int128  Int128,
+				uint128 UInt128,
+				int256  Int256,
+				uint256 UInt256
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_big_int (
+				int128,
+				uint128,
+				int256,
+				uint256
+			) VALUES (
+				?,
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				int128,
+				uint128,
+				int256,
+				uint256
+			FROM clickhouse_test_big_int
+		`
+	)
+	var (
+		one     = big.NewInt(1)
+		int128  = new(big.Int).Neg(new(big.Int).Lsh(one, 127))
+		uint128 = new(big.Int).Sub(new(big.Int).Lsh(one, 128), one)
+		int256  = new(big.Int).Neg(new(big.Int).Lsh(one, 255))
+		uint256 = new(big.Int).Sub(new(big.Int).Lsh(one, 256), one)
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_big_int"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						if _, err := stmt.Exec(int128, uint128, int256, uint256); !assert.NoError(t, err) {
+							t.Fatal(err)
+						}
+						_, err := stmt.Exec(new(big.Int).Sub(int128, one), uint128, int256, uint256)
+						assert.Error(t, err)
+					}
+					assert.NoError(t, tx.Rollback())
+				}
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						if _, err := stmt.Exec(int128, uint128, int256, uint256); !assert.NoError(t, err) {
+							t.Fatal(err)
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var a, b, c, d *big.Int
+						if err := rows.Scan(&a, &b, &c, &d); assert.NoError(t, err) {
+							assert.Equal(t, int128.String(), a.String())
+							assert.Equal(t, uint128.String(), b.String())
+							assert.Equal(t, int256.String(), c.String())
+							assert.Equal(t, uint256.String(), d.String())
+						}
+					}
+					assert.Equal(t, 1, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_Nested(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_nested (
+				id    UInt8,
+				items Nested(id UInt32, name String, tags Array(String))
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_nested (
+				id,
+				items
+			) VALUES (
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				items
+			FROM clickhouse_test_nested
+			ORDER BY id
+		`
+	)
+	type item struct {
+		ID   uint32
+		Name string
+		Tags []string
+	}
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&flatten_nested=0"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_nested"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							items := []item{{uint32(i), fmt.Sprint(i), []string{"a", "b"}}, {uint32(i + 1), "", []string{}}}
+							if _, err := stmt.Exec(uint8(i), items); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var items []map[string]interface{}
+						if err := rows.Scan(&items); assert.NoError(t, err) {
+							assert.Equal(t, []map[string]interface{}{
+								{"id": uint32(i), "name": fmt.Sprint(i), "tags": []string{"a", "b"}},
+								{"id": uint32(i + 1), "name": "", "tags": []string{}},
+							}, items)
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_Tuple(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_tuple (
+				id    UInt8,
+				tuple Tuple(UInt32, String, Tuple(Float64, Float64)),
+				named Tuple(a UInt32, b String)
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_tuple (
+				id,
+				tuple,
+				named
+			) VALUES (
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				tuple,
+				named
+			FROM clickhouse_test_tuple
+			ORDER BY id
+		`
+	)
+	type (
+		point struct {
+			X, Y float64
+		}
+		element struct {
+			ID    uint32
+			Name  string
+			Point point
+		}
+		named struct {
+			B string `ch:"b"`
+			A uint32 `ch:"a"`
+		}
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_tuple"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							if _, err := stmt.Exec(
+								uint8(i),
+								element{uint32(i), fmt.Sprint(i), point{float64(i), 0.5}},
+								[]interface{}{uint32(i), "named"},
+							); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							e element
+							n named
+						)
+						if err := rows.Scan(clickhouse.ScanTuple(&e), clickhouse.ScanTuple(&n)); assert.NoError(t, err) {
+							assert.Equal(t, element{uint32(i), fmt.Sprint(i), point{float64(i), 0.5}}, e)
+							assert.Equal(t, named{"named", uint32(i)}, n)
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_Bool(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_bool (
+				id           UInt8,
+				bool         Bool,
+				boolNullable Nullable(Bool),
+				boolArray    Array(Bool)
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_bool (
+				id,
+				bool,
+				boolNullable,
+				boolArray
+			) VALUES (
+				?,
+				?,
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				bool,
+				boolNullable,
+				boolArray
+			FROM clickhouse_test_bool
+			ORDER BY id
+		`
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_bool"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i := 0; i < 10; i++ {
+							var nullable interface{}
+							if i%3 != 0 {
+								nullable = i%2 == 0
+							}
+							if _, err := stmt.Exec(uint8(i), i%2 == 0, nullable, []bool{true, i%2 == 0}); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var i int
+					for ; rows.Next(); i++ {
+						var (
+							b         bool
+							nullable  *bool
+							boolArray []bool
+						)
+						if err := rows.Scan(&b, &nullable, &boolArray); assert.NoError(t, err) {
+							assert.Equal(t, i%2 == 0, b)
+							if i%3 != 0 {
+								if assert.NotNil(t, nullable) {
+									assert.Equal(t, i%2 == 0, *nullable)
+								}
+							} else {
+								assert.Nil(t, nullable)
+							}
+							assert.Equal(t, []bool{true, i%2 == 0}, boolArray)
+						}
+					}
+					assert.Equal(t, 10, i)
+				}
+			}
+		}
+	}
+}
+
+func Test_JSON(t *testing.T) {
+	const (
+		ddl = `
+			CREATE TABLE clickhouse_test_json (
+				id       UInt8,
+				document Object('json')
+			) Engine=Memory;
+		`
+		dml = `
+			INSERT INTO clickhouse_test_json (
+				id,
+				document
+			) VALUES (
+				?,
+				?
+			)
+		`
+		query = `
+			SELECT
+				document
+			FROM clickhouse_test_json
+			ORDER BY id
+		`
+	)
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&allow_experimental=true&allow_experimental_object_type=1"); assert.NoError(t, err) {
+		if _, err := connect.Exec("DROP TABLE IF EXISTS clickhouse_test_json"); assert.NoError(t, err) {
+			if _, err := connect.Exec(ddl); assert.NoError(t, err) {
+				if tx, err := connect.Begin(); assert.NoError(t, err) {
+					if stmt, err := tx.Prepare(dml); assert.NoError(t, err) {
+						for i, document := range []string{`{"name": "a", "count": 1}`, `{"name": "b", "count": 2}`} {
+							if _, err := stmt.Exec(uint8(i), document); !assert.NoError(t, err) {
+								t.Fatal(err)
+							}
+						}
+					}
+					if err := tx.Commit(); !assert.NoError(t, err) {
+						t.Fatal(err)
+					}
+				}
+				if rows, err := connect.Query(query); assert.NoError(t, err) {
+					var documents []json.RawMessage
+					for rows.Next() {
+						var document json.RawMessage
+						if err := rows.Scan(clickhouse.ScanJSON(&document)); assert.NoError(t, err) {
+							documents = append(documents, document)
+						}
+					}
+					if assert.Len(t, documents, 2) {
+						assert.JSONEq(t, `{"name": "a", "count": 1}`, string(documents[0]))
+						assert.JSONEq(t, `{"name": "b", "count": 2}`, string(documents[1]))
+					}
+				}
+			}
+		}
+	}
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		_, err := connect.Query(query)
+		assert.Error(t, err)
+	}
+}
+
+func Test_ContextSettings(t *testing.T) {
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true&max_threads=2"); assert.NoError(t, err) {
+		// a single connection, the settings of a query must not be those of the following ones
+		connect.SetMaxOpenConns(1)
+		ctx := clickhouse.WithSettings(context.Background(), map[string]interface{}{
+			"max_threads":        4,
+			"max_execution_time": 60,
+			"readonly":           1,
+		})
+		var value string
+		if err := connect.QueryRowContext(ctx, "SELECT getSetting('max_threads')").Scan(&value); assert.NoError(t, err) {
+			assert.Equal(t, "4", value)
+		}
+		if err := connect.QueryRowContext(ctx, "SELECT getSetting('readonly')").Scan(&value); assert.NoError(t, err) {
+			assert.Equal(t, "1", value)
+		}
+		if err := connect.QueryRow("SELECT getSetting('max_threads')").Scan(&value); assert.NoError(t, err) {
+			assert.Equal(t, "2", value)
+		}
+		if err := connect.QueryRow("SELECT getSetting('readonly')").Scan(&value); assert.NoError(t, err) {
+			assert.Equal(t, "0", value)
+		}
+		ctx = clickhouse.WithSettings(context.Background(), map[string]interface{}{
+			"no_such_setting": 1,
+		})
+		if _, err := connect.ExecContext(ctx, "SELECT 1"); assert.Error(t, err) {
+			if exception, ok := err.(*clickhouse.Exception); assert.True(t, ok, err) {
+				assert.Contains(t, exception.Message, "no_such_setting")
+			}
+		}
+	}
+}
+
+func Test_QueryParameters(t *testing.T) {
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		var (
+			str      string
+			number   int64
+			array    []string
+			date     time.Time
+			dateTime = time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+		)
+		row := connect.QueryRow("SELECT {str:String}, {number:Int64}, {array:Array(String)}, {date:Date}, {date_time}",
+			sql.Named("str", "it's a 'test'"),
+			sql.Named("number", int64(-42)),
+			sql.Named("array", []string{"a", "b'c"}),
+			sql.Named("date", dateTime),
+			sql.Named("date_time", dateTime),
+		)
+		var scannedDateTime time.Time
+		if assert.NoError(t, row.Scan(&str, &number, &array, &date, &scannedDateTime)) {
+			assert.Equal(t, "it's a 'test'", str)
+			assert.Equal(t, int64(-42), number)
+			assert.Equal(t, []string{"a", "b'c"}, array)
+			assert.Equal(t, "2021-03-04", date.Format("2006-01-02"))
+			assert.Equal(t, dateTime.Unix(), scannedDateTime.Unix())
+		}
+		if _, err := connect.Exec("SELECT {a:String}", sql.Named("b", "b")); assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "query parameter a is not bound")
+		}
+	}
+}
+
+func Test_SessionTemporaryTable(t *testing.T) {
+	if connect, err := sql.Open("clickhouse", "tcp://127.0.0.1:9000?debug=true"); assert.NoError(t, err) {
+		conn, err := connect.Conn(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		ctx := clickhouse.WithSessionID(context.Background(), "clickhouse_test_session", false)
+		if _, err := conn.ExecContext(ctx, "CREATE TEMPORARY TABLE clickhouse_test_session AS SELECT number FROM numbers(10)"); !assert.NoError(t, err) {
+			return
+		}
+		var count uint64
+		ctx = clickhouse.WithSessionID(context.Background(), "clickhouse_test_session", true)
+		if assert.NoError(t, conn.QueryRowContext(ctx, "SELECT count() FROM clickhouse_test_session").Scan(&count)) {
+			assert.Equal(t, uint64(10), count)
+		}
+	}
+}
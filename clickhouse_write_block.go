@@ -1,6 +1,8 @@
 package clickhouse
 
 import (
+	"io"
+
 	"github.com/c3mb0/clickhouse-go/lib/data"
 	"github.com/c3mb0/clickhouse-go/lib/protocol"
 )
@@ -8,7 +10,7 @@ import (
 func (ch *clickhouse) writeBlock(block *data.Block, tableName string) error {
 	ch.Lock()
 	defer ch.Unlock()
-	if err := ch.encoder.Uvarint(protocol.ClientData); err != nil {
+	if err := ch.writePacket(protocol.ClientData); err != nil {
 		return err
 	}
 
@@ -38,3 +40,19 @@ func (ch *clickhouse) writeBlock(block *data.Block, tableName string) error {
 	ch.encoder.SelectCompress(false)
 	return err
 }
+
+// writeNativeBlock writes r, a block in the Native format, as the block of the table.
+func (ch *clickhouse) writeNativeBlock(r io.Reader, tableName string) error {
+	ch.Lock()
+	defer ch.Unlock()
+	if err := ch.writePacket(protocol.ClientData); err != nil {
+		return err
+	}
+	if err := ch.encoder.String(tableName); err != nil { // temporary table
+		return err
+	}
+	ch.encoder.SelectCompress(ch.compress)
+	err := (&data.Block{}).WriteNative(ch.encoder, r)
+	ch.encoder.SelectCompress(false)
+	return err
+}
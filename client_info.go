@@ -0,0 +1,58 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/c3mb0/clickhouse-go/lib/data"
+)
+
+// maxClientNameLength bounds the client_name of the DSN, the name is recorded by the server with every query.
+const maxClientNameLength = 128
+
+// parseClientInfo returns the client info of the client_name and client_version of the DSN, the empty values
+// are those of the driver.
+func parseClientInfo(name, version string) (data.ClientInfo, error) {
+	if err := validateClientName("client_name", name); err != nil {
+		return data.ClientInfo{}, err
+	}
+	major, minor, err := parseClientVersion(version)
+	if err != nil {
+		return data.ClientInfo{}, err
+	}
+	return data.ClientInfo{Name: name, VersionMajor: major, VersionMinor: minor}, nil
+}
+
+func validateClientName(key, name string) error {
+	if len(name) > maxClientNameLength {
+		return fmt.Errorf("invalid %s - must be at most %d characters, got %d", key, maxClientNameLength, len(name))
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] < ' ' || name[i] > '~' {
+			return fmt.Errorf("invalid %s - must be printable ASCII characters, got %q", key, name)
+		}
+	}
+	return nil
+}
+
+func validateClientVersion(_, version string) error {
+	_, _, err := parseClientVersion(version)
+	return err
+}
+
+// parseClientVersion parses the major.minor client_version, e.g. 2.3.
+func parseClientVersion(version string) (major, minor uint64, err error) {
+	if version == "" {
+		return 0, 0, nil
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) == 2 {
+		if major, err = strconv.ParseUint(parts[0], 10, 32); err == nil {
+			if minor, err = strconv.ParseUint(parts[1], 10, 32); err == nil {
+				return major, minor, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid client_version - must be the major and minor numbers of the version, e.g. 2.3, got %q", version)
+}
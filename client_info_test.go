@@ -0,0 +1,59 @@
+package clickhouse
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClientInfo(t *testing.T) {
+	for info, expected := range map[data.ClientInfo]string{
+		{}: "Golang SQLDriver 1.1",
+		{Name: "billing", VersionMajor: 2, VersionMinor: 3}: "billing 2.3",
+	} {
+		var buf bytes.Buffer
+		if assert.NoError(t, info.Write(binary.NewEncoder(&buf))) {
+			decoder := binary.NewDecoder(&buf)
+			name, _ := decoder.String()
+			major, _ := decoder.Uvarint()
+			minor, _ := decoder.Uvarint()
+			revision, _ := decoder.Uvarint()
+			assert.Equal(t, expected, fmt.Sprintf("%s %d.%d", name, major, minor))
+			assert.Equal(t, uint64(data.ClickHouseRevision), revision)
+		}
+	}
+
+	server := newHelloServer(t, data.ServerInfo{Name: "ClickHouse", MajorVersion: 21, MinorVersion: 8, Revision: protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE}, "UTC")
+	defer server.close()
+	for _, query := range []string{"", "&client_name=billing&client_version=2.3"} {
+		db, err := sql.Open("clickhouse", "tcp://"+server.addr+"?timeout=1&read_timeout=1"+query)
+		if assert.NoError(t, err) {
+			assert.NoError(t, db.Ping())
+			db.Close()
+		}
+	}
+	server.mutex.Lock()
+	assert.Equal(t, []string{"Golang SQLDriver 1.1", "billing 2.3"}, server.clients)
+	server.mutex.Unlock()
+
+	for query, expected := range map[string]string{
+		"client_name=" + strings.Repeat("a", 129): "invalid client_name - must be at most 128 characters, got 129",
+		"client_name=billing%C3%A9":               `invalid client_name - must be printable ASCII characters, got "billingé"`,
+		"client_version=2":                        `invalid client_version - must be the major and minor numbers of the version, e.g. 2.3, got "2"`,
+	} {
+		db, err := sql.Open("clickhouse", "tcp://"+server.addr+"?"+query)
+		if assert.NoError(t, err) {
+			if err := db.Ping(); assert.Error(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+			db.Close()
+		}
+	}
+}
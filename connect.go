@@ -2,12 +2,20 @@ package clickhouse
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/net/proxy"
 )
 
 var tick int32
@@ -20,6 +28,8 @@ func (s openStrategy) String() string {
 		return "in_order"
 	case connOpenTimeRandom:
 		return "time_random"
+	case connOpenLeastConn:
+		return "least_conn"
 	}
 	return "random"
 }
@@ -28,16 +38,52 @@ const (
 	connOpenRandom openStrategy = iota + 1
 	connOpenInOrder
 	connOpenTimeRandom
+	connOpenLeastConn
+)
+
+const (
+	defaultDialRetryBackoff = 100 * time.Millisecond
+	maxDialRetryBackoff     = 30 * time.Second
 )
 
 type connOptions struct {
 	secure, skipVerify                     bool
 	tlsConfig                              *tls.Config
+	tlsServerName                          string
+	tlsMinVersion                          uint16
+	tlsFingerprints                        [][]byte
+	verifyOCSP, allowMissingOCSP           bool
 	hosts                                  []string
+	socks5                                 string
+	weights                                []int
 	connTimeout, readTimeout, writeTimeout time.Duration
-	noDelay                                bool
+	totalDialTimeout                       time.Duration
+	strictDeadlines                        bool
+	noDelay, strictSocketOptions           bool
 	openStrategy                           openStrategy
+	balancer                               Balancer
+	balancerName                           string
+	parallelDial, dialRetries              int
+	readBufferSize                         int
+	dialRetryBackoff                       time.Duration
+	keepAlive, heartbeat, idleTimeout      time.Duration
 	logf                                   func(string, ...interface{})
+	logger                                 Logger  // nil if none is registered, the logs are printed by logf
+	metrics                                Metrics // nil if none is registered
+	dumpPackets                            bool    // log the packets, see packetDump
+}
+
+// log logs the structured log by the logger of the options, see logEvent.
+func (options *connOptions) log(level, msg string, kv ...interface{}) {
+	logEvent(options.logger, options.logf, level, msg, kv...)
+}
+
+// strategyName returns the name of the custom balancer or of the open strategy.
+func (options *connOptions) strategyName() string {
+	if options.balancer != nil {
+		return options.balancerName
+	}
+	return options.openStrategy.String()
 }
 
 // DialFunc is a function which can be used to establish the network connection.
@@ -63,114 +109,650 @@ func DeregisterDial() {
 	customDialLock.Unlock()
 }
 
-func dial(options connOptions) (*connect, error) {
+var (
+	liveConnsLock sync.Mutex
+	liveConns     = make(map[string]*int64)
+	openedConns   = make(map[string]*int64)
+	// poolStatsLock is held shared by the updates of the counters and exclusively by PoolStats,
+	// so that the counters of all the hosts are read at once
+	poolStatsLock sync.RWMutex
+)
+
+// liveConnCounter returns the counter of open connections to the host.
+func liveConnCounter(host string) *int64 {
+	liveConnsLock.Lock()
+	defer liveConnsLock.Unlock()
+	counter, ok := liveConns[host]
+	if !ok {
+		counter = new(int64)
+		liveConns[host] = counter
+	}
+	return counter
+}
+
+// openedConnCounter returns the counter of the connections ever opened to the host.
+func openedConnCounter(host string) *int64 {
+	liveConnsLock.Lock()
+	defer liveConnsLock.Unlock()
+	counter, ok := openedConns[host]
+	if !ok {
+		counter = new(int64)
+		openedConns[host] = counter
+	}
+	return counter
+}
+
+// HostStat are the connections to a host, see PoolStats.
+type HostStat struct {
+	Host   string
+	Live   int64 // the connections open
+	Opened int64 // the connections ever opened
+}
+
+// PoolStats returns the connections to the hosts dialed by the process, sorted by host, e.g. to check that
+// the connection_open_strategy spreads the connections over the replicas. The connections of all the DSNs
+// are counted together by host. The counters of all the hosts are read at once: no connection is opened
+// or closed in between.
+func PoolStats() []HostStat {
+	poolStatsLock.Lock()
+	defer poolStatsLock.Unlock()
+	liveConnsLock.Lock()
+	defer liveConnsLock.Unlock()
+	stats := make([]HostStat, 0, len(openedConns))
+	for host, opened := range openedConns {
+		var live int64
+		if counter, ok := liveConns[host]; ok {
+			live = atomic.LoadInt64(counter)
+		}
+		stats = append(stats, HostStat{Host: host, Live: live, Opened: atomic.LoadInt64(opened)})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+// leastConnHost returns the index of the not yet checked host with the smallest number of open connections.
+// Ties are broken by the ident so that concurrent dials do not pile up on the same host.
+func leastConnHost(hosts []string, ident int, checkedHosts map[int]struct{}) int {
 	var (
-		err error
-		abs = func(v int) int {
+		num   = -1
+		least int64
+	)
+	for i := range hosts {
+		idx := (ident + i) % len(hosts)
+		if _, ok := checkedHosts[idx]; ok {
+			continue
+		}
+		if count := atomic.LoadInt64(liveConnCounter(hosts[idx])); num == -1 || count < least {
+			num, least = idx, count
+		}
+	}
+	return num
+}
+
+// HostError describes the failure to dial a single host.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+// DialError is returned when none of the hosts could be dialed.
+type DialError struct {
+	Strategy string
+	Hosts    []HostError
+}
+
+func (e *DialError) Error() string {
+	failures := make([]string, 0, len(e.Hosts))
+	for _, host := range e.Hosts {
+		failures = append(failures, fmt.Sprintf("%s: %v", host.Host, host.Err))
+	}
+	return fmt.Sprintf("[dial] all hosts failed, strategy=%s: %s", e.Strategy, strings.Join(failures, "; "))
+}
+
+// Unwrap returns the error of the last dialed host.
+func (e *DialError) Unwrap() error {
+	if len(e.Hosts) == 0 {
+		return nil
+	}
+	return e.Hosts[len(e.Hosts)-1].Err
+}
+
+// connectionError wraps the error of a connection that couldn't be established, it is ErrConnectionFailed
+// to errors.Is and unwraps to the error of the dial or of the handshake, e.g. the *DialError.
+type connectionError struct {
+	err error
+}
+
+func (e *connectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *connectionError) Unwrap() error {
+	return e.err
+}
+
+func (e *connectionError) Is(target error) bool {
+	return target == ErrConnectionFailed
+}
+
+func dial(ctx context.Context, options connOptions) (*connect, error) {
+	var (
+		failures []HostError
+		abs      = func(v int) int {
 			if v < 0 {
 				return -1 * v
 			}
 			return v
 		}
-		conn  net.Conn
 		ident = abs(int(atomic.AddInt32(&tick, 1)))
 	)
-	tlsConfig := options.tlsConfig
+	var tlsConfig *tls.Config
 	if options.secure {
-		if tlsConfig == nil {
+		// the config is shared by all connections of the DSN, so every dial modifies its own copy
+		if tlsConfig = options.tlsConfig.Clone(); tlsConfig == nil {
 			tlsConfig = &tls.Config{}
 		}
 		tlsConfig.InsecureSkipVerify = options.skipVerify
+		if len(options.tlsServerName) != 0 {
+			tlsConfig.ServerName = options.tlsServerName
+		}
+		if len(options.tlsFingerprints) != 0 {
+			pinCertificate(tlsConfig, options.tlsFingerprints)
+		}
+		if options.verifyOCSP {
+			verifyOCSP(tlsConfig, options.allowMissingOCSP)
+		}
+		switch {
+		case options.tlsMinVersion != 0:
+			tlsConfig.MinVersion = options.tlsMinVersion
+		case tlsConfig.MinVersion == 0:
+			tlsConfig.MinVersion = tls.VersionTLS12
+		}
+	}
+	var deadline time.Time
+	if options.totalDialTimeout > 0 {
+		deadline = time.Now().Add(options.totalDialTimeout)
 	}
-	checkedHosts := make(map[int]struct{}, len(options.hosts))
-	for i := range options.hosts {
+	for attempt := 0; ; attempt++ {
+		conn, num, roundFailures, err := dialRound(ctx, options, tlsConfig, ident, deadline)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			c, err := newConnect(conn, num, ident, options)
+			if err == nil && options.metrics != nil {
+				options.metrics.ConnectionOpened(options.hosts[num])
+			}
+			return c, err
+		}
+		failures = append(failures, roundFailures...)
+		if attempt >= options.dialRetries {
+			break
+		}
+		backoff := dialBackoff(options.dialRetryBackoff, attempt)
+		if !deadline.IsZero() && time.Until(deadline) <= backoff {
+			options.log(LogWarn, "[dial] total connect timeout exceeded", "ident", ident, "strategy", options.strategyName())
+			break
+		}
+		options.log(LogInfo, "[dial] retry", "attempt", attempt+1, "backoff", backoff, "ident", ident, "strategy", options.strategyName())
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("[dial] aborted: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+	return nil, &DialError{
+		Strategy: options.strategyName(),
+		Hosts:    failures,
+	}
+}
+
+// dialRound makes a single pass over the hosts and returns the first established connection
+// or the failures of all hosts. The pass stops early if the deadline of the total dial budget is reached.
+func dialRound(ctx context.Context, options connOptions, tlsConfig *tls.Config, ident int, deadline time.Time) (net.Conn, int, []HostError, error) {
+	var (
+		failures []HostError
+		parallel = options.parallelDial
+		skipOpen = !breaker.allOpen(options.hosts) // all hosts are tried if none of them is healthy
+		balancer = options.balancer
+	)
+	if balancer == nil {
+		balancer = &strategyBalancer{
+			strategy: options.openStrategy,
+			weights:  options.weights,
+			ident:    ident,
+		}
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	for attempt := 0; attempt < len(options.hosts); {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, 0, nil, fmt.Errorf("[dial] aborted: %w", ctxErr)
+		}
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				options.log(LogWarn, "[dial] total connect timeout exceeded", "ident", ident, "strategy", options.strategyName())
+				break
+			}
+			if options.connTimeout == 0 || remaining < options.connTimeout {
+				options.connTimeout = remaining // shrink the per host timeout to the rest of the budget
+			}
+		}
+		// the hosts are picked batch by batch, after the failures of the previous ones
+		batch, err := pickHosts(balancer, options.hosts, attempt, parallel)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("[dial] %s: %w", options.strategyName(), err)
+		}
+		attempt += len(batch)
+		conn, num, batchFailures := dialBatch(ctx, options, tlsConfig, ident, batch, skipOpen)
+		if conn != nil {
+			return conn, num, nil, nil
+		}
+		failures = append(failures, batchFailures...)
+	}
+	return nil, 0, failures, nil
+}
+
+// dialBackoff returns the exponential backoff before the retry with equal jitter.
+func dialBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = defaultDialRetryBackoff
+	}
+	backoff := base
+	for i := 0; i < attempt && backoff < maxDialRetryBackoff; i++ {
+		backoff <<= 1
+	}
+	if backoff > maxDialRetryBackoff {
+		backoff = maxDialRetryBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// minReadBufferSize mirrors the unexported minimum buffer size of bufio.Reader
+const minReadBufferSize = 16
+
+// newReadBuffer returns the buffered reader of the connection, the size of 0 selects the bufio default.
+// The read deadline is refreshed on every Read of the connection, not per syscall, so even a large
+// buffer is filled under a deadline that is at most readTimeout old and the timeout heuristic still holds.
+func newReadBuffer(conn net.Conn, size int) *bufio.Reader {
+	if size == 0 {
+		return bufio.NewReader(conn)
+	}
+	return bufio.NewReaderSize(conn, size)
+}
+
+func newConnect(conn net.Conn, num, ident int, options connOptions) (*connect, error) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		if err := setSocketOptions(tcp, options); err != nil {
+			if options.strictSocketOptions {
+				conn.Close()
+				return nil, err
+			}
+			options.log(LogWarn, "[dial] set socket options failed", "host", options.hosts[num], "ident", ident, "error", err)
+		}
+	}
+	var tlsState *tls.ConnectionState
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		tlsState = &state
+	}
+	counter, opened := liveConnCounter(options.hosts[num]), openedConnCounter(options.hosts[num])
+	poolStatsLock.RLock()
+	atomic.AddInt64(opened, 1)
+	atomic.AddInt64(counter, 1)
+	poolStatsLock.RUnlock()
+	c := &connect{
+		Conn:               conn,
+		tlsState:           tlsState,
+		liveConns:          counter,
+		busy:               make(chan struct{}, 1),
+		logf:               options.logf,
+		logger:             options.logger,
+		ident:              ident,
+		server:             num,
+		host:               options.hosts[num],
+		buffer:             newReadBuffer(conn, options.readBufferSize),
+		readTimeout:        options.readTimeout,
+		defaultReadTimeout: options.readTimeout,
+		writeTimeout:       options.writeTimeout,
+		strictDeadlines:    options.strictDeadlines,
+		idleTimeout:        options.idleTimeout,
+		clock:              time.Now,
+		created:            time.Now(),
+		metrics:            options.metrics,
+	}
+	if options.dumpPackets {
+		c.dump = &packetDump{logf: options.logf}
+	}
+	c.writer = bufio.NewWriter(writerFunc(c.writeSocket))
+	return c, nil
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
+
+func setSocketOptions(tcp *net.TCPConn, options connOptions) error {
+	if err := tcp.SetNoDelay(options.noDelay); err != nil { // Disable or enable the Nagle Algorithm for this tcp socket
+		return err
+	}
+	if options.keepAlive != 0 {
+		if err := tcp.SetKeepAlive(true); err != nil {
+			return err
+		}
+		if err := tcp.SetKeepAlivePeriod(options.keepAlive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostOrder returns the indexes of the hosts in the order in which they are to be dialed.
+func hostOrder(strategy openStrategy, hosts []string, weights []int, ident int) []int {
+	switch {
+	case strategy == connOpenRandom && isWeighted(weights):
+		return weightedOrder(weights, ident)
+	case strategy == connOpenTimeRandom:
+		// shuffle hosts based on the current time
+		return rand.New(rand.NewSource(time.Now().UnixNano())).Perm(len(hosts))
+	}
+	var (
+		order        = make([]int, 0, len(hosts))
+		checkedHosts = make(map[int]struct{}, len(hosts))
+	)
+	for i := range hosts {
 		var num int
-		switch options.openStrategy {
+		switch strategy {
 		case connOpenInOrder:
 			num = i
 		case connOpenRandom:
-			num = (ident + i) % len(options.hosts)
-		case connOpenTimeRandom:
-			// select host based on milliseconds
-			num = int((time.Now().UnixNano()/1000)%1000) % len(options.hosts)
-			for _, ok := checkedHosts[num]; ok; _, ok = checkedHosts[num] {
-				num = int(time.Now().UnixNano()) % len(options.hosts)
-			}
+			num = (ident + i) % len(hosts)
+		case connOpenLeastConn:
+			num = leastConnHost(hosts, ident, checkedHosts)
 			checkedHosts[num] = struct{}{}
 		}
-		customDialLock.RLock()
-		cd := customDial
-		customDialLock.RUnlock()
-		switch {
-		case options.secure:
-			if cd != nil {
-				conn, err = cd("tcp", options.hosts[num], options.connTimeout, tlsConfig)
-			} else {
-				conn, err = tls.DialWithDialer(
-					&net.Dialer{
-						Timeout: options.connTimeout,
-					},
-					"tcp",
-					options.hosts[num],
-					tlsConfig,
-				)
+		order = append(order, num)
+	}
+	return order
+}
+
+func isWeighted(weights []int) bool {
+	for _, weight := range weights {
+		if weight != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// weightedOrder picks the hosts one by one by weighted random without replacement,
+// using the ident as the source of randomness.
+func weightedOrder(weights []int, ident int) []int {
+	var (
+		order     = make([]int, 0, len(weights))
+		remaining = make([]int, len(weights))
+	)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	for len(remaining) != 0 {
+		var total int
+		for _, num := range remaining {
+			total += weights[num]
+		}
+		point := ident % total
+		for i, num := range remaining {
+			if point -= weights[num]; point < 0 {
+				order = append(order, num)
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				break
 			}
-		default:
-			if cd != nil {
-				conn, err = cd("tcp", options.hosts[num], options.connTimeout, nil)
-			} else {
-				conn, err = net.DialTimeout("tcp", options.hosts[num], options.connTimeout)
+		}
+	}
+	return order
+}
+
+type dialResult struct {
+	conn net.Conn
+	num  int
+	err  error
+}
+
+// dialBatch races the hosts of the batch and returns the first established connection
+// or the failures of all hosts. Connections established after the winner was chosen are closed.
+func dialBatch(ctx context.Context, options connOptions, tlsConfig *tls.Config, ident int, batch []int, skipOpen bool) (net.Conn, int, []HostError) {
+	if len(batch) == 1 {
+		conn, err := dialCandidate(ctx, options, tlsConfig, ident, batch[0], skipOpen)
+		if err != nil {
+			return nil, 0, []HostError{{Host: options.hosts[batch[0]], Err: err}}
+		}
+		return conn, batch[0], nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan dialResult, len(batch))
+	for _, num := range batch {
+		go func(num int) {
+			conn, err := dialCandidate(ctx, options, tlsConfig, ident, num, skipOpen)
+			results <- dialResult{
+				conn: conn,
+				num:  num,
+				err:  err,
 			}
+		}(num)
+	}
+	var failures []HostError
+	for i := range batch {
+		result := <-results
+		if result.err != nil {
+			failures = append(failures, HostError{
+				Host: options.hosts[result.num],
+				Err:  result.err,
+			})
+			continue
 		}
-		if err == nil {
-			options.logf(
-				"[dial] secure=%t, skip_verify=%t, strategy=%s, ident=%d, server=%d -> %s",
-				options.secure,
-				options.skipVerify,
-				options.openStrategy,
-				ident,
-				num,
-				conn.RemoteAddr(),
-			)
-			if tcp, ok := conn.(*net.TCPConn); ok {
-				err = tcp.SetNoDelay(options.noDelay) // Disable or enable the Nagle Algorithm for this tcp socket
-				if err != nil {
-					return nil, err
+		cancel()
+		go func(pending int) {
+			for ; pending > 0; pending-- {
+				if loser := <-results; loser.err == nil {
+					loser.conn.Close()
 				}
 			}
-			return &connect{
-				Conn:         conn,
-				logf:         options.logf,
-				ident:        ident,
-				buffer:       bufio.NewReader(conn),
-				readTimeout:  options.readTimeout,
-				writeTimeout: options.writeTimeout,
-			}, nil
-		} else {
-			options.logf(
-				"[dial err] secure=%t, skip_verify=%t, strategy=%s, ident=%d, addr=%s\n%#v",
-				options.secure,
-				options.skipVerify,
-				options.openStrategy,
-				ident,
-				options.hosts[num],
-				err,
-			)
-		}
-	}
-	return nil, err
+		}(len(batch) - i - 1)
+		return result.conn, result.num, nil
+	}
+	cancel()
+	return nil, 0, failures
+}
+
+// dialCandidate dials the host with the given index, unless it is skipped by the circuit breaker or the health check.
+func dialCandidate(ctx context.Context, options connOptions, tlsConfig *tls.Config, ident, num int, skipOpen bool) (net.Conn, error) {
+	host := options.hosts[num]
+	if skipOpen && breaker.isOpen(host) {
+		options.log(LogDebug, "[dial] circuit breaker is open, skip", "host", host, "ident", ident)
+		return nil, fmt.Errorf("[dial] circuit breaker is open for %s", host)
+	}
+	if err := checkHealth(host); err != nil {
+		options.log(LogWarn, "[dial err] health check failed", "host", host, "ident", ident, "error", err)
+		breaker.failure(host)
+		if options.metrics != nil {
+			options.metrics.DialFailed(host, err)
+		}
+		return nil, err
+	}
+	conn, err := dialHost(ctx, host, options, tlsConfig)
+	if err != nil {
+		options.log(LogWarn, "[dial err]",
+			"host", host,
+			"ident", ident,
+			"strategy", options.strategyName(),
+			"secure", options.secure,
+			"skip_verify", options.skipVerify,
+			"error", err,
+		)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("[dial] aborted: %w", ctxErr)
+		}
+		breaker.failure(host)
+		if options.metrics != nil {
+			options.metrics.DialFailed(host, err)
+		}
+		return nil, err
+	}
+	breaker.success(host)
+	options.log(LogInfo, "[dial]",
+		"host", host,
+		"ident", ident,
+		"strategy", options.strategyName(),
+		"secure", options.secure,
+		"skip_verify", options.skipVerify,
+		"server", num,
+		"addr", remoteAddr(conn, host),
+	)
+	return conn, nil
+}
+
+// unixSocketPrefix marks a host as the path to a Unix domain socket, e.g. unix:///var/run/clickhouse.sock
+const unixSocketPrefix = "unix://"
+
+// hostNetwork splits the host into the network and the address to dial.
+func hostNetwork(host string) (network, address string) {
+	if strings.HasPrefix(host, unixSocketPrefix) {
+		return "unix", strings.TrimPrefix(host, unixSocketPrefix)
+	}
+	return "tcp", host
+}
+
+func dialHost(ctx context.Context, host string, options connOptions, tlsConfig *tls.Config) (net.Conn, error) {
+	customDialLock.RLock()
+	cd := customDial
+	customDialLock.RUnlock()
+	var (
+		timeout          = options.connTimeout
+		network, address = hostNetwork(host)
+	)
+	switch {
+	case options.secure && network == "tcp":
+		if cd != nil {
+			return cd(network, address, timeout, tlsConfig)
+		}
+		if len(options.socks5) != 0 {
+			conn, err := dialSOCKS5(ctx, options.socks5, address, timeout)
+			if err != nil {
+				return nil, err
+			}
+			return clientTLS(ctx, conn, address, timeout, tlsConfig)
+		}
+		dialer := tls.Dialer{
+			NetDialer: &net.Dialer{
+				Timeout: timeout,
+			},
+			Config: tlsConfig,
+		}
+		return dialer.DialContext(ctx, network, address)
+	default:
+		// TLS is never used over Unix domain sockets
+		if cd != nil {
+			return cd(network, address, timeout, nil)
+		}
+		if len(options.socks5) != 0 && network == "tcp" {
+			return dialSOCKS5(ctx, options.socks5, address, timeout)
+		}
+		dialer := net.Dialer{
+			Timeout: timeout,
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// dialSOCKS5 dials the address through the SOCKS5 proxy, the timeout covers both
+// the connection to the proxy and the proxy handshake.
+func dialSOCKS5(ctx context.Context, proxyAddress, address string, timeout time.Duration) (net.Conn, error) {
+	dialer, err := proxy.SOCKS5("tcp", proxyAddress, nil, &net.Dialer{
+		Timeout: timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if dialer, ok := dialer.(proxy.ContextDialer); ok {
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+	return dialer.Dial("tcp", address)
+}
+
+// clientTLS runs the TLS handshake over the already established connection.
+func clientTLS(ctx context.Context, conn net.Conn, address string, timeout time.Duration, tlsConfig *tls.Config) (net.Conn, error) {
+	if len(tlsConfig.ServerName) == 0 {
+		tlsConfig = tlsConfig.Clone()
+		if tlsConfig.ServerName, _, _ = net.SplitHostPort(address); len(tlsConfig.ServerName) == 0 {
+			tlsConfig.ServerName = address
+		}
+	}
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// remoteAddr returns the address of the server for logging, Unix domain sockets have no remote address.
+func remoteAddr(conn net.Conn, host string) string {
+	if addr := conn.RemoteAddr(); addr != nil && addr.String() != "" {
+		return addr.String()
+	}
+	return host
 }
 
 type connect struct {
 	net.Conn
 	logf                  func(string, ...interface{})
+	logger                Logger // nil if none is registered, see logEvent
 	ident                 int
+	server                int                  // index of the dialed host
+	host                  string               // the dialed host
+	tlsState              *tls.ConnectionState // nil if the connection is not secure
+	serverVersion         ServerVersion        // set by the hello of the server
+	serverTimezone        *time.Location
+	liveConns             *int64
 	buffer                *bufio.Reader
-	closed                bool
+	writer                *bufio.Writer
+	ioErr                 error // the I/O error which closed the connection
+	closed                int32 // connStateOpen, connStateDraining or connStateClosed
+	ioMutex               sync.Mutex
+	inFlight              int           // reads and writes in progress, guarded by ioMutex
+	drained               chan struct{} // closed once inFlight drops to 0 while draining, guarded by ioMutex
+	inUse                 int32
+	busy                  chan struct{} // held by the driver while a query is running or by the heartbeat while it pings
+	done                  chan struct{}
 	readTimeout           time.Duration
+	defaultReadTimeout    time.Duration // read timeout of the DSN, readTimeout may be overridden for a query
 	writeTimeout          time.Duration
+	strictDeadlines       bool             // set the deadline on every read and write instead of refreshing it
+	clock                 func() time.Time // source of the deadlines, time.Now carries the monotonic reading
 	lastReadDeadlineTime  time.Time
 	lastWriteDeadlineTime time.Time
+	idleTimeout           time.Duration
+	created               time.Time
+	lastActivity          int64       // time of the last successful read or write since created, accessed atomically
+	received              uint64      // the bytes read since created, see QueryStats
+	metrics               Metrics     // nil if none is registered
+	dump                  *packetDump // nil unless debug of the DSN is set
 }
 
 func (conn *connect) Read(b []byte) (int, error) {
@@ -180,47 +762,198 @@ func (conn *connect) Read(b []byte) (int, error) {
 		total  int
 		dstLen = len(b)
 	)
-	if currentTime := now(); conn.readTimeout != 0 && currentTime.Sub(conn.lastReadDeadlineTime) > (conn.readTimeout>>2) {
-		conn.SetReadDeadline(time.Now().Add(conn.readTimeout))
+	if !conn.beginIO() {
+		return 0, driver.ErrBadConn
+	}
+	defer conn.endIO()
+	if currentTime := conn.clock(); conn.readTimeout != 0 && (conn.strictDeadlines || refreshDeadline(currentTime, conn.lastReadDeadlineTime, conn.readTimeout)) {
+		conn.SetReadDeadline(currentTime.Add(conn.readTimeout))
 		conn.lastReadDeadlineTime = currentTime
 	}
 	for total < dstLen {
 		if n, err = conn.buffer.Read(b[total:]); err != nil {
-			conn.logf("[connect] read error: %v", err)
-			conn.Close()
-			return n, driver.ErrBadConn
+			conn.fail("read", err)
+			return total + n, driver.ErrBadConn
 		}
 		total += n
 	}
+	conn.touch()
+	conn.received += uint64(total)
+	if conn.metrics != nil {
+		conn.metrics.BytesRead(total)
+	}
+	if conn.dump != nil {
+		conn.dump.bytesRead(b[:total])
+	}
 	return total, nil
 }
 
+// Write buffers the data, it is sent to the server by Flush or once the buffer is full.
 func (conn *connect) Write(b []byte) (int, error) {
+	if conn.dump != nil {
+		conn.dump.bytesWritten(b)
+	}
+	return conn.writer.Write(b)
+}
+
+// Flush sends the buffered data to the server.
+func (conn *connect) Flush() error {
+	err := conn.writer.Flush()
+	if err == nil && conn.dump != nil {
+		conn.dump.flushed()
+	}
+	return err
+}
+
+// writeSocket writes to the underlying connection, it is the sink of the write buffer.
+func (conn *connect) writeSocket(b []byte) (int, error) {
 	var (
 		n      int
 		err    error
 		total  int
 		srcLen = len(b)
 	)
-	if currentTime := now(); conn.writeTimeout != 0 && currentTime.Sub(conn.lastWriteDeadlineTime) > (conn.writeTimeout>>2) {
-		conn.SetWriteDeadline(time.Now().Add(conn.writeTimeout))
+	if !conn.beginIO() {
+		return 0, driver.ErrBadConn
+	}
+	defer conn.endIO()
+	if currentTime := conn.clock(); conn.writeTimeout != 0 && (conn.strictDeadlines || refreshDeadline(currentTime, conn.lastWriteDeadlineTime, conn.writeTimeout)) {
+		conn.SetWriteDeadline(currentTime.Add(conn.writeTimeout))
 		conn.lastWriteDeadlineTime = currentTime
 	}
 	for total < srcLen {
 		if n, err = conn.Conn.Write(b[total:]); err != nil {
-			conn.logf("[connect] write error: %v", err)
-			conn.Close()
-			return n, driver.ErrBadConn
+			conn.fail("write", err)
+			return total + n, driver.ErrBadConn
 		}
 		total += n
 	}
-	return n, nil
+	conn.touch()
+	if conn.metrics != nil {
+		conn.metrics.BytesWritten(total)
+	}
+	return total, nil
+}
+
+// touch records the activity on the connection.
+func (conn *connect) touch() {
+	if conn.idleTimeout != 0 {
+		atomic.StoreInt64(&conn.lastActivity, int64(conn.clock().Sub(conn.created)))
+	}
+}
+
+// IsExpired reports whether the connection has been idle for longer than the idle timeout.
+func (conn *connect) IsExpired() bool {
+	if conn.idleTimeout == 0 {
+		return false
+	}
+	idle := conn.clock().Sub(conn.created) - time.Duration(atomic.LoadInt64(&conn.lastActivity))
+	return idle > conn.idleTimeout
+}
+
+// fail records the error of the I/O and closes the connection, the error is reported as driver.ErrBadConn.
+func (conn *connect) fail(op string, err error) {
+	var netErr net.Error
+	msg := "[connect] " + op + " error"
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		msg = "[connect] " + op + " timeout"
+	}
+	logEvent(conn.logger, conn.logf, LogError, msg, "host", conn.host, "ident", conn.ident, "error", err)
+	conn.ioErr = err
+	conn.Close()
+}
+
+// overrideReadTimeout sets the read timeout, the deadline is moved by the next read.
+func (conn *connect) overrideReadTimeout(timeout time.Duration) {
+	conn.readTimeout = timeout
+	conn.lastReadDeadlineTime = time.Time{}
+	if timeout == 0 {
+		conn.SetReadDeadline(time.Time{})
+	}
+}
+
+// refreshDeadline reports whether the deadline set at last has to be moved, it is moved once a quarter
+// of the timeout has elapsed to save syscalls, so an I/O may take up to 1.25 of the timeout.
+// The clock readings are monotonic, but the deadline is also moved if the clock went backwards,
+// so a stalled I/O always hits a deadline set from the current time.
+func refreshDeadline(currentTime, last time.Time, timeout time.Duration) bool {
+	elapsed := currentTime.Sub(last)
+	return elapsed < 0 || elapsed > (timeout>>2)
+}
+
+const (
+	connStateOpen int32 = iota
+	connStateClosed
+	connStateDraining // no new I/O is accepted, the connection is closed once the I/O in progress is done
+)
+
+func (conn *connect) isClosed() bool {
+	return atomic.LoadInt32(&conn.closed) != connStateOpen
+}
+
+// beginIO registers a read or a write, it returns false if the connection does not accept new I/O.
+func (conn *connect) beginIO() bool {
+	conn.ioMutex.Lock()
+	defer conn.ioMutex.Unlock()
+	if atomic.LoadInt32(&conn.closed) != connStateOpen {
+		return false
+	}
+	conn.inFlight++
+	return true
+}
+
+func (conn *connect) endIO() {
+	conn.ioMutex.Lock()
+	defer conn.ioMutex.Unlock()
+	if conn.inFlight--; conn.inFlight == 0 && conn.drained != nil {
+		close(conn.drained)
+		conn.drained = nil
+	}
+}
+
+// CloseGracefully stops accepting new reads and writes and closes the connection once the ones
+// in progress are done, but waits no longer than the timeout. Close closes the connection immediately.
+func (conn *connect) CloseGracefully(timeout time.Duration) error {
+	conn.ioMutex.Lock()
+	if !atomic.CompareAndSwapInt32(&conn.closed, connStateOpen, connStateDraining) {
+		conn.ioMutex.Unlock()
+		return nil
+	}
+	var drained chan struct{}
+	if conn.inFlight != 0 {
+		drained = make(chan struct{})
+		conn.drained = drained
+	}
+	conn.ioMutex.Unlock()
+	if drained != nil {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-drained:
+		case <-timer.C:
+			logEvent(conn.logger, conn.logf, LogWarn, "[connect] I/O in progress not finished, closing", "host", conn.host, "ident", conn.ident, "timeout", timeout)
+		}
+	}
+	return conn.Close()
 }
 
 func (conn *connect) Close() error {
-	if !conn.closed {
-		conn.closed = true
-		return conn.Conn.Close()
+	for {
+		state := atomic.LoadInt32(&conn.closed)
+		if state == connStateClosed {
+			return nil
+		}
+		if atomic.CompareAndSwapInt32(&conn.closed, state, connStateClosed) {
+			break
+		}
 	}
-	return nil
+	if conn.liveConns != nil {
+		poolStatsLock.RLock()
+		atomic.AddInt64(conn.liveConns, -1)
+		poolStatsLock.RUnlock()
+	}
+	if conn.done != nil {
+		close(conn.done)
+	}
+	return conn.Conn.Close()
 }
@@ -0,0 +1,85 @@
+package clickhouse
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+)
+
+// acquire marks the connection as used by the driver, the heartbeat is suspended until release is called.
+func (conn *connect) acquire() {
+	if atomic.CompareAndSwapInt32(&conn.inUse, 0, 1) {
+		conn.busy <- struct{}{}
+	}
+}
+
+// release marks the connection as idle and restores the read timeout of the DSN,
+// it is safe to call release more than once.
+func (conn *connect) release() {
+	if atomic.CompareAndSwapInt32(&conn.inUse, 1, 0) {
+		if conn.readTimeout != conn.defaultReadTimeout {
+			conn.overrideReadTimeout(conn.defaultReadTimeout)
+		}
+		<-conn.busy
+	}
+}
+
+// startHeartbeat pings the server every interval while the connection is idle
+// and closes the connection if the server does not answer.
+func (conn *connect) startHeartbeat(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	conn.done = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-conn.done:
+				return
+			case <-ticker.C:
+				select {
+				case conn.busy <- struct{}{}:
+				default:
+					continue // the connection is in use
+				}
+				err := conn.ping()
+				<-conn.busy
+				if err != nil {
+					logEvent(conn.logger, conn.logf, LogWarn, "[heartbeat] ping failed", "host", conn.host, "ident", conn.ident, "error", err)
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+}
+
+func (conn *connect) ping() error {
+	if conn.isClosed() {
+		return fmt.Errorf("connection is closed")
+	}
+	if conn.dump != nil {
+		conn.dump.beginWrite(protocol.ClientPing)
+	}
+	if _, err := conn.Write([]byte{protocol.ClientPing}); err != nil {
+		return err
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	packet := make([]byte, 1)
+	if _, err := conn.Read(packet); err != nil {
+		return err
+	}
+	if conn.dump != nil {
+		conn.dump.beginRead(uint64(packet[0]))
+	}
+	if packet[0] != protocol.ServerPong {
+		return fmt.Errorf("[heartbeat] unexpected packet [%d] from server", packet[0])
+	}
+	return nil
+}
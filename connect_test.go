@@ -0,0 +1,789 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_leastConnHost(t *testing.T) {
+	hosts := []string{"least-conn-1:9000", "least-conn-2:9000", "least-conn-3:9000"}
+	atomic.AddInt64(liveConnCounter(hosts[0]), 2)
+	atomic.AddInt64(liveConnCounter(hosts[1]), 1)
+	atomic.AddInt64(liveConnCounter(hosts[2]), 1)
+	defer func() {
+		atomic.AddInt64(liveConnCounter(hosts[0]), -2)
+		atomic.AddInt64(liveConnCounter(hosts[1]), -1)
+		atomic.AddInt64(liveConnCounter(hosts[2]), -1)
+	}()
+	assert.Equal(t, 1, leastConnHost(hosts, 0, map[int]struct{}{}))
+	assert.Equal(t, 2, leastConnHost(hosts, 2, map[int]struct{}{}))
+	assert.Equal(t, 2, leastConnHost(hosts, 0, map[int]struct{}{1: {}}))
+	assert.Equal(t, 0, leastConnHost(hosts, 0, map[int]struct{}{1: {}, 2: {}}))
+}
+
+func Test_PoolStats(t *testing.T) {
+	stat := func(host string) HostStat {
+		for _, stat := range PoolStats() {
+			if stat.Host == host {
+				return stat
+			}
+		}
+		return HostStat{Host: host}
+	}
+	var (
+		hosts   = []string{"pool-stats-1:9000", "pool-stats-2:9000"}
+		options = connOptions{hosts: hosts, logf: func(string, ...interface{}) {}}
+		conns   []*connect
+	)
+	for _, num := range []int{0, 0, 1} {
+		conn, err := newConnect(&stubConn{}, num, 0, options)
+		if !assert.NoError(t, err) {
+			return
+		}
+		conns = append(conns, conn)
+	}
+	assert.Equal(t, HostStat{Host: hosts[0], Live: 2, Opened: 2}, stat(hosts[0]))
+	assert.Equal(t, HostStat{Host: hosts[1], Live: 1, Opened: 1}, stat(hosts[1]))
+	conns[0].Close()
+	// closed twice, counted once
+	conns[0].Close()
+	assert.Equal(t, HostStat{Host: hosts[0], Live: 1, Opened: 2}, stat(hosts[0]))
+	for _, conn := range conns[1:] {
+		conn.Close()
+	}
+	assert.Equal(t, HostStat{Host: hosts[1], Live: 0, Opened: 1}, stat(hosts[1]))
+	stats := PoolStats()
+	assert.True(t, sort.SliceIsSorted(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host }))
+}
+
+func Test_DialCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := dial(ctx, connOptions{
+		hosts:        []string{"127.0.0.1:9000"},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if assert.Error(t, err) {
+		assert.True(t, errors.Is(err, context.Canceled))
+	}
+}
+
+func Test_CircuitBreaker(t *testing.T) {
+	const host = "circuit-breaker:9000"
+	defer breaker.success(host)
+	for i := 0; i < circuitBreakerThreshold; i++ {
+		assert.False(t, breaker.isOpen(host))
+		breaker.failure(host)
+	}
+	assert.True(t, breaker.isOpen(host))
+	assert.True(t, breaker.allOpen([]string{host}))
+	assert.False(t, breaker.allOpen([]string{host, "circuit-breaker-healthy:9000"}))
+	breaker.success(host)
+	assert.False(t, breaker.isOpen(host))
+}
+
+func Test_RegisterHealthCheck(t *testing.T) {
+	var checked []string
+	RegisterHealthCheck(func(host string) error {
+		checked = append(checked, host)
+		return errors.New("unhealthy")
+	})
+	defer DeregisterHealthCheck()
+	hosts := []string{"health-check-1:9000", "health-check-2:9000"}
+	defer breaker.success(hosts[0])
+	defer breaker.success(hosts[1])
+	_, err := dial(context.Background(), connOptions{
+		hosts:        hosts,
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, hosts, checked)
+	}
+}
+
+func Test_DialUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "clickhouse.sock")
+	listener, err := net.Listen("unix", path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	conn, err := dial(context.Background(), connOptions{
+		secure:       true,
+		hosts:        []string{unixSocketPrefix + path},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		_, ok := conn.Conn.(*net.UnixConn)
+		assert.True(t, ok)
+	}
+}
+
+func Test_ParallelDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	hosts := []string{"127.0.0.1:1", listener.Addr().String()}
+	defer breaker.success("127.0.0.1:1")
+	defer breaker.success("127.0.0.1:2")
+	conn, err := dial(context.Background(), connOptions{
+		hosts:        hosts,
+		openStrategy: connOpenInOrder,
+		parallelDial: 2,
+		logf:         func(string, ...interface{}) {},
+	})
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		assert.Equal(t, listener.Addr().String(), conn.RemoteAddr().String())
+		assert.Equal(t, 1, conn.server)
+		assert.Equal(t, listener.Addr().String(), (&clickhouse{conn: conn}).RemoteHost())
+	}
+	_, err = dial(context.Background(), connOptions{
+		hosts:        []string{"127.0.0.1:1", "127.0.0.1:2"},
+		openStrategy: connOpenInOrder,
+		parallelDial: 2,
+		logf:         func(string, ...interface{}) {},
+	})
+	assert.Error(t, err)
+}
+
+func Test_DialError(t *testing.T) {
+	hosts := []string{"127.0.0.1:1", "127.0.0.1:2"}
+	defer breaker.success(hosts[0])
+	defer breaker.success(hosts[1])
+	_, err := dial(context.Background(), connOptions{
+		hosts:        hosts,
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	var dialErr *DialError
+	if assert.True(t, errors.As(err, &dialErr)) {
+		assert.Equal(t, "in_order", dialErr.Strategy)
+		if assert.Len(t, dialErr.Hosts, 2) {
+			assert.Equal(t, hosts[0], dialErr.Hosts[0].Host)
+			assert.Equal(t, hosts[1], dialErr.Hosts[1].Host)
+			assert.Equal(t, dialErr.Hosts[1].Err, errors.Unwrap(err))
+		}
+		assert.Contains(t, err.Error(), hosts[0])
+		assert.Contains(t, err.Error(), hosts[1])
+	}
+}
+
+func Test_weightedOrder(t *testing.T) {
+	counts := make([]int, 2)
+	for ident := 0; ident < 40; ident++ {
+		order := weightedOrder([]int{3, 1}, ident)
+		if assert.Len(t, order, 2) {
+			assert.NotEqual(t, order[0], order[1])
+			counts[order[0]]++
+		}
+	}
+	assert.Equal(t, []int{30, 10}, counts)
+	assert.Equal(t, []int{0, 1, 2}, hostOrder(connOpenRandom, make([]string, 3), []int{1, 1, 1}, 0))
+}
+
+func Test_Heartbeat(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	pings := make(chan struct{}, 10)
+	go func() {
+		server, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer server.Close()
+		packet := make([]byte, 1)
+		for {
+			if _, err := server.Read(packet); err != nil {
+				return
+			}
+			pings <- struct{}{}
+			if _, err := server.Write([]byte{protocol.ServerPong}); err != nil {
+				return
+			}
+		}
+	}()
+	conn, err := dial(context.Background(), connOptions{
+		hosts:        []string{listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	conn.startHeartbeat(10 * time.Millisecond)
+	select {
+	case <-pings:
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat did not ping the server")
+	}
+	conn.acquire()
+	for len(pings) != 0 {
+		<-pings
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, pings, 0, "heartbeat must not ping a connection in use")
+	conn.release()
+	assert.NoError(t, conn.Close())
+	assert.True(t, conn.isClosed())
+}
+
+// socks5Stub accepts a single SOCKS5 CONNECT request without authentication and proxies it to the target.
+func socks5Stub(listener net.Listener) <-chan string {
+	targets := make(chan string, 1)
+	go func() {
+		client, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(client, header); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(client, make([]byte, header[1])); err != nil {
+			return
+		}
+		client.Write([]byte{5, 0})
+		request := make([]byte, 5)
+		if _, err := io.ReadFull(client, request); err != nil {
+			return
+		}
+		var host string
+		switch request[3] {
+		case 1:
+			addr := make([]byte, 3)
+			io.ReadFull(client, addr)
+			host = net.IP(append(request[4:5], addr...)).String()
+		case 3:
+			addr := make([]byte, request[4])
+			io.ReadFull(client, addr)
+			host = string(addr)
+		}
+		port := make([]byte, 2)
+		io.ReadFull(client, port)
+		target := net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+		targets <- target
+		server, err := net.Dial("tcp", target)
+		if err != nil {
+			client.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer server.Close()
+		client.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		go io.Copy(server, client)
+		io.Copy(client, server)
+	}()
+	return targets
+}
+
+func Test_DialSOCKS5(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer target.Close()
+	socks, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer socks.Close()
+	targets := socks5Stub(socks)
+	conn, err := dial(context.Background(), connOptions{
+		hosts:        []string{target.Addr().String()},
+		socks5:       socks.Addr().String(),
+		connTimeout:  time.Second,
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		assert.Equal(t, target.Addr().String(), <-targets)
+		assert.Equal(t, socks.Addr().String(), conn.RemoteAddr().String())
+	}
+}
+
+func Test_dialBackoff(t *testing.T) {
+	for attempt, max := range []time.Duration{100, 200, 400, 800} {
+		backoff := dialBackoff(100, attempt)
+		assert.True(t, backoff >= max/2 && backoff <= max, "attempt %d: %s", attempt, backoff)
+	}
+	assert.True(t, dialBackoff(time.Second, 100) <= maxDialRetryBackoff)
+}
+
+func Test_DialRetries(t *testing.T) {
+	hosts := []string{"127.0.0.1:1"}
+	defer breaker.success(hosts[0])
+	_, err := dial(context.Background(), connOptions{
+		hosts:            hosts,
+		openStrategy:     connOpenInOrder,
+		dialRetries:      2,
+		dialRetryBackoff: time.Millisecond,
+		logf:             func(string, ...interface{}) {},
+	})
+	var dialErr *DialError
+	if assert.True(t, errors.As(err, &dialErr)) {
+		assert.Len(t, dialErr.Hosts, 3)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = dial(ctx, connOptions{
+		hosts:            hosts,
+		openStrategy:     connOpenInOrder,
+		dialRetries:      10,
+		dialRetryBackoff: time.Second,
+		logf:             func(string, ...interface{}) {},
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func Test_DialTimeRandomUnreachable(t *testing.T) {
+	hosts := []string{"127.0.0.1:1", "127.0.0.1:2", "127.0.0.1:3", "127.0.0.1:4", "127.0.0.1:5"}
+	for _, host := range hosts {
+		defer breaker.success(host)
+	}
+	var (
+		begin = time.Now()
+		done  = make(chan error, 1)
+	)
+	go func() {
+		_, err := dial(context.Background(), connOptions{
+			hosts:        hosts,
+			connTimeout:  100 * time.Millisecond,
+			openStrategy: connOpenTimeRandom,
+			logf:         func(string, ...interface{}) {},
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		var dialErr *DialError
+		if assert.True(t, errors.As(err, &dialErr)) {
+			assert.Len(t, dialErr.Hosts, len(hosts))
+		}
+		assert.True(t, time.Since(begin) < time.Duration(len(hosts))*100*time.Millisecond)
+	case <-time.After(5 * time.Second):
+		t.Fatal("dial with time_random strategy did not return")
+	}
+}
+
+func Test_hostOrderTimeRandom(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		order := hostOrder(connOpenTimeRandom, make([]string, 4), nil, i)
+		seen := make(map[int]struct{}, len(order))
+		for _, num := range order {
+			seen[num] = struct{}{}
+		}
+		assert.Len(t, seen, 4)
+	}
+}
+
+func Test_DialTotalTimeout(t *testing.T) {
+	RegisterDial(func(network, address string, timeout time.Duration, config *tls.Config) (net.Conn, error) {
+		time.Sleep(timeout)
+		return nil, errors.New("timeout")
+	})
+	defer DeregisterDial()
+	hosts := []string{"total-timeout-1:9000", "total-timeout-2:9000", "total-timeout-3:9000"}
+	for _, host := range hosts {
+		defer breaker.success(host)
+	}
+	begin := time.Now()
+	_, err := dial(context.Background(), connOptions{
+		hosts:            hosts,
+		connTimeout:      100 * time.Millisecond,
+		totalDialTimeout: 150 * time.Millisecond,
+		openStrategy:     connOpenInOrder,
+		logf:             func(string, ...interface{}) {},
+	})
+	assert.True(t, time.Since(begin) < 250*time.Millisecond)
+	var dialErr *DialError
+	if assert.True(t, errors.As(err, &dialErr)) {
+		assert.Len(t, dialErr.Hosts, 2)
+	}
+}
+
+// stubConn serves the data and then fails with the err, it records the deadlines set on it.
+type stubConn struct {
+	net.Conn
+	data          []byte
+	err           error
+	writes        int
+	written       []byte
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func (c *stubConn) Read(b []byte) (int, error) {
+	if len(c.data) == 0 {
+		return 0, c.err
+	}
+	n := copy(b, c.data)
+	c.data = c.data[n:]
+	return n, nil
+}
+
+func (c *stubConn) Write(b []byte) (int, error) {
+	c.writes++
+	c.written = append(c.written, b...)
+	return len(b), nil
+}
+
+func (c *stubConn) Close() error { return nil }
+
+func (c *stubConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
+	return nil
+}
+
+func (c *stubConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline = t
+	return nil
+}
+
+// newStubConnect returns the connection over the stub as dial does.
+func newStubConnect(t *testing.T, stub net.Conn, options connOptions) *connect {
+	options.hosts = []string{"stub:9000"}
+	options.logf = func(string, ...interface{}) {}
+	conn, err := newConnect(stub, 0, 0, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func Test_ConnectDeadlineClockJump(t *testing.T) {
+	stub := &stubConn{data: make([]byte, 16), err: io.EOF}
+	currentTime := time.Now()
+	conn := newStubConnect(t, stub, connOptions{
+		readTimeout:  time.Minute,
+		writeTimeout: time.Minute,
+	})
+	conn.clock = func() time.Time { return currentTime }
+	read := func() {
+		_, err := conn.Read(make([]byte, 1))
+		assert.NoError(t, err)
+	}
+	read()
+	assert.Equal(t, currentTime.Add(time.Minute), stub.readDeadline)
+
+	// the deadline is kept within a quarter of the timeout
+	start := currentTime
+	currentTime = currentTime.Add(10 * time.Second)
+	read()
+	assert.Equal(t, start.Add(time.Minute), stub.readDeadline)
+
+	// the clock stepped back an hour; without the refresh a stalled read would wait for an hour more
+	currentTime = start.Add(-time.Hour)
+	read()
+	assert.Equal(t, currentTime.Add(time.Minute), stub.readDeadline)
+	currentTime = currentTime.Add(20 * time.Second)
+	read()
+	assert.Equal(t, currentTime.Add(time.Minute), stub.readDeadline)
+
+	currentTime = start.Add(-2 * time.Hour)
+	conn.Write([]byte{1})
+	assert.NoError(t, conn.Flush())
+	assert.Equal(t, currentTime.Add(time.Minute), stub.writeDeadline)
+	currentTime = currentTime.Add(-time.Second)
+	conn.Write([]byte{1})
+	assert.NoError(t, conn.Flush())
+	assert.Equal(t, currentTime.Add(time.Minute), stub.writeDeadline)
+}
+
+func Test_ConnectStrictDeadlines(t *testing.T) {
+	stub := &stubConn{data: make([]byte, 16), err: io.EOF}
+	currentTime := time.Now()
+	conn := newStubConnect(t, stub, connOptions{
+		readTimeout:     time.Minute,
+		writeTimeout:    time.Minute,
+		strictDeadlines: true,
+	})
+	conn.clock = func() time.Time { return currentTime }
+	for i := 0; i < 3; i++ {
+		currentTime = currentTime.Add(time.Second)
+		_, err := conn.Read(make([]byte, 1))
+		assert.NoError(t, err)
+		assert.Equal(t, currentTime.Add(time.Minute), stub.readDeadline)
+		conn.Write([]byte{1})
+		assert.NoError(t, conn.Flush())
+		assert.Equal(t, currentTime.Add(time.Minute), stub.writeDeadline)
+	}
+}
+
+func Test_WithReadTimeout(t *testing.T) {
+	exec := func(ctx context.Context, data []byte) (*stubConn, *connect, error) {
+		stub := &stubConn{data: data, err: io.EOF}
+		currentTime := time.Now()
+		conn := newStubConnect(t, stub, connOptions{
+			readTimeout: time.Minute,
+		})
+		conn.clock = func() time.Time { return currentTime }
+		ch := &clickhouse{
+			conn:     conn,
+			logf:     func(string, ...interface{}) {},
+			settings: &querySettings{},
+			decoder:  binary.NewDecoder(conn),
+			encoder:  binary.NewEncoder(conn),
+		}
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		return stub, conn, err
+	}
+	ctx := WithReadTimeout(context.Background(), time.Hour)
+	stub, conn, err := exec(ctx, []byte{protocol.ServerEndOfStream})
+	if assert.NoError(t, err) {
+		assert.True(t, stub.readDeadline.After(time.Now().Add(time.Minute)))
+		assert.Equal(t, time.Minute, conn.readTimeout)
+	}
+	// the timeout of the DSN is restored even if the query fails
+	stub, conn, err = exec(ctx, nil)
+	if assert.Error(t, err) {
+		assert.True(t, stub.readDeadline.After(time.Now().Add(time.Minute)))
+		assert.Equal(t, time.Minute, conn.readTimeout)
+	}
+	stub, conn, err = exec(context.Background(), []byte{protocol.ServerEndOfStream})
+	if assert.NoError(t, err) {
+		assert.True(t, stub.readDeadline.Before(time.Now().Add(time.Minute+time.Second)))
+		assert.Equal(t, time.Minute, conn.readTimeout)
+	}
+}
+
+func Test_ConnectReadPartial(t *testing.T) {
+	stub := &stubConn{data: []byte{1, 2, 3, 4, 5}, err: errors.New("connection reset")}
+	conn := newStubConnect(t, stub, connOptions{
+		readBufferSize: minReadBufferSize,
+	})
+	b := make([]byte, 8)
+	n, err := conn.Read(b)
+	assert.Equal(t, driver.ErrBadConn, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5}, b[:n])
+}
+
+func Test_ConnectWriteBuffer(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{writeTimeout: time.Minute})
+	for i := 0; i < 100; i++ {
+		n, err := conn.Write([]byte{byte(i)})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, n)
+		}
+	}
+	assert.Equal(t, 0, stub.writes)
+	assert.True(t, stub.writeDeadline.IsZero())
+	if assert.NoError(t, conn.Flush()) {
+		assert.Equal(t, 1, stub.writes)
+		assert.Len(t, stub.written, 100)
+		assert.False(t, stub.writeDeadline.IsZero())
+	}
+}
+
+func Test_ConnectIOError(t *testing.T) {
+	for _, tt := range []struct {
+		err     error
+		timeout bool
+	}{
+		{err: os.ErrDeadlineExceeded, timeout: true},
+		{err: errors.New("connection reset by peer")},
+	} {
+		stub := &stubConn{err: tt.err}
+		ch := &clickhouse{conn: newStubConnect(t, stub, connOptions{})}
+		assert.NoError(t, ch.IOError())
+		_, err := ch.conn.Read(make([]byte, 1))
+		assert.Equal(t, driver.ErrBadConn, err)
+		assert.True(t, ch.conn.isClosed())
+		if assert.Error(t, ch.IOError()) {
+			assert.Equal(t, tt.timeout, errors.Is(ch.IOError(), os.ErrDeadlineExceeded))
+		}
+	}
+}
+
+func Test_ConnectCloseGracefully(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newStubConnect(t, client, connOptions{})
+	read := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 4))
+		read <- err
+	}()
+	for conn.inFlightIO() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.CloseGracefully(time.Second)
+	}()
+	for !conn.isClosed() {
+		time.Sleep(time.Millisecond)
+	}
+	// no new I/O is accepted while draining
+	_, err := conn.Write([]byte{1})
+	if assert.NoError(t, err) {
+		assert.Equal(t, driver.ErrBadConn, conn.Flush())
+	}
+	select {
+	case <-closed:
+		t.Fatal("closed before the read in progress is done")
+	case <-time.After(20 * time.Millisecond):
+	}
+	server.Write([]byte{1, 2, 3, 4})
+	assert.NoError(t, <-read)
+	assert.NoError(t, <-closed)
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, driver.ErrBadConn, err)
+
+	// the read in progress is cut once the timeout is over
+	client, server = net.Pipe()
+	defer server.Close()
+	conn = newStubConnect(t, client, connOptions{})
+	go func() {
+		_, err := conn.Read(make([]byte, 4))
+		read <- err
+	}()
+	for conn.inFlightIO() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	begin := time.Now()
+	assert.NoError(t, conn.CloseGracefully(50*time.Millisecond))
+	assert.True(t, time.Since(begin) >= 50*time.Millisecond)
+	assert.Equal(t, driver.ErrBadConn, <-read)
+}
+
+func (conn *connect) inFlightIO() int {
+	conn.ioMutex.Lock()
+	defer conn.ioMutex.Unlock()
+	return conn.inFlight
+}
+
+func Test_ConnectIdleTimeout(t *testing.T) {
+	stub := &stubConn{data: make([]byte, 16), err: io.EOF}
+	ch := &clickhouse{
+		conn: newStubConnect(t, stub, connOptions{idleTimeout: time.Minute}),
+		logf: func(string, ...interface{}) {},
+	}
+	currentTime := ch.conn.created
+	ch.conn.clock = func() time.Time { return currentTime }
+	assert.False(t, ch.conn.IsExpired())
+	currentTime = currentTime.Add(50 * time.Second)
+	_, err := ch.conn.Read(make([]byte, 1))
+	assert.NoError(t, err)
+	currentTime = currentTime.Add(50 * time.Second)
+	assert.False(t, ch.conn.IsExpired())
+	ch.conn.Write([]byte{1})
+	assert.NoError(t, ch.conn.Flush())
+	currentTime = currentTime.Add(50 * time.Second)
+	if assert.True(t, ch.IsValid()) {
+		currentTime = currentTime.Add(11 * time.Second)
+		assert.True(t, ch.conn.IsExpired())
+		assert.False(t, ch.IsValid())
+		assert.True(t, ch.conn.isClosed())
+	}
+
+	conn := newStubConnect(t, &stubConn{}, connOptions{})
+	conn.clock = func() time.Time { return conn.created.Add(time.Hour) }
+	assert.False(t, conn.IsExpired())
+}
+
+func Test_OpenErrorClassification(t *testing.T) {
+	// a server answering the hello by an exception of the code, or closing the connection if the code is 0
+	server := func(code int32) string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			defer listener.Close()
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if code == 0 {
+				return
+			}
+			encoder := binary.NewEncoder(conn)
+			encoder.Uvarint(protocol.ServerException)
+			encoder.Int32(code)
+			encoder.String("DB::Exception")
+			encoder.String("DB::Exception: rejected")
+			encoder.String("")
+			encoder.Bool(false)
+			// wait for the client to close the connection
+			conn.Read(make([]byte, 1024))
+		}()
+		return listener.Addr().String()
+	}
+	defer breaker.success("127.0.0.1:1")
+
+	_, err := Open("tcp://127.0.0.1:1?timeout=1")
+	assert.True(t, errors.Is(err, ErrConnectionFailed), err)
+	assert.False(t, errors.Is(err, ErrAuthFailed), err)
+	var dialErr *DialError
+	assert.True(t, errors.As(err, &dialErr), err)
+
+	// the handshake broke off
+	_, err = Open("tcp://" + server(0) + "?timeout=1&read_timeout=1")
+	assert.True(t, errors.Is(err, ErrConnectionFailed), err)
+	assert.False(t, errors.Is(err, ErrAuthFailed), err)
+
+	for _, code := range []int32{192, 193, 194, 516} {
+		_, err = Open("tcp://" + server(code) + "?timeout=1&read_timeout=1")
+		assert.True(t, errors.Is(err, ErrAuthFailed), err)
+		assert.False(t, errors.Is(err, ErrConnectionFailed), err)
+		var exception *Exception
+		if assert.True(t, errors.As(err, &exception), err) {
+			assert.Equal(t, code, exception.Code)
+		}
+	}
+
+	// an exception of another code, the server is reachable and the credentials are accepted
+	_, err = Open("tcp://" + server(81) + "?timeout=1&read_timeout=1")
+	assert.False(t, errors.Is(err, ErrAuthFailed), err)
+	assert.False(t, errors.Is(err, ErrConnectionFailed), err)
+
+	// the same code sent for a query is not the failure of the credentials of the connection
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	var buf bytes.Buffer
+	encoder := binary.NewEncoder(&buf)
+	encoder.Int32(516)
+	encoder.String("DB::Exception")
+	encoder.String("DB::Exception: of a remote server")
+	encoder.String("")
+	encoder.Bool(false)
+	stub.data = buf.Bytes()
+	err = (&clickhouse{conn: conn, decoder: binary.NewDecoder(conn)}).exception()
+	assert.False(t, errors.Is(err, ErrAuthFailed), err)
+}
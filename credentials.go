@@ -0,0 +1,73 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// ErrCredentialsMismatch is the error of the queries run on a connection authenticated as another user than
+// the user of their context, see WithCredentials.
+var ErrCredentialsMismatch = errors.New("clickhouse: the connection is authenticated as another user")
+
+// credentials are the user and the password of the hello of a connection.
+type credentials struct {
+	username string
+	password string
+}
+
+// WithCredentials sets the user the queries run with the context are run as, instead of the user of the DSN.
+// The native protocol authenticates a connection once, by its hello: a connection opened with the context
+// (database/sql opens the connections with the context of the query which needs one, e.g. db.Conn(ctx))
+// is authenticated as the user, and a query whose user isn't the user of its connection is not sent,
+// it fails with ErrCredentialsMismatch. The queries without credentials are run as the user of the DSN,
+// the connections opened for other users fail them too.
+//
+// The pool of database/sql doesn't pick its connections by user: the error is a driver.ErrBadConn too,
+// so that database/sql (of Go 1.18 or later) closes the connection of another user and retries the query,
+// on a new connection authenticated as the user at the last attempt. The connections of the users are
+// thus replaced by one another as the queries of the users alternate, a pool per user, or a connection
+// pinned to the user with db.Conn(ctx), avoids it. The requests of the HTTP interface are authenticated
+// one by one by the credentials of their context.
+func WithCredentials(ctx context.Context, username, password string) context.Context {
+	if username == "" {
+		username = DefaultUsername
+	}
+	return context.WithValue(ctx, credentialsKey, credentials{username: username, password: password})
+}
+
+// contextCredentials returns the credentials of the context, or the credentials of the DSN if it has none.
+func contextCredentials(ctx context.Context, dsn credentials) credentials {
+	if c, ok := ctx.Value(credentialsKey).(credentials); ok {
+		return c
+	}
+	return dsn
+}
+
+// checkCredentials fails the query of the context if its user is not the user of the connection.
+func (ch *clickhouse) checkCredentials(ctx context.Context) error {
+	expected := contextCredentials(ctx, ch.dsnCredentials)
+	if expected == ch.credentials {
+		return nil
+	}
+	ch.logf("[credentials] the connection is authenticated as %s, not %s", ch.credentials.username, expected.username)
+	return &credentialsError{conn: ch.credentials.username, query: expected.username}
+}
+
+// credentialsError is the error of a query whose user, or password, isn't the one of its connection.
+type credentialsError struct {
+	conn  string // the user of the connection
+	query string // the user of the query
+}
+
+func (e *credentialsError) Error() string {
+	if e.conn == e.query {
+		return fmt.Sprintf("%s with another password than the password of the query of %s", ErrCredentialsMismatch, e.query)
+	}
+	return fmt.Sprintf("%s: %s, the query is run as %s", ErrCredentialsMismatch, e.conn, e.query)
+}
+
+func (e *credentialsError) Is(target error) bool {
+	return target == ErrCredentialsMismatch || target == driver.ErrBadConn
+}
@@ -0,0 +1,72 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithCredentials(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:           conn,
+		logf:           func(string, ...interface{}) {},
+		settings:       &querySettings{},
+		decoder:        binary.NewDecoder(conn),
+		encoder:        binary.NewEncoder(conn),
+		credentials:    credentials{username: "alice", password: "a"},
+		dsnCredentials: credentials{username: "default"},
+	}
+	for ctx, message := range map[context.Context]string{
+		context.Background():                                "clickhouse: the connection is authenticated as another user: alice, the query is run as default",
+		WithCredentials(context.Background(), "bob", "a"):   "clickhouse: the connection is authenticated as another user: alice, the query is run as bob",
+		WithCredentials(context.Background(), "alice", "b"): "clickhouse: the connection is authenticated as another user with another password than the password of the query of alice",
+	} {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		if _, err := ch.ExecContext(ctx, "SELECT 1", nil); assert.Error(t, err) {
+			assert.Equal(t, message, err.Error())
+			assert.True(t, errors.Is(err, ErrCredentialsMismatch))
+			// database/sql retries the query on another connection
+			assert.True(t, errors.Is(err, driver.ErrBadConn))
+			// the query is not sent, the connection is kept
+			assert.Empty(t, stub.written)
+			assert.False(t, conn.isClosed())
+		}
+	}
+	stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+	if _, err := ch.ExecContext(WithCredentials(context.Background(), "alice", "a"), "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Contains(t, string(stub.written), "SELECT 1")
+	}
+}
+
+func Test_OpenWithCredentials(t *testing.T) {
+	server := newHelloServer(t, data.ServerInfo{Name: "ClickHouse", MajorVersion: 21, MinorVersion: 8, Revision: protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE}, "UTC")
+	defer server.close()
+	db, err := sql.Open("clickhouse", "tcp://"+server.addr+"?timeout=1&read_timeout=1&username=service")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	// the connections are opened with the credentials of the context of the query opening them
+	alice, err := db.Conn(WithCredentials(context.Background(), "alice", "secret"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer alice.Close()
+	service, err := db.Conn(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer service.Close()
+	server.mutex.Lock()
+	assert.Equal(t, []string{"alice", "service"}, server.users)
+	server.mutex.Unlock()
+}
@@ -0,0 +1,50 @@
+package clickhouse
+
+import (
+	"context"
+	"strings"
+)
+
+// WithDatabase runs the queries run with the context in the database name instead of the database of the DSN:
+// their unqualified table names resolve against it. The query packet of the native protocol has no database,
+// the database of a connection is the one of its handshake: the driver switches the connection to the database
+// with a USE query before the query, and back to the database of the DSN before the next query run without it.
+func WithDatabase(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, databaseKey, name)
+}
+
+// switchDatabase switches the connection to the database of the query, if need be.
+func (ch *clickhouse) switchDatabase(ctx context.Context) error {
+	database, ok := ctx.Value(databaseKey).(string)
+	if !ok {
+		database = ch.database
+	}
+	// the handshake without a database is made in the default database
+	if database == "" {
+		database = DefaultDatabase
+	}
+	if current := ch.currentDatabase; database == current || current == "" && database == DefaultDatabase {
+		return nil
+	}
+	ch.logf("[database] %s", database)
+	// the USE query itself is run in the database
+	previous := ch.currentDatabase
+	ch.currentDatabase = database
+	if err := ch.sendQuery(WithDatabase(context.Background(), database), "USE "+quoteIdentifier(database), nil); err != nil {
+		ch.currentDatabase = previous
+		return err
+	}
+	ch.beginReading()
+	err := ch.process()
+	ch.endReading()
+	if err != nil {
+		// the failed USE leaves the connection in the previous database
+		ch.currentDatabase = previous
+	}
+	return err
+}
+
+// quoteIdentifier quotes the identifier with backquotes.
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
@@ -0,0 +1,87 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithDatabase(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	// the USE queries and the queries end with the end of their streams
+	for i := 0; i < 8; i++ {
+		encoder.Uvarint(protocol.ServerEndOfStream)
+	}
+	// the USE query of the unknown database fails after the query in db1
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	encoder.Uvarint(protocol.ServerException)
+	encoder.Int32(81)
+	encoder.String("DB::Exception")
+	encoder.String("DB::Exception: Database unknown doesn't exist")
+	encoder.String("")
+	encoder.Bool(false)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	// the DSN has no database, the handshake is made in the default database
+	ch := &clickhouse{
+		conn:            conn,
+		logf:            func(string, ...interface{}) {},
+		settings:        &querySettings{},
+		decoder:         binary.NewDecoder(conn),
+		encoder:         binary.NewEncoder(conn),
+		database:        "",
+		currentDatabase: DefaultDatabase,
+	}
+	// used reports the databases of the USE queries written since the previous call
+	used := func() []string {
+		var databases []string
+		for _, part := range strings.Split(string(stub.written), "USE `")[1:] {
+			databases = append(databases, part[:strings.Index(part, "`")])
+		}
+		stub.written = nil
+		return databases
+	}
+	ctx := context.Background()
+	for _, test := range []struct {
+		ctx  context.Context
+		used []string
+	}{
+		{WithDatabase(ctx, "db1"), []string{"db1"}},
+		{WithDatabase(ctx, "db2"), []string{"db2"}},
+		{WithDatabase(ctx, "db2"), nil},
+		// the query without a database runs in the database of the DSN
+		{ctx, []string{"default"}},
+		{ctx, nil},
+	} {
+		if _, err := ch.ExecContext(test.ctx, "SELECT 1", nil); assert.NoError(t, err) {
+			assert.Equal(t, test.used, used())
+		}
+	}
+	if _, err := ch.ExecContext(WithDatabase(ctx, "db1"), "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Equal(t, []string{"db1"}, used())
+	}
+	if _, err := ch.ExecContext(WithDatabase(ctx, "unknown"), "SELECT 1", nil); assert.Error(t, err) {
+		if exception, ok := err.(*Exception); assert.True(t, ok) {
+			assert.Equal(t, int32(81), exception.Code)
+		}
+		assert.Equal(t, []string{"unknown"}, used())
+		// the connection is still in db1
+		assert.Equal(t, "db1", ch.currentDatabase)
+	}
+	if _, err := ch.ExecContext(ctx, "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Equal(t, []string{"default"}, used())
+	}
+	assert.Equal(t, "`a\\`b`", quoteIdentifier("a`b"))
+}
@@ -0,0 +1,401 @@
+package clickhouse
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Options are the options of a DSN, see ParseDSN. The options of the DSN other than the fields are kept in Params.
+type Options struct {
+	// Hosts are the host of the DSN followed by the alt_hosts, e.g. 127.0.0.1:9000, unix:///path/to/clickhouse.sock,
+	// optionally suffixed with their weight, e.g. 127.0.0.1:9000*3. The host of the DSNs of the HTTP interface
+	// keeps its scheme, e.g. http://127.0.0.1:8123 or https://127.0.0.1:8443
+	Hosts      []string
+	Database   string
+	Username   string
+	Password   string
+	Secure     bool
+	SkipVerify bool
+	// ConnTimeout, ReadTimeout and WriteTimeout are timeout, read_timeout and write_timeout, zero for the defaults
+	// DefaultConnTimeout, DefaultReadTimeout and DefaultWriteTimeout
+	ConnTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Strategy is the connection_open_strategy: random (if empty), in_order, time_random or least_conn
+	Strategy string
+	// Compress is lz4, zstd or empty if the blocks are not compressed, CompressLevel is compress_level
+	Compress      string
+	CompressLevel int
+	Debug         bool
+	// Settings are the query settings sent with every query, e.g. max_execution_time
+	Settings map[string]string
+	// Params are the other options, e.g. heartbeat or tls_config
+	Params url.Values
+}
+
+// dsnParams are the validators of the values of the options of Params.
+var dsnParams = map[string]func(key, value string) error{
+	"no_delay":               validateBool,
+	"strict_socket_options":  validateBool,
+	"strict_deadlines":       validateBool,
+	"allow_experimental":     validateBool,
+	"allow_multi_statements": validateBool,
+	"verify_blocks":          validateBool,
+	"total_connect_timeout":  validateSeconds,
+	"keep_alive":             validateDuration,
+	"heartbeat":              validateDuration,
+	"idle_timeout":           validateDuration,
+	"dial_retry_backoff":     validateDuration,
+	"block_size":             validateCount,
+	"read_buffer_size":       validateReadBufferSize,
+	"pool_size":              validateCount,
+	"parallel_dial":          validateCount,
+	"dial_retries":           validateCount,
+	"compress_block_size":    validateCount,
+	"retry_attempts":         validateCount,
+	"retry_on_codes":         validateCodes,
+	"verify_ocsp":            validateOCSP,
+	"tls_min_version": func(_, v string) error {
+		_, err := parseTLSVersion(v)
+		return err
+	},
+	"tls_cert_fingerprint": func(_, v string) error {
+		_, err := parseFingerprints(v)
+		return err
+	},
+	"trace_statement": func(_, v string) error {
+		_, err := parseTraceStatement(v)
+		return err
+	},
+	"tls_config":       validateString,
+	"tls_cert":         validateString,
+	"tls_key":          validateString,
+	"tls_key_password": validateString,
+	"tls_ca":           validateString,
+	"tls_server_name":  validateString,
+	"balancer":         validateString,
+	"quota_key":        validateString,
+	"client_name":      validateClientName,
+	"client_version":   validateClientVersion,
+	"socks5":           validateString,
+}
+
+// openStrategies are the values of connection_open_strategy.
+var openStrategies = []string{"random", "in_order", "time_random", "least_conn"}
+
+// ParseDSN parses the DSN into its options. Unlike Open, which ignores the options it doesn't know and the values
+// it can't parse, ParseDSN rejects the unknown options, the options set more than once and the invalid values,
+// e.g. a negative timeout or an unknown connection_open_strategy.
+// Secure is inferred from the TLS options as Open does if secure is not set: true if any of tls_config,
+// tls_cert, tls_key, tls_ca or tls_cert_fingerprint is set.
+func ParseDSN(dsn string) (*Options, error) {
+	var firstWeight string
+	if match := hostWeightRe.FindStringSubmatch(dsn); match != nil {
+		firstWeight = "*" + match[2]
+		dsn = match[1] + dsn[len(match[0]):]
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN - %v", err)
+	}
+	var host string
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid DSN - no host")
+		}
+		host = u.Host
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid DSN - no path of the socket")
+		}
+		host = unixSocketPrefix + u.Path
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid DSN - no host")
+		}
+		host = u.Scheme + "://" + u.Host
+	default:
+		return nil, fmt.Errorf("invalid DSN - unknown scheme %q, must be tcp, unix, http or https", u.Scheme)
+	}
+	if _, _, err := splitHostWeight(host + firstWeight); err != nil {
+		return nil, err
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN - %v", err)
+	}
+	options := &Options{
+		Hosts:    []string{host + firstWeight},
+		Settings: make(map[string]string),
+		Params:   make(url.Values),
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	// the errors do not depend on the order of the map
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(query[key]) != 1 {
+			return nil, fmt.Errorf("invalid DSN - option %s is set more than once", key)
+		}
+		if err := options.set(key, query.Get(key)); err != nil {
+			return nil, err
+		}
+	}
+	if options.Compress == "zstd" {
+		if err := binary.CheckZSTDLevel(options.CompressLevel); err != nil {
+			return nil, fmt.Errorf("invalid compress_level - %v", err)
+		}
+	}
+	if _, ok := query["secure"]; !ok {
+		options.Secure = options.impliesSecure()
+	}
+	// the checksums of the blocks are those of their compressed frames, see verifyBlocksWarning of Open
+	if verifyBlocks, _ := strconv.ParseBool(options.Params.Get("verify_blocks")); verifyBlocks && options.Compress == "" {
+		return nil, fmt.Errorf("invalid verify_blocks - the server sends no checksums of the uncompressed blocks, set compress")
+	}
+	return options, nil
+}
+
+// set sets the option of the DSN.
+func (o *Options) set(key, value string) error {
+	var err error
+	switch key {
+	case "alt_hosts":
+		for _, host := range strings.Split(value, ",") {
+			if host == "" {
+				continue
+			}
+			if _, _, err := splitHostWeight(host); err != nil {
+				return err
+			}
+			o.Hosts = append(o.Hosts, host)
+		}
+	case "database":
+		o.Database = value
+	case "username":
+		o.Username = value
+	case "password":
+		o.Password = value
+	case "secure":
+		o.Secure, err = parseBoolOption(key, value)
+	case "skip_verify":
+		o.SkipVerify, err = parseBoolOption(key, value)
+	case "debug":
+		o.Debug, err = parseBoolOption(key, value)
+	case "timeout":
+		o.ConnTimeout, err = parseTimeoutOption(key, value)
+	case "read_timeout":
+		o.ReadTimeout, err = parseTimeoutOption(key, value)
+	case "write_timeout":
+		o.WriteTimeout, err = parseTimeoutOption(key, value)
+	case "connection_open_strategy":
+		for _, strategy := range openStrategies {
+			if value == strategy {
+				o.Strategy = value
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid connection_open_strategy - must be one of %s, got %q", strings.Join(openStrategies, ", "), value)
+	case "compress":
+		switch v := strings.ToLower(value); v {
+		case "lz4", "zstd":
+			o.Compress = v
+		default:
+			compress, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid compress - must be lz4, zstd or a boolean value, got %q", value)
+			}
+			if compress {
+				o.Compress = "lz4"
+			}
+		}
+	case "compress_level":
+		if o.CompressLevel, err = strconv.Atoi(value); err != nil || o.CompressLevel < 0 {
+			return fmt.Errorf("invalid compress_level - must be a non-negative integer, got %q", value)
+		}
+	default:
+		if qsType, ok := querySettingTypes[key]; ok {
+			switch qsType {
+			case boolQS:
+				err = validateBool(key, value)
+			case stringQS:
+			case floatQS:
+				if _, parseErr := strconv.ParseFloat(value, 64); parseErr != nil {
+					err = fmt.Errorf("invalid %s - must be a number, got %q", key, value)
+				}
+			default:
+				err = validateUint(key, value)
+			}
+			o.Settings[key] = value
+			return err
+		}
+		validate, ok := dsnParams[key]
+		if !ok {
+			return fmt.Errorf("invalid DSN - unknown option %s", key)
+		}
+		if err := validate(key, value); err != nil {
+			return err
+		}
+		o.Params.Set(key, value)
+	}
+	return err
+}
+
+// DSN returns the DSN of the options, ParseDSN(o.DSN()) returns the same options.
+func (o *Options) DSN() string {
+	query := make(url.Values)
+	for key, values := range o.Params {
+		query[key] = append([]string(nil), values...)
+	}
+	for key, value := range o.Settings {
+		query.Set(key, value)
+	}
+	setString := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	setBool := func(key string, value bool) {
+		if value {
+			query.Set(key, "true")
+		}
+	}
+	setTimeout := func(key string, value time.Duration) {
+		if value != 0 {
+			query.Set(key, strconv.FormatFloat(value.Seconds(), 'f', -1, 64))
+		}
+	}
+	setString("database", o.Database)
+	setString("username", o.Username)
+	setString("password", o.Password)
+	if o.Secure != o.impliesSecure() {
+		// Open infers secure from the TLS options unless it is set
+		query.Set("secure", strconv.FormatBool(o.Secure))
+	}
+	setBool("skip_verify", o.SkipVerify)
+	setBool("debug", o.Debug)
+	setTimeout("timeout", o.ConnTimeout)
+	setTimeout("read_timeout", o.ReadTimeout)
+	setTimeout("write_timeout", o.WriteTimeout)
+	setString("connection_open_strategy", o.Strategy)
+	setString("compress", o.Compress)
+	if o.CompressLevel != 0 {
+		query.Set("compress_level", strconv.Itoa(o.CompressLevel))
+	}
+	var host string
+	if len(o.Hosts) != 0 {
+		host = o.Hosts[0]
+		if len(o.Hosts) > 1 {
+			query.Set("alt_hosts", strings.Join(o.Hosts[1:], ","))
+		}
+	}
+	if !strings.HasPrefix(host, unixSocketPrefix) && !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "tcp://" + host
+	}
+	if len(query) == 0 {
+		return host
+	}
+	return host + "?" + query.Encode()
+}
+
+// impliesSecure reports whether the TLS options of Params imply secure, see Open.
+func (o *Options) impliesSecure() bool {
+	for _, key := range []string{"tls_config", "tls_cert", "tls_key", "tls_ca", "tls_cert_fingerprint"} {
+		if o.Params.Get(key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBoolOption(key, value string) (bool, error) {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s - must be a boolean value, got %q", key, value)
+	}
+	return v, nil
+}
+
+// parseTimeoutOption parses the timeout in seconds, zero is the default and so is rejected.
+func parseTimeoutOption(key, value string) (time.Duration, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid %s - must be a positive number of seconds, got %q", key, value)
+	}
+	return time.Duration(v * float64(time.Second)), nil
+}
+
+func validateBool(key, v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("invalid %s - must be a boolean value, got %q", key, v)
+	}
+	return nil
+}
+
+func validateSeconds(key, v string) error {
+	if seconds, err := strconv.ParseFloat(v, 64); err != nil || seconds < 0 {
+		return fmt.Errorf("invalid %s - must be a non-negative number of seconds, got %q", key, v)
+	}
+	return nil
+}
+
+func validateDuration(key, v string) error {
+	if duration, err := time.ParseDuration(v); err != nil || duration < 0 {
+		return fmt.Errorf("invalid %s - must be a non-negative duration, e.g. 30s, got %q", key, v)
+	}
+	return nil
+}
+
+func validateCount(key, v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("invalid %s - must be a non-negative integer, got %q", key, v)
+	}
+	return nil
+}
+
+// validateReadBufferSize validates the read_buffer_size as Open does: 0 is the default size.
+func validateReadBufferSize(key, v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("invalid %s - must be a non-negative integer, got %q", key, v)
+	} else if n != 0 && n < minReadBufferSize {
+		return fmt.Errorf("invalid %s - must be at least %d bytes", key, minReadBufferSize)
+	}
+	return nil
+}
+
+func validateUint(key, v string) error {
+	if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+		return fmt.Errorf("invalid %s - must be a non-negative integer, got %q", key, v)
+	}
+	return nil
+}
+
+func validateCodes(key, v string) error {
+	for _, code := range strings.Split(v, ",") {
+		if _, err := strconv.ParseInt(strings.TrimSpace(code), 10, 32); err != nil {
+			return fmt.Errorf("invalid %s - must be a comma separated list of error codes, got %q", key, v)
+		}
+	}
+	return nil
+}
+
+func validateOCSP(key, v string) error {
+	if v == "lenient" {
+		return nil
+	}
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("invalid %s - must be lenient or a boolean value, got %q", key, v)
+	}
+	return nil
+}
+
+func validateString(string, string) error { return nil }
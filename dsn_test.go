@@ -0,0 +1,126 @@
+package clickhouse
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDSN(t *testing.T) {
+	dsn := "tcp://127.0.0.1:9000*3?alt_hosts=127.0.0.2:9000,unix:///var/run/clickhouse.sock*2&database=db&username=user&password=p%40ss" +
+		"&secure=true&skip_verify=1&timeout=0.5&read_timeout=30&write_timeout=20&connection_open_strategy=in_order" +
+		"&compress=zstd&compress_level=3&debug=true&max_execution_time=60&heartbeat=30s&tls_config=custom"
+	options, err := ParseDSN(dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	expected := &Options{
+		Hosts:         []string{"127.0.0.1:9000*3", "127.0.0.2:9000", "unix:///var/run/clickhouse.sock*2"},
+		Database:      "db",
+		Username:      "user",
+		Password:      "p@ss",
+		Secure:        true,
+		SkipVerify:    true,
+		ConnTimeout:   500 * time.Millisecond,
+		ReadTimeout:   30 * time.Second,
+		WriteTimeout:  20 * time.Second,
+		Strategy:      "in_order",
+		Compress:      "zstd",
+		CompressLevel: 3,
+		Debug:         true,
+		Settings:      map[string]string{"max_execution_time": "60"},
+		Params:        url.Values{"heartbeat": {"30s"}, "tls_config": {"custom"}},
+	}
+	assert.Equal(t, expected, options)
+	if roundTrip, err := ParseDSN(options.DSN()); assert.NoError(t, err) {
+		assert.Equal(t, expected, roundTrip)
+	}
+
+	if options, err := ParseDSN("unix:///var/run/clickhouse.sock"); assert.NoError(t, err) {
+		assert.Equal(t, []string{"unix:///var/run/clickhouse.sock"}, options.Hosts)
+		assert.Equal(t, "unix:///var/run/clickhouse.sock", options.DSN())
+	}
+	// secure is inferred from the TLS options as Open does, it is kept in the DSN if it differs
+	for dsn, expected := range map[string]string{
+		"tcp://127.0.0.1:9000?secure=false&tls_config=custom": "tcp://127.0.0.1:9000?secure=false&tls_config=custom",
+		"tcp://127.0.0.1:9000?secure=true&tls_ca=ca.pem":      "tcp://127.0.0.1:9000?tls_ca=ca.pem",
+		"tcp://127.0.0.1:9000?secure=true":                    "tcp://127.0.0.1:9000?secure=true",
+		"tcp://127.0.0.1:9000?secure=false":                   "tcp://127.0.0.1:9000",
+	} {
+		if options, err := ParseDSN(dsn); assert.NoError(t, err) {
+			assert.Equal(t, expected, options.DSN())
+			if roundTrip, err := ParseDSN(options.DSN()); assert.NoError(t, err) {
+				assert.Equal(t, options.Secure, roundTrip.Secure, dsn)
+			}
+		}
+	}
+	if options, err := ParseDSN("tcp://127.0.0.1:9000?tls_config=custom"); assert.NoError(t, err) {
+		assert.True(t, options.Secure)
+	}
+	for dsn, host := range map[string]string{
+		"http://127.0.0.1:8123?compress=true&database=db&max_execution_time=60": "http://127.0.0.1:8123",
+		"https://clickhouse.local:8443?skip_verify=true&tls_ca=ca.pem":          "https://clickhouse.local:8443",
+	} {
+		if options, err := ParseDSN(dsn); assert.NoError(t, err) {
+			assert.Equal(t, []string{host}, options.Hosts)
+			if roundTrip, err := ParseDSN(options.DSN()); assert.NoError(t, err) {
+				assert.Equal(t, options, roundTrip)
+			}
+		}
+	}
+	if options, err := ParseDSN("https://clickhouse.local:8443?skip_verify=true&tls_ca=ca.pem"); assert.NoError(t, err) {
+		assert.True(t, options.SkipVerify)
+		assert.Equal(t, "ca.pem", options.Params.Get("tls_ca"))
+		assert.Equal(t, "https://clickhouse.local:8443?skip_verify=true&tls_ca=ca.pem", options.DSN())
+	}
+	built := Options{Hosts: []string{"127.0.0.1:9000"}, Compress: "lz4", ReadTimeout: 90 * time.Second}
+	assert.Equal(t, "tcp://127.0.0.1:9000?compress=lz4&read_timeout=90", built.DSN())
+}
+
+func Test_ParseDSNErrors(t *testing.T) {
+	for dsn, expected := range map[string]string{
+		"127.0.0.1:9000":                                       `invalid DSN - parse "127.0.0.1:9000": first path segment in URL cannot contain colon`,
+		"udp://127.0.0.1:9000":                                 `invalid DSN - unknown scheme "udp", must be tcp, unix, http or https`,
+		"http://?compress=true":                                "invalid DSN - no host",
+		"tcp://?debug=true":                                    "invalid DSN - no host",
+		"unix://":                                              "invalid DSN - no path of the socket",
+		"tcp://127.0.0.1:9000*0":                               "invalid weight of host 127.0.0.1:9000*0",
+		"tcp://127.0.0.1:9000?alt_hosts=127.0.0.2:9000*x":      "invalid weight of host 127.0.0.2:9000*x",
+		"tcp://127.0.0.1:9000?debug=true&debgu=true":           "invalid DSN - unknown option debgu",
+		"tcp://127.0.0.1:9000?debug=true&debug=false":          "invalid DSN - option debug is set more than once",
+		"tcp://127.0.0.1:9000?timeout=-1":                      `invalid timeout - must be a positive number of seconds, got "-1"`,
+		"tcp://127.0.0.1:9000?read_timeout=0":                  `invalid read_timeout - must be a positive number of seconds, got "0"`,
+		"tcp://127.0.0.1:9000?write_timeout=10s":               `invalid write_timeout - must be a positive number of seconds, got "10s"`,
+		"tcp://127.0.0.1:9000?connection_open_strategy=rand":   `invalid connection_open_strategy - must be one of random, in_order, time_random, least_conn, got "rand"`,
+		"tcp://127.0.0.1:9000?compress=gzip":                   `invalid compress - must be lz4, zstd or a boolean value, got "gzip"`,
+		"tcp://127.0.0.1:9000?compress_level=-1":               `invalid compress_level - must be a non-negative integer, got "-1"`,
+		"tcp://127.0.0.1:9000?compress=zstd&compress_level=23": "invalid compress_level - must be between 0 and 22, got 23",
+		"tcp://127.0.0.1:9000?secure=yes":                      `invalid secure - must be a boolean value, got "yes"`,
+		"tcp://127.0.0.1:9000?read_buffer_size=15":             "invalid read_buffer_size - must be at least 16 bytes",
+		"tcp://127.0.0.1:9000?read_buffer_size=-1":             `invalid read_buffer_size - must be a non-negative integer, got "-1"`,
+		"tcp://127.0.0.1:9000?max_execution_time=-5":           `invalid max_execution_time - must be a non-negative integer, got "-5"`,
+		"tcp://127.0.0.1:9000?heartbeat=30":                    `invalid heartbeat - must be a non-negative duration, e.g. 30s, got "30"`,
+		"tcp://127.0.0.1:9000?dial_retries=-1":                 `invalid dial_retries - must be a non-negative integer, got "-1"`,
+		"tcp://127.0.0.1:9000?retry_on_codes=159,x":            `invalid retry_on_codes - must be a comma separated list of error codes, got "159,x"`,
+		"tcp://127.0.0.1:9000?verify_ocsp=strict":              `invalid verify_ocsp - must be lenient or a boolean value, got "strict"`,
+		"tcp://127.0.0.1:9000?tls_min_version=1.1":             "invalid tls_min_version - 1.1, supported versions are 1.2 and 1.3",
+		"tcp://127.0.0.1:9000?trace_statement=all":             `invalid trace_statement - must be full, hash, none or a length in bytes, got "all"`,
+		"tcp://127.0.0.1:9000?total_connect_timeout=-0.5":      `invalid total_connect_timeout - must be a non-negative number of seconds, got "-0.5"`,
+		"tcp://127.0.0.1:9000?debug=true;compress=true":        "invalid DSN - invalid semicolon separator in query",
+		"tcp://127.0.0.1:9000?tls_cert_fingerprint=0011":       "invalid tls_cert_fingerprint - 0011 is not a hex encoded SHA-256 fingerprint",
+		"tcp://127.0.0.1:9000?allow_experimental=experimental": `invalid allow_experimental - must be a boolean value, got "experimental"`,
+		"tcp://127.0.0.1:9000?allow_multi_statements=yes":      `invalid allow_multi_statements - must be a boolean value, got "yes"`,
+		"tcp://127.0.0.1:9000?verify_blocks=on":                `invalid verify_blocks - must be a boolean value, got "on"`,
+		"tcp://127.0.0.1:9000?verify_blocks=true":              "invalid verify_blocks - the server sends no checksums of the uncompressed blocks, set compress",
+		"tcp://127.0.0.1:9000?verify_blocks=1&compress=false":  "invalid verify_blocks - the server sends no checksums of the uncompressed blocks, set compress",
+		"tcp://127.0.0.1:9000?client_name=billing%0A":          `invalid client_name - must be printable ASCII characters, got "billing\n"`,
+		"tcp://127.0.0.1:9000?client_version=2.3.1":            `invalid client_version - must be the major and minor numbers of the version, e.g. 2.3, got "2.3.1"`,
+	} {
+		_, err := ParseDSN(dsn)
+		if assert.Error(t, err, dsn) {
+			assert.Equal(t, expected, err.Error(), dsn)
+		}
+	}
+}
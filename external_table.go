@@ -0,0 +1,142 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+)
+
+// externalTable is an external table of the queries run with a context, see WithExternalTable.
+type externalTable struct {
+	name      string
+	structure string
+	data      interface{}
+}
+
+// contextTable is an external table of the query ready to be sent, the rows of block or the block in the Native
+// format of reader.
+type contextTable struct {
+	name   string
+	block  *data.Block
+	reader io.Reader
+}
+
+// WithExternalTable attaches the external table name to the query run with the context: the server holds its rows
+// as a temporary table for the query only, e.g. to filter a table by the ids of the client:
+//
+//	ctx := clickhouse.WithExternalTable(ctx, "ids", "id UInt64", [][]driver.Value{{uint64(1)}, {uint64(42)}})
+//	rows, err := db.QueryContext(ctx, "SELECT id, name FROM example WHERE id IN ids")
+//
+// structure is the names and the types of the columns of the table, e.g. "id UInt64, name String".
+// data is the rows of the table, a [][]driver.Value of the values of the columns, or an io.Reader of a block
+// in the Native format of the columns, sent as is. A reader is read once, by the first query run with the context:
+// the query is not retried. The external tables are attached to the context one after another.
+func WithExternalTable(ctx context.Context, name, structure string, data interface{}) context.Context {
+	parent, _ := ctx.Value(externalTablesKey).([]externalTable)
+	tables := make([]externalTable, 0, len(parent)+1)
+	tables = append(append(tables, parent...), externalTable{name: name, structure: structure, data: data})
+	return context.WithValue(ctx, externalTablesKey, tables)
+}
+
+// contextTables returns the external tables of the context, their rows are appended into the blocks before
+// the query is written, so that an invalid table fails the query without breaking the connection.
+func (ch *clickhouse) contextTables(ctx context.Context) ([]contextTable, error) {
+	tables, _ := ctx.Value(externalTablesKey).([]externalTable)
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	prepared := make([]contextTable, 0, len(tables))
+	for _, table := range tables {
+		switch v := table.data.(type) {
+		case io.Reader:
+			prepared = append(prepared, contextTable{name: table.name, reader: v})
+		case [][]driver.Value:
+			columns, err := parseStructure(table.structure, ch.ServerInfo.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("clickhouse: invalid structure %q of the external table %s - %v", table.structure, table.name, err)
+			}
+			block := &data.Block{Columns: columns, NumColumns: uint64(len(columns))}
+			for _, row := range v {
+				if err := block.AppendRow(row); err != nil {
+					return nil, fmt.Errorf("clickhouse: external table %s: %v", table.name, err)
+				}
+			}
+			prepared = append(prepared, contextTable{name: table.name, block: block})
+		default:
+			return nil, fmt.Errorf("clickhouse: the data of the external table %s must be a [][]driver.Value or an io.Reader, got %T", table.name, table.data)
+		}
+	}
+	return prepared, nil
+}
+
+// sendContextTables sends the external tables of the context after the query.
+func (ch *clickhouse) sendContextTables(tables []contextTable) error {
+	for _, table := range tables {
+		ch.logf("[send external table] name %s", table.name)
+		var err error
+		if table.reader != nil {
+			err = ch.writeNativeBlock(table.reader, table.name)
+		} else {
+			err = ch.writeBlock(table.block, table.name)
+		}
+		if err != nil {
+			return err
+		}
+		if err := ch.encoder.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasExternalReaders reports whether an external table of the context is read from a reader, which can't be sent again.
+func hasExternalReaders(ctx context.Context) bool {
+	tables, _ := ctx.Value(externalTablesKey).([]externalTable)
+	for _, table := range tables {
+		if _, ok := table.data.(io.Reader); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStructure returns the columns of the structure, e.g. "id UInt64, name String".
+func parseStructure(structure string, timezone *time.Location) ([]column.Column, error) {
+	if strings.TrimSpace(structure) == "" {
+		return nil, fmt.Errorf("no columns")
+	}
+	var columns []column.Column
+	for _, definition := range column.SplitTypes(structure) {
+		definition = strings.TrimSpace(definition)
+		var name string
+		if strings.HasPrefix(definition, "`") {
+			end := strings.Index(definition[1:], "`")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated name of the column %s", definition)
+			}
+			name, definition = definition[1:end+1], definition[end+2:]
+		} else {
+			end := strings.IndexAny(definition, " \t\n")
+			if end == -1 {
+				return nil, fmt.Errorf("no type of the column %s", definition)
+			}
+			name, definition = definition[:end], definition[end:]
+		}
+		chType := strings.TrimSpace(definition)
+		if name == "" || chType == "" {
+			return nil, fmt.Errorf("no type of the column %s", name)
+		}
+		c, err := column.Factory(name, chType, timezone)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
@@ -0,0 +1,77 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithExternalTable(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	stub := &stubConn{data: buf.Bytes(), err: io.EOF}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	// the block of the table "names" in the Native format: 1 column, 2 rows
+	var native bytes.Buffer
+	{
+		encoder := binary.NewEncoder(&native)
+		encoder.Uvarint(1)
+		encoder.Uvarint(2)
+		encoder.String("name")
+		encoder.String("String")
+		encoder.String("native_a")
+		encoder.String("native_b")
+	}
+	ctx := WithExternalTable(context.Background(), "ids", "id UInt64, `the tags` Map(String, UInt8)", [][]driver.Value{
+		{uint64(1), map[string]uint8{"a": 1}},
+		{uint64(42), map[string]uint8{}},
+	})
+	ctx = WithExternalTable(ctx, "names", "name String", bytes.NewReader(native.Bytes()))
+	if _, err := ch.ExecContext(ctx, "SELECT 1 FROM example WHERE id IN ids AND name IN names", nil); assert.NoError(t, err) {
+		written := string(stub.written)
+		query := bytes.Index(stub.written, []byte("SELECT 1 FROM example"))
+		ids := bytes.Index(stub.written, []byte("\x03ids"))
+		names := bytes.Index(stub.written, []byte("\x05names"))
+		// the tables follow the query in their order
+		assert.True(t, query != -1 && query < ids && ids < names, "query=%d, ids=%d, names=%d", query, ids, names)
+		assert.Contains(t, written, "\x08the tags\x12Map(String, UInt8)")
+		assert.Contains(t, written, string(native.Bytes()))
+	}
+
+	for _, test := range []struct {
+		structure string
+		data      interface{}
+		err       string
+	}{
+		{"id", [][]driver.Value{}, `clickhouse: invalid structure "id" of the external table t - no type of the column id`},
+		{"", [][]driver.Value{}, `clickhouse: invalid structure "" of the external table t - no columns`},
+		{"id UInt64", [][]driver.Value{{uint64(1), "a"}}, "clickhouse: external table t: block: expected 1 arguments (columns: id), got 2"},
+		{"id UInt64", []uint64{1}, "clickhouse: the data of the external table t must be a [][]driver.Value or an io.Reader, got []uint64"},
+	} {
+		stub.written = nil
+		if _, err := ch.ExecContext(WithExternalTable(context.Background(), "t", test.structure, test.data), "SELECT 1", nil); assert.Error(t, err) {
+			assert.Equal(t, test.err, err.Error())
+			// the query is not written, the connection is kept
+			assert.Empty(t, stub.written)
+			assert.False(t, conn.isClosed())
+		}
+	}
+	assert.Equal(t, []string{"a Enum8('x,y' = 1)", "b Map(String, Tuple(UInt8, String))"}, column.SplitTypes("a Enum8('x,y' = 1), b Map(String, Tuple(UInt8, String))"))
+}
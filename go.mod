@@ -1,11 +1,20 @@
 module github.com/c3mb0/clickhouse-go
 
-go 1.12
+go 1.17
 
 require (
 	github.com/bkaradzic/go-lz4 v1.0.0
 	github.com/cloudflare/golz4 v0.0.0-20150217214814-ef862a3cdc58
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/klauspost/compress v1.15.15
 	github.com/pierrec/lz4 v2.0.5+incompatible
 	github.com/stretchr/testify v1.3.0
+	github.com/youmark/pkcs8 v0.0.0-20201027041543-1326539a0a0a
+	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 )
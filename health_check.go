@@ -0,0 +1,100 @@
+package clickhouse
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// circuitBreakerThreshold is the number of consecutive dial failures after which a host is skipped
+	circuitBreakerThreshold = 3
+	// circuitBreakerCooldown is the time a host is skipped for after the threshold is reached
+	circuitBreakerCooldown = 10 * time.Second
+)
+
+// HealthCheckFunc is a function which is called before dialing a host.
+// A non-nil error marks the host as failed and the next host is tried.
+// Custom health checks must be registered with RegisterHealthCheck
+type HealthCheckFunc func(host string) error
+
+var (
+	healthCheckLock sync.RWMutex
+	healthCheck     HealthCheckFunc
+)
+
+// RegisterHealthCheck registers a custom health check function.
+func RegisterHealthCheck(check HealthCheckFunc) {
+	healthCheckLock.Lock()
+	healthCheck = check
+	healthCheckLock.Unlock()
+}
+
+// DeregisterHealthCheck deregisters the custom health check function.
+func DeregisterHealthCheck() {
+	healthCheckLock.Lock()
+	healthCheck = nil
+	healthCheckLock.Unlock()
+}
+
+func checkHealth(host string) error {
+	healthCheckLock.RLock()
+	check := healthCheck
+	healthCheckLock.RUnlock()
+	if check == nil {
+		return nil
+	}
+	return check(host)
+}
+
+type hostState struct {
+	failures    int
+	lastFailure time.Time
+}
+
+type circuitBreaker struct {
+	mutex sync.Mutex
+	hosts map[string]*hostState
+}
+
+var breaker = circuitBreaker{
+	hosts: make(map[string]*hostState),
+}
+
+// isOpen reports whether the host failed too often recently and should not be dialed.
+func (cb *circuitBreaker) isOpen(host string) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	state, ok := cb.hosts[host]
+	if !ok || state.failures < circuitBreakerThreshold {
+		return false
+	}
+	return time.Since(state.lastFailure) < circuitBreakerCooldown
+}
+
+// allOpen reports whether every host is in the open state.
+func (cb *circuitBreaker) allOpen(hosts []string) bool {
+	for _, host := range hosts {
+		if !cb.isOpen(host) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cb *circuitBreaker) failure(host string) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	state, ok := cb.hosts[host]
+	if !ok {
+		state = &hostState{}
+		cb.hosts[host] = state
+	}
+	state.failures++
+	state.lastFailure = time.Now()
+}
+
+func (cb *circuitBreaker) success(host string) {
+	cb.mutex.Lock()
+	delete(cb.hosts, host)
+	cb.mutex.Unlock()
+}
@@ -85,6 +85,12 @@ func numInput(query string) int {
 			break
 		}
 	}
+	for _, p := range placeholders(query) {
+		if _, found := args[p.name]; !found {
+			args[p.name] = struct{}{}
+			count++
+		}
+	}
 	return count
 }
 
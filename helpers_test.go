@@ -30,14 +30,19 @@ func Test_NumInput(t *testing.T) {
 			?
 		)
 		`: 3,
-		"SELECT * from EXAMPLE LIMIT ?":                                       1,
-		"SELECT * from EXAMPLE LIMIT ?, ?":                                    2,
-		"SELECT * from EXAMPLE WHERE os_id like ?":                            1,
-		"SELECT * FROM example WHERE a BETWEEN ? AND ?":                       2,
-		"SELECT * FROM example WHERE a BETWEEN ? AND ? AND b = ?":             3,
-		"SELECT * FROM example WHERE a = ? AND b BETWEEN ? AND ?":             3,
-		"SELECT * FROM example WHERE a BETWEEN ? AND ? AND b BETWEEN ? AND ?": 4,
-		"SELECT replace(a, '\\'', '\"') FROM example WHERE b = ?":             1,
+		"SELECT * from EXAMPLE LIMIT ?":                                        1,
+		"SELECT * from EXAMPLE LIMIT ?, ?":                                     2,
+		"SELECT * from EXAMPLE WHERE os_id like ?":                             1,
+		"SELECT * FROM example WHERE a BETWEEN ? AND ?":                        2,
+		"SELECT * FROM example WHERE a BETWEEN ? AND ? AND b = ?":              3,
+		"SELECT * FROM example WHERE a = ? AND b BETWEEN ? AND ?":              3,
+		"SELECT * FROM example WHERE a BETWEEN ? AND ? AND b BETWEEN ? AND ?":  4,
+		"SELECT replace(a, '\\'', '\"') FROM example WHERE b = ?":              1,
+		"SELECT * FROM example WHERE a = {a:String} AND b IN {b:Array(UInt8)}": 2,
+		"SELECT * FROM example WHERE a = {a:String} OR b = {a:String}":         1,
+		"SELECT * FROM example WHERE a = @a AND b = {a}":                       1,
+		"SELECT * FROM example WHERE a = '{a:String}' AND b = map('{', 1)":     0,
+		"SELECT {'a': 1}, `{b:String}`":                                        0,
 	} {
 		assert.Equal(t, num, numInput(query), query)
 	}
@@ -0,0 +1,720 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+)
+
+// httpConn is the connection of the http:// and https:// DSNs: the queries are sent to the HTTP interface
+// of the server, their results are read in the RowBinaryWithNamesAndTypes format and the rows of the INSERTs
+// written in the RowBinary format. Each query is a request, the connection only holds the INSERT of its transaction.
+type httpConn struct {
+	client    *http.Client
+	transport *http.Transport
+	url       string // the URL of the server, e.g. http://127.0.0.1:8123/
+	database  string
+	username  string
+	password  string
+	// settings are the query settings of the DSN, sent as the parameters of the requests
+	settings url.Values
+	// compress gzips the requests and makes the server gzip its responses
+	compress      bool
+	logf          logger
+	inTransaction bool
+	insert        *httpInsert // the INSERT of the transaction, sent on commit
+	closed        bool
+}
+
+// httpInsert is the INSERT of a transaction of an HTTP connection, its rows are buffered until the commit.
+type httpInsert struct {
+	query   string          // the INSERT followed by FORMAT RowBinary
+	ctx     context.Context // the context of the prepare, the rows are sent with its credentials and settings
+	columns []httpColumn
+	data    bytes.Buffer
+	row     bytes.Buffer // the row being encoded, appended to data once all its values are
+	encoder *binary.Encoder
+}
+
+// valuesContext is the context of the values of its parent only, the rows of an INSERT are sent on commit
+// once the context of its prepare may be done.
+type valuesContext struct {
+	context.Context
+}
+
+func (valuesContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (valuesContext) Done() <-chan struct{}       { return nil }
+func (valuesContext) Err() error                  { return nil }
+
+// httpColumn is a column of the RowBinary format: its values are the values of the column of the native format
+// prefixed, if it is nullable, by 1 for NULL and 0 otherwise.
+type httpColumn struct {
+	column   column.Column
+	nullable bool
+}
+
+func openHTTP(ctx context.Context, dsn string) (*httpConn, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid DSN - no host")
+	}
+	var (
+		query       = u.Query()
+		database    = query.Get("database")
+		username    = query.Get("username")
+		connTimeout = DefaultConnTimeout
+		readTimeout = DefaultReadTimeout
+		skipVerify  = false
+		compress    = false
+	)
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+	if len(username) == 0 {
+		username = DefaultUsername
+	}
+	if duration, err := strconv.ParseFloat(query.Get("timeout"), 64); err == nil {
+		connTimeout = time.Duration(duration * float64(time.Second))
+	}
+	if duration, err := strconv.ParseFloat(query.Get("read_timeout"), 64); err == nil {
+		readTimeout = time.Duration(duration * float64(time.Second))
+	}
+	if v, err := strconv.ParseBool(query.Get("skip_verify")); err == nil {
+		skipVerify = v
+	}
+	switch v := query.Get("compress"); strings.ToLower(v) {
+	case "":
+	case "lz4", "zstd":
+		// the HTTP interface compresses with gzip
+		compress = true
+	default:
+		if compress, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid compress - must be lz4, zstd or a boolean value, got %q", v)
+		}
+	}
+	tlsConfigName := query.Get("tls_config")
+	tlsConfig := getTLSConfigClone(tlsConfigName)
+	if tlsConfigName != "" && tlsConfig == nil {
+		return nil, fmt.Errorf("invalid tls_config - no config registered under name %s", tlsConfigName)
+	}
+	if tlsConfig, err = loadTLSFiles(tlsConfig, query.Get("tls_cert"), query.Get("tls_key"), query.Get("tls_key_password"), query.Get("tls_ca")); err != nil {
+		return nil, err
+	}
+	if u.Scheme == "https" {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.InsecureSkipVerify = tlsConfig.InsecureSkipVerify || skipVerify
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+	}
+	// the settings are checked as the settings of the native DSNs
+	if _, err := makeQuerySettings(query); err != nil {
+		return nil, err
+	}
+	settings := make(url.Values)
+	for _, info := range querySettingList {
+		if v := query.Get(info.name); v != "" {
+			settings.Set(info.name, v)
+		}
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: connTimeout}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConnsPerHost:   1,
+	}
+	conn := &httpConn{
+		client:    &http.Client{Transport: transport},
+		transport: transport,
+		url:       u.Scheme + "://" + u.Host + "/",
+		database:  database,
+		username:  username,
+		password:  query.Get("password"),
+		settings:  settings,
+		compress:  compress,
+		logf:      func(string, ...interface{}) {},
+	}
+	if debug, _ := strconv.ParseBool(query.Get("debug")); debug {
+		conn.logf = log.New(logOutput, "[clickhouse][http]", 0).Printf
+	}
+	if custom := getLogger(); custom != nil {
+		conn.logf = loggerLogf(custom)
+	}
+	conn.logf("host=%s, database=%s, username=%s", u.Host, database, username)
+	if err := conn.Ping(ctx); err != nil {
+		transport.CloseIdleConnections()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Ping checks that the server answers its /ping endpoint.
+func (conn *httpConn) Ping(ctx context.Context) error {
+	if conn.closed {
+		return driver.ErrBadConn
+	}
+	req, err := http.NewRequest(http.MethodGet, conn.url+"ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := conn.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpException(resp.StatusCode, body)
+	}
+	return nil
+}
+
+// do sends the query, in the body of the request or, if the data of an INSERT is the body, in its URL.
+// The response is returned if its status is OK, the exception of the server otherwise.
+func (conn *httpConn) do(ctx context.Context, query string, params url.Values, data io.Reader) (*http.Response, error) {
+	if conn.closed {
+		return nil, driver.ErrBadConn
+	}
+	id, err := queryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make(url.Values, len(conn.settings)+len(params)+3)
+	for name, value := range conn.settings {
+		values[name] = value
+	}
+	if overrides, ok := ctx.Value(querySettingsKey).(map[string]interface{}); ok {
+		for name, value := range overrides {
+			values.Set(name, httpSettingValue(value))
+		}
+	}
+	for name, value := range params {
+		values[name] = value
+	}
+	values.Set("database", conn.database)
+	values.Set("query_id", id)
+	if conn.compress {
+		values.Set("enable_http_compression", "1")
+	}
+	body := data
+	if data == nil {
+		body = strings.NewReader(query)
+	} else {
+		values.Set("query", query)
+	}
+	if conn.compress {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := io.Copy(writer, body); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		body = &compressed
+	}
+	conn.logf("[http] query_id=%s, %s", id, query)
+	req, err := http.NewRequest(http.MethodPost, conn.url+"?"+values.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	// the requests are authenticated one by one, by the credentials of their context if any
+	user := contextCredentials(ctx, credentials{username: conn.username, password: conn.password})
+	req.Header.Set("X-ClickHouse-User", user.username)
+	if user.password != "" {
+		req.Header.Set("X-ClickHouse-Key", user.password)
+	}
+	if conn.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := conn.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, httpException(resp.StatusCode, message)
+	}
+	return resp, nil
+}
+
+// httpSettingValue returns the setting as the parameter of the request, the booleans as 0 or 1.
+func httpSettingValue(v interface{}) string {
+	if b, ok := v.(bool); ok {
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+var httpExceptionRe = regexp.MustCompile(`^Code: (\d+)[.,] (?:e\.displayText\(\) = )?([\s\S]*)$`)
+
+// httpException returns the exception of the server of the body of the response, e.g.
+// "Code: 60. DB::Exception: Table default.example doesn't exist.", or an error of the status if it isn't one.
+func httpException(status int, body []byte) error {
+	message := strings.TrimSpace(string(body))
+	if match := httpExceptionRe.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.ParseInt(match[1], 10, 32)
+		return &Exception{
+			Code:    int32(code),
+			Name:    "DB::Exception",
+			Message: match[2],
+		}
+	}
+	return fmt.Errorf("clickhouse: HTTP status %d: %s", status, message)
+}
+
+// bindHTTP binds the arguments to the placeholders of the query, the parameters of the {name:Type} placeholders
+// are returned as the param_<name> parameters of the request.
+func bindHTTP(query string, args []driver.NamedValue) (string, url.Values, error) {
+	// the arguments are bound as the arguments of the statements of the native connections, which have no block
+	query, externalTables := (&stmt{ch: &clickhouse{}, query: query, numInput: numInput(query)}).bind(args)
+	if len(externalTables) != 0 {
+		return "", nil, fmt.Errorf("clickhouse: the external tables are not supported over HTTP")
+	}
+	query, parameters, err := bindParameters(query, args)
+	if err != nil {
+		return "", nil, err
+	}
+	params := make(url.Values, len(parameters))
+	for name, value := range parameters {
+		params.Set(name, fmt.Sprint(value))
+	}
+	return query, params, nil
+}
+
+func (conn *httpConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, params, err := bindHTTP(query, args)
+	if err != nil {
+		return nil, err
+	}
+	params.Set("default_format", "RowBinaryWithNamesAndTypes")
+	resp, err := conn.do(ctx, query, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows := &httpRows{
+		body:    resp.Body,
+		decoder: binary.NewDecoder(fullReader{bufio.NewReader(resp.Body)}),
+	}
+	// the DateTime values are read in the timezone of the server, as over the native protocol
+	timezone := time.Local
+	if name := resp.Header.Get("X-ClickHouse-Timezone"); name != "" {
+		if location, err := time.LoadLocation(name); err == nil {
+			timezone = location
+		}
+	}
+	if err := rows.readHeader(timezone); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (conn *httpConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if conn.inTransaction && isInsert(query) {
+		stmt, err := conn.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	}
+	query, params, err := bindHTTP(query, args)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := conn.do(ctx, query, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, resp.Body); err != nil {
+		return nil, err
+	}
+	if rows := writtenRows(resp.Header); rows != 0 {
+		return &insertResult{rows: rows}, nil
+	}
+	return emptyResult, nil
+}
+
+// writtenRows returns the written rows of the summary of the query the server sends in the X-ClickHouse-Summary header.
+func writtenRows(header http.Header) int64 {
+	var summary struct {
+		WrittenRows string `json:"written_rows"`
+	}
+	if err := json.Unmarshal([]byte(header.Get("X-ClickHouse-Summary")), &summary); err != nil {
+		return 0
+	}
+	rows, _ := strconv.ParseInt(summary.WrittenRows, 10, 64)
+	return rows
+}
+
+func (conn *httpConn) Prepare(query string) (driver.Stmt, error) {
+	return conn.PrepareContext(context.Background(), query)
+}
+
+func (conn *httpConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	conn.logf("[prepare] %s", query)
+	switch {
+	case conn.closed:
+		return nil, driver.ErrBadConn
+	case conn.insert != nil:
+		return nil, ErrLimitDataRequestInTx
+	case isInsert(query):
+		if !conn.inTransaction {
+			return nil, ErrInsertInNotBatchMode
+		}
+		insert, err := conn.prepareInsert(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		conn.insert = insert
+		return &httpStmt{conn: conn, insert: insert}, nil
+	}
+	return &httpStmt{conn: conn, query: query, numInput: numInput(query)}, nil
+}
+
+// httpInsertRe matches the table and the optional columns of an INSERT, up to its VALUES
+var httpInsertRe = regexp.MustCompile("(?is)^\\s*INSERT\\s+INTO\\s+(?:TABLE\\s+)?((?:`[^`]*`|[^\\s(`])+)\\s*(?:\\((.*)\\))?\\s*(?:VALUES\\s*)?$")
+
+// prepareInsert returns the INSERT of the rows of the columns of the query, their types are described by the server.
+func (conn *httpConn) prepareInsert(ctx context.Context, query string) (*httpInsert, error) {
+	prefix := splitInsertRe.Split(query, -1)[0]
+	match := httpInsertRe.FindStringSubmatch(prefix)
+	if match == nil {
+		return nil, fmt.Errorf("clickhouse: can't parse the table and the columns of the INSERT %q", query)
+	}
+	table, names := match[1], match[2]
+	rows, err := conn.QueryContext(ctx, "DESCRIBE TABLE "+table, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	described := rows.(*httpRows)
+	var (
+		types   = make(map[string]string)
+		columns []string // the columns of the table inserted if the INSERT names none
+		values  = make([]driver.Value, len(described.Columns()))
+	)
+	for {
+		if err := described.Next(values); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		name, chType, defaultType := fmt.Sprint(values[0]), fmt.Sprint(values[1]), fmt.Sprint(values[2])
+		types[name] = chType
+		switch defaultType {
+		case "MATERIALIZED", "ALIAS", "EPHEMERAL":
+		default:
+			columns = append(columns, name)
+		}
+	}
+	insert := &httpInsert{
+		query: "INSERT INTO " + table + " FORMAT RowBinary",
+		ctx:   valuesContext{ctx},
+	}
+	if names != "" {
+		insert.query = "INSERT INTO " + table + " (" + names + ") FORMAT RowBinary"
+		columns = columns[:0]
+		for _, name := range strings.Split(names, ",") {
+			columns = append(columns, strings.Trim(strings.TrimSpace(name), "`"))
+		}
+	}
+	insert.encoder = binary.NewEncoder(&insert.row)
+	for _, name := range columns {
+		chType, ok := types[name]
+		if !ok {
+			return nil, fmt.Errorf("clickhouse: no column %s in the table %s", name, table)
+		}
+		c, err := newHTTPColumn(name, chType, described.timezone)
+		if err != nil {
+			return nil, err
+		}
+		insert.columns = append(insert.columns, c)
+	}
+	return insert, nil
+}
+
+// newHTTPColumn returns the column of the type in the RowBinary format. The LowCardinality columns are written
+// as the columns of their type, the Array, Map, Tuple, Nested and AggregateFunction columns are not supported.
+func newHTTPColumn(name, chType string, timezone *time.Location) (httpColumn, error) {
+	inner, nullable := chType, false
+	if strings.HasPrefix(inner, "LowCardinality(") {
+		inner = inner[15 : len(inner)-1]
+	}
+	if strings.HasPrefix(inner, "Nullable(") {
+		inner, nullable = inner[9:len(inner)-1], true
+	}
+	c, err := column.Factory(name, inner, timezone)
+	if err != nil {
+		return httpColumn{}, err
+	}
+	switch c.(type) {
+	case *column.Array, *column.Map, *column.Tuple, *column.Nested, *column.Nullable, *column.LowCardinality,
+		*column.AggregateFunction, *column.JSON, *column.Point:
+		return httpColumn{}, fmt.Errorf("clickhouse: column %s of the type %s is not supported over HTTP", name, chType)
+	}
+	return httpColumn{column: c, nullable: nullable}, nil
+}
+
+func (c httpColumn) read(decoder *binary.Decoder) (interface{}, error) {
+	if c.nullable {
+		isNull, err := decoder.UInt8()
+		if err != nil || isNull == 1 {
+			return nil, err
+		}
+	}
+	return c.column.Read(decoder, false)
+}
+
+func (c httpColumn) write(encoder *binary.Encoder, v interface{}) error {
+	if c.nullable {
+		if value := reflect.ValueOf(v); v == nil || value.Kind() == reflect.Ptr && value.IsNil() {
+			return encoder.UInt8(1)
+		}
+		if err := encoder.UInt8(0); err != nil {
+			return err
+		}
+	}
+	return c.column.Write(encoder, v)
+}
+
+func (insert *httpInsert) appendRow(args []driver.NamedValue) error {
+	if len(args) != len(insert.columns) {
+		names := make([]string, 0, len(insert.columns))
+		for _, c := range insert.columns {
+			names = append(names, c.column.Name())
+		}
+		return fmt.Errorf("clickhouse: expected %d values (columns: %v), got %d", len(insert.columns), names, len(args))
+	}
+	insert.row.Reset()
+	for i, arg := range args {
+		if err := insert.columns[i].write(insert.encoder, arg.Value); err != nil {
+			return fmt.Errorf("clickhouse: column %s: %v", insert.columns[i].column.Name(), err)
+		}
+	}
+	_, err := insert.row.WriteTo(&insert.data)
+	return err
+}
+
+func (conn *httpConn) Begin() (driver.Tx, error) {
+	return conn.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (conn *httpConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	conn.logf("[begin] tx=%t", conn.inTransaction)
+	if conn.closed {
+		return nil, driver.ErrBadConn
+	}
+	conn.inTransaction = true
+	return conn, nil
+}
+
+// Commit sends the rows of the INSERT of the transaction, if any.
+func (conn *httpConn) Commit() error {
+	conn.logf("[commit] tx=%t, data=%t", conn.inTransaction, conn.insert != nil)
+	insert := conn.insert
+	defer func() {
+		conn.insert = nil
+		conn.inTransaction = false
+	}()
+	switch {
+	case !conn.inTransaction:
+		return sql.ErrTxDone
+	case insert == nil:
+		return nil
+	}
+	resp, err := conn.do(insert.ctx, insert.query, nil, &insert.data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// Rollback gives up the rows of the INSERT of the transaction, none were sent.
+func (conn *httpConn) Rollback() error {
+	conn.logf("[rollback] tx=%t, data=%t", conn.inTransaction, conn.insert != nil)
+	if !conn.inTransaction {
+		return sql.ErrTxDone
+	}
+	conn.insert = nil
+	conn.inTransaction = false
+	return nil
+}
+
+func (conn *httpConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+func (conn *httpConn) Close() error {
+	conn.closed = true
+	conn.insert = nil
+	conn.transport.CloseIdleConnections()
+	return nil
+}
+
+type httpStmt struct {
+	conn     *httpConn
+	query    string
+	numInput int
+	insert   *httpInsert // the INSERT of the transaction the rows are appended to, nil for the other queries
+}
+
+func (stmt *httpStmt) NumInput() int {
+	switch {
+	case stmt.insert != nil:
+		return len(stmt.insert.columns)
+	case stmt.numInput < 0:
+		return 0
+	}
+	return stmt.numInput
+}
+
+func (stmt *httpStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return stmt.ExecContext(context.Background(), convertOldArgs(args))
+}
+
+func (stmt *httpStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if stmt.insert != nil {
+		if err := stmt.insert.appendRow(args); err != nil {
+			return nil, err
+		}
+		// the row is sent on commit
+		return &insertResult{rows: 1}, nil
+	}
+	return stmt.conn.ExecContext(ctx, stmt.query, args)
+}
+
+func (stmt *httpStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.QueryContext(context.Background(), convertOldArgs(args))
+}
+
+func (stmt *httpStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if stmt.insert != nil {
+		return nil, fmt.Errorf("clickhouse: the rows of an INSERT can't be queried")
+	}
+	return stmt.conn.QueryContext(ctx, stmt.query, args)
+}
+
+func (stmt *httpStmt) Close() error {
+	return nil
+}
+
+// httpRows are the rows of the RowBinaryWithNamesAndTypes response of a query.
+type httpRows struct {
+	body     io.ReadCloser
+	decoder  *binary.Decoder
+	timezone *time.Location // the timezone of the server
+	columns  []string
+	types    []string
+	readers  []httpColumn
+}
+
+// readHeader reads the names and the types of the columns preceding the rows, the responses of the queries
+// which return no rows, e.g. the DDL, are empty.
+func (rows *httpRows) readHeader(timezone *time.Location) error {
+	rows.timezone = timezone
+	n, err := rows.decoder.Uvarint()
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	}
+	rows.columns, rows.types = make([]string, n), make([]string, n)
+	for i := range rows.columns {
+		if rows.columns[i], err = rows.decoder.String(); err != nil {
+			return err
+		}
+	}
+	for i := range rows.types {
+		if rows.types[i], err = rows.decoder.String(); err != nil {
+			return err
+		}
+		c, err := newHTTPColumn(rows.columns[i], rows.types[i], timezone)
+		if err != nil {
+			return err
+		}
+		rows.readers = append(rows.readers, c)
+	}
+	return nil
+}
+
+func (rows *httpRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *httpRows) ColumnTypeDatabaseTypeName(index int) string {
+	return rows.types[index]
+}
+
+func (rows *httpRows) Next(dest []driver.Value) error {
+	for i, c := range rows.readers {
+		value, err := c.read(rows.decoder)
+		switch {
+		case err == io.EOF && i == 0:
+			return io.EOF
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			return &truncatedError{err: io.ErrUnexpectedEOF}
+		case err != nil:
+			return err
+		}
+		dest[i] = value
+	}
+	if len(rows.readers) == 0 {
+		return io.EOF
+	}
+	return nil
+}
+
+func (rows *httpRows) Close() error {
+	return rows.body.Close()
+}
+
+// fullReader reads its buffers in full, as the decoder expects: the body of a response may be read in smaller parts.
+type fullReader struct {
+	r io.Reader
+}
+
+func (r fullReader) Read(b []byte) (int, error) {
+	return io.ReadFull(r.r, b)
+}
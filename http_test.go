@@ -0,0 +1,216 @@
+package clickhouse
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStubHTTPServer returns the server answering the queries as the HTTP interface of ClickHouse would,
+// the rows of the INSERTs are written to inserted.
+func newStubHTTPServer(t *testing.T, inserted *bytes.Buffer) *httptest.Server {
+	// header writes the header of the RowBinaryWithNamesAndTypes format
+	header := func(encoder *binary.Encoder, columns ...string) {
+		encoder.Uvarint(uint64(len(columns) / 2))
+		for i := 0; i < len(columns); i += 2 {
+			encoder.String(columns[i])
+		}
+		for i := 1; i < len(columns); i += 2 {
+			encoder.String(columns[i])
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			w.Write([]byte("Ok.\n"))
+			return
+		}
+		assert.Equal(t, "default", r.Header.Get("X-ClickHouse-User"))
+		assert.Equal(t, "secret", r.Header.Get("X-ClickHouse-Key"))
+		assert.Equal(t, "60", r.URL.Query().Get("max_execution_time"))
+		body, err := ioutil.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(bytes.NewReader(body))
+			if !assert.NoError(t, err) {
+				return
+			}
+			if body, err = ioutil.ReadAll(reader); !assert.NoError(t, err) {
+				return
+			}
+		}
+		query := string(body)
+		if q := r.URL.Query().Get("query"); q != "" {
+			query = q
+		}
+		var (
+			buf     bytes.Buffer
+			encoder = binary.NewEncoder(&buf)
+		)
+		switch {
+		case query == "SELECT id, name, day FROM example WHERE id > 0 AND id IN {ids:Array(UInt64)}":
+			assert.Equal(t, "[1,2]", r.URL.Query().Get("param_ids"))
+			assert.Equal(t, "RowBinaryWithNamesAndTypes", r.URL.Query().Get("default_format"))
+			header(encoder, "id", "UInt64", "name", "LowCardinality(Nullable(String))", "day", "Date")
+			encoder.UInt64(1)
+			encoder.UInt8(0)
+			encoder.String("a")
+			encoder.Int16(18690) // 2021-03-04
+			encoder.UInt64(2)
+			encoder.UInt8(1)
+			encoder.Int16(18691)
+		case query == "SELECT truncated":
+			header(encoder, "id", "UInt64", "name", "String")
+			encoder.UInt64(1)
+			encoder.String("a")
+			encoder.UInt64(2)
+		case query == "SELECT tags":
+			header(encoder, "tags", "Array(String)")
+		case query == "DESCRIBE TABLE example":
+			header(encoder, "name", "String", "type", "String", "default_type", "String", "default_expression", "String")
+			for _, column := range [][]string{
+				{"id", "UInt64", "", ""},
+				{"name", "Nullable(String)", "", ""},
+				{"day", "Date", "DEFAULT", "today()"},
+				{"length", "UInt64", "MATERIALIZED", "length(name)"},
+			} {
+				for _, v := range column {
+					encoder.String(v)
+				}
+			}
+		case query == "INSERT INTO example FORMAT RowBinary" || query == "INSERT INTO example (id, `name`) FORMAT RowBinary":
+			inserted.Write([]byte(query + "\n"))
+			inserted.Write(body)
+		case strings.HasPrefix(query, "INSERT INTO example SELECT"):
+			w.Header().Set("X-ClickHouse-Summary", `{"read_rows":"10","read_bytes":"80","written_rows":"10","written_bytes":"80"}`)
+		case strings.HasPrefix(query, "CREATE TABLE"):
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			buf.WriteString("Code: 60. DB::Exception: Table default.missing doesn't exist. (UNKNOWN_TABLE)\n")
+		}
+		w.Write(buf.Bytes())
+	}))
+}
+
+func Test_HTTP(t *testing.T) {
+	var inserted bytes.Buffer
+	server := newStubHTTPServer(t, &inserted)
+	defer server.Close()
+	for _, compress := range []string{"false", "true"} {
+		inserted.Reset()
+		db, err := sql.Open("clickhouse", server.URL+"?password=secret&max_execution_time=60&compress="+compress)
+		if !assert.NoError(t, err) || !assert.NoError(t, db.Ping()) {
+			return
+		}
+		rows, err := db.Query("SELECT id, name, day FROM example WHERE id > ? AND id IN {ids:Array(UInt64)}", 0, sql.Named("ids", Array([]uint64{1, 2})))
+		if assert.NoError(t, err) {
+			if types, err := rows.ColumnTypes(); assert.NoError(t, err) {
+				assert.Equal(t, "LowCardinality(Nullable(String))", types[1].DatabaseTypeName())
+			}
+			var results []string
+			for rows.Next() {
+				var (
+					id   uint64
+					name sql.NullString
+					day  time.Time
+				)
+				if assert.NoError(t, rows.Scan(&id, &name, &day)) {
+					results = append(results, strings.Join([]string{name.String, day.Format("2006-01-02")}, " "))
+				}
+			}
+			assert.NoError(t, rows.Err())
+			assert.Equal(t, []string{"a 2021-03-04", " 2021-03-05"}, results)
+			rows.Close()
+		}
+
+		// the rows of the INSERTs of a transaction are sent in the RowBinary format on commit
+		for _, query := range []string{"INSERT INTO example VALUES (?, ?, ?)", "INSERT INTO example (id, `name`) VALUES (?, ?)"} {
+			inserted.Reset()
+			tx, err := db.Begin()
+			if !assert.NoError(t, err) {
+				return
+			}
+			stmt, err := tx.Prepare(query)
+			if !assert.NoError(t, err) {
+				return
+			}
+			values := [][]interface{}{{uint64(1), "a"}, {uint64(2), nil}}
+			if strings.Contains(query, "?, ?, ?") {
+				values = [][]interface{}{{uint64(1), "a", time.Date(2021, 3, 4, 0, 0, 0, 0, time.Local)}, {uint64(2), nil, "2021-03-05"}}
+			}
+			for _, row := range values {
+				if result, err := stmt.Exec(row...); assert.NoError(t, err) {
+					affected, err := result.RowsAffected()
+					assert.NoError(t, err)
+					assert.Equal(t, int64(1), affected)
+				}
+			}
+			if _, err := stmt.Exec(uint64(3)); assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "expected")
+			}
+			if assert.NoError(t, tx.Commit()) {
+				var (
+					expected bytes.Buffer
+					encoder  = binary.NewEncoder(&expected)
+				)
+				if strings.Contains(query, "?, ?, ?") {
+					expected.WriteString("INSERT INTO example FORMAT RowBinary\n")
+					encoder.UInt64(1)
+					encoder.UInt8(0)
+					encoder.String("a")
+					encoder.Int16(18690)
+					encoder.UInt64(2)
+					encoder.UInt8(1)
+					encoder.Int16(18691)
+				} else {
+					expected.WriteString("INSERT INTO example (id, `name`) FORMAT RowBinary\n")
+					encoder.UInt64(1)
+					encoder.UInt8(0)
+					encoder.String("a")
+					encoder.UInt64(2)
+					encoder.UInt8(1)
+				}
+				assert.Equal(t, expected.Bytes(), inserted.Bytes(), query)
+			}
+		}
+		if _, err := db.Exec("CREATE TABLE example (id UInt64) Engine=Memory"); !assert.NoError(t, err) {
+			return
+		}
+		// the written rows are those of the summary of the server
+		if result, err := db.Exec("INSERT INTO example SELECT number FROM system.numbers LIMIT 10"); assert.NoError(t, err) {
+			affected, err := result.RowsAffected()
+			assert.NoError(t, err)
+			assert.Equal(t, int64(10), affected)
+		}
+		if _, err := db.Exec("SELECT * FROM missing"); assert.Error(t, err) {
+			if exception, ok := err.(*Exception); assert.True(t, ok) {
+				assert.Equal(t, int32(60), exception.Code)
+				assert.Equal(t, "DB::Exception: Table default.missing doesn't exist. (UNKNOWN_TABLE)", exception.Message)
+			}
+		}
+		if _, err := db.Query("SELECT tags"); assert.Error(t, err) {
+			assert.Equal(t, "clickhouse: column tags of the type Array(String) is not supported over HTTP", err.Error())
+		}
+		if rows, err := db.Query("SELECT truncated"); assert.NoError(t, err) {
+			var count int
+			for rows.Next() {
+				count++
+			}
+			assert.Equal(t, 1, count)
+			assert.True(t, errors.Is(rows.Err(), ErrTruncatedResult))
+			rows.Close()
+		}
+		db.Close()
+	}
+}
@@ -0,0 +1,139 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// insertFormats are the formats of the data of InsertReader.
+var insertFormats = []string{
+	"Native",
+	"RowBinary",
+	"RowBinaryWithNames",
+	"RowBinaryWithNamesAndTypes",
+	"TabSeparated",
+	"TSV",
+	"CSV",
+	"CSVWithNames",
+	"JSONEachRow",
+	"Values",
+}
+
+// queryData is the data following the text of the query in its packet, see InsertReader.
+type queryData struct {
+	r    io.Reader
+	size int64
+}
+
+// InsertReader inserts into the table the data of r, already serialized in the format, e.g. Native
+// or RowBinary, without decoding it: the query INSERT INTO table FORMAT format is sent with the bytes
+// of r following it, which the server parses as the rows of the INSERT. The bytes are copied
+// in chunks of the size of the write buffer. Their number has to be known before they are sent:
+// it is the Len of a *bytes.Reader, *bytes.Buffer or *strings.Reader, or the size left
+// in a regular *os.File; any other reader is read to its end into memory first.
+// The table is written into the query as is, e.g. db.table.
+func (ch *clickhouse) InsertReader(ctx context.Context, table, format string, r io.Reader) error {
+	ch.logf("[insert reader] table=%s, format=%s", table, format)
+	switch {
+	case ch.conn.isClosed():
+		return driver.ErrBadConn
+	case ch.block != nil:
+		return ErrLimitDataRequestInTx
+	case !isInsertFormat(format):
+		return fmt.Errorf("clickhouse: unsupported format %q of InsertReader - must be one of %s", format, strings.Join(insertFormats, ", "))
+	}
+	size, ok := readerSize(r)
+	if !ok {
+		buf, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(buf), int64(len(buf))
+	}
+	finish := ch.watchCancel(ctx)
+	defer finish()
+	query := "INSERT INTO " + table + " FORMAT " + format + "\n"
+	ctx, span := ch.startSpan(ctx, query)
+	err := ch.insertReader(ctx, query, &queryData{r: r, size: size})
+	endSpan(span, err)
+	return err
+}
+
+func (ch *clickhouse) insertReader(ctx context.Context, query string, data *queryData) error {
+	if err := ch.sendQuery(context.WithValue(ctx, queryDataKey, data), query, nil); err != nil {
+		return err
+	}
+	ch.beginReading()
+	err := ch.process()
+	if cancelErr := ch.endReading(); cancelErr != nil {
+		return cancelErr
+	}
+	return err
+}
+
+// writeQueryData writes the text of the query followed by the data as the query of its packet.
+func (ch *clickhouse) writeQueryData(query string, data *queryData) error {
+	if err := ch.encoder.Uvarint(uint64(len(query)) + uint64(data.size)); err != nil {
+		return err
+	}
+	if _, err := ch.encoder.Write([]byte(query)); err != nil {
+		return err
+	}
+	var (
+		chunk   = make([]byte, ch.conn.writer.Size())
+		written int64
+	)
+	for written < data.size {
+		if left := data.size - written; left < int64(len(chunk)) {
+			chunk = chunk[:left]
+		}
+		n, err := io.ReadFull(data.r, chunk)
+		if err != nil {
+			// the length of the query has been written, the connection can't be reused
+			ch.conn.Close()
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return fmt.Errorf("clickhouse: the data of InsertReader ended after %d of its %d bytes", written+int64(n), data.size)
+			}
+			return err
+		}
+		if _, err := ch.encoder.Write(chunk); err != nil {
+			return err
+		}
+		written += int64(n)
+	}
+	return nil
+}
+
+func isInsertFormat(format string) bool {
+	for _, f := range insertFormats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
+
+// readerSize returns the number of the bytes left in r, false if it can't be told without reading r.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size() - offset, true
+	}
+	return 0, false
+}
@@ -0,0 +1,65 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// lyingReader reports more bytes left than it has.
+type lyingReader struct{ *bytes.Reader }
+
+func (r lyingReader) Len() int { return r.Reader.Len() + 10 }
+
+func Test_InsertReader(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	for i := 0; i < 2; i++ {
+		encoder.Uvarint(protocol.ServerEndOfStream)
+	}
+	stub := &stubConn{data: buf.Bytes(), err: io.EOF}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	// packet returns the query of the packet followed by the data, as it is written
+	packet := func(query string, data []byte) []byte {
+		var buf bytes.Buffer
+		binary.NewEncoder(&buf).String(query + string(data))
+		return buf.Bytes()
+	}
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 5000)
+	const query = "INSERT INTO example FORMAT RowBinary\n"
+	if assert.NoError(t, ch.InsertReader(context.Background(), "example", "RowBinary", bytes.NewReader(data))) {
+		assert.Contains(t, string(stub.written), string(packet(query, data)))
+		// the data is copied in chunks of the write buffer
+		assert.True(t, stub.writes > len(data)/conn.writer.Size(), "writes=%d", stub.writes)
+	}
+	// the reader of an unknown size is read into memory
+	stub.written = nil
+	if assert.NoError(t, ch.InsertReader(context.Background(), "db.example", "Native", io.MultiReader(bytes.NewReader(data[:10]), bytes.NewReader(data[10:])))) {
+		assert.Contains(t, string(stub.written), string(packet("INSERT INTO db.example FORMAT Native\n", data)))
+	}
+
+	stub.written = nil
+	if err := ch.InsertReader(context.Background(), "example", "Parquet", bytes.NewReader(data)); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `unsupported format "Parquet" of InsertReader`)
+		assert.Empty(t, stub.written)
+	}
+	if err := ch.InsertReader(context.Background(), "example", "RowBinary", lyingReader{bytes.NewReader(data[:100])}); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: the data of InsertReader ended after 100 of its 110 bytes", err.Error())
+		// the packet is broken, the connection is not reused
+		assert.True(t, conn.isClosed())
+	}
+}
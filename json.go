@@ -0,0 +1,44 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// ScanJSON returns a sql.Scanner scanning the value of an Object('json') column into the value pointed to by dest
+// as encoding/json would decode the document, e.g. into a struct, a map or a json.RawMessage.
+// The documents of a String column are scanned the same way.
+//
+//	var document json.RawMessage
+//	rows.Scan(clickhouse.ScanJSON(&document))
+func ScanJSON(dest interface{}) sql.Scanner {
+	return &jsonScanner{dest: dest}
+}
+
+type jsonScanner struct {
+	dest interface{}
+}
+
+func (scanner *jsonScanner) Scan(src interface{}) error {
+	var document []byte
+	switch src := src.(type) {
+	case string:
+		document = []byte(src)
+	case []byte:
+		document = src
+	case map[string]interface{}:
+		var err error
+		if document, err = json.Marshal(src); err != nil {
+			return fmt.Errorf("clickhouse: ScanJSON: %v", err)
+		}
+	case nil:
+		document = []byte("null")
+	default:
+		return fmt.Errorf("clickhouse: can not scan %T as a JSON document", src)
+	}
+	if err := json.Unmarshal(document, scanner.dest); err != nil {
+		return fmt.Errorf("clickhouse: ScanJSON: %v", err)
+	}
+	return nil
+}
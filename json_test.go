@@ -0,0 +1,51 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScanJSON(t *testing.T) {
+	document := map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": []string{"x"}}}
+	var raw json.RawMessage
+	if err := ScanJSON(&raw).Scan(document); assert.NoError(t, err) {
+		assert.JSONEq(t, `{"a": 1, "b": {"c": ["x"]}}`, string(raw))
+	}
+	var value struct {
+		A int
+		B struct {
+			C []string `json:"c"`
+		} `json:"b"`
+	}
+	if err := ScanJSON(&value).Scan(document); assert.NoError(t, err) {
+		assert.Equal(t, 1, value.A)
+		assert.Equal(t, []string{"x"}, value.B.C)
+	}
+	var object map[string]interface{}
+	if err := ScanJSON(&object).Scan(`{"a": "b"}`); assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{"a": "b"}, object)
+	}
+	assert.Error(t, ScanJSON(&value).Scan(uint8(1)))
+	assert.Error(t, ScanJSON(&value).Scan(`{"a": `))
+}
+
+func Test_CheckExperimental(t *testing.T) {
+	var block data.Block
+	for _, chType := range []string{"String", "Object('json')"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		block.Columns = append(block.Columns, c)
+	}
+	if err := (&clickhouse{}).checkExperimental(&block); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "allow_experimental=true")
+	}
+	assert.NoError(t, (&clickhouse{allowExperimental: true}).checkExperimental(&block))
+	assert.NoError(t, (&clickhouse{}).checkExperimental(&data.Block{Columns: block.Columns[:1]}))
+}
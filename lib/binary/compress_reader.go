@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/c3mb0/clickhouse-go/lib/cityhash102"
 	"github.com/c3mb0/clickhouse-go/lib/lz4"
 )
 
@@ -20,6 +21,8 @@ type compressReader struct {
 	zdata []byte
 	// lz4 headers
 	header []byte
+	// onBlock is called with the sizes of each block read, see Decoder.OnCompressedBlock
+	onBlock func(size, compressedSize int)
 }
 
 // NewCompressReader wrap the io.Reader
@@ -74,33 +77,62 @@ func (cr *compressReader) readCompressedData() (err error) {
 	compressedSize := int(binary.LittleEndian.Uint32(cr.header[17:])) - 9
 	decompressedSize := int(binary.LittleEndian.Uint32(cr.header[21:]))
 
-	if compressedSize > cap(cr.zdata) {
-		cr.zdata = make([]byte, compressedSize)
+	if compressedSize < 0 || decompressedSize < 0 {
+		return fmt.Errorf("Decompress invalid block size")
+	}
+	if compressedSize > CompressedSizeLimit || decompressedSize > BlockSizeLimit {
+		// the sizes are not covered by a verified checksum yet, those of a corrupted header are not allocated
+		return ErrChecksumMismatch
+	}
+	// zdata holds the compression header followed by the data compressed, as the checksum covers them
+	if compressedSize+CompressHeaderSize > cap(cr.zdata) {
+		cr.zdata = make([]byte, compressedSize+CompressHeaderSize)
 	}
 	if decompressedSize > cap(cr.data) {
 		cr.data = make([]byte, decompressedSize)
 	}
 
-	cr.zdata = cr.zdata[:compressedSize]
+	cr.zdata = cr.zdata[:compressedSize+CompressHeaderSize]
 	cr.data = cr.data[:decompressedSize]
 
-	// @TODO checksum
-	if cr.header[16] == LZ4 {
-		n, err = cr.reader.Read(cr.zdata)
-		if err != nil {
-			return
-		}
+	switch cr.header[16] {
+	case LZ4, ZSTD:
+	default:
+		return fmt.Errorf("Unknown compression method: 0x%02x ", cr.header[16])
+	}
+	copy(cr.zdata, cr.header[ChecksumSize:])
+	n, err = cr.reader.Read(cr.zdata[CompressHeaderSize:])
+	if err != nil {
+		return
+	}
 
-		if n != len(cr.zdata) {
-			return fmt.Errorf("Decompress read size not match")
-		}
+	if n != compressedSize {
+		return fmt.Errorf("Decompress read size not match")
+	}
 
-		_, err = lz4.Decode(cr.data, cr.zdata)
-		if err != nil {
+	checkSum := cityhash102.CityHash128(cr.zdata, uint32(len(cr.zdata)))
+	if checkSum.Lower64() != binary.LittleEndian.Uint64(cr.header[0:]) || checkSum.Higher64() != binary.LittleEndian.Uint64(cr.header[8:]) {
+		return ErrChecksumMismatch
+	}
+	if cr.onBlock != nil {
+		cr.onBlock(decompressedSize, ChecksumSize+len(cr.zdata))
+	}
+	zdata := cr.zdata[CompressHeaderSize:]
+
+	if cr.header[16] == ZSTD {
+		var data []byte
+		if data, err = zstdDecompress(cr.data[:0], zdata); err != nil {
 			return
 		}
-	} else {
-		return fmt.Errorf("Unknown compression method: 0x%02x ", cr.header[16])
+		if len(data) != decompressedSize {
+			return fmt.Errorf("Decompress size not match")
+		}
+		cr.data = data
+		return nil
+	}
+	_, err = lz4.Decode(cr.data, zdata)
+	if err != nil {
+		return
 	}
 
 	return nil
@@ -1,5 +1,11 @@
 package binary
 
+import "errors"
+
+// ErrChecksumMismatch is returned when the checksum of a compressed block doesn't match its data: the block was
+// corrupted on the way, it is not decompressed.
+var ErrChecksumMismatch = errors.New("clickhouse: the checksum of the compressed block doesn't match its data")
+
 type CompressionMethodByte byte
 
 const (
@@ -16,6 +22,26 @@ const (
 
 	// HeaderSize
 	HeaderSize = ChecksumSize + CompressHeaderSize
-	// BlockMaxSize 1MB
+	// BlockMaxSize 1MB, the default size of the blocks compressed
 	BlockMaxSize = 1 << 20
+	// BlockMinSize and BlockSizeLimit bound the size of the blocks compressed by a writer
+	BlockMinSize   = 4 << 10
+	BlockSizeLimit = 128 << 20
+	// CompressedSizeLimit bounds the compressed data of the blocks read, BlockSizeLimit bytes compressed by LZ4
+	// take at most its bound, and ZSTD less
+	CompressedSizeLimit = BlockSizeLimit + BlockSizeLimit/255 + 16
 )
+
+// clampBlockSize returns the size of the blocks compressed for the size given: BlockMaxSize if 0, else bounded
+// by BlockMinSize and BlockSizeLimit.
+func clampBlockSize(size int) int {
+	switch {
+	case size == 0:
+		return BlockMaxSize
+	case size < BlockMinSize:
+		return BlockMinSize
+	case size > BlockSizeLimit:
+		return BlockSizeLimit
+	}
+	return size
+}
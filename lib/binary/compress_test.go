@@ -1,6 +1,9 @@
 package binary
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"log"
 	"math/rand"
 	"testing"
@@ -30,6 +33,135 @@ func Test_CompressCorrect(t *testing.T) {
 	}
 }
 
+func Test_CompressMethodRoundtrip(t *testing.T) {
+	// the blocks are filled up to BlockMaxSize, the data spans several of them
+	data := make([]byte, 2*BlockMaxSize+BlockMaxSize/3)
+	for i := range data {
+		data[i] = byte(i % 251)
+		if i%7 == 0 {
+			data[i] = byte(rand.Int())
+		}
+	}
+	for _, method := range []CompressionMethodByte{LZ4, ZSTD} {
+		var (
+			buf    bytes.Buffer
+			writer, _ = NewCompressWriterMethod(&buf, method, 3, 0)
+		)
+		if _, err := writer.Write(data); !assert.NoError(t, err) {
+			return
+		}
+		if !assert.NoError(t, writer.Flush()) {
+			return
+		}
+		assert.Equal(t, byte(method), buf.Bytes()[16], "the method of the first block")
+		assert.True(t, buf.Len() < len(data), "compressed by 0x%02x", method)
+		decompressed := make([]byte, len(data))
+		if _, err := io.ReadFull(NewCompressReader(&buf), decompressed); assert.NoError(t, err) {
+			assert.Equal(t, data, decompressed, "method 0x%02x", method)
+		}
+	}
+}
+
+func Test_CompressZSTDLevels(t *testing.T) {
+	// the random data is incompressible, its frame is larger than the data
+	random := make([]byte, BlockMinSize)
+	rand.Read(random)
+	for _, data := range [][]byte{genBytes(100 << 10), random} {
+		for _, level := range []int{0, 1, 3, 9, 19, 22} {
+			var (
+				buf    bytes.Buffer
+				writer, _ = NewCompressWriterMethod(&buf, ZSTD, level, 0)
+			)
+			if _, err := writer.Write(data); !assert.NoError(t, err) || !assert.NoError(t, writer.Flush()) {
+				return
+			}
+			decompressed := make([]byte, len(data))
+			if _, err := io.ReadFull(NewCompressReader(&buf), decompressed); assert.NoError(t, err, "level %d", level) {
+				assert.Equal(t, data, decompressed, "level %d", level)
+			}
+		}
+	}
+	for _, level := range []int{-1, ZSTDMaxLevel + 1} {
+		if _, err := NewCompressWriterMethod(&bytes.Buffer{}, ZSTD, level, 0); assert.Error(t, err) {
+			assert.Equal(t, err, CheckZSTDLevel(level))
+		}
+	}
+	// the level of LZ4 is not used
+	_, err := NewCompressWriterMethod(&bytes.Buffer{}, LZ4, ZSTDMaxLevel+1, 0)
+	assert.NoError(t, err)
+}
+
+func Test_CompressBlockSize(t *testing.T) {
+	data := genBytes(100 << 10)
+	for blockSize, want := range map[int]int{0: BlockMaxSize, 10: BlockMinSize, 16 << 10: 16 << 10, 1 << 30: BlockSizeLimit} {
+		for _, method := range []CompressionMethodByte{LZ4, ZSTD} {
+			var (
+				buf    bytes.Buffer
+				writer, _ = NewCompressWriterMethod(&buf, method, 0, blockSize)
+			)
+			if _, err := writer.Write(data); !assert.NoError(t, err) || !assert.NoError(t, writer.Flush()) {
+				return
+			}
+			// the uncompressed sizes of the blocks on the wire, all of them but the last one filled up
+			var sizes []int
+			for stream := buf.Bytes(); len(stream) >= HeaderSize; {
+				sizes = append(sizes, int(binary.LittleEndian.Uint32(stream[21:])))
+				stream = stream[ChecksumSize+int(binary.LittleEndian.Uint32(stream[17:])):]
+			}
+			var expected []int
+			for left := len(data); left > 0; left -= want {
+				if left < want {
+					expected = append(expected, left)
+				} else {
+					expected = append(expected, want)
+				}
+			}
+			assert.Equal(t, expected, sizes, "block size %d, method 0x%02x", blockSize, method)
+			decompressed := make([]byte, len(data))
+			if _, err := io.ReadFull(NewCompressReader(&buf), decompressed); assert.NoError(t, err) {
+				assert.Equal(t, data, decompressed)
+			}
+		}
+	}
+}
+
+func Test_CompressChecksumMismatch(t *testing.T) {
+	for _, method := range []CompressionMethodByte{LZ4, ZSTD} {
+		var (
+			buf    bytes.Buffer
+			writer, _ = NewCompressWriterMethod(&buf, method, 0, 0)
+		)
+		if _, err := writer.Write(genBytes(4096)); !assert.NoError(t, err) || !assert.NoError(t, writer.Flush()) {
+			return
+		}
+		block := buf.Bytes()
+		// a byte of the compressed data, then a byte of the checksum
+		for _, idx := range []int{HeaderSize + 10, 3} {
+			corrupted := append([]byte(nil), block...)
+			corrupted[idx] ^= 0x10
+			_, err := io.ReadFull(NewCompressReader(bytes.NewReader(corrupted)), make([]byte, 4096))
+			assert.Equal(t, ErrChecksumMismatch, err, "method 0x%02x, byte %d", method, idx)
+		}
+	}
+}
+
+func Test_CompressCorruptedSize(t *testing.T) {
+	var (
+		buf       bytes.Buffer
+		writer, _ = NewCompressWriterMethod(&buf, LZ4, 0, 0)
+	)
+	if _, err := writer.Write(genBytes(4096)); !assert.NoError(t, err) || !assert.NoError(t, writer.Flush()) {
+		return
+	}
+	// the compressed size, then the decompressed size, of the header
+	for _, idx := range []int{17, 21} {
+		corrupted := append([]byte(nil), buf.Bytes()...)
+		corrupted[idx+3] = 0xff
+		_, err := io.ReadFull(NewCompressReader(bytes.NewReader(corrupted)), make([]byte, 4096))
+		assert.Equal(t, ErrChecksumMismatch, err, "byte %d", idx+3)
+	}
+}
+
 func Benchmark_CompressCf(b *testing.B) {
 	var c = genBytes(1 << 10)
 	for i := 0; i < b.N; i++ {
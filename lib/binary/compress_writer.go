@@ -8,26 +8,50 @@ import (
 
 	"github.com/c3mb0/clickhouse-go/lib/cityhash102"
 	"github.com/c3mb0/clickhouse-go/lib/lz4"
+	"github.com/klauspost/compress/zstd"
 )
 
 type compressWriter struct {
 	writer io.Writer
+	// the compression method of the blocks, and the encoder of ZSTD at its level
+	method CompressionMethodByte
+	zstd   *zstd.Encoder
 	// data uncompressed
 	data []byte
 	// data position
 	pos int
 	// data compressed
 	zdata []byte
+	// onBlock is called with the sizes of each block written, see Encoder.OnCompressedBlock
+	onBlock func(size, compressedSize int)
 }
 
-// NewCompressWriter wrap the io.Writer
+// NewCompressWriter wrap the io.Writer, the blocks are compressed by LZ4
 func NewCompressWriter(w io.Writer) *compressWriter {
-	p := &compressWriter{writer: w}
-	p.data = make([]byte, BlockMaxSize, BlockMaxSize)
+	cw, _ := NewCompressWriterMethod(w, LZ4, 0, 0)
+	return cw
+}
+
+// NewCompressWriterMethod wrap the io.Writer, the blocks are compressed by the method: LZ4, or ZSTD at the level.
+// The data is cut into blocks of blockSize bytes (BlockMaxSize if 0, bounded by BlockMinSize and BlockSizeLimit).
+// The level of ZSTD is checked as CheckZSTDLevel does.
+func NewCompressWriterMethod(w io.Writer, method CompressionMethodByte, level, blockSize int) (*compressWriter, error) {
+	p := &compressWriter{writer: w, method: method}
+	if method == ZSTD {
+		var err error
+		if p.zstd, err = zstdEncoder(level); err != nil {
+			return nil, err
+		}
+	}
+	blockSize = clampBlockSize(blockSize)
+	p.data = make([]byte, blockSize, blockSize)
 
-	zlen := lz4.CompressBound(BlockMaxSize) + HeaderSize
+	zlen := lz4.CompressBound(blockSize) + HeaderSize
+	if method == ZSTD {
+		zlen = zstdCompressBound(blockSize) + HeaderSize
+	}
 	p.zdata = make([]byte, zlen, zlen)
-	return p
+	return p, nil
 }
 
 func (cw *compressWriter) Write(buf []byte) (int, error) {
@@ -55,13 +79,17 @@ func (cw *compressWriter) Flush() (err error) {
 	}
 
 	// write the headers
-	compressedSize, err := lz4.Encode(cw.zdata[HeaderSize:], cw.data[:cw.pos])
-	if err != nil {
+	var compressedSize int
+	if cw.method == ZSTD {
+		// the frame is appended to the header, zdata grows if the frame exceeds the bound
+		cw.zdata = cw.zstd.EncodeAll(cw.data[:cw.pos], cw.zdata[:HeaderSize])
+		compressedSize = len(cw.zdata) - HeaderSize
+	} else if compressedSize, err = lz4.Encode(cw.zdata[HeaderSize:], cw.data[:cw.pos]); err != nil {
 		return err
 	}
 	compressedSize += CompressHeaderSize
 	// fill the header, compressed_size_32 + uncompressed_size_32
-	cw.zdata[16] = LZ4
+	cw.zdata[16] = byte(cw.method)
 	binary.LittleEndian.PutUint32(cw.zdata[17:], uint32(compressedSize))
 	binary.LittleEndian.PutUint32(cw.zdata[21:], uint32(cw.pos))
 
@@ -71,6 +99,9 @@ func (cw *compressWriter) Flush() (err error) {
 	binary.LittleEndian.PutUint64(cw.zdata[8:], checkSum.Higher64())
 
 	cw.writer.Write(cw.zdata[:compressedSize+ChecksumSize])
+	if cw.onBlock != nil {
+		cw.onBlock(cw.pos, compressedSize+ChecksumSize)
+	}
 	if w, ok := cw.writer.(WriteFlusher); ok {
 		err = w.Flush()
 	}
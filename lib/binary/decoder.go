@@ -30,6 +30,14 @@ func (decoder *Decoder) SelectCompress(compress bool) {
 	decoder.compress = compress
 }
 
+// OnCompressedBlock sets the function called with the uncompressed size and the compressed size on the wire
+// of each compressed block read, the decoder has to be created by NewDecoderWithCompress.
+func (decoder *Decoder) OnCompressedBlock(fn func(size, compressedSize int)) {
+	if reader, ok := decoder.compressInput.(*compressReader); ok {
+		reader.onBlock = fn
+	}
+}
+
 func (decoder *Decoder) Get() io.Reader {
 	if decoder.compress && decoder.compressInput != nil {
 		return decoder.compressInput
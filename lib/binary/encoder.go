@@ -21,6 +21,18 @@ func NewEncoderWithCompress(w io.Writer) *Encoder {
 	}
 }
 
+// NewEncoderWithCompressMethod returns the encoder compressing the blocks by the method, see NewCompressWriterMethod.
+func NewEncoderWithCompressMethod(w io.Writer, method CompressionMethodByte, level, blockSize int) (*Encoder, error) {
+	compressOutput, err := NewCompressWriterMethod(w, method, level, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder{
+		output:         w,
+		compressOutput: compressOutput,
+	}, nil
+}
+
 type Encoder struct {
 	compress       bool
 	output         io.Writer
@@ -38,6 +50,14 @@ func (enc *Encoder) SelectCompress(compress bool) {
 	enc.compress = compress
 }
 
+// OnCompressedBlock sets the function called with the uncompressed size and the compressed size on the wire
+// of each compressed block written, the encoder has to be created by NewEncoderWithCompress(Method).
+func (enc *Encoder) OnCompressedBlock(fn func(size, compressedSize int)) {
+	if writer, ok := enc.compressOutput.(*compressWriter); ok {
+		writer.onBlock = fn
+	}
+}
+
 func (enc *Encoder) Get() io.Writer {
 	if enc.compress && enc.compressOutput != nil {
 		return enc.compressOutput
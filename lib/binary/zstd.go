@@ -0,0 +1,61 @@
+package binary
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// zstdDecoder decompresses the ZSTD blocks of all the readers, DecodeAll is safe for concurrent use
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(BlockSizeLimit))
+	// zstdEncoders are the encoders of the levels, shared by the writers as EncodeAll is safe for concurrent use
+	zstdEncoders sync.Map
+)
+
+// zstdCompressBound returns the maximum size of the frame of n bytes compressed by ZSTD.
+func zstdCompressBound(n int) int {
+	bound := n + n>>8
+	if n < 128<<10 {
+		bound += (128<<10 - n) >> 11
+	}
+	return bound
+}
+
+// ZSTDMaxLevel is the highest level of the ZSTD compression.
+const ZSTDMaxLevel = 22
+
+// zstdEncoder returns the encoder of the level shared by the writers, the level 0 is the default level 3.
+func zstdEncoder(level int) (*zstd.Encoder, error) {
+	if level == 0 {
+		level = 3
+	}
+	if level < 0 || level > ZSTDMaxLevel {
+		return nil, fmt.Errorf("must be between 0 and %d, got %d", ZSTDMaxLevel, level)
+	}
+	if encoder, ok := zstdEncoders.Load(level); ok {
+		return encoder.(*zstd.Encoder), nil
+	}
+	created, err := zstd.NewWriter(nil,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithZeroFrames(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	encoder, _ := zstdEncoders.LoadOrStore(level, created)
+	return encoder.(*zstd.Encoder), nil
+}
+
+// CheckZSTDLevel returns the error of the ZSTD compression at the level, if any, see NewCompressWriterMethod.
+func CheckZSTDLevel(level int) error {
+	_, err := zstdEncoder(level)
+	return err
+}
+
+// zstdDecompress appends the data of the ZSTD frame src to dst.
+func zstdDecompress(dst, src []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(src, dst)
+}
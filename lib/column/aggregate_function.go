@@ -0,0 +1,122 @@
+package column
+
+import (
+	gobinary "encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// AggregateFunction represents AggregateFunction(f, T) ClickHouse, the intermediate state of the aggregate function.
+// The states are not deserialized, they are scanned as the raw state bytes and written back as is.
+//
+// The states are transferred without their sizes, so a state can only be read if the layout of the state of the function
+// is known: count(), sum(T), min(T), max(T), any(T) and anyLast(T) of the numeric and date types T are supported,
+// the other functions (uniq, quantiles, groupArray, etc.) return an error for the column.
+// Use finalizeAggregation(column) or the -Merge combinator to read their values instead.
+type AggregateFunction struct {
+	base
+	function  string
+	readState func(*binary.Decoder) ([]byte, error)
+}
+
+func (af *AggregateFunction) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return af.readState(decoder)
+}
+
+func (af *AggregateFunction) Write(encoder *binary.Encoder, v interface{}) error {
+	switch v := v.(type) {
+	case []byte:
+		_, err := encoder.Write(v)
+		return err
+	case *[]byte:
+		_, err := encoder.Write(*v)
+		return err
+	}
+	return &ErrUnexpectedType{
+		T:      v,
+		Column: af,
+	}
+}
+
+// Function returns the name of the aggregate function.
+func (af *AggregateFunction) Function() string {
+	return af.function
+}
+
+// aggregateFunctionSizes are the sizes of the numeric and date types supported as the arguments.
+var aggregateFunctionSizes = map[string]int{
+	"Int8": 1, "Int16": 2, "Int32": 4, "Int64": 8,
+	"UInt8": 1, "UInt16": 2, "UInt32": 4, "UInt64": 8,
+	"Float32": 4, "Float64": 8,
+	"Date": 2, "Date32": 4, "DateTime": 4,
+}
+
+func parseAggregateFunction(name, chType string) (*AggregateFunction, error) {
+	if !strings.HasPrefix(chType, "AggregateFunction(") || !strings.HasSuffix(chType, ")") {
+		return nil, fmt.Errorf("invalid AggregateFunction column type: %s", chType)
+	}
+	var (
+		params = SplitTypes(chType[18 : len(chType)-1])
+		af     = &AggregateFunction{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: reflect.ValueOf([]byte{}),
+			},
+			function: params[0],
+		}
+		size, fixed = 0, false
+	)
+	if len(params) == 2 {
+		size, fixed = aggregateFunctionSizes[params[1]]
+	}
+	switch {
+	case af.function == "count" && len(params) <= 2:
+		// the number of the rows as VarUInt
+		af.readState = readUvarintState
+	case af.function == "sum" && fixed:
+		// the sum is Int64, UInt64 or Float64 whatever the size of the argument is
+		af.readState = fixedState(8)
+	case (af.function == "min" || af.function == "max" || af.function == "any" || af.function == "anyLast") && fixed:
+		// whether a value is set, followed by the value if so
+		af.readState = optionalState(size)
+	default:
+		return nil, fmt.Errorf("%s: the state of the function is not supported, use finalizeAggregation to read its value", chType)
+	}
+	return af, nil
+}
+
+func readUvarintState(decoder *binary.Decoder) ([]byte, error) {
+	v, err := decoder.Uvarint()
+	if err != nil {
+		return nil, err
+	}
+	state := make([]byte, gobinary.MaxVarintLen64)
+	return state[:gobinary.PutUvarint(state, v)], nil
+}
+
+func fixedState(size int) func(*binary.Decoder) ([]byte, error) {
+	return func(decoder *binary.Decoder) ([]byte, error) {
+		return decoder.Fixed(size)
+	}
+}
+
+func optionalState(size int) func(*binary.Decoder) ([]byte, error) {
+	return func(decoder *binary.Decoder) ([]byte, error) {
+		set, err := decoder.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if set == 0 {
+			return []byte{0}, nil
+		}
+		value, err := decoder.Fixed(size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{set}, value...), nil
+	}
+}
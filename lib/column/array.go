@@ -2,6 +2,7 @@ package column
 
 import (
 	"fmt"
+	"math/big"
 	"net"
 	"reflect"
 	"strings"
@@ -47,15 +48,30 @@ func (array *Array) ReadArray(decoder *binary.Decoder, rows int) (_ []interface{
 	}
 
 	// Read values
+	readValue := func() (interface{}, error) {
+		return array.column.Read(decoder, false)
+	}
+	if NeedsColumnarWrite(array) {
+		// the maps, the points and the NULL maps are laid out column by column
+		maps, err := readColumn(array.column, decoder, int(lastOffset))
+		if err != nil {
+			return nil, err
+		}
+		readValue = func() (interface{}, error) {
+			value := maps[0]
+			maps = maps[1:]
+			return value, nil
+		}
+	}
 	for i := 0; i < rows; i++ {
-		if values[i], err = array.read(decoder, offsets, uint64(i), 0); err != nil {
+		if values[i], err = array.read(readValue, offsets, uint64(i), 0); err != nil {
 			return nil, err
 		}
 	}
 	return values, nil
 }
 
-func (array *Array) read(decoder *binary.Decoder, offsets [][]uint64, index uint64, level int) (interface{}, error) {
+func (array *Array) read(readValue func() (interface{}, error), offsets [][]uint64, index uint64, level int) (interface{}, error) {
 	end := offsets[level][index]
 	start := uint64(0)
 	if index > 0 {
@@ -63,26 +79,50 @@ func (array *Array) read(decoder *binary.Decoder, offsets [][]uint64, index uint
 	}
 
 	slice := reflect.MakeSlice(array.arrayType(level), 0, int(end-start))
+	elementType := slice.Type().Elem()
 	for i := start; i < end; i++ {
 		var (
 			value interface{}
 			err   error
 		)
 		if level == array.depth-1 {
-			value, err = array.column.Read(decoder, false)
+			value, err = readValue()
 		} else {
-			value, err = array.read(decoder, offsets, i, level+1)
+			value, err = array.read(readValue, offsets, i, level+1)
 		}
 		if err != nil {
 			return nil, err
 		}
-		slice = reflect.Append(slice, reflect.ValueOf(value))
+		switch {
+		case value == nil:
+			slice = reflect.Append(slice, reflect.Zero(elementType))
+		case elementType.Kind() == reflect.Ptr && reflect.TypeOf(value) == elementType.Elem():
+			element := reflect.New(elementType.Elem())
+			element.Elem().Set(reflect.ValueOf(value))
+			slice = reflect.Append(slice, element)
+		default:
+			slice = reflect.Append(slice, reflect.ValueOf(value))
+		}
 	}
 	return slice.Interface(), nil
 }
 
-func (array *Array) arrayType(level int) reflect.Type {
+// elementType returns the type of the elements of the innermost slices:
+// T, or *T for Array(Nullable(T)) unless nil is a value of T, e.g. *big.Int and net.IP.
+func (array *Array) elementType() reflect.Type {
 	t := array.column.ScanType()
+	if _, ok := array.column.(*Nullable); ok {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		default:
+			t = reflect.PtrTo(t)
+		}
+	}
+	return t
+}
+
+func (array *Array) arrayType(level int) reflect.Type {
+	t := array.elementType()
 	for i := 0; i < array.depth-level; i++ {
 		t = reflect.SliceOf(t)
 	}
@@ -102,20 +142,17 @@ func parseArray(name, chType string, timezone *time.Location) (*Array, error) {
 		columnType = chType
 	)
 
-loop:
-	for _, str := range strings.Split(chType, "Array(") {
-		switch {
-		case len(str) == 0:
-			depth++
-		default:
-			chType = str[:len(str)-depth]
-			break loop
-		}
+	for strings.HasPrefix(chType, "Array(") && strings.HasSuffix(chType, ")") {
+		chType = chType[6 : len(chType)-1]
+		depth++
 	}
 	column, err := Factory(name, chType, timezone)
 	if err != nil {
 		return nil, fmt.Errorf("Array(T): %v", err)
 	}
+	if _, ok := column.(*LowCardinality); ok {
+		return nil, fmt.Errorf("Array(T): LowCardinality(T) is only supported at the top level")
+	}
 
 	var scanType interface{}
 	switch t := column.ScanType(); t {
@@ -141,14 +178,23 @@ loop:
 		scanType = []float64{}
 	case arrayBaseTypes[string("")]:
 		scanType = []string{}
+	case arrayBaseTypes[false]:
+		scanType = []bool{}
 	case arrayBaseTypes[time.Time{}]:
 		scanType = []time.Time{}
 	case arrayBaseTypes[IPv4{}], arrayBaseTypes[IPv6{}]:
 		scanType = []net.IP{}
+	case arrayBaseTypes[(*big.Int)(nil)]:
+		scanType = []*big.Int{}
 	default:
-		return nil, fmt.Errorf("unsupported Array type '%s'", column.ScanType().Name())
+		switch column.(type) {
+		case *Map, *Point, *Tuple:
+			scanType = reflect.MakeSlice(reflect.SliceOf(t), 0, 0).Interface()
+		default:
+			return nil, fmt.Errorf("unsupported Array type '%s'", column.ScanType().Name())
+		}
 	}
-	return &Array{
+	array := &Array{
 		base: base{
 			name:    name,
 			chType:  columnType,
@@ -156,5 +202,14 @@ loop:
 		},
 		depth:  depth,
 		column: column,
-	}, nil
+	}
+	if _, ok := column.(*Nullable); ok {
+		// the NULL elements are scanned as nil, e.g. []*int32 for Array(Nullable(Int32))
+		array.valueOf = reflect.MakeSlice(reflect.SliceOf(array.elementType()), 0, 0)
+	}
+	// the values of Array(Array(T)) and deeper are scanned as the nested slices, e.g. [][]float64 for Array(Array(Float64))
+	for i := 1; i < depth; i++ {
+		array.valueOf = reflect.MakeSlice(reflect.SliceOf(array.valueOf.Type()), 0, 0)
+	}
+	return array, nil
 }
@@ -0,0 +1,97 @@
+package column
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// BigInt represents Int128, UInt128, Int256 and UInt256 ClickHouse, the little-endian two's complement integers
+// of 16 and 32 bytes. They are scanned as *big.Int.
+type BigInt struct {
+	base
+	size   int // the size in bytes
+	signed bool
+}
+
+func (i *BigInt) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return readBigInt(decoder, i.size, i.signed)
+}
+
+func (i *BigInt) Write(encoder *binary.Encoder, v interface{}) error {
+	var value *big.Int
+	switch v := v.(type) {
+	case *big.Int:
+		value = v
+		if v == nil { // the default value written for NULL
+			value = new(big.Int)
+		}
+	case big.Int:
+		value = &v
+	default:
+		// this relies on Nullable never sending nil values through
+		switch v := reflect.Indirect(reflect.ValueOf(v)); v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value = big.NewInt(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = new(big.Int).SetUint64(v.Uint())
+		}
+	}
+	if value == nil {
+		return &ErrUnexpectedType{
+			T:      v,
+			Column: i,
+		}
+	}
+	min, max := bigIntRange(i.size, i.signed)
+	if value.Cmp(min) < 0 || value.Cmp(max) > 0 {
+		return fmt.Errorf("%s: value %s overflows %s, the range is [%s, %s]", i.name, value, i.chType, min, max)
+	}
+	return writeBigInt(encoder, value, i.size)
+}
+
+// bigIntRange returns the minimum and the maximum values of the integers of the size in bytes.
+func bigIntRange(size int, signed bool) (min, max *big.Int) {
+	bits := uint(8 * size)
+	if !signed {
+		return new(big.Int), new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits), big.NewInt(1))
+	}
+	max = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bits-1), big.NewInt(1))
+	return new(big.Int).Neg(new(big.Int).Add(max, big.NewInt(1))), max
+}
+
+// readBigInt reads the little-endian two's complement integer of the size in bytes.
+func readBigInt(decoder *binary.Decoder, size int, signed bool) (*big.Int, error) {
+	b, err := decoder.Fixed(size)
+	if err != nil {
+		return nil, err
+	}
+	be := make([]byte, size)
+	for i := range b {
+		be[size-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if signed && be[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*size)))
+	}
+	return v, nil
+}
+
+// writeBigInt writes the integer as the little-endian two's complement integer of the size in bytes,
+// the value must fit in the size.
+func writeBigInt(encoder *binary.Encoder, v *big.Int, size int) error {
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), uint(8*size)))
+	}
+	var (
+		be = v.Bytes()
+		b  = make([]byte, size)
+	)
+	for i := range be {
+		b[i] = be[len(be)-1-i]
+	}
+	_, err := encoder.Write(b)
+	return err
+}
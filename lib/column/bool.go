@@ -0,0 +1,40 @@
+package column
+
+import (
+	"reflect"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Bool represents Bool ClickHouse, stored as UInt8: any non-zero value is read as true, true is written as 1.
+type Bool struct{ base }
+
+func (Bool) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	v, err := decoder.UInt8()
+	if err != nil {
+		return false, err
+	}
+	return v != 0, nil
+}
+
+func (b *Bool) Write(encoder *binary.Encoder, v interface{}) error {
+	switch v := v.(type) {
+	case bool:
+		return encoder.Bool(v)
+
+	// this relies on Nullable never sending nil values through
+	case *bool:
+		return encoder.Bool(*v)
+	}
+	// the integers are normalized to 0 and 1, database/sql passes bool as uint8
+	switch value := reflect.Indirect(reflect.ValueOf(v)); value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encoder.Bool(value.Int() != 0)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return encoder.Bool(value.Uint() != 0)
+	}
+	return &ErrUnexpectedType{
+		T:      v,
+		Column: b,
+	}
+}
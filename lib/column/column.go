@@ -2,6 +2,7 @@ package column
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 	"time"
@@ -85,6 +86,34 @@ func Factory(name, chType string, timezone *time.Location) (Column, error) {
 				valueOf: columnBaseTypes[uint64(0)],
 			},
 		}, nil
+	case "Int128", "UInt128":
+		return &BigInt{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: columnBaseTypes[(*big.Int)(nil)],
+			},
+			size:   16,
+			signed: chType == "Int128",
+		}, nil
+	case "Int256", "UInt256":
+		return &BigInt{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: columnBaseTypes[(*big.Int)(nil)],
+			},
+			size:   32,
+			signed: chType == "Int256",
+		}, nil
+	case "Bool":
+		return &Bool{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: columnBaseTypes[false],
+			},
+		}, nil
 	case "Float32":
 		return &Float32{
 			base: base{
@@ -117,6 +146,14 @@ func Factory(name, chType string, timezone *time.Location) (Column, error) {
 				valueOf: columnBaseTypes[string("")],
 			},
 		}, nil
+	case "Date32":
+		return &Date32{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: columnBaseTypes[time.Time{}],
+			},
+		}, nil
 	case "Date":
 		_, offset := time.Unix(0, 0).In(timezone).Zone()
 		return &Date{
@@ -144,6 +181,10 @@ func Factory(name, chType string, timezone *time.Location) (Column, error) {
 				valueOf: columnBaseTypes[IPv6{}],
 			},
 		}, nil
+	case "Point", "Ring", "Polygon", "MultiPolygon":
+		return parseGeo(name, chType, timezone)
+	case "JSON", "Object('json')":
+		return parseJSON(name, chType, timezone)
 	}
 	switch {
 	case strings.HasPrefix(chType, "DateTime") && !strings.HasPrefix(chType, "DateTime64"):
@@ -156,24 +197,27 @@ func Factory(name, chType string, timezone *time.Location) (Column, error) {
 			Timezone: timezone,
 		}, nil
 	case strings.HasPrefix(chType, "DateTime64"):
-		return &DateTime64{
-			base: base{
-				name:    name,
-				chType:  chType,
-				valueOf: columnBaseTypes[time.Time{}],
-			},
-			Timezone: timezone,
-		}, nil
+		return parseDateTime64(name, chType, timezone)
 	case strings.HasPrefix(chType, "Array"):
 		return parseArray(name, chType, timezone)
 	case strings.HasPrefix(chType, "Nullable"):
 		return parseNullable(name, chType, timezone)
+	case strings.HasPrefix(chType, "LowCardinality"):
+		return parseLowCardinality(name, chType, timezone)
+	case strings.HasPrefix(chType, "Nested"):
+		return parseNested(name, chType, timezone)
+	case strings.HasPrefix(chType, "Tuple"):
+		return parseTuple(name, chType, timezone)
+	case strings.HasPrefix(chType, "Map"):
+		return parseMap(name, chType, timezone)
 	case strings.HasPrefix(chType, "FixedString"):
 		return parseFixedString(name, chType)
 	case strings.HasPrefix(chType, "Enum8"), strings.HasPrefix(chType, "Enum16"):
 		return parseEnum(name, chType)
 	case strings.HasPrefix(chType, "Decimal"):
 		return parseDecimal(name, chType)
+	case strings.HasPrefix(chType, "AggregateFunction"):
+		return parseAggregateFunction(name, chType)
 	case strings.HasPrefix(chType, "SimpleAggregateFunction"):
 		if nestedType, err := getNestedType(chType, "SimpleAggregateFunction"); err != nil {
 			return nil, err
@@ -188,10 +232,10 @@ func getNestedType(chType string, wrapType string) (string, error) {
 	prefixLen := len(wrapType) + 1
 	suffixLen := 1
 
-	if len(chType) > prefixLen+suffixLen {
-		nested := strings.Split(chType[prefixLen:len(chType)-suffixLen], ",")
+	if len(chType) > prefixLen+suffixLen && strings.HasSuffix(chType, ")") {
+		nested := SplitTypes(chType[prefixLen : len(chType)-suffixLen])
 		if len(nested) == 2 {
-			return strings.TrimSpace(nested[1]), nil
+			return nested[1], nil
 		}
 	}
 	return "", fmt.Errorf("column: invalid %s type (%s)", wrapType, chType)
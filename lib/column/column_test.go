@@ -2,9 +2,12 @@ package column_test
 
 import (
 	"bytes"
+	gobinary "encoding/binary"
 	"fmt"
+	"math/big"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -329,6 +332,35 @@ func Test_Column_FixedString(t *testing.T) {
 	}
 }
 
+func Test_Column_FixedStringLength(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "FixedString(6)", time.Local); assert.NoError(t, err) {
+		if assert.Equal(t, 6, column.(*columns.FixedString).Len()) {
+			// "héllo" is 6 bytes long, the "é" is 2 bytes
+			for _, v := range []interface{}{"héllo", []byte("hé"), "日本"} {
+				if err := column.Write(encoder, v); !assert.NoError(t, err) {
+					return
+				}
+			}
+			for _, expected := range []string{"héllo", "hé\x00\x00\x00", "日本"} {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, expected, v)
+				}
+			}
+		}
+		for _, v := range []interface{}{"héllo!", "日本語", []byte("1234567")} {
+			if err := column.Write(encoder, v); assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "FixedString(6): too large value")
+			}
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+}
+
 func Test_Column_Enum8(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -436,6 +468,70 @@ func Test_Column_Date(t *testing.T) {
 	}
 }
 
+func Test_Column_Date32(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "Date32", time.Local); assert.NoError(t, err) {
+		for _, date := range []time.Time{
+			time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(1969, 7, 20, 0, 0, 0, 0, time.UTC),
+			time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2299, 12, 31, 0, 0, 0, 0, time.UTC),
+		} {
+			// time.Time type, the date in the location of the value
+			if err := column.Write(encoder, time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 0, 0, time.FixedZone("UTC-10", -10*3600))); assert.NoError(t, err) {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, date, v)
+				}
+			}
+
+			// string type
+			if err := column.Write(encoder, date.Format("2006-01-02")); assert.NoError(t, err) {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, date, v)
+				}
+			}
+		}
+
+		// int32 type, the days since 1970-01-01
+		for days, date := range map[int32]time.Time{
+			-25567: time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+			-165:   time.Date(1969, 7, 20, 0, 0, 0, 0, time.UTC),
+			120529: time.Date(2299, 12, 31, 0, 0, 0, 0, time.UTC),
+		} {
+			if err := column.Write(encoder, days); assert.NoError(t, err) {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, date, v)
+				}
+			}
+		}
+
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "Date32", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf(time.Time{}).Kind(), column.ScanType().Kind())
+		}
+		for _, v := range []interface{}{
+			time.Date(1899, 12, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC),
+			"1899-12-31",
+			int32(120530),
+		} {
+			if err := column.Write(encoder, v); assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "column_name: value")
+				assert.Contains(t, err.Error(), "overflows Date32, the range is [1900-01-01, 2299-12-31]")
+			}
+		}
+		assert.Equal(t, 0, buf.Len())
+		if err := column.Write(encoder, int8(0)); assert.Error(t, err) {
+			if e, ok := err.(*columns.ErrUnexpectedType); assert.True(t, ok) {
+				assert.Equal(t, int8(0), e.T)
+			}
+		}
+	}
+}
+
 func Test_Column_DateTime(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -510,6 +606,96 @@ func Test_Column_DateTime64(t *testing.T) {
 	}
 }
 
+func Test_Column_DateTime64Precision(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, tc := range []struct {
+		chType    string
+		precision int
+		location  *time.Location
+		value     time.Time
+		ticks     int64
+		expected  time.Time
+	}{
+		{
+			chType:    "DateTime64(9)",
+			precision: 9,
+			location:  time.UTC,
+			value:     time.Unix(1600000000, 123456789),
+			ticks:     1600000000123456789,
+			expected:  time.Unix(1600000000, 123456789),
+		},
+		{
+			chType:    "DateTime64(3, 'Europe/Berlin')",
+			precision: 3,
+			location:  berlin,
+			value:     time.Unix(1600000000, 123456789),
+			ticks:     1600000000123,
+			expected:  time.Unix(1600000000, 123000000),
+		},
+		{
+			chType:    "DateTime64(3)",
+			precision: 3,
+			location:  time.UTC,
+			value:     time.Unix(-1, 500000000),
+			ticks:     -500,
+			expected:  time.Unix(-1, 500000000),
+		},
+		{
+			chType:    "DateTime64(0)",
+			precision: 0,
+			location:  time.UTC,
+			value:     time.Unix(-86400, 999999999),
+			ticks:     -86400,
+			expected:  time.Unix(-86400, 0),
+		},
+		{
+			chType:    "DateTime64(9, 'Europe/Berlin')",
+			precision: 9,
+			location:  berlin,
+			value:     time.Unix(-1, 1),
+			ticks:     -999999999,
+			expected:  time.Unix(-1, 1),
+		},
+	} {
+		if column, err := columns.Factory("column_name", tc.chType, time.UTC); assert.NoError(t, err, tc.chType) {
+			dt, ok := column.(*columns.DateTime64)
+			if assert.True(t, ok) && assert.Equal(t, tc.precision, dt.GetPrecision(), tc.chType) {
+				assert.Equal(t, tc.location, dt.Timezone, tc.chType)
+			}
+			assert.Equal(t, tc.chType, column.CHType())
+			if err := column.Write(encoder, tc.value); assert.NoError(t, err, tc.chType) {
+				assert.Equal(t, tc.ticks, int64(gobinary.LittleEndian.Uint64(buf.Bytes())), tc.chType)
+				if v, err := column.Read(decoder, false); assert.NoError(t, err, tc.chType) {
+					if value, ok := v.(time.Time); assert.True(t, ok) {
+						assert.True(t, tc.expected.Equal(value), tc.chType)
+						assert.Equal(t, tc.location, value.Location(), tc.chType)
+					}
+				}
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "DateTime64(3, 'Europe/Berlin')", time.UTC); assert.NoError(t, err) {
+		// the strings are parsed in the timezone of the column
+		if err := column.Write(encoder, "2020-09-13 14:26:40.123456"); assert.NoError(t, err) {
+			if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+				assert.Equal(t, time.Date(2020, 9, 13, 14, 26, 40, 123000000, berlin), v)
+			}
+		}
+	}
+	for _, chType := range []string{"DateTime64(10)", "DateTime64(x)", "DateTime64(3, 'Unknown/Timezone')", "DateTime64(3"} {
+		_, err := columns.Factory("column_name", chType, time.UTC)
+		assert.Error(t, err, chType)
+	}
+}
+
 func Test_Column_DateTimeWithTZ(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -586,7 +772,7 @@ func Test_Column_IP(t *testing.T) {
 		} {
 			if err := column.Write(encoder, ip); assert.NoError(t, err) {
 				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
-					assert.Equal(t, net.ParseIP(ip), v)
+					assert.Equal(t, net.ParseIP(ip).To4(), v)
 				}
 			}
 		}
@@ -632,6 +818,8 @@ func Test_Column_SimpleAggregateFunc(t *testing.T) {
 		"SimpleAggregateFunction(anyLast, UInt8)":          "UInt8",
 		"SimpleAggregateFunction(anyLast, Nullable(IPv4))": "Nullable(IPv4)",
 		"SimpleAggregateFunction(max, Nullable(DateTime))": "Nullable(DateTime)",
+		"SimpleAggregateFunction(sum, Decimal(18, 2))":     "Decimal(18, 2)",
+		"SimpleAggregateFunction(max, Map(String, UInt8))": "Map(String, UInt8)",
 	}
 
 	for key, val := range data {
@@ -642,6 +830,55 @@ func Test_Column_SimpleAggregateFunc(t *testing.T) {
 	}
 }
 
+func Test_Column_AggregateFunction(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	for chType, states := range map[string][][]byte{
+		"AggregateFunction(count)":             {{0}, {0xac, 0x02}},
+		"AggregateFunction(count, UInt8)":      {{1}},
+		"AggregateFunction(sum, UInt8)":        {{1, 2, 3, 4, 5, 6, 7, 8}},
+		"AggregateFunction(sum, Float32)":      {{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}},
+		"AggregateFunction(max, UInt32)":       {{0}, {1, 1, 0, 0, 0}},
+		"AggregateFunction(anyLast, DateTime)": {{1, 0xff, 0xff, 0xff, 0x7f}, {0}},
+	} {
+		if column, err := columns.Factory("column_name", chType, time.Local); assert.NoError(t, err, chType) {
+			for _, state := range states {
+				if err := column.Write(encoder, state); assert.NoError(t, err, chType) {
+					if v, err := column.Read(decoder, false); assert.NoError(t, err, chType) {
+						assert.Equal(t, state, v, chType)
+					}
+				}
+				assert.Equal(t, 0, buf.Len(), chType)
+			}
+			if assert.Equal(t, chType, column.CHType()) {
+				assert.Equal(t, reflect.TypeOf([]byte{}), column.ScanType())
+			}
+			if err := column.Write(encoder, "state"); assert.Error(t, err) {
+				if e, ok := err.(*columns.ErrUnexpectedType); assert.True(t, ok) {
+					assert.Equal(t, "state", e.T)
+				}
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "AggregateFunction(sum, UInt64)", time.Local); assert.NoError(t, err) {
+		assert.Equal(t, "sum", column.(*columns.AggregateFunction).Function())
+	}
+	for _, chType := range []string{
+		"AggregateFunction(uniq, String)",
+		"AggregateFunction(quantiles(0.5, 0.9), UInt64)",
+		"AggregateFunction(sum, Decimal(18, 2))",
+		"AggregateFunction(max, String)",
+		"AggregateFunction(sum)",
+	} {
+		if _, err := columns.Factory("column_name", chType, time.Local); assert.Error(t, err, chType) {
+			assert.Contains(t, err.Error(), "is not supported", chType)
+		}
+	}
+}
+
 func Test_Column_Decimal64(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -667,6 +904,140 @@ func Test_Column_Decimal64(t *testing.T) {
 	}
 }
 
+func Test_Column_NullableBaseTypes(t *testing.T) {
+	var (
+		date     = time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		datetime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		uuid     = "00000000-0000-0000-0000-000000000001"
+	)
+	for _, test := range []struct {
+		chType   string
+		value    interface{}
+		expected interface{}
+	}{
+		{"Int8", int8(-8), int8(-8)},
+		{"Int16", int16(-16), int16(-16)},
+		{"Int32", int32(-32), int32(-32)},
+		{"Int64", int64(-64), int64(-64)},
+		{"UInt8", uint8(8), uint8(8)},
+		{"UInt16", uint16(16), uint16(16)},
+		{"UInt32", uint32(32), uint32(32)},
+		{"UInt64", uint64(64), uint64(64)},
+		{"Float32", float32(1.5), float32(1.5)},
+		{"Float64", 2.5, 2.5},
+		{"Int128", big.NewInt(-128), big.NewInt(-128)},
+		{"UInt128", big.NewInt(128), big.NewInt(128)},
+		{"Int256", big.NewInt(-256), big.NewInt(-256)},
+		{"UInt256", big.NewInt(256), big.NewInt(256)},
+		{"Decimal(9, 2)", int32(150), int32(150)},
+		{"Decimal(18, 2)", int64(150), int64(150)},
+		{"Decimal(76, 2)", big.NewInt(150), big.NewInt(150)},
+		{"Bool", true, true},
+		{"String", "a", "a"},
+		{"FixedString(2)", "ab", "ab"},
+		{"Date", date, date},
+		{"DateTime", datetime, datetime},
+		{"DateTime64(3)", datetime, datetime},
+		{"Enum8('a' = 1)", "a", "a"},
+		{"UUID", uuid, uuid},
+		{"IPv4", "1.2.3.4", net.IPv4(1, 2, 3, 4).To4()},
+		{"IPv6", "::1", net.ParseIP("::1")},
+	} {
+		column, err := columns.Factory("column_name", "Nullable("+test.chType+")", time.UTC)
+		if !assert.NoError(t, err, test.chType) {
+			continue
+		}
+		nullable := column.(*columns.Nullable)
+		// a value, NULL, a pointer to a value, a nil pointer
+		var (
+			ptr  = reflect.New(reflect.TypeOf(test.value))
+			rows = []interface{}{test.value, nil, ptr.Interface(), reflect.Zero(ptr.Type()).Interface(), test.value}
+		)
+		ptr.Elem().Set(reflect.ValueOf(test.value))
+		var (
+			buf, values   bytes.Buffer
+			nulls         = binary.NewEncoder(&buf)
+			valuesEncoder = binary.NewEncoder(&values)
+		)
+		for _, v := range rows {
+			if err := nullable.WriteNull(nulls, valuesEncoder, v); !assert.NoError(t, err, test.chType) {
+				return
+			}
+		}
+		// the NULL map precedes the values
+		buf.Write(values.Bytes())
+		expected := []interface{}{test.expected, nil, test.expected, nil, test.expected}
+		encoded := append([]byte(nil), buf.Bytes()...)
+		if v, err := nullable.ReadNull(binary.NewDecoder(&buf), len(rows)); assert.NoError(t, err, test.chType) {
+			assert.Equal(t, expected, v, test.chType)
+		}
+		// the columnar writes of Array(Nullable(T)) encode the same
+		buf.Reset()
+		if err := columns.WriteColumn(nullable, nulls, rows); assert.NoError(t, err, test.chType) {
+			assert.Equal(t, encoded, buf.Bytes(), test.chType)
+		}
+	}
+}
+
+func Test_Column_ArrayNullable(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+		one     = int32(1)
+	)
+	if column, err := columns.Factory("column_name", "Array(Nullable(Int32))", time.Local); assert.NoError(t, err) {
+		assert.True(t, columns.NeedsColumnarWrite(column))
+		assert.Equal(t, reflect.TypeOf([]*int32{}), column.ScanType())
+		rows := []interface{}{[]interface{}{int32(1), nil}, []*int32{}, []*int32{nil, &one}}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			// the offsets, the NULL map and the values
+			var (
+				expected        bytes.Buffer
+				expectedEncoder = binary.NewEncoder(&expected)
+			)
+			for _, v := range []uint64{2, 2, 4} {
+				expectedEncoder.UInt64(v)
+			}
+			expectedEncoder.Write([]byte{0, 1, 1, 0})
+			for _, v := range []int32{1, 0, 0, 1} {
+				expectedEncoder.Int32(v)
+			}
+			assert.Equal(t, expected.Bytes(), buf.Bytes())
+			if v, err := column.(*columns.Array).ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{[]*int32{&one, nil}, []*int32{}, []*int32{nil, &one}}, v)
+			}
+		}
+	}
+	for chType, scanType := range map[string]reflect.Type{
+		"Array(Array(Nullable(String)))": reflect.TypeOf([][]*string{}),
+		"Array(Nullable(UInt256))":       reflect.TypeOf([]*big.Int{}),
+		"Array(Nullable(IPv4))":          reflect.TypeOf([]net.IP{}),
+	} {
+		if column, err := columns.Factory("column_name", chType, time.Local); assert.NoError(t, err, chType) {
+			assert.Equal(t, scanType, column.ScanType(), chType)
+		}
+	}
+	if column, err := columns.Factory("column_name", "Array(Array(Nullable(String)))", time.Local); assert.NoError(t, err) {
+		a := "a"
+		rows := []interface{}{[][]*string{{&a, nil}, {}}, [][]*string{}}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			if v, err := column.(*columns.Array).ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, rows, v)
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "Array(Nullable(IPv4))", time.Local); assert.NoError(t, err) {
+		rows := []interface{}{[]interface{}{"1.2.3.4", nil}}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			if v, err := column.(*columns.Array).ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{[]net.IP{net.IPv4(1, 2, 3, 4).To4(), nil}}, v)
+			}
+		}
+	}
+	assert.Equal(t, 0, buf.Len())
+}
+
 func Test_Column_NullableDecimal64(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -700,6 +1071,149 @@ func Test_Column_NullableDecimal64(t *testing.T) {
 	}
 }
 
+func Test_Column_Decimal256(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if columnBase, err := columns.Factory("column_name", "Decimal(76, 10)", time.Local); assert.NoError(t, err) {
+		decimalCol, ok := columnBase.(*columns.Decimal)
+		if assert.True(t, ok) {
+			assert.Equal(t, 76, decimalCol.GetPrecision())
+			assert.Equal(t, 10, decimalCol.GetScale())
+		}
+		maxValue, _ := new(big.Int).SetString(strings.Repeat("9", 76), 10)
+		for _, v := range []*big.Int{
+			big.NewInt(0),
+			big.NewInt(1),
+			big.NewInt(-1),
+			big.NewInt(-112312345),
+			maxValue,
+			new(big.Int).Neg(maxValue),
+		} {
+			if err := columnBase.Write(encoder, v); assert.NoError(t, err) {
+				assert.Equal(t, 32, buf.Len())
+				if v2, err := columnBase.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, 0, v.Cmp(v2.(*big.Int)), "%s != %s", v, v2)
+				}
+			}
+		}
+		for v, want := range map[interface{}]string{
+			float64(-1123.5):                   "-11235000000000",
+			int64(-5):                          "-5",
+			uint64(18446744073709551615):       "18446744073709551615",
+			big.NewFloat(0.25):                 "2500000000",
+			new(big.Float).SetInt64(123456789): "1234567890000000000",
+		} {
+			if err := columnBase.Write(encoder, v); assert.NoError(t, err) {
+				if v2, err := columnBase.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, want, v2.(*big.Int).String())
+				}
+			}
+		}
+		// the two's complement of -1 is all ones
+		if err := columnBase.Write(encoder, big.NewInt(-1)); assert.NoError(t, err) {
+			assert.Equal(t, bytes.Repeat([]byte{0xff}, 32), buf.Next(32))
+		}
+		assert.Error(t, columnBase.Write(encoder, new(big.Int).Add(maxValue, big.NewInt(1))))
+		assert.Error(t, columnBase.Write(encoder, "1"))
+		if assert.Equal(t, "column_name", columnBase.Name()) && assert.Equal(t, "Decimal(76, 10)", columnBase.CHType()) {
+			assert.Equal(t, reflect.TypeOf((*big.Int)(nil)), columnBase.ScanType())
+		}
+	}
+	_, err := columns.Factory("column_name", "Decimal(77, 10)", time.Local)
+	assert.Error(t, err)
+}
+
+func Test_Column_NullableDecimal256(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if columnBase, err := columns.Factory("column_name", "Nullable(Decimal(76, 2))", time.Local); assert.NoError(t, err) {
+		nullableCol := columnBase.(*columns.Nullable)
+		if err := nullableCol.WriteNull(encoder, encoder, big.NewInt(-12345)); assert.NoError(t, err) {
+			if v, err := nullableCol.ReadNull(decoder, 1); assert.NoError(t, err) {
+				assert.Equal(t, "-12345", v[0].(*big.Int).String())
+			}
+		}
+		if err := nullableCol.WriteNull(encoder, encoder, (*big.Int)(nil)); assert.NoError(t, err) {
+			if v, err := nullableCol.ReadNull(decoder, 1); assert.NoError(t, err) {
+				assert.Nil(t, v[0])
+			}
+		}
+	}
+	if columnBase, err := columns.Factory("column_name", "Array(Decimal(76, 2))", time.Local); assert.NoError(t, err) {
+		assert.Equal(t, reflect.TypeOf([]*big.Int{}), columnBase.ScanType())
+	}
+}
+
+func Test_Column_EnumMapping(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", `Enum8('active' = 1, 'a, b = c' = -128, 'it\'s' = 127)`, time.Local); assert.NoError(t, err) {
+		enum := column.(*columns.Enum)
+		for ident, value := range map[string]int8{"active": 1, "a, b = c": -128, "it's": 127} {
+			if v, found := enum.Value(ident); assert.True(t, found, ident) {
+				assert.Equal(t, value, v)
+			}
+			if err := column.Write(encoder, ident); assert.NoError(t, err) {
+				assert.Equal(t, []byte{byte(value)}, buf.Bytes())
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, ident, v)
+				}
+			}
+			if err := column.Write(encoder, int64(value)); assert.NoError(t, err) {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, ident, v)
+				}
+			}
+		}
+		assert.Equal(t, reflect.TypeOf(int8(0)), enum.ValueType())
+		if err := column.Write(encoder, "closed"); assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "unknown Enum name 'closed'")
+		}
+		for _, v := range []interface{}{int8(2), uint8(2), int64(2), int64(300)} {
+			if err := column.Write(encoder, v); assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "unknown Enum value")
+			}
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+	if column, err := columns.Factory("column_name", "Enum16('min' = -32768, 'zero' = 0, 'max' = 32767)", time.Local); assert.NoError(t, err) {
+		enum := column.(*columns.Enum)
+		assert.Equal(t, reflect.TypeOf(int16(0)), enum.ValueType())
+		for ident, value := range map[string]int16{"min": -32768, "zero": 0, "max": 32767} {
+			if v, found := enum.Value(ident); assert.True(t, found, ident) {
+				assert.Equal(t, value, v)
+			}
+			if err := column.Write(encoder, value); assert.NoError(t, err) {
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, ident, v)
+				}
+			}
+		}
+		_, found := enum.Value("closed")
+		assert.False(t, found)
+	}
+	for _, chType := range []string{
+		"Enum8('a' = 128)",
+		"Enum16('a' = 32768)",
+		"Enum8('a' = 1, 'b')",
+		"Enum8('a = 1)",
+		"Enum8(a = 1)",
+		"Enum8()",
+	} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+}
+
 func Test_Column_NullableEnum8(t *testing.T) {
 	var (
 		buf     bytes.Buffer
@@ -742,3 +1256,767 @@ func Test_Column_NullableEnum8(t *testing.T) {
 		}
 	}
 }
+
+func Test_Column_ArrayOfArrays(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "Array(Array(Float64))", time.Local); assert.NoError(t, err) {
+		array, ok := column.(*columns.Array)
+		if assert.True(t, ok) && assert.Equal(t, 2, array.Depth()) {
+			assert.Equal(t, reflect.TypeOf([][]float64{}), column.ScanType())
+			rows := []interface{}{
+				[][]float64{{1, 2}, {}},
+				[][]float64{},
+				[]interface{}{[]float64{3}},
+				[][]float64{{}},
+			}
+			if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+				// the offsets of the rows, the offsets of the inner arrays and the values
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				for _, v := range []uint64{2, 2, 3, 4, 2, 2, 3, 3} {
+					expectedEncoder.UInt64(v)
+				}
+				for _, v := range []float64{1, 2, 3} {
+					expectedEncoder.Float64(v)
+				}
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := array.ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, []interface{}{
+						[][]float64{{1, 2}, {}},
+						[][]float64{},
+						[][]float64{{3}},
+						[][]float64{{}},
+					}, v)
+				}
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "Array(Array(Array(String)))", time.Local); assert.NoError(t, err) {
+		assert.Equal(t, reflect.TypeOf([][][]string{}), column.ScanType())
+		rows := []interface{}{[][][]string{}, [][][]string{{}, {{}}}, [][][]string{{{"a"}}}}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			if v, err := column.(*columns.Array).ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, rows, v)
+			}
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+}
+
+func Test_Column_Map(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if columnBase, err := columns.Factory("column_name", "Map(String, UInt64)", time.Local); assert.NoError(t, err) {
+		mapCol, ok := columnBase.(*columns.Map)
+		if assert.True(t, ok) {
+			rows := []interface{}{
+				map[string]uint64{"b": 2, "a": 1},
+				map[string]uint64{},
+				map[string]uint64{"c": 3},
+			}
+			if err := columns.WriteColumn(mapCol, encoder, rows); assert.NoError(t, err) {
+				// the offsets, the sorted keys and the values
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				for _, v := range []uint64{2, 2, 3} {
+					expectedEncoder.UInt64(v)
+				}
+				for _, v := range []string{"a", "b", "c"} {
+					expectedEncoder.String(v)
+				}
+				for _, v := range []uint64{1, 2, 3} {
+					expectedEncoder.UInt64(v)
+				}
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := mapCol.ReadMap(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, rows, v)
+				}
+			}
+			assert.Error(t, mapCol.Write(encoder, rows[0]))
+			assert.Error(t, columns.WriteColumn(mapCol, encoder, []interface{}{"a"}))
+		}
+		if assert.Equal(t, "column_name", columnBase.Name()) && assert.Equal(t, "Map(String, UInt64)", columnBase.CHType()) {
+			assert.Equal(t, reflect.TypeOf(map[string]uint64{}), columnBase.ScanType())
+		}
+	}
+}
+
+func Test_Column_MapNested(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	one, two := uint64(1), uint64(2)
+	for chType, rows := range map[string][]interface{}{
+		"Map(String, Array(Int32))": {
+			map[string][]int32{"a": {1, 2}, "b": {}},
+			map[string][]int32{"c": {3}},
+		},
+		"Map(Int8, Nullable(UInt64))": {
+			map[int8]*uint64{1: &one, 2: nil, -1: &two},
+		},
+		"Map(String, Map(String, Decimal(18, 2)))": {
+			map[string]map[string]int64{"a": {"b": 100}, "c": {}},
+		},
+		"Array(Map(String, UInt8))": {
+			[]map[string]uint8{{"a": 1}, {}},
+			[]map[string]uint8{},
+		},
+	} {
+		if columnBase, err := columns.Factory("column_name", chType, time.Local); assert.NoError(t, err, chType) {
+			assert.True(t, columns.NeedsColumnarWrite(columnBase))
+			if err := columns.WriteColumn(columnBase, encoder, rows); assert.NoError(t, err, chType) {
+				var (
+					v   []interface{}
+					err error
+				)
+				switch column := columnBase.(type) {
+				case *columns.Map:
+					v, err = column.ReadMap(decoder, len(rows))
+				case *columns.Array:
+					v, err = column.ReadArray(decoder, len(rows))
+				}
+				if assert.NoError(t, err, chType) {
+					assert.Equal(t, rows, v, chType)
+				}
+			}
+			assert.Equal(t, 0, buf.Len(), chType)
+		}
+	}
+	// the keys are sorted, so the encoding does not depend on the iteration order of the map
+	column, _ := columns.Factory("column_name", "Map(String, UInt8)", time.Local)
+	value := map[string]uint8{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+	columns.WriteColumn(column, encoder, []interface{}{value})
+	expected := append([]byte(nil), buf.Bytes()...)
+	for i := 0; i < 10; i++ {
+		buf.Reset()
+		columns.WriteColumn(column, encoder, []interface{}{value})
+		assert.Equal(t, expected, buf.Bytes())
+	}
+
+	for _, chType := range []string{"Nullable(Map(String, UInt8))", "Map(String)", "Map(Array(String), UInt8)"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+	if column, err := columns.Factory("column_name", "Nullable(UInt8)", time.Local); assert.NoError(t, err) {
+		assert.False(t, columns.NeedsColumnarWrite(column))
+	}
+}
+
+func Test_Column_Geo(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "Point", time.Local); assert.NoError(t, err) {
+		point, ok := column.(*columns.Point)
+		if assert.True(t, ok) {
+			rows := []interface{}{[2]float64{1.5, -2.25}, [2]float64{3, 4}}
+			if err := columns.WriteColumn(point, encoder, rows); assert.NoError(t, err) {
+				// the X coordinates followed by the Y coordinates
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				for _, v := range []float64{1.5, 3, -2.25, 4} {
+					expectedEncoder.Float64(v)
+				}
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := point.ReadPoint(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, rows, v)
+				}
+			}
+			assert.Error(t, point.Write(encoder, rows[0]))
+			assert.Error(t, columns.WriteColumn(point, encoder, []interface{}{[]float64{1, 2}}))
+		}
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "Point", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf([2]float64{}), column.ScanType())
+		}
+	}
+	for chType, rows := range map[string][]interface{}{
+		"Ring": {
+			[][2]float64{{0, 0}, {1, 0}, {1, 1}},
+			[][2]float64{},
+		},
+		"Polygon": {
+			[][][2]float64{{{0, 0}, {10, 0}, {10, 10}}, {{1, 1}, {2, 2}}},
+		},
+		"MultiPolygon": {
+			[][][][2]float64{{{{0, 0}, {10, 0}, {10, 10}}}, {{{-1.25, 0.1}}, {}}},
+			[][][][2]float64{},
+		},
+		"Array(Point)": {
+			[][2]float64{{0.1, 0.2}},
+		},
+	} {
+		if column, err := columns.Factory("column_name", chType, time.Local); assert.NoError(t, err, chType) {
+			array, ok := column.(*columns.Array)
+			if assert.True(t, ok, chType) && assert.Equal(t, chType, column.CHType()) {
+				assert.Equal(t, reflect.TypeOf(rows[0]), column.ScanType(), chType)
+				if err := columns.WriteColumn(array, encoder, rows); assert.NoError(t, err, chType) {
+					if v, err := array.ReadArray(decoder, len(rows)); assert.NoError(t, err, chType) {
+						assert.Equal(t, rows, v, chType)
+					}
+				}
+				assert.Equal(t, 0, buf.Len(), chType)
+			}
+		}
+	}
+}
+
+func Test_Column_LowCardinality(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "LowCardinality(String)", time.Local); assert.NoError(t, err) {
+		lc, ok := column.(*columns.LowCardinality)
+		if assert.True(t, ok) {
+			rows := []interface{}{"b", "a", "b", "", "a"}
+			if err := columns.WriteColumn(lc, encoder, rows); assert.NoError(t, err) {
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				// the version, the UInt8 key with the additional keys, the dictionary and the indexes
+				for _, v := range []uint64{1, 1<<9 | 1<<10, 3} {
+					expectedEncoder.UInt64(v)
+				}
+				for _, v := range []string{"b", "a", ""} {
+					expectedEncoder.String(v)
+				}
+				expectedEncoder.UInt64(5)
+				expectedEncoder.Write([]byte{0, 1, 0, 2, 1})
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := lc.ReadLowCardinality(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, rows, v)
+				}
+			}
+			assert.Error(t, lc.Write(encoder, "a"))
+			assert.Error(t, columns.WriteColumn(lc, encoder, []interface{}{1}))
+		}
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "LowCardinality(String)", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf(""), column.ScanType())
+		}
+	}
+	// the key is as narrow as the size of the dictionary allows
+	for size, key := range map[int]uint64{1: 0, 256: 0, 257: 1, 65537: 2} {
+		buf.Reset()
+		column, _ := columns.Factory("column_name", "LowCardinality(UInt32)", time.Local)
+		rows := make([]interface{}, size)
+		for i := range rows {
+			rows[i] = uint32(i)
+		}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			assert.Equal(t, key, gobinary.LittleEndian.Uint64(buf.Bytes()[8:])&0xff, size)
+			if v, err := column.(*columns.LowCardinality).ReadLowCardinality(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, rows, v, size)
+			}
+		}
+	}
+	for _, chType := range []string{"Nullable(LowCardinality(String))", "Array(LowCardinality(String))", "LowCardinality(Array(String))", "LowCardinality(Strin)"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+}
+
+func Test_Column_LowCardinalityNullable(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "LowCardinality(Nullable(String))", time.Local); assert.NoError(t, err) {
+		lc := column.(*columns.LowCardinality)
+		value := "a"
+		if err := columns.WriteColumn(lc, encoder, []interface{}{"", nil, &value, (*string)(nil), "a"}); assert.NoError(t, err) {
+			var (
+				expected        bytes.Buffer
+				expectedEncoder = binary.NewEncoder(&expected)
+			)
+			// the index 0 is NULL, so the empty string is not NULL
+			for _, v := range []uint64{1, 1<<9 | 1<<10, 3} {
+				expectedEncoder.UInt64(v)
+			}
+			for _, v := range []string{"", "", "a"} {
+				expectedEncoder.String(v)
+			}
+			expectedEncoder.UInt64(5)
+			expectedEncoder.Write([]byte{1, 0, 2, 0, 2})
+			assert.Equal(t, expected.Bytes(), buf.Bytes())
+			if v, err := lc.ReadLowCardinality(decoder, 5); assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{"", nil, "a", nil, "a"}, v)
+			}
+		}
+		assert.Equal(t, reflect.TypeOf(""), column.ScanType())
+	}
+	if v, err := (&columns.LowCardinality{}).ReadLowCardinality(decoder, 0); assert.NoError(t, err) {
+		assert.Empty(t, v)
+	}
+}
+
+func Test_Column_BigInt(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	pow2 := func(n uint) *big.Int {
+		return new(big.Int).Lsh(big.NewInt(1), n)
+	}
+	for _, tc := range []struct {
+		chType   string
+		size     int
+		min, max *big.Int
+	}{
+		{"Int128", 16, new(big.Int).Neg(pow2(127)), new(big.Int).Sub(pow2(127), big.NewInt(1))},
+		{"UInt128", 16, new(big.Int), new(big.Int).Sub(pow2(128), big.NewInt(1))},
+		{"Int256", 32, new(big.Int).Neg(pow2(255)), new(big.Int).Sub(pow2(255), big.NewInt(1))},
+		{"UInt256", 32, new(big.Int), new(big.Int).Sub(pow2(256), big.NewInt(1))},
+	} {
+		if column, err := columns.Factory("column_name", tc.chType, time.Local); assert.NoError(t, err, tc.chType) {
+			for _, v := range []*big.Int{tc.min, tc.max, big.NewInt(1), new(big.Int).Add(tc.min, big.NewInt(1))} {
+				if err := column.Write(encoder, v); assert.NoError(t, err, tc.chType) {
+					assert.Equal(t, tc.size, buf.Len(), tc.chType)
+					if value, err := column.Read(decoder, false); assert.NoError(t, err, tc.chType) {
+						assert.Equal(t, v.String(), value.(*big.Int).String(), tc.chType)
+					}
+				}
+			}
+			for _, v := range []*big.Int{new(big.Int).Add(tc.max, big.NewInt(1)), new(big.Int).Sub(tc.min, big.NewInt(1))} {
+				if err := column.Write(encoder, v); assert.Error(t, err, tc.chType) {
+					assert.Contains(t, err.Error(), "overflows "+tc.chType)
+				}
+				assert.Equal(t, 0, buf.Len())
+			}
+			if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, tc.chType, column.CHType()) {
+				assert.Equal(t, reflect.TypeOf((*big.Int)(nil)), column.ScanType())
+			}
+			if err := column.Write(encoder, "1"); assert.Error(t, err) {
+				if e, ok := err.(*columns.ErrUnexpectedType); assert.True(t, ok) {
+					assert.Equal(t, "1", e.T)
+				}
+			}
+		}
+	}
+	// sign extension and the Go integers
+	if column, err := columns.Factory("column_name", "Int128", time.Local); assert.NoError(t, err) {
+		if err := column.Write(encoder, int8(-2)); assert.NoError(t, err) {
+			assert.Equal(t, append([]byte{0xfe}, bytes.Repeat([]byte{0xff}, 15)...), buf.Bytes())
+			if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+				assert.Equal(t, big.NewInt(-2), v)
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "UInt128", time.Local); assert.NoError(t, err) {
+		if err := column.Write(encoder, uint64(1<<63)); assert.NoError(t, err) {
+			if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+				assert.Equal(t, new(big.Int).SetUint64(1<<63), v)
+			}
+		}
+		assert.Error(t, column.Write(encoder, -1))
+	}
+	if column, err := columns.Factory("column_name", "Nullable(UInt256)", time.Local); assert.NoError(t, err) {
+		if err := column.(*columns.Nullable).WriteNull(encoder, encoder, nil); assert.NoError(t, err) {
+			assert.Equal(t, 33, buf.Len())
+		}
+	}
+}
+
+func Test_Column_Nested(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	type item struct {
+		ID   uint32
+		Name string
+		Tags []string
+	}
+	if column, err := columns.Factory("column_name", "Nested(id UInt32, name String, tags Array(String))", time.Local); assert.NoError(t, err) {
+		nested, ok := column.(*columns.Nested)
+		if assert.True(t, ok) {
+			assert.Equal(t, []string{"id", "name", "tags"}, nested.Names())
+			rows := []interface{}{
+				[]item{{1, "a", []string{"x", "y"}}, {2, "b", []string{}}},
+				[]item{},
+				[]*item{{3, "c", []string{"z"}}},
+				[]map[string]interface{}{{"id": uint32(4), "name": "d", "tags": []string{}}},
+			}
+			if err := columns.WriteColumn(nested, encoder, rows); assert.NoError(t, err) {
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				// the offsets of the rows, the ids, the names and the tags
+				for _, v := range []uint64{2, 2, 3, 4} {
+					expectedEncoder.UInt64(v)
+				}
+				for _, v := range []uint32{1, 2, 3, 4} {
+					expectedEncoder.UInt32(v)
+				}
+				for _, v := range []string{"a", "b", "c", "d"} {
+					expectedEncoder.String(v)
+				}
+				for _, v := range []uint64{2, 2, 3, 3} {
+					expectedEncoder.UInt64(v)
+				}
+				for _, v := range []string{"x", "y", "z"} {
+					expectedEncoder.String(v)
+				}
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := nested.ReadNested(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, []interface{}{
+						[]map[string]interface{}{
+							{"id": uint32(1), "name": "a", "tags": []string{"x", "y"}},
+							{"id": uint32(2), "name": "b", "tags": []string{}},
+						},
+						[]map[string]interface{}{},
+						[]map[string]interface{}{{"id": uint32(3), "name": "c", "tags": []string{"z"}}},
+						[]map[string]interface{}{{"id": uint32(4), "name": "d", "tags": []string{}}},
+					}, v)
+				}
+			}
+			for _, rows := range [][]interface{}{
+				{item{}},
+				{[]struct{ ID uint32 }{{1}}},
+				{[]map[string]interface{}{{"id": uint32(1), "name": "a"}}},
+				{[]struct {
+					ID   string
+					Name string
+					Tags []string
+				}{{"1", "a", nil}}},
+			} {
+				buf.Reset()
+				assert.Error(t, columns.WriteColumn(nested, encoder, rows))
+			}
+		}
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "Nested(id UInt32, name String, tags Array(String))", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf([]map[string]interface{}{}), column.ScanType())
+		}
+	}
+	for _, chType := range []string{"Nested(id)", "Nested(id UInt3)", "Nested(id UInt32"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+}
+
+func Test_SplitTypes(t *testing.T) {
+	for list, expected := range map[string][]string{
+		"UInt8": {"UInt8"},
+		"String, Map(String, Tuple(UInt8, String))": {"String", "Map(String, Tuple(UInt8, String))"},
+		"Enum8('a,(' = 1), String":                  {"Enum8('a,(' = 1)", "String"},
+		"String, Enum8('x)' = 1, 'it\\'s,' = 2)":    {"String", "Enum8('x)' = 1, 'it\\'s,' = 2)"},
+	} {
+		assert.Equal(t, expected, columns.SplitTypes(list), list)
+	}
+	for _, chType := range []string{"Tuple(Enum8('a,(' = 1), String)", "Map(String, Enum8('x)' = 1))"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.NoError(t, err, chType)
+	}
+}
+
+func Test_Column_Tuple(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	type point struct {
+		X, Y float64
+	}
+	type element struct {
+		ID    uint32
+		Name  string
+		Point point
+	}
+	if column, err := columns.Factory("column_name", "Tuple(UInt32, String, Tuple(Float64, Float64))", time.Local); assert.NoError(t, err) {
+		tuple, ok := column.(*columns.Tuple)
+		if assert.True(t, ok) {
+			assert.Nil(t, tuple.Names())
+			rows := []interface{}{
+				[]interface{}{uint32(1), "a", []interface{}{1.5, 2.5}},
+				element{2, "b", point{3, 4}},
+				&element{3, "c", point{5, 6}},
+			}
+			if err := columns.WriteColumn(tuple, encoder, rows); assert.NoError(t, err) {
+				var (
+					expected        bytes.Buffer
+					expectedEncoder = binary.NewEncoder(&expected)
+				)
+				// the first elements of the rows, the second ones, the third ones
+				for _, v := range []uint32{1, 2, 3} {
+					expectedEncoder.UInt32(v)
+				}
+				for _, v := range []string{"a", "b", "c"} {
+					expectedEncoder.String(v)
+				}
+				for _, v := range []float64{1.5, 3, 5, 2.5, 4, 6} {
+					expectedEncoder.Float64(v)
+				}
+				assert.Equal(t, expected.Bytes(), buf.Bytes())
+				if v, err := tuple.ReadTuple(decoder, len(rows)); assert.NoError(t, err) {
+					assert.Equal(t, []interface{}{
+						[]interface{}{uint32(1), "a", []interface{}{1.5, 2.5}},
+						[]interface{}{uint32(2), "b", []interface{}{float64(3), float64(4)}},
+						[]interface{}{uint32(3), "c", []interface{}{float64(5), float64(6)}},
+					}, v)
+				}
+			}
+			for _, row := range []interface{}{
+				[]interface{}{uint32(1), "a"},
+				struct{ ID uint32 }{1},
+				[]interface{}{"1", "a", []interface{}{1.5, 2.5}},
+				"a",
+			} {
+				buf.Reset()
+				assert.Error(t, columns.WriteColumn(tuple, encoder, []interface{}{row}))
+			}
+			buf.Reset()
+			if err := columns.WriteColumn(tuple, encoder, []interface{}{[]interface{}{uint32(1), 2, []interface{}{1.5, 2.5}}}); assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "element 1")
+			}
+		}
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "Tuple(UInt32, String, Tuple(Float64, Float64))", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf([]interface{}{}), column.ScanType())
+		}
+	}
+	buf.Reset()
+	if column, err := columns.Factory("column_name", "Tuple(a UInt32, b Nullable(String), c Decimal(18, 2))", time.Local); assert.NoError(t, err) {
+		tuple := column.(*columns.Tuple)
+		assert.Equal(t, []string{"a", "b", "c"}, tuple.Names())
+		type named struct {
+			B *string `ch:"b"`
+			C int64
+			A uint32 `ch:"a"`
+		}
+		value := "value"
+		rows := []interface{}{
+			named{B: &value, C: 100, A: 1},
+			map[string]interface{}{"a": uint32(2), "b": nil, "c": int64(200)},
+		}
+		if err := columns.WriteColumn(tuple, encoder, rows); assert.NoError(t, err) {
+			if v, err := tuple.ReadTuple(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"a": uint32(1), "b": "value", "c": int64(100)},
+					map[string]interface{}{"a": uint32(2), "b": nil, "c": int64(200)},
+				}, v)
+			}
+		}
+		assert.Equal(t, reflect.TypeOf(map[string]interface{}{}), column.ScanType())
+		for _, row := range []interface{}{
+			struct{ A, B, D uint32 }{},
+			map[string]interface{}{"a": uint32(2), "b": nil},
+		} {
+			assert.Error(t, columns.WriteColumn(tuple, encoder, []interface{}{row}))
+		}
+	}
+	buf.Reset()
+	if column, err := columns.Factory("column_name", "Array(Tuple(String, UInt8))", time.Local); assert.NoError(t, err) {
+		rows := []interface{}{[][]interface{}{{"a", uint8(1)}, {"b", uint8(2)}}}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			if v, err := column.(*columns.Array).ReadArray(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, [][]interface{}{{"a", uint8(1)}, {"b", uint8(2)}}, v[0])
+			}
+		}
+	}
+	for _, chType := range []string{"Tuple(a UInt8, String)", "Tuple(UInt8, b String)", "Tuple(UInt3)", "Tuple(UInt8"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+}
+
+func Test_Column_JSON(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	for _, chType := range []string{"Object('json')", "JSON"} {
+		column, err := columns.Factory("column_name", chType, time.Local)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, columns.NeedsColumnarWrite(column))
+		assert.Equal(t, reflect.TypeOf(map[string]interface{}{}), column.ScanType())
+		rows := []interface{}{
+			`{"a": 1, "b": "x", "e": [1, 2]}`,
+			[]byte(`{"b": "y", "c": {"d": 1.5}, "f": null}`),
+			map[string]interface{}{"a": 2.5, "c": map[string]interface{}{"d": 2}, "g": true},
+		}
+		if err := columns.WriteColumn(column, encoder, rows); assert.NoError(t, err) {
+			var (
+				expected        bytes.Buffer
+				expectedEncoder = binary.NewEncoder(&expected)
+			)
+			expectedEncoder.UInt8(0)
+			expectedEncoder.String("Tuple(`a` Float64, `b` String, `c` Tuple(`d` Float64), `e` Array(Int64), `g` Bool)")
+			assert.True(t, bytes.HasPrefix(buf.Bytes(), expected.Bytes()))
+			if v, err := column.(*columns.JSON).ReadJSON(decoder, len(rows)); assert.NoError(t, err) {
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"a": 1.0, "b": "x", "c": map[string]interface{}{"d": 0.0}, "e": []int64{1, 2}, "g": false},
+					map[string]interface{}{"a": 0.0, "b": "y", "c": map[string]interface{}{"d": 1.5}, "e": []int64{}, "g": false},
+					map[string]interface{}{"a": 2.5, "b": "", "c": map[string]interface{}{"d": 2.0}, "e": []int64{}, "g": true},
+				}, v)
+			}
+		}
+		assert.Equal(t, 0, buf.Len())
+		for _, rows := range [][]interface{}{
+			{`{"a": 1}`, `{"a": "x"}`},
+			{`{"a": 1}`, `{"a": {"b": 1}}`},
+			{`{"a": [1, null]}`},
+			{`[1, 2]`},
+			{`{"a": `},
+			{`{}`},
+			{nil},
+		} {
+			assert.Error(t, columns.WriteColumn(column, encoder, rows), rows)
+		}
+		buf.Reset()
+	}
+	// the documents sent as strings
+	column, _ := columns.Factory("column_name", "Object('json')", time.Local)
+	encoder.UInt8(1)
+	encoder.String(`{"a": {"b": [1, "c"]}}`)
+	if v, err := column.(*columns.JSON).ReadJSON(decoder, 1); assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1.0, "c"}}}}, v)
+	}
+	for _, chType := range []string{"Nullable(Object('json'))", "Array(JSON)", "LowCardinality(JSON)", "Tuple(JSON)", "Map(String, JSON)"} {
+		_, err := columns.Factory("column_name", chType, time.Local)
+		assert.Error(t, err, chType)
+	}
+}
+
+func Test_Column_IPNormalize(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	ipv4, _ := columns.Factory("column_name", "IPv4", time.Local)
+	ipv6, _ := columns.Factory("column_name", "IPv6", time.Local)
+	loopback6 := "::1"
+	for _, tc := range []struct {
+		column   columns.Column
+		value    interface{}
+		bytes    []byte
+		expected net.IP
+	}{
+		// IPv4 is stored as UInt32, the bytes are reversed on the wire
+		{ipv4, "127.0.0.1", []byte{1, 0, 0, 127}, net.IP{127, 0, 0, 1}},
+		{ipv4, net.IPv4(192, 168, 0, 1), []byte{1, 0, 168, 192}, net.IP{192, 168, 0, 1}},
+		{ipv4, "::ffff:10.0.0.1", []byte{1, 0, 0, 10}, net.IP{10, 0, 0, 1}},
+		{ipv6, &loopback6, net.IPv6loopback, net.IPv6loopback},
+		{ipv6, net.IP{10, 0, 0, 1}, net.ParseIP("::ffff:10.0.0.1"), net.ParseIP("::ffff:10.0.0.1")},
+		{ipv6, "::ffff:10.0.0.1", net.ParseIP("::ffff:10.0.0.1"), net.ParseIP("::ffff:10.0.0.1")},
+	} {
+		if err := tc.column.Write(encoder, tc.value); assert.NoError(t, err, tc.value) {
+			assert.Equal(t, tc.bytes, buf.Bytes(), tc.value)
+			if v, err := tc.column.Read(decoder, false); assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, v, tc.value)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		column columns.Column
+		value  interface{}
+	}{
+		{ipv4, "256.0.0.1"},
+		{ipv4, "127.0.0"},
+		{ipv4, "::1"},
+		{ipv4, (*net.IP)(nil)},
+		{ipv6, "::1::"},
+		{ipv6, net.IP{127, 0, 0}},
+		{ipv6, (*string)(nil)},
+	} {
+		if err := tc.column.Write(encoder, tc.value); assert.Error(t, err, tc.value) {
+			assert.Equal(t, &columns.ErrUnexpectedType{Column: tc.column, T: tc.value}, err)
+		}
+		assert.Equal(t, 0, buf.Len())
+	}
+	// NULL is written as the zero address
+	for chType, size := range map[string]int{"Nullable(IPv4)": 4, "Nullable(IPv6)": 16} {
+		buf.Reset()
+		if column, err := columns.Factory("column_name", chType, time.Local); assert.NoError(t, err) {
+			if err := column.(*columns.Nullable).WriteNull(encoder, encoder, nil); assert.NoError(t, err, chType) {
+				assert.Equal(t, append([]byte{1}, make([]byte, size)...), buf.Bytes(), chType)
+			}
+		}
+	}
+}
+
+func Test_Column_Bool(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		decoder = binary.NewDecoder(&buf)
+	)
+	if column, err := columns.Factory("column_name", "Bool", time.Local); assert.NoError(t, err) {
+		yes := true
+		for _, tc := range []struct {
+			value    interface{}
+			expected bool
+		}{
+			{true, true},
+			{false, false},
+			{&yes, true},
+			{uint8(1), true},
+			{uint8(0), false},
+			{int64(-7), true},
+		} {
+			if err := column.Write(encoder, tc.value); assert.NoError(t, err) {
+				// normalized to 0 and 1 on the wire
+				if tc.expected {
+					assert.Equal(t, []byte{1}, buf.Bytes())
+				} else {
+					assert.Equal(t, []byte{0}, buf.Bytes())
+				}
+				if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+					assert.Equal(t, tc.expected, v)
+				}
+			}
+		}
+		// any non-zero value is true
+		buf.Write([]byte{2})
+		if v, err := column.Read(decoder, false); assert.NoError(t, err) {
+			assert.Equal(t, true, v)
+		}
+		if assert.Equal(t, "column_name", column.Name()) && assert.Equal(t, "Bool", column.CHType()) {
+			assert.Equal(t, reflect.TypeOf(true), column.ScanType())
+		}
+		if err := column.Write(encoder, "true"); assert.Error(t, err) {
+			if e, ok := err.(*columns.ErrUnexpectedType); assert.True(t, ok) {
+				assert.Equal(t, "true", e.T)
+			}
+		}
+	}
+	if column, err := columns.Factory("column_name", "Nullable(Bool)", time.Local); assert.NoError(t, err) {
+		nullable := column.(*columns.Nullable)
+		for _, v := range []interface{}{true, nil, false} {
+			if err := nullable.WriteNull(encoder, encoder, v); assert.NoError(t, err) {
+				if values, err := nullable.ReadNull(decoder, 1); assert.NoError(t, err) {
+					assert.Equal(t, v, values[0])
+				}
+			}
+		}
+		assert.Equal(t, reflect.TypeOf(true), column.ScanType())
+	}
+	if column, err := columns.Factory("column_name", "Array(Bool)", time.Local); assert.NoError(t, err) {
+		assert.Equal(t, reflect.TypeOf([]bool{}), column.ScanType())
+	}
+}
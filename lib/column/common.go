@@ -2,6 +2,7 @@ package column
 
 import (
 	"fmt"
+	"math/big"
 	"net"
 	"reflect"
 	"time"
@@ -28,9 +29,12 @@ var columnBaseTypes = map[interface{}]reflect.Value{
 	float32(0):  reflect.ValueOf(float32(0)),
 	float64(0):  reflect.ValueOf(float64(0)),
 	string(""):  reflect.ValueOf(string("")),
+	false:       reflect.ValueOf(false),
 	time.Time{}: reflect.ValueOf(time.Time{}),
 	IPv4{}:      reflect.ValueOf(net.IP{}),
 	IPv6{}:      reflect.ValueOf(net.IP{}),
+
+	(*big.Int)(nil): reflect.ValueOf((*big.Int)(nil)),
 }
 
 var arrayBaseTypes = map[interface{}]reflect.Type{
@@ -45,9 +49,12 @@ var arrayBaseTypes = map[interface{}]reflect.Type{
 	float32(0):  reflect.ValueOf(float32(0)).Type(),
 	float64(0):  reflect.ValueOf(float64(0)).Type(),
 	string(""):  reflect.ValueOf(string("")).Type(),
+	false:       reflect.ValueOf(false).Type(),
 	time.Time{}: reflect.ValueOf(time.Time{}).Type(),
 	IPv4{}:      reflect.ValueOf(net.IP{}).Type(),
 	IPv6{}:      reflect.ValueOf(net.IP{}).Type(),
+
+	(*big.Int)(nil): reflect.ValueOf((*big.Int)(nil)).Type(),
 }
 
 type base struct {
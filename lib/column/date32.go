@@ -0,0 +1,71 @@
+package column
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// the range of Date32, in days since 1970-01-01
+const (
+	minDate32 = -25567 // 1900-01-01
+	maxDate32 = 120529 // 2299-12-31
+)
+
+// Date32 represents Date32 ClickHouse, the signed number of days since 1970-01-01 from 1900-01-01 to 2299-12-31.
+// The values are read as the midnights UTC of the dates, the dates of the time.Time values are written
+// whatever their location.
+type Date32 struct{ base }
+
+func (Date32) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	days, err := decoder.Int32()
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(int64(days)*24*3600, 0).UTC(), nil
+}
+
+func (dt *Date32) Write(encoder *binary.Encoder, v interface{}) error {
+	var days int64
+	switch value := v.(type) {
+	case time.Time:
+		days = date32Days(value)
+	case int32:
+		days = int64(value)
+	case string:
+		tv, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return err
+		}
+		days = date32Days(tv)
+
+	// this relies on Nullable never sending nil values through
+	case *time.Time:
+		days = date32Days(*value)
+	case *int32:
+		days = int64(*value)
+	case *string:
+		tv, err := time.Parse("2006-01-02", *value)
+		if err != nil {
+			return err
+		}
+		days = date32Days(tv)
+
+	default:
+		return &ErrUnexpectedType{
+			T:      v,
+			Column: dt,
+		}
+	}
+	if days < minDate32 || days > maxDate32 {
+		return fmt.Errorf("%s: value %v overflows %s, the range is [1900-01-01, 2299-12-31]", dt.name, v, dt.chType)
+	}
+	return encoder.Int32(int32(days))
+}
+
+// date32Days returns the days since 1970-01-01 of the date of the value in its location.
+func date32Days(value time.Time) int64 {
+	year, month, day := value.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 3600)
+}
@@ -1,6 +1,7 @@
 package column
 
 import (
+	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -9,9 +10,13 @@ import (
 	"github.com/c3mb0/clickhouse-go/lib/binary"
 )
 
+// DateTime64 represents DateTime64(P[, 'timezone']) ClickHouse, the number of the ticks of 10^-P seconds since the epoch.
+// The values are read in the timezone of the column, or in the timezone of the connection if the column has none.
 type DateTime64 struct {
 	base
-	Timezone *time.Location
+	Timezone  *time.Location
+	precision int
+	scale     int64 // 10^P ticks per second
 }
 
 func (dt *DateTime64) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
@@ -19,34 +24,23 @@ func (dt *DateTime64) Read(decoder *binary.Decoder, isNull bool) (interface{}, e
 	if err != nil {
 		return nil, err
 	}
-
-	precision, err := dt.getPrecision()
-	if err != nil {
-		return nil, err
+	sec := value / dt.scale
+	if value%dt.scale < 0 {
+		sec--
 	}
-
-	var nano int64
-	if precision < 19 {
-		nano = value * int64(math.Pow10(9-precision))
-	}
-
-	sec := nano / int64(10e8)
-	nsec := nano - sec*10e8
-
+	nsec := (value - sec*dt.scale) * (1e9 / dt.scale)
 	return time.Unix(sec, nsec).In(dt.Timezone), nil
 }
 
 func (dt *DateTime64) Write(encoder *binary.Encoder, v interface{}) error {
-	var timestamp int64
+	var timestamp time.Time
 	switch value := v.(type) {
 	case time.Time:
-		if !value.IsZero() {
-			timestamp = value.UnixNano()
-		}
+		timestamp = value
 	case uint64:
-		timestamp = int64(value)
+		timestamp = time.Unix(0, int64(value))
 	case int64:
-		timestamp = value
+		timestamp = time.Unix(0, value)
 	case string:
 		var err error
 		timestamp, err = dt.parse(value)
@@ -54,11 +48,11 @@ func (dt *DateTime64) Write(encoder *binary.Encoder, v interface{}) error {
 			return err
 		}
 	case *time.Time:
-		if value != nil && !(*value).IsZero() {
-			timestamp = (*value).UnixNano()
+		if value != nil {
+			timestamp = *value
 		}
 	case *int64:
-		timestamp = *value
+		timestamp = time.Unix(0, *value)
 	case *string:
 		var err error
 		timestamp, err = dt.parse(*value)
@@ -71,30 +65,48 @@ func (dt *DateTime64) Write(encoder *binary.Encoder, v interface{}) error {
 			Column: dt,
 		}
 	}
-
-	precision, err := dt.getPrecision()
-	if err != nil {
-		return err
+	if timestamp.IsZero() {
+		return encoder.Int64(0)
 	}
+	// the sub-second part is truncated to the precision of the column
+	return encoder.Int64(timestamp.Unix()*dt.scale + int64(timestamp.Nanosecond())/(1e9/dt.scale))
+}
 
-	timestamp = timestamp / int64(math.Pow10(9-precision))
-
-	return encoder.Int64(timestamp)
+func (dt *DateTime64) parse(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02 15:04:05.999999999", value, dt.Timezone)
 }
 
-func (dt *DateTime64) parse(value string) (int64, error) {
-	tv, err := time.Parse("2006-01-02 15:04:05.999", value)
-	if err != nil {
-		return 0, err
-	}
-	return tv.UnixNano(), nil
+// GetPrecision returns the number of the digits of the sub-second part of the column.
+func (dt *DateTime64) GetPrecision() int {
+	return dt.precision
 }
 
-func (dt *DateTime64) getPrecision() (int, error) {
-	dtParams := dt.base.chType[11 : len(dt.base.chType)-1]
-	precision, err := strconv.Atoi(strings.Split(dtParams, ",")[0])
-	if err != nil {
-		return 0, err
+func parseDateTime64(name, chType string, timezone *time.Location) (Column, error) {
+	dt := &DateTime64{
+		base: base{
+			name:    name,
+			chType:  chType,
+			valueOf: columnBaseTypes[time.Time{}],
+		},
+		Timezone:  timezone,
+		precision: 3,
+	}
+	if chType != "DateTime64" {
+		if !strings.HasPrefix(chType, "DateTime64(") || !strings.HasSuffix(chType, ")") {
+			return nil, fmt.Errorf("invalid DateTime64 format: '%s'", chType)
+		}
+		params := strings.SplitN(chType[11:len(chType)-1], ",", 2)
+		precision, err := strconv.Atoi(strings.TrimSpace(params[0]))
+		if err != nil || precision < 0 || precision > 9 {
+			return nil, fmt.Errorf("invalid DateTime64 precision: '%s'", chType)
+		}
+		dt.precision = precision
+		if len(params) == 2 {
+			if dt.Timezone, err = time.LoadLocation(strings.Trim(strings.TrimSpace(params[1]), "'")); err != nil {
+				return nil, fmt.Errorf("invalid DateTime64 timezone: '%s': %v", chType, err)
+			}
+		}
 	}
-	return precision, nil
+	dt.scale = int64(math.Pow10(dt.precision))
+	return dt, nil
 }
@@ -3,6 +3,8 @@ package column
 import (
 	"errors"
 	"fmt"
+	"math/big"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -18,11 +20,11 @@ var factors10 = []float64{
 
 // Decimal represents Decimal(P, S) ClickHouse. Since there is support for
 // int128 in Golang, the implementation does not support to 128-bits decimals
-// as well. Decimal is represented as integral. Also floating-point types are
-// supported for query parameters.
+// as well. Decimal is represented as integral, the 256-bits decimals as *big.Int.
+// Also floating-point types and *big.Float are supported for query parameters.
 type Decimal struct {
 	base
-	nobits    int // its domain is {32, 64}
+	nobits    int // its domain is {32, 64, 256}
 	precision int
 	scale     int
 }
@@ -33,6 +35,8 @@ func (d *Decimal) Read(decoder *binary.Decoder, isNull bool) (interface{}, error
 		return decoder.Int32()
 	case 64:
 		return decoder.Int64()
+	case 256:
+		return d.read256(decoder)
 	default:
 		return nil, errors.New("unachievable execution path")
 	}
@@ -44,6 +48,8 @@ func (d *Decimal) Write(encoder *binary.Encoder, v interface{}) error {
 		return d.write32(encoder, v)
 	case 64:
 		return d.write64(encoder, v)
+	case 256:
+		return d.write256(encoder, v)
 	default:
 		return errors.New("unachievable execution path")
 	}
@@ -183,6 +189,71 @@ func (d *Decimal) write64(encoder *binary.Encoder, v interface{}) error {
 	}
 }
 
+// decimal256Size is the size of Decimal256 on the wire, a little-endian two's complement integer.
+const decimal256Size = 32
+
+func (d *Decimal) read256(decoder *binary.Decoder) (interface{}, error) {
+	return readBigInt(decoder, decimal256Size, true)
+}
+
+func (d *Decimal) write256(encoder *binary.Encoder, v interface{}) error {
+	fixed, err := d.bigInt(v)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).Abs(fixed).Cmp(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.precision)), nil)) >= 0 {
+		return fmt.Errorf("%s: value %s exceeds the precision %d", d.chType, fixed, d.precision)
+	}
+	return writeBigInt(encoder, fixed, decimal256Size)
+}
+
+// bigInt converts the value to the integral representation of the decimal, the floating-point
+// values are scaled by the scale of the column.
+func (d *Decimal) bigInt(v interface{}) (*big.Int, error) {
+	switch v := v.(type) {
+	case *big.Int:
+		if v == nil { // the default value written for NULL
+			return new(big.Int), nil
+		}
+		return v, nil
+	case big.Int:
+		return &v, nil
+	case *big.Float:
+		return d.scaleFloat(v), nil
+	case float32:
+		return d.scaleFloat(big.NewFloat(float64(v))), nil
+	case float64:
+		return d.scaleFloat(big.NewFloat(v)), nil
+	case *float32:
+		return d.scaleFloat(big.NewFloat(float64(*v))), nil
+	case *float64:
+		return d.scaleFloat(big.NewFloat(*v)), nil
+	case uint:
+		return new(big.Int).SetUint64(uint64(v)), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case *uint64:
+		return new(big.Int).SetUint64(*v), nil
+	}
+	// this relies on Nullable never sending nil values through
+	switch value := reflect.Indirect(reflect.ValueOf(v)); value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(value.Int()), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return new(big.Int).SetUint64(value.Uint()), nil
+	}
+	return nil, &ErrUnexpectedType{
+		T:      v,
+		Column: d,
+	}
+}
+
+func (d *Decimal) scaleFloat(floating *big.Float) *big.Int {
+	factor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.scale)), nil))
+	fixed, _ := new(big.Float).SetPrec(512).Mul(floating, factor).Int(nil)
+	return fixed
+}
+
 func parseDecimal(name, chType string) (Column, error) {
 	switch {
 	case len(chType) < 12:
@@ -233,6 +304,9 @@ func parseDecimal(name, chType string) (Column, error) {
 		decimal.valueOf = columnBaseTypes[int64(0)]
 	case decimal.precision <= 38:
 		return nil, errors.New("Decimal128 is not supported")
+	case decimal.precision <= 76:
+		decimal.nobits = 256
+		decimal.valueOf = columnBaseTypes[(*big.Int)(nil)]
 	default:
 		return nil, errors.New("precision of Decimal exceeds max bound")
 	}
@@ -2,6 +2,8 @@ package column
 
 import (
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -37,45 +39,62 @@ func (enum *Enum) Read(decoder *binary.Decoder, isNull bool) (interface{}, error
 }
 
 func (enum *Enum) Write(encoder *binary.Encoder, v interface{}) error {
+	var value interface{}
 	switch v := v.(type) {
 	case string:
 		ident, found := enum.iv[v]
 		if !found {
-			return fmt.Errorf("invalid Enum ident: %s", v)
-		}
-		switch ident := ident.(type) {
-		case int8:
-			return encoder.Int8(ident)
-		case int16:
-			return encoder.Int16(ident)
+			return fmt.Errorf("%s: unknown Enum name '%s'", enum.chType, v)
 		}
+		value = ident
+	case *string:
+		// this relies on Nullable never sending nil values through
+		return enum.Write(encoder, *v)
 	case uint8:
 		if _, ok := enum.baseType.(int8); ok {
-			return encoder.Int8(int8(v))
+			value = int8(v)
 		}
 	case int8:
 		if _, ok := enum.baseType.(int8); ok {
-			return encoder.Int8(v)
+			value = v
 		}
 	case uint16:
 		if _, ok := enum.baseType.(int16); ok {
-			return encoder.Int16(int16(v))
+			value = int16(v)
 		}
 	case int16:
 		if _, ok := enum.baseType.(int16); ok {
-			return encoder.Int16(v)
+			value = v
 		}
 	case int64:
 		switch enum.baseType.(type) {
 		case int8:
-			return encoder.Int8(int8(v))
+			if v >= math.MinInt8 && v <= math.MaxInt8 {
+				value = int8(v)
+			}
 		case int16:
-			return encoder.Int16(int16(v))
+			if v >= math.MinInt16 && v <= math.MaxInt16 {
+				value = int16(v)
+			}
+		}
+		if value == nil {
+			return fmt.Errorf("%s: unknown Enum value %d", enum.chType, v)
+		}
+	}
+	if value == nil {
+		return &ErrUnexpectedType{
+			T:      v,
+			Column: enum,
 		}
 	}
-	return &ErrUnexpectedType{
-		T:      v,
-		Column: enum,
+	if _, found := enum.vi[value]; !found {
+		return fmt.Errorf("%s: unknown Enum value %v", enum.chType, value)
+	}
+	switch value := value.(type) {
+	case int8:
+		return encoder.Int8(value)
+	default:
+		return encoder.Int16(value.(int16))
 	}
 }
 
@@ -83,23 +102,37 @@ func (enum *Enum) defaultValue() interface{} {
 	return enum.baseType
 }
 
+// Value returns the value of the name, int8 for Enum8 and int16 for Enum16.
+func (enum *Enum) Value(ident string) (interface{}, bool) {
+	value, found := enum.iv[ident]
+	return value, found
+}
+
+// ValueType returns the type of the values, int8 for Enum8 and int16 for Enum16.
+func (enum *Enum) ValueType() reflect.Type {
+	return reflect.TypeOf(enum.baseType)
+}
+
 func parseEnum(name, chType string) (*Enum, error) {
 	var (
-		data     string
-		isEnum16 bool
+		data    string
+		bitSize = 8
 	)
 	if len(chType) < 8 {
 		return nil, fmt.Errorf("invalid Enum format: %s", chType)
 	}
 	switch {
-	case strings.HasPrefix(chType, "Enum8"):
+	case strings.HasPrefix(chType, "Enum8("):
 		data = chType[6:]
-	case strings.HasPrefix(chType, "Enum16"):
+	case strings.HasPrefix(chType, "Enum16("):
 		data = chType[7:]
-		isEnum16 = true
+		bitSize = 16
 	default:
 		return nil, fmt.Errorf("'%s' is not Enum type", chType)
 	}
+	if !strings.HasSuffix(data, ")") {
+		return nil, fmt.Errorf("invalid Enum format: %s", chType)
+	}
 	enum := Enum{
 		base: base{
 			name:    name,
@@ -109,32 +142,61 @@ func parseEnum(name, chType string) (*Enum, error) {
 		iv: make(map[string]interface{}),
 		vi: make(map[interface{}]string),
 	}
-	for _, block := range strings.Split(data[:len(data)-1], ",") {
-		parts := strings.Split(block, "=")
-		if len(parts) != 2 {
+	data = strings.TrimSpace(data[:len(data)-1])
+	for len(data) != 0 {
+		// 'name' = value, the quotes within the name are escaped by a backslash
+		ident, rest, err := parseEnumIdent(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Enum format: %s", chType)
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "=") {
 			return nil, fmt.Errorf("invalid Enum format: %s", chType)
 		}
-		var (
-			ident      = strings.TrimSpace(parts[0])
-			value, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 16)
-		)
+		rest = rest[1:]
+		end := strings.IndexByte(rest, ',')
+		if end == -1 {
+			end = len(rest)
+		}
+		parsed, err := strconv.ParseInt(strings.TrimSpace(rest[:end]), 10, bitSize)
 		if err != nil {
 			return nil, fmt.Errorf("invalid Enum value: %v", chType)
 		}
-		{
-			var (
-				ident             = ident[1 : len(ident)-1]
-				value interface{} = int16(value)
-			)
-			if !isEnum16 {
-				value = int8(value.(int16))
-			}
-			if enum.baseType == nil {
-				enum.baseType = value
-			}
-			enum.iv[ident] = value
-			enum.vi[value] = ident
+		var value interface{} = int16(parsed)
+		if bitSize == 8 {
+			value = int8(parsed)
+		}
+		if enum.baseType == nil {
+			enum.baseType = value
 		}
+		enum.iv[ident] = value
+		enum.vi[value] = ident
+		if data = strings.TrimSpace(rest[end:]); len(data) != 0 {
+			data = strings.TrimSpace(data[1:])
+		}
+	}
+	if enum.baseType == nil {
+		return nil, fmt.Errorf("invalid Enum format: %s", chType)
 	}
 	return &enum, nil
 }
+
+// parseEnumIdent parses the quoted name the data starts with, it returns the name and the rest of the data.
+func parseEnumIdent(data string) (string, string, error) {
+	if !strings.HasPrefix(data, "'") {
+		return "", "", fmt.Errorf("the name is not quoted")
+	}
+	var ident strings.Builder
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '\\' && i+1 < len(data):
+			i++
+			ident.WriteByte(data[i])
+		case c == '\'':
+			return ident.String(), data[i+1:], nil
+		default:
+			ident.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("the name is not terminated")
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/c3mb0/clickhouse-go/lib/binary"
 )
 
+// FixedString represents FixedString(N) ClickHouse: the values are N bytes long, the shorter ones are padded
+// with the zero bytes. The values are scanned as the strings of the N bytes, the padding included.
 type FixedString struct {
 	base
 	len      int
@@ -43,7 +45,7 @@ func (str *FixedString) Write(encoder *binary.Encoder, v interface{}) error {
 	}
 	switch {
 	case len(fixedString) > str.len:
-		return fmt.Errorf("too large value '%s' (expected %d, got %d)", fixedString, str.len, len(fixedString))
+		return fmt.Errorf("%s: too large value '%s' (expected at most %d bytes, got %d)", str.chType, fixedString, str.len, len(fixedString))
 	case len(fixedString) < str.len:
 		tmp := make([]byte, str.len)
 		copy(tmp, fixedString)
@@ -55,6 +57,11 @@ func (str *FixedString) Write(encoder *binary.Encoder, v interface{}) error {
 	return nil
 }
 
+// Len returns N, the length of the values in bytes.
+func (str *FixedString) Len() int {
+	return str.len
+}
+
 func parseFixedString(name, chType string) (*FixedString, error) {
 	var strLen int
 	if _, err := fmt.Sscanf(chType, "FixedString(%d)", &strLen); err != nil {
@@ -6,6 +6,8 @@ import (
 	"github.com/c3mb0/clickhouse-go/lib/binary"
 )
 
+// IPv4 represents IPv4 ClickHouse, stored as UInt32: the address is transferred in the little-endian byte order.
+// The values are scanned as the 4-byte form of net.IP.
 type IPv4 struct {
 	base
 }
@@ -15,32 +17,11 @@ func (*IPv4) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return net.IPv4(v[3], v[2], v[1], v[0]), nil
+	return net.IP{v[3], v[2], v[1], v[0]}, nil
 }
 
 func (ip *IPv4) Write(encoder *binary.Encoder, v interface{}) error {
-	var netIP net.IP
-	switch v.(type) {
-	case string:
-		netIP = net.ParseIP(v.(string))
-	case net.IP:
-		netIP = v.(net.IP)
-	case *net.IP:
-		netIP = *(v.(*net.IP))
-	default:
-		return &ErrUnexpectedType{
-			T:      v,
-			Column: ip,
-		}
-	}
-
-	if netIP == nil {
-		return &ErrUnexpectedType{
-			T:      v,
-			Column: ip,
-		}
-	}
-	ip4 := netIP.To4()
+	ip4 := parseIP(v).To4()
 	if ip4 == nil {
 		return &ErrUnexpectedType{
 			T:      v,
@@ -52,3 +33,26 @@ func (ip *IPv4) Write(encoder *binary.Encoder, v interface{}) error {
 	}
 	return nil
 }
+
+func (*IPv4) defaultValue() interface{} {
+	return net.IPv4zero
+}
+
+// parseIP returns the address of the value, nil if the value is not a valid address.
+func parseIP(v interface{}) net.IP {
+	switch v := v.(type) {
+	case string:
+		return net.ParseIP(v)
+	case net.IP:
+		return v
+	case *net.IP:
+		if v != nil {
+			return *v
+		}
+	case *string:
+		if v != nil {
+			return net.ParseIP(*v)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/c3mb0/clickhouse-go/lib/binary"
 )
 
+// IPv6 represents IPv6 ClickHouse, stored as FixedString(16). The IPv4 addresses are written as the IPv4-mapped IPv6 addresses.
+// The values are scanned as the 16-byte form of net.IP.
 type IPv6 struct {
 	base
 }
@@ -19,29 +21,19 @@ func (*IPv6) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
 }
 
 func (ip *IPv6) Write(encoder *binary.Encoder, v interface{}) error {
-	var netIP net.IP
-	switch v.(type) {
-	case string:
-		netIP = net.ParseIP(v.(string))
-	case net.IP:
-		netIP = v.(net.IP)
-	case *net.IP:
-		netIP = *(v.(*net.IP))
-	default:
+	ip16 := parseIP(v).To16()
+	if ip16 == nil {
 		return &ErrUnexpectedType{
 			T:      v,
 			Column: ip,
 		}
 	}
-
-	if netIP == nil {
-		return &ErrUnexpectedType{
-			T:      v,
-			Column: ip,
-		}
-	}
-	if _, err := encoder.Write([]byte(netIP.To16())); err != nil {
+	if _, err := encoder.Write([]byte(ip16)); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (*IPv6) defaultValue() interface{} {
+	return net.IPv6zero
+}
@@ -0,0 +1,294 @@
+package column
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+const (
+	// the serializations of the documents of Object('json'), written before the data of the column
+	jsonSerializationTuple  = 0
+	jsonSerializationString = 1
+)
+
+// JSON represents the experimental Object('json') (or JSON) ClickHouse. The column is transferred as a named tuple
+// of the paths of the documents: the type of the tuple is written before its data, so the documents of a block share
+// the structure inferred from all of them. This is the wire format of ClickHouse 22.x, it may change with the type.
+// The values are scanned as map[string]interface{}, they are written from the JSON encoded documents
+// (string, []byte or json.RawMessage) or from the values encoded by json.Marshal, e.g. maps and structs.
+type JSON struct {
+	base
+	timezone *time.Location
+}
+
+func (j *JSON) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for %s column", j.chType)
+}
+
+func (j *JSON) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for %s column", j.chType)
+}
+
+func (j *JSON) ReadJSON(decoder *binary.Decoder, rows int) ([]interface{}, error) {
+	values := make([]interface{}, rows)
+	if rows == 0 {
+		return values, nil
+	}
+	serialization, err := decoder.UInt8()
+	if err != nil {
+		return nil, err
+	}
+	switch serialization {
+	case jsonSerializationTuple:
+		chType, err := decoder.String()
+		if err != nil {
+			return nil, err
+		}
+		column, err := Factory(j.name, chType, j.timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", j.chType, err)
+		}
+		if tuple, ok := column.(*Tuple); !ok || tuple.names == nil {
+			return nil, fmt.Errorf("%s: unexpected structure %s", j.chType, chType)
+		}
+		return readColumn(column, decoder, rows)
+	case jsonSerializationString:
+		for i := range values {
+			document, err := decoder.String()
+			if err != nil {
+				return nil, err
+			}
+			var value map[string]interface{}
+			if err := json.Unmarshal([]byte(document), &value); err != nil {
+				return nil, fmt.Errorf("%s: %v", j.chType, err)
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported serialization %d", j.chType, serialization)
+}
+
+// writeJSON infers the structure of the documents of the rows and writes them as the named tuple of the structure.
+func (j *JSON) writeJSON(encoder *binary.Encoder, rows []interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var (
+		node      *jsonNode
+		documents = make([]interface{}, len(rows))
+	)
+	for i, row := range rows {
+		document, err := j.decode(row)
+		if err != nil {
+			return err
+		}
+		if node, err = node.merge(document); err != nil {
+			return fmt.Errorf("%s: %v", j.chType, err)
+		}
+		documents[i] = document
+	}
+	if len(node.names) == 0 {
+		return fmt.Errorf("%s: the documents have no fields", j.chType)
+	}
+	chType := node.chType()
+	column, err := Factory(j.name, chType, j.timezone)
+	if err != nil {
+		return fmt.Errorf("%s: %v", j.chType, err)
+	}
+	for i, document := range documents {
+		documents[i] = node.value(document)
+	}
+	if err := encoder.UInt8(jsonSerializationTuple); err != nil {
+		return err
+	}
+	if err := encoder.String(chType); err != nil {
+		return err
+	}
+	return WriteColumn(column, encoder, documents)
+}
+
+// decode returns the JSON object of the row as decoded by encoding/json, the numbers are kept as json.Number.
+func (j *JSON) decode(row interface{}) (map[string]interface{}, error) {
+	var document []byte
+	switch v := row.(type) {
+	case string:
+		document = []byte(v)
+	case []byte:
+		document = v
+	case json.RawMessage:
+		document = v
+	default:
+		if value := reflect.ValueOf(row); !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+			return nil, &ErrUnexpectedType{
+				T:      row,
+				Column: j,
+			}
+		}
+		var err error
+		if document, err = json.Marshal(row); err != nil {
+			return nil, fmt.Errorf("%s: %v", j.chType, err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value map[string]interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("%s: %v", j.chType, err)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%s: the document is not a JSON object", j.chType)
+	}
+	return value, nil
+}
+
+// jsonNode is the structure inferred from the documents: a scalar of the ClickHouse type,
+// an object of the fields, or an array of the elements.
+type jsonNode struct {
+	scalar  string
+	names   []string // the names of the fields of an object, sorted
+	fields  map[string]*jsonNode
+	element *jsonNode // nil for an array of no elements
+	array   bool
+}
+
+// merge returns the structure of both the node and the value, a nil node is the structure of no value.
+func (node *jsonNode) merge(v interface{}) (*jsonNode, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch {
+		case node == nil:
+			node = &jsonNode{fields: make(map[string]*jsonNode)}
+		case node.fields == nil:
+			return nil, fmt.Errorf("an object conflicts with %s", node.chType())
+		}
+		for name, field := range v {
+			if field == nil {
+				// the NULLs are left out as the missing fields
+				continue
+			}
+			merged, err := node.fields[name].merge(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			if _, found := node.fields[name]; !found {
+				node.names = append(node.names, name)
+				sort.Strings(node.names)
+			}
+			node.fields[name] = merged
+		}
+		return node, nil
+	case []interface{}:
+		switch {
+		case node == nil:
+			node = &jsonNode{array: true}
+		case !node.array:
+			return nil, fmt.Errorf("an array conflicts with %s", node.chType())
+		}
+		for _, element := range v {
+			if element == nil {
+				return nil, fmt.Errorf("NULL elements of the arrays are not supported")
+			}
+			merged, err := node.element.merge(element)
+			if err != nil {
+				return nil, err
+			}
+			node.element = merged
+		}
+		return node, nil
+	}
+	var scalar string
+	switch v := v.(type) {
+	case string:
+		scalar = "String"
+	case bool:
+		scalar = "Bool"
+	case json.Number:
+		scalar = "Int64"
+		if _, err := v.Int64(); err != nil {
+			scalar = "Float64"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value %T", v)
+	}
+	switch {
+	case node == nil:
+		return &jsonNode{scalar: scalar}, nil
+	case node.scalar == scalar:
+		return node, nil
+	case node.scalar == "Int64" && scalar == "Float64", node.scalar == "Float64" && scalar == "Int64":
+		return &jsonNode{scalar: "Float64"}, nil
+	}
+	return nil, fmt.Errorf("%s conflicts with %s", scalar, node.chType())
+}
+
+func (node *jsonNode) chType() string {
+	switch {
+	case node.fields != nil:
+		fields := make([]string, 0, len(node.names))
+		for _, name := range node.names {
+			fields = append(fields, "`"+name+"` "+node.fields[name].chType())
+		}
+		return "Tuple(" + strings.Join(fields, ", ") + ")"
+	case node.array && node.element == nil:
+		// the type of the elements is unknown, the arrays are empty
+		return "Array(String)"
+	case node.array:
+		return "Array(" + node.element.chType() + ")"
+	}
+	return node.scalar
+}
+
+// value returns the value of the structure written for the decoded value, the missing fields are the default values.
+func (node *jsonNode) value(v interface{}) interface{} {
+	switch {
+	case node.fields != nil:
+		object, _ := v.(map[string]interface{})
+		value := make(map[string]interface{}, len(node.names))
+		for _, name := range node.names {
+			value[name] = node.fields[name].value(object[name])
+		}
+		return value
+	case node.array:
+		elements, _ := v.([]interface{})
+		value := make([]interface{}, len(elements))
+		for i, element := range elements {
+			value[i] = node.element.value(element)
+		}
+		return value
+	}
+	switch node.scalar {
+	case "String":
+		value, _ := v.(string)
+		return value
+	case "Bool":
+		value, _ := v.(bool)
+		return value
+	case "Int64":
+		number, _ := v.(json.Number)
+		value, _ := number.Int64()
+		return value
+	}
+	number, _ := v.(json.Number)
+	value, _ := strconv.ParseFloat(string(number), 64)
+	return value
+}
+
+func parseJSON(name, chType string, timezone *time.Location) (*JSON, error) {
+	return &JSON{
+		base: base{
+			name:    name,
+			chType:  chType,
+			valueOf: reflect.ValueOf(map[string]interface{}{}),
+		},
+		timezone: timezone,
+	}, nil
+}
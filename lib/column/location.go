@@ -0,0 +1,32 @@
+package column
+
+import "time"
+
+// SetLocation makes the DateTime and DateTime64 columns, those of the arrays, the nullables, the maps,
+// the tuples, the nested and the low cardinality columns included, read their values in the location
+// instead of the timezone of their type or of the server.
+func SetLocation(c Column, loc *time.Location) {
+	switch c := c.(type) {
+	case *DateTime:
+		c.Timezone = loc
+	case *DateTime64:
+		c.Timezone = loc
+	case *Array:
+		SetLocation(c.column, loc)
+	case *Nullable:
+		SetLocation(c.column, loc)
+	case *LowCardinality:
+		SetLocation(c.column, loc)
+	case *Map:
+		SetLocation(c.key, loc)
+		SetLocation(c.value, loc)
+	case *Tuple:
+		for _, column := range c.columns {
+			SetLocation(column, loc)
+		}
+	case *Nested:
+		for _, column := range c.columns {
+			SetLocation(column, loc)
+		}
+	}
+}
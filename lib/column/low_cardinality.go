@@ -0,0 +1,217 @@
+package column
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+const (
+	// sharedDictionariesWithAdditionalKeys is the version of the serialization written before the data of the column.
+	sharedDictionariesWithAdditionalKeys = 1
+
+	lowCardinalityKeyUInt8             = 0
+	lowCardinalityKeyUInt16            = 1
+	lowCardinalityKeyUInt32            = 2
+	lowCardinalityKeyUInt64            = 3
+	lowCardinalityKeyMask              = 0xff
+	lowCardinalityNeedGlobalDictionary = 1 << 8
+	lowCardinalityHasAdditionalKeys    = 1 << 9
+	lowCardinalityNeedUpdateDictionary = 1 << 10
+)
+
+// LowCardinality represents LowCardinality(T) ClickHouse, it is transferred as a dictionary of the distinct values
+// followed by the indexes of the values of the rows in the dictionary. The index key is as narrow as the size of the dictionary allows.
+// For LowCardinality(Nullable(T)) the dictionary holds the values of T and the index 0 stands for NULL.
+// The values are scanned exactly as the values of T.
+type LowCardinality struct {
+	base
+	nullable bool
+	column   Column
+}
+
+func (lc *LowCardinality) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for LowCardinality(T) column")
+}
+
+func (lc *LowCardinality) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for LowCardinality(T) column")
+}
+
+// Nullable reports whether the column is LowCardinality(Nullable(T)).
+func (lc *LowCardinality) Nullable() bool {
+	return lc.nullable
+}
+
+func (lc *LowCardinality) ReadLowCardinality(decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	values := make([]interface{}, rows)
+	if rows == 0 {
+		return values, nil
+	}
+	version, err := decoder.UInt64()
+	if err != nil {
+		return nil, err
+	}
+	if version != sharedDictionariesWithAdditionalKeys {
+		return nil, fmt.Errorf("LowCardinality(T): unsupported serialization version %d", version)
+	}
+	serializationType, err := decoder.UInt64()
+	if err != nil {
+		return nil, err
+	}
+	if serializationType&lowCardinalityNeedGlobalDictionary != 0 {
+		return nil, fmt.Errorf("LowCardinality(T): global dictionary is not supported")
+	}
+	if serializationType&lowCardinalityHasAdditionalKeys == 0 {
+		return nil, fmt.Errorf("LowCardinality(T): additional keys are missing")
+	}
+	size, err := decoder.UInt64()
+	if err != nil {
+		return nil, err
+	}
+	dictionary, err := readColumn(lc.column, decoder, int(size))
+	if err != nil {
+		return nil, err
+	}
+	count, err := decoder.UInt64()
+	if err != nil {
+		return nil, err
+	}
+	if int(count) != rows {
+		return nil, fmt.Errorf("LowCardinality(T): got %d indexes for %d rows", count, rows)
+	}
+	for i := range values {
+		var index uint64
+		switch serializationType & lowCardinalityKeyMask {
+		case lowCardinalityKeyUInt8:
+			var v uint8
+			v, err = decoder.UInt8()
+			index = uint64(v)
+		case lowCardinalityKeyUInt16:
+			var v uint16
+			v, err = decoder.UInt16()
+			index = uint64(v)
+		case lowCardinalityKeyUInt32:
+			var v uint32
+			v, err = decoder.UInt32()
+			index = uint64(v)
+		case lowCardinalityKeyUInt64:
+			index, err = decoder.UInt64()
+		default:
+			return nil, fmt.Errorf("LowCardinality(T): unsupported index type %d", serializationType&lowCardinalityKeyMask)
+		}
+		switch {
+		case err != nil:
+			return nil, err
+		case index >= size:
+			return nil, fmt.Errorf("LowCardinality(T): index %d is out of the dictionary of %d values", index, size)
+		case lc.nullable && index == 0:
+			values[i] = nil
+		default:
+			values[i] = dictionary[index]
+		}
+	}
+	return values, nil
+}
+
+// writeLowCardinality builds the dictionary of the values of the rows, the values are told apart by their encoding.
+func (lc *LowCardinality) writeLowCardinality(encoder *binary.Encoder, rows []interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var (
+		buf        bytes.Buffer
+		dictionary = binary.NewEncoder(&buf)
+		size       uint64
+		keys       = make(map[string]uint64)
+		indexes    = make([]uint64, len(rows))
+	)
+	if lc.nullable {
+		// the index 0 stands for NULL, the default value takes its place in the dictionary
+		if err := lc.column.Write(dictionary, lc.column.defaultValue()); err != nil {
+			return err
+		}
+		size++
+	}
+	for i, v := range rows {
+		if value := reflect.ValueOf(v); lc.nullable && (v == nil || (value.Kind() == reflect.Ptr && value.IsNil())) {
+			continue
+		}
+		start := buf.Len()
+		if err := lc.column.Write(dictionary, v); err != nil {
+			return err
+		}
+		key := string(buf.Bytes()[start:])
+		if index, found := keys[key]; found {
+			buf.Truncate(start)
+			indexes[i] = index
+			continue
+		}
+		keys[key], indexes[i] = size, size
+		size++
+	}
+	var (
+		key      uint64 = lowCardinalityKeyUInt64
+		writeKey        = encoder.UInt64
+	)
+	switch {
+	case size <= math.MaxUint8+1:
+		key, writeKey = lowCardinalityKeyUInt8, func(v uint64) error { return encoder.UInt8(uint8(v)) }
+	case size <= math.MaxUint16+1:
+		key, writeKey = lowCardinalityKeyUInt16, func(v uint64) error { return encoder.UInt16(uint16(v)) }
+	case size <= math.MaxUint32+1:
+		key, writeKey = lowCardinalityKeyUInt32, func(v uint64) error { return encoder.UInt32(uint32(v)) }
+	}
+	for _, v := range []uint64{
+		sharedDictionariesWithAdditionalKeys,
+		key | lowCardinalityHasAdditionalKeys | lowCardinalityNeedUpdateDictionary,
+		size,
+	} {
+		if err := encoder.UInt64(v); err != nil {
+			return err
+		}
+	}
+	if _, err := encoder.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := encoder.UInt64(uint64(len(rows))); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := writeKey(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseLowCardinality(name, chType string, timezone *time.Location) (*LowCardinality, error) {
+	if !strings.HasPrefix(chType, "LowCardinality(") || !strings.HasSuffix(chType, ")") {
+		return nil, fmt.Errorf("invalid LowCardinality column type: %s", chType)
+	}
+	column, err := Factory(name, chType[15:len(chType)-1], timezone)
+	if err != nil {
+		return nil, fmt.Errorf("LowCardinality(T): %v", err)
+	}
+	lc := &LowCardinality{
+		base: base{
+			name:   name,
+			chType: chType,
+		},
+		column: column,
+	}
+	if nullable, ok := column.(*Nullable); ok {
+		lc.nullable, lc.column = true, nullable.column
+	}
+	switch lc.column.(type) {
+	case *Array, *Map, *Point, *LowCardinality, *JSON:
+		return nil, fmt.Errorf("LowCardinality(T): %s can not be inside LowCardinality", lc.column.CHType())
+	}
+	lc.valueOf = reflect.Zero(lc.column.ScanType())
+	return lc, nil
+}
@@ -0,0 +1,303 @@
+package column
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Map represents Map(K, V) ClickHouse, it is transferred as Array(Tuple(K, V)):
+// the offsets of the rows followed by the column of the keys and the column of the values.
+// The values are scanned as map[K]V, or map[K]*V if V is Nullable.
+type Map struct {
+	base
+	key   Column
+	value Column
+}
+
+func (m *Map) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for Map(K, V) column")
+}
+
+func (m *Map) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for Map(K, V) column")
+}
+
+func (m *Map) ReadMap(decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	offsets := make([]uint64, rows)
+	for i := range offsets {
+		if offsets[i], err = decoder.UInt64(); err != nil {
+			return nil, err
+		}
+	}
+	var size int
+	if rows > 0 {
+		size = int(offsets[rows-1])
+	}
+	keys, err := readColumn(m.key, decoder, size)
+	if err != nil {
+		return nil, err
+	}
+	values, err := readColumn(m.value, decoder, size)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		start  int
+		result = make([]interface{}, rows)
+	)
+	for i, offset := range offsets {
+		end := int(offset)
+		value := reflect.MakeMapWithSize(m.valueOf.Type(), end-start)
+		for j := start; j < end; j++ {
+			value.SetMapIndex(reflect.ValueOf(keys[j]), m.mapValue(values[j]))
+		}
+		result[i] = value.Interface()
+		start = end
+	}
+	return result, nil
+}
+
+func (m *Map) mapValue(v interface{}) reflect.Value {
+	if _, ok := m.value.(*Nullable); !ok {
+		return reflect.ValueOf(v)
+	}
+	if v == nil {
+		return reflect.Zero(m.valueOf.Type().Elem())
+	}
+	ptr := reflect.New(m.value.ScanType())
+	ptr.Elem().Set(reflect.ValueOf(v))
+	return ptr
+}
+
+// writeMap writes the offsets, the keys and the values of the maps,
+// the keys of a map are sorted so that the encoding is deterministic.
+func (m *Map) writeMap(encoder *binary.Encoder, rows []interface{}) error {
+	var (
+		offset uint64
+		keys   []interface{}
+		values []interface{}
+	)
+	for _, row := range rows {
+		value := reflect.ValueOf(row)
+		if value.Kind() != reflect.Map {
+			return &ErrUnexpectedType{
+				T:      row,
+				Column: m,
+			}
+		}
+		mapKeys := value.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return lessValue(mapKeys[i], mapKeys[j])
+		})
+		for _, key := range mapKeys {
+			keys = append(keys, key.Interface())
+			values = append(values, value.MapIndex(key).Interface())
+		}
+		offset += uint64(len(mapKeys))
+		if err := encoder.UInt64(offset); err != nil {
+			return err
+		}
+	}
+	if err := WriteColumn(m.key, encoder, keys); err != nil {
+		return err
+	}
+	return WriteColumn(m.value, encoder, values)
+}
+
+func lessValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// readColumn reads the values of the rows of the column, which is laid out column by column.
+func readColumn(column Column, decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	switch column := column.(type) {
+	case *Array:
+		return column.ReadArray(decoder, rows)
+	case *Nullable:
+		return column.ReadNull(decoder, rows)
+	case *Map:
+		return column.ReadMap(decoder, rows)
+	case *Point:
+		return column.ReadPoint(decoder, rows)
+	case *LowCardinality:
+		return column.ReadLowCardinality(decoder, rows)
+	case *Nested:
+		return column.ReadNested(decoder, rows)
+	case *Tuple:
+		return column.ReadTuple(decoder, rows)
+	case *JSON:
+		return column.ReadJSON(decoder, rows)
+	}
+	values := make([]interface{}, rows)
+	for i := range values {
+		if values[i], err = column.Read(decoder, false); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// NeedsColumnarWrite reports whether the column can't be written row by row
+// and the values of all the rows have to be written at once by WriteColumn.
+func NeedsColumnarWrite(column Column) bool {
+	switch column := column.(type) {
+	case *Map, *Point, *LowCardinality, *Nested, *Tuple, *JSON:
+		return true
+	case *Array:
+		if _, ok := column.column.(*Nullable); ok {
+			// the NULL map of the elements follows the offsets
+			return true
+		}
+		return NeedsColumnarWrite(column.column)
+	}
+	return false
+}
+
+// WriteColumn writes the values of the rows of the column laid out column by column.
+func WriteColumn(column Column, encoder *binary.Encoder, values []interface{}) error {
+	switch column := column.(type) {
+	case *Map:
+		return column.writeMap(encoder, values)
+	case *Point:
+		return column.writePoint(encoder, values)
+	case *LowCardinality:
+		return column.writeLowCardinality(encoder, values)
+	case *Nested:
+		return column.writeNested(encoder, values)
+	case *Tuple:
+		return column.writeTuple(encoder, values)
+	case *JSON:
+		return column.writeJSON(encoder, values)
+	case *Array:
+		for level := 0; level < column.depth; level++ {
+			var (
+				offset   uint64
+				elements []interface{}
+			)
+			for _, v := range values {
+				value := reflect.ValueOf(v)
+				if value.Kind() != reflect.Slice {
+					return fmt.Errorf("unsupported Array(T) type [%T]", v)
+				}
+				for i := 0; i < value.Len(); i++ {
+					elements = append(elements, value.Index(i).Interface())
+				}
+				offset += uint64(value.Len())
+				if err := encoder.UInt64(offset); err != nil {
+					return err
+				}
+			}
+			values = elements
+		}
+		return WriteColumn(column.column, encoder, values)
+	case *Nullable:
+		var (
+			nulls   = make([]byte, len(values))
+			written = make([]interface{}, len(values))
+		)
+		for i, v := range values {
+			var isNull bool
+			if written[i], isNull = column.value(v); isNull {
+				nulls[i] = 1
+			}
+		}
+		if _, err := encoder.Write(nulls); err != nil {
+			return err
+		}
+		for _, v := range written {
+			if err := column.column.Write(encoder, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, v := range values {
+		if err := column.Write(encoder, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseMap(name, chType string, timezone *time.Location) (*Map, error) {
+	if !strings.HasPrefix(chType, "Map(") || !strings.HasSuffix(chType, ")") {
+		return nil, fmt.Errorf("invalid Map column type: %s", chType)
+	}
+	types := SplitTypes(chType[4 : len(chType)-1])
+	if len(types) != 2 {
+		return nil, fmt.Errorf("invalid Map column type: %s", chType)
+	}
+	key, err := Factory(name, types[0], timezone)
+	if err != nil {
+		return nil, fmt.Errorf("Map(K, V): %v", err)
+	}
+	if !key.ScanType().Comparable() {
+		return nil, fmt.Errorf("Map(K, V): unsupported key type %s", types[0])
+	}
+	value, err := Factory(name, types[1], timezone)
+	if err != nil {
+		return nil, fmt.Errorf("Map(K, V): %v", err)
+	}
+	for _, column := range []Column{key, value} {
+		switch column.(type) {
+		case *LowCardinality, *JSON:
+			return nil, fmt.Errorf("Map(K, V): %s is only supported at the top level", column.CHType())
+		}
+	}
+	valueType := value.ScanType()
+	if _, ok := value.(*Nullable); ok {
+		valueType = reflect.PtrTo(valueType)
+	}
+	return &Map{
+		base: base{
+			name:    name,
+			chType:  chType,
+			valueOf: reflect.MakeMap(reflect.MapOf(key.ScanType(), valueType)),
+		},
+		key:   key,
+		value: value,
+	}, nil
+}
+
+// SplitTypes splits the comma separated list of types, e.g. the elements of a Tuple or the columns of a structure,
+// the commas within the parentheses and the quoted strings, e.g. Enum8('a,(' = 1), are skipped.
+func SplitTypes(list string) []string {
+	var (
+		types  []string
+		depth  int
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			types = append(types, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(types, strings.TrimSpace(list[start:]))
+}
@@ -0,0 +1,167 @@
+package column
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Nested represents Nested(name T, ...) ClickHouse, as the server sends it when flatten_nested is disabled.
+// It is transferred as an array of tuples: the offsets of the rows followed by the columns of the fields.
+// The values are scanned as []map[string]interface{} keyed by the names of the fields, they are written
+// from a slice of such maps or from a slice of structs whose fields are in the order of the column definition.
+type Nested struct {
+	base
+	names   []string
+	columns []Column
+}
+
+func (nested *Nested) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for Nested column")
+}
+
+func (nested *Nested) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for Nested column")
+}
+
+// Names returns the names of the fields in the order of the column definition.
+func (nested *Nested) Names() []string {
+	return nested.names
+}
+
+func (nested *Nested) ReadNested(decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	offsets := make([]uint64, rows)
+	for i := range offsets {
+		if offsets[i], err = decoder.UInt64(); err != nil {
+			return nil, err
+		}
+	}
+	var size int
+	if rows > 0 {
+		size = int(offsets[rows-1])
+	}
+	fields := make([][]interface{}, len(nested.columns))
+	for i, column := range nested.columns {
+		if fields[i], err = readColumn(column, decoder, size); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		start  int
+		result = make([]interface{}, rows)
+	)
+	for i, offset := range offsets {
+		end := int(offset)
+		value := make([]map[string]interface{}, 0, end-start)
+		for j := start; j < end; j++ {
+			element := make(map[string]interface{}, len(nested.names))
+			for k, name := range nested.names {
+				element[name] = fields[k][j]
+			}
+			value = append(value, element)
+		}
+		result[i] = value
+		start = end
+	}
+	return result, nil
+}
+
+func (nested *Nested) writeNested(encoder *binary.Encoder, rows []interface{}) error {
+	var (
+		offset uint64
+		fields = make([][]interface{}, len(nested.columns))
+	)
+	for _, row := range rows {
+		value := reflect.ValueOf(row)
+		if value.Kind() != reflect.Slice {
+			return &ErrUnexpectedType{
+				T:      row,
+				Column: nested,
+			}
+		}
+		for i := 0; i < value.Len(); i++ {
+			element, err := nested.fields(value.Index(i))
+			if err != nil {
+				return err
+			}
+			for k, v := range element {
+				fields[k] = append(fields[k], v)
+			}
+		}
+		offset += uint64(value.Len())
+		if err := encoder.UInt64(offset); err != nil {
+			return err
+		}
+	}
+	for i, column := range nested.columns {
+		if err := WriteColumn(column, encoder, fields[i]); err != nil {
+			return fmt.Errorf("Nested: field %s: %v", nested.names[i], err)
+		}
+	}
+	return nil
+}
+
+// fields returns the values of the fields of the element in the order of the column definition.
+func (nested *Nested) fields(element reflect.Value) ([]interface{}, error) {
+	for element.Kind() == reflect.Interface || element.Kind() == reflect.Ptr {
+		element = element.Elem()
+	}
+	values := make([]interface{}, len(nested.names))
+	switch element.Kind() {
+	case reflect.Map:
+		if element.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("Nested: unsupported element type %s", element.Type())
+		}
+		for i, name := range nested.names {
+			value := element.MapIndex(reflect.ValueOf(name).Convert(element.Type().Key()))
+			if !value.IsValid() {
+				return nil, fmt.Errorf("Nested: field %s is missing", name)
+			}
+			values[i] = value.Interface()
+		}
+	case reflect.Struct:
+		if element.NumField() != len(nested.names) {
+			return nil, fmt.Errorf("Nested: %s has %d fields, the column has %d", element.Type(), element.NumField(), len(nested.names))
+		}
+		for i := range nested.names {
+			values[i] = element.Field(i).Interface()
+		}
+	default:
+		return nil, fmt.Errorf("Nested: unsupported element type %s", element.Type())
+	}
+	return values, nil
+}
+
+func parseNested(name, chType string, timezone *time.Location) (*Nested, error) {
+	if !strings.HasPrefix(chType, "Nested(") || !strings.HasSuffix(chType, ")") {
+		return nil, fmt.Errorf("invalid Nested column type: %s", chType)
+	}
+	nested := &Nested{
+		base: base{
+			name:    name,
+			chType:  chType,
+			valueOf: reflect.ValueOf([]map[string]interface{}{}),
+		},
+	}
+	for _, field := range SplitTypes(chType[7 : len(chType)-1]) {
+		parts := strings.SplitN(field, " ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid Nested column type: %s", chType)
+		}
+		fieldName := strings.Trim(parts[0], "`")
+		column, err := Factory(name+"."+fieldName, strings.TrimSpace(parts[1]), timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Nested: %v", err)
+		}
+		switch column.(type) {
+		case *LowCardinality, *JSON:
+			return nil, fmt.Errorf("Nested: %s is only supported at the top level", column.CHType())
+		}
+		nested.names = append(nested.names, fieldName)
+		nested.columns = append(nested.columns, column)
+	}
+	return nested, nil
+}
@@ -1,6 +1,7 @@
 package column
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
 	"time"
@@ -51,16 +52,31 @@ func (null *Nullable) ReadNull(decoder *binary.Decoder, rows int) (_ []interface
 	return values, nil
 }
 func (null *Nullable) WriteNull(nulls, encoder *binary.Encoder, v interface{}) error {
-	if value := reflect.ValueOf(v); v == nil || (value.Kind() == reflect.Ptr && value.IsNil()) {
-		if _, err := nulls.Write([]byte{1}); err != nil {
-			return err
-		}
-		return null.column.Write(encoder, null.column.defaultValue())
+	value, isNull := null.value(v)
+	flag := []byte{0}
+	if isNull {
+		flag[0] = 1
 	}
-	if _, err := nulls.Write([]byte{0}); err != nil {
+	if _, err := nulls.Write(flag); err != nil {
 		return err
 	}
-	return null.column.Write(encoder, v)
+	return null.column.Write(encoder, value)
+}
+
+// value returns the value of T written for v and whether v is NULL: the default value of T for nil and the nil pointers,
+// the value pointed to for the other pointers, so that the columns of T are written from *T. The pointers to the structs
+// (e.g. *big.Int, *time.Time) and the pointers implementing encoding.BinaryMarshaler are left to the column of T.
+func (null *Nullable) value(v interface{}) (interface{}, bool) {
+	value := reflect.ValueOf(v)
+	switch {
+	case v == nil, value.Kind() == reflect.Ptr && value.IsNil():
+		return null.column.defaultValue(), true
+	case value.Kind() == reflect.Ptr && value.Elem().Kind() != reflect.Struct:
+		if _, ok := v.(encoding.BinaryMarshaler); !ok {
+			return value.Elem().Interface(), false
+		}
+	}
+	return v, false
 }
 
 func parseNullable(name, chType string, timezone *time.Location) (*Nullable, error) {
@@ -71,6 +87,14 @@ func parseNullable(name, chType string, timezone *time.Location) (*Nullable, err
 	if err != nil {
 		return nil, fmt.Errorf("Nullable(T): %v", err)
 	}
+	switch column.(type) {
+	case *Map:
+		return nil, fmt.Errorf("Nullable(T): Map(K, V) can not be inside Nullable")
+	case *LowCardinality:
+		return nil, fmt.Errorf("Nullable(T): LowCardinality(T) can not be inside Nullable")
+	case *JSON:
+		return nil, fmt.Errorf("Nullable(T): %s can not be inside Nullable", column.CHType())
+	}
 	return &Nullable{
 		base: base{
 			name:   name,
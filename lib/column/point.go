@@ -0,0 +1,87 @@
+package column
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Point represents Point ClickHouse, an alias of Tuple(Float64, Float64) scanned as [2]float64.
+// The tuple is transferred element by element: the X coordinates of the rows followed by the Y coordinates.
+// Ring, Polygon and MultiPolygon are the arrays of Point of depth 1, 2 and 3.
+type Point struct {
+	base
+}
+
+func (point *Point) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for Point column")
+}
+
+func (point *Point) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for Point column")
+}
+
+func (point *Point) ReadPoint(decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	points := make([][2]float64, rows)
+	for coordinate := 0; coordinate < 2; coordinate++ {
+		for i := range points {
+			if points[i][coordinate], err = decoder.Float64(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	values := make([]interface{}, rows)
+	for i, v := range points {
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (point *Point) writePoint(encoder *binary.Encoder, rows []interface{}) error {
+	points := make([][2]float64, len(rows))
+	for i, row := range rows {
+		v, ok := row.([2]float64)
+		if !ok {
+			return &ErrUnexpectedType{
+				T:      row,
+				Column: point,
+			}
+		}
+		points[i] = v
+	}
+	for coordinate := 0; coordinate < 2; coordinate++ {
+		for _, v := range points {
+			if err := encoder.Float64(v[coordinate]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var geoTypes = map[string]string{
+	"Ring":         "Array(Point)",
+	"Polygon":      "Array(Array(Point))",
+	"MultiPolygon": "Array(Array(Array(Point)))",
+}
+
+func parseGeo(name, chType string, timezone *time.Location) (Column, error) {
+	if chType == "Point" {
+		return &Point{
+			base: base{
+				name:    name,
+				chType:  chType,
+				valueOf: reflect.ValueOf([2]float64{}),
+			},
+		}, nil
+	}
+	array, err := parseArray(name, geoTypes[chType], timezone)
+	if err != nil {
+		return nil, err
+	}
+	// the geo types are scanned as the nested slices of the points, e.g. [][][2]float64 for Polygon
+	array.chType = chType
+	return array, nil
+}
@@ -0,0 +1,181 @@
+package column
+
+import (
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// SliceReader is implemented by the columns whose rows can be read at once into a slice of their Go type,
+// e.g. []int64 for Int64 or []time.Time for DateTime, without boxing every value into an interface{} as Read does.
+type SliceReader interface {
+	ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error)
+}
+
+func (Int8) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]int8, rows)
+	for i := range values {
+		v, err := decoder.Int8()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (Int16) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]int16, rows)
+	for i := range values {
+		v, err := decoder.Int16()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (Int32) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]int32, rows)
+	for i := range values {
+		v, err := decoder.Int32()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (Int64) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]int64, rows)
+	for i := range values {
+		v, err := decoder.Int64()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (UInt8) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]uint8, rows)
+	for i := range values {
+		v, err := decoder.UInt8()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (UInt16) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]uint16, rows)
+	for i := range values {
+		v, err := decoder.UInt16()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (UInt32) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]uint32, rows)
+	for i := range values {
+		v, err := decoder.UInt32()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (UInt64) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]uint64, rows)
+	for i := range values {
+		v, err := decoder.UInt64()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (Float32) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]float32, rows)
+	for i := range values {
+		v, err := decoder.Float32()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (Float64) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]float64, rows)
+	for i := range values {
+		v, err := decoder.Float64()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (String) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]string, rows)
+	for i := range values {
+		v, err := decoder.String()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (dt *Date) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]time.Time, rows)
+	for i := range values {
+		days, err := decoder.Int16()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = time.Unix(int64(days)*24*3600-dt.offset, 0).In(dt.Timezone)
+	}
+	return values, nil
+}
+
+func (Date32) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]time.Time, rows)
+	for i := range values {
+		days, err := decoder.Int32()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = time.Unix(int64(days)*24*3600, 0).UTC()
+	}
+	return values, nil
+}
+
+func (dt *DateTime) ReadSlice(decoder *binary.Decoder, rows int) (interface{}, error) {
+	values := make([]time.Time, rows)
+	for i := range values {
+		sec, err := decoder.Int32()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = time.Unix(int64(sec), 0).In(dt.Timezone)
+	}
+	return values, nil
+}
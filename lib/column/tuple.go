@@ -0,0 +1,196 @@
+package column
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+)
+
+// Tuple represents Tuple(T1, T2, ...) and the named Tuple(a T1, b T2, ...) ClickHouse.
+// The tuple is transferred element by element: the column of the first elements of the rows, then of the second ones, etc.
+// The values are scanned as []interface{}, or map[string]interface{} keyed by the names of the elements of a named tuple.
+// They are written from []interface{}, map[string]interface{} for a named tuple, or a struct: its exported fields are
+// matched in the declared order, or by the `ch` tag (or the name of the field) for a named tuple.
+type Tuple struct {
+	base
+	names   []string // the names of the elements, nil if the tuple is not named
+	columns []Column
+}
+
+func (tuple *Tuple) Read(decoder *binary.Decoder, isNull bool) (interface{}, error) {
+	return nil, fmt.Errorf("do not use Read method for Tuple column")
+}
+
+func (tuple *Tuple) Write(encoder *binary.Encoder, v interface{}) error {
+	return fmt.Errorf("do not use Write method for Tuple column")
+}
+
+// Names returns the names of the elements of a named tuple in the declared order, nil if the tuple is not named.
+func (tuple *Tuple) Names() []string {
+	return tuple.names
+}
+
+func (tuple *Tuple) ReadTuple(decoder *binary.Decoder, rows int) (_ []interface{}, err error) {
+	elements := make([][]interface{}, len(tuple.columns))
+	for i, column := range tuple.columns {
+		if elements[i], err = readColumn(column, decoder, rows); err != nil {
+			return nil, err
+		}
+	}
+	values := make([]interface{}, rows)
+	for row := range values {
+		if tuple.names != nil {
+			value := make(map[string]interface{}, len(tuple.names))
+			for i, name := range tuple.names {
+				value[name] = elements[i][row]
+			}
+			values[row] = value
+			continue
+		}
+		value := make([]interface{}, len(tuple.columns))
+		for i := range value {
+			value[i] = elements[i][row]
+		}
+		values[row] = value
+	}
+	return values, nil
+}
+
+func (tuple *Tuple) writeTuple(encoder *binary.Encoder, rows []interface{}) error {
+	elements := make([][]interface{}, len(tuple.columns))
+	for _, row := range rows {
+		value, err := tuple.elements(row)
+		if err != nil {
+			return err
+		}
+		for i, v := range value {
+			elements[i] = append(elements[i], v)
+		}
+	}
+	for i, column := range tuple.columns {
+		if err := WriteColumn(column, encoder, elements[i]); err != nil {
+			return fmt.Errorf("%s: element %d: %v", tuple.chType, i, err)
+		}
+	}
+	return nil
+}
+
+// elements returns the elements of the value in the declared order.
+func (tuple *Tuple) elements(v interface{}) ([]interface{}, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	elements := make([]interface{}, len(tuple.columns))
+	switch {
+	case value.Kind() == reflect.Slice, value.Kind() == reflect.Array:
+		if value.Len() != len(elements) {
+			return nil, fmt.Errorf("%s: got %d elements, expected %d", tuple.chType, value.Len(), len(elements))
+		}
+		for i := range elements {
+			elements[i] = value.Index(i).Interface()
+		}
+	case value.Kind() == reflect.Map && tuple.names != nil && value.Type().Key().Kind() == reflect.String:
+		if value.Len() != len(elements) {
+			return nil, fmt.Errorf("%s: got %d elements, expected %d", tuple.chType, value.Len(), len(elements))
+		}
+		for i, name := range tuple.names {
+			element := value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+			if !element.IsValid() {
+				return nil, fmt.Errorf("%s: element %d (%s) is missing", tuple.chType, i, name)
+			}
+			elements[i] = element.Interface()
+		}
+	case value.Kind() == reflect.Struct:
+		fields := TupleFields(value.Type())
+		if len(fields) != len(elements) {
+			return nil, fmt.Errorf("%s: %s has %d fields, expected %d", tuple.chType, value.Type(), len(fields), len(elements))
+		}
+		for i := range elements {
+			field := fields[i]
+			if tuple.names != nil {
+				var found bool
+				if field, found = TupleField(value.Type(), tuple.names[i]); !found {
+					return nil, fmt.Errorf("%s: element %d (%s) is missing in %s", tuple.chType, i, tuple.names[i], value.Type())
+				}
+			}
+			elements[i] = value.FieldByIndex(field.Index).Interface()
+		}
+	default:
+		return nil, &ErrUnexpectedType{
+			T:      v,
+			Column: tuple,
+		}
+	}
+	return elements, nil
+}
+
+// TupleFields returns the exported fields of the struct, the fields a tuple is matched with.
+func TupleFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.PkgPath == "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// TupleField returns the exported field of the struct matched with the element of a named tuple:
+// the field tagged `ch:"name"`, or the field of the name (case-insensitively) if none is tagged so.
+func TupleField(t reflect.Type, name string) (reflect.StructField, bool) {
+	fields := TupleFields(t)
+	for _, field := range fields {
+		if field.Tag.Get("ch") == name {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if _, tagged := field.Tag.Lookup("ch"); !tagged && strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func parseTuple(name, chType string, timezone *time.Location) (*Tuple, error) {
+	if !strings.HasPrefix(chType, "Tuple(") || !strings.HasSuffix(chType, ")") {
+		return nil, fmt.Errorf("invalid Tuple column type: %s", chType)
+	}
+	tuple := &Tuple{
+		base: base{
+			name:    name,
+			chType:  chType,
+			valueOf: reflect.ValueOf([]interface{}{}),
+		},
+	}
+	for i, element := range SplitTypes(chType[6 : len(chType)-1]) {
+		// the name of an element comes before its type: the space precedes the parameters of the type, if any
+		elementType, named := element, false
+		if space := strings.IndexByte(element, ' '); space != -1 {
+			if paren := strings.IndexByte(element, '('); paren == -1 || space < paren {
+				elementType, named = strings.TrimSpace(element[space+1:]), true
+				tuple.names = append(tuple.names, strings.Trim(element[:space], "`"))
+			}
+		}
+		if named != (len(tuple.names) != 0) || (named && len(tuple.names) != i+1) {
+			return nil, fmt.Errorf("invalid Tuple column type: %s", chType)
+		}
+		column, err := Factory(name, elementType, timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Tuple: %v", err)
+		}
+		switch column.(type) {
+		case *LowCardinality, *JSON:
+			return nil, fmt.Errorf("Tuple: %s is only supported at the top level", column.CHType())
+		}
+		tuple.columns = append(tuple.columns, column)
+	}
+	if tuple.names != nil {
+		tuple.valueOf = reflect.ValueOf(map[string]interface{}{})
+	}
+	return tuple, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/binary"
 	"github.com/c3mb0/clickhouse-go/lib/column"
@@ -15,12 +16,21 @@ import (
 type offset [][]int
 
 type Block struct {
-	Values     [][]interface{}
+	Values [][]interface{}
+	// Slices are the values of the columns read at once into the slices of their Go types, e.g. []int64 for Int64,
+	// if the block is read with Columnar; the Values of such a column are nil, see column.SliceReader
+	Slices []interface{}
+	// Columnar makes Read read the columns that are column.SliceReader into Slices instead of Values
+	Columnar bool
+	// Location, if not nil, is the location of the DateTime and DateTime64 values read instead of the timezone
+	// of their columns or of the server, see column.SetLocation
+	Location   *time.Location
 	Columns    []column.Column
 	NumRows    uint64
 	NumColumns uint64
 	offsets    []offset
 	buffers    []*buffer
+	columnar   [][]interface{} // rows of the columns written by column.WriteColumn
 	info       blockInfo
 }
 
@@ -40,6 +50,40 @@ func (block *Block) ColumnNames() []string {
 	return names
 }
 
+// Value returns the value of the column at the row, boxed from Slices if the column is read into a slice.
+func (block *Block) Value(column, row int) interface{} {
+	if block.Slices == nil || block.Slices[column] == nil {
+		return block.Values[column][row]
+	}
+	switch values := block.Slices[column].(type) {
+	case []int8:
+		return values[row]
+	case []int16:
+		return values[row]
+	case []int32:
+		return values[row]
+	case []int64:
+		return values[row]
+	case []uint8:
+		return values[row]
+	case []uint16:
+		return values[row]
+	case []uint32:
+		return values[row]
+	case []uint64:
+		return values[row]
+	case []float32:
+		return values[row]
+	case []float64:
+		return values[row]
+	case []string:
+		return values[row]
+	case []time.Time:
+		return values[row]
+	}
+	return reflect.ValueOf(block.Slices[column]).Index(row).Interface()
+}
+
 func (block *Block) Read(serverInfo *ServerInfo, decoder *binary.Decoder) (err error) {
 	if err = block.info.read(decoder); err != nil {
 		return err
@@ -73,7 +117,20 @@ func (block *Block) Read(serverInfo *ServerInfo, decoder *binary.Decoder) (err e
 		if err != nil {
 			return err
 		}
+		if block.Location != nil {
+			column.SetLocation(c, block.Location)
+		}
 		block.Columns = append(block.Columns, c)
+		if reader, ok := c.(column.SliceReader); ok && block.Columnar {
+			if block.Slices == nil {
+				block.Slices = make([]interface{}, block.NumColumns)
+			}
+			if block.Slices[i], err = reader.ReadSlice(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+			block.Values[i] = nil
+			continue
+		}
 		switch column := c.(type) {
 		case *column.Array:
 			if block.Values[i], err = column.ReadArray(decoder, int(block.NumRows)); err != nil {
@@ -83,6 +140,30 @@ func (block *Block) Read(serverInfo *ServerInfo, decoder *binary.Decoder) (err e
 			if block.Values[i], err = column.ReadNull(decoder, int(block.NumRows)); err != nil {
 				return err
 			}
+		case *column.Map:
+			if block.Values[i], err = column.ReadMap(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+		case *column.Point:
+			if block.Values[i], err = column.ReadPoint(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+		case *column.LowCardinality:
+			if block.Values[i], err = column.ReadLowCardinality(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+		case *column.Nested:
+			if block.Values[i], err = column.ReadNested(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+		case *column.Tuple:
+			if block.Values[i], err = column.ReadTuple(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
+		case *column.JSON:
+			if block.Values[i], err = column.ReadJSON(decoder, int(block.NumRows)); err != nil {
+				return err
+			}
 		default:
 			for row := 0; row < int(block.NumRows); row++ {
 				if value, err = column.Read(decoder, false); err != nil {
@@ -115,9 +196,8 @@ func (block *Block) writeArray(column column.Column, value Value, num, level int
 			}
 		}
 	default:
-		if err := column.Write(block.buffers[num].Column, value.Interface()); err != nil {
-			return err
-		}
+		// the value is shallower than the column, its offsets can't be written
+		return fmt.Errorf("unsupported Array(T) type [%T] for %s", value.Interface(), column.CHType())
 	}
 	return nil
 }
@@ -130,33 +210,94 @@ func (block *Block) AppendRow(args []driver.Value) error {
 	{
 		block.NumRows++
 	}
-	for num, c := range block.Columns {
-		switch column := c.(type) {
-		case *column.Array:
-			value := reflect.ValueOf(args[num])
-			if value.Kind() != reflect.Slice {
-				return fmt.Errorf("unsupported Array(T) type [%T]", value.Interface())
-			}
-			if err := block.writeArray(c, newValue(value), num, 1); err != nil {
-				return err
-			}
-		case *column.Nullable:
-			if err := column.WriteNull(block.buffers[num].Offset, block.buffers[num].Column, args[num]); err != nil {
-				return err
-			}
-		default:
-			if err := column.Write(block.buffers[num].Column, args[num]); err != nil {
-				return err
-			}
+	for num := range block.Columns {
+		if err := block.AppendColumn(num, args[num]); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// ColumnMark is the size of the values appended to a column of a block, see Mark.
+type ColumnMark struct {
+	offset   int
+	column   int
+	offsets  []int
+	columnar int
+}
+
+// Mark returns the mark of the values appended to the column num so far, the block has to be reserved.
+func (block *Block) Mark(num int) ColumnMark {
+	mark := ColumnMark{
+		offset:   block.buffers[num].offsetBuffer.Len(),
+		column:   block.buffers[num].columnBuffer.Len(),
+		columnar: len(block.columnar[num]),
+	}
+	for _, offsets := range block.offsets[num] {
+		mark.offsets = append(mark.offsets, len(offsets))
+	}
+	return mark
+}
+
+// Truncate drops the values appended to the column num since the mark, e.g. those of a row failed to be appended.
+func (block *Block) Truncate(num int, mark ColumnMark) {
+	block.buffers[num].offsetBuffer.Truncate(mark.offset)
+	block.buffers[num].columnBuffer.Truncate(mark.column)
+	block.offsets[num] = block.offsets[num][:len(mark.offsets)]
+	for level, n := range mark.offsets {
+		block.offsets[num][level] = block.offsets[num][level][:n]
+	}
+	block.columnar[num] = block.columnar[num][:mark.columnar]
+}
+
+// AppendColumn appends the value to the column num, the block has to be reserved.
+// Unlike AppendRow, it does not count the rows of the block: NumRows is up to the caller.
+func (block *Block) AppendColumn(num int, v driver.Value) error {
+	c := block.Columns[num]
+	if column.NeedsColumnarWrite(c) {
+		return block.appendColumnar(c, num, v)
+	}
+	switch column := c.(type) {
+	case *column.Array:
+		value := reflect.ValueOf(v)
+		if value.Kind() != reflect.Slice {
+			return fmt.Errorf("unsupported Array(T) type [%T]", value.Interface())
+		}
+		return block.writeArray(c, newValue(value), num, 1)
+	case *column.Nullable:
+		return column.WriteNull(block.buffers[num].Offset, block.buffers[num].Column, v)
+	}
+	return c.Write(block.buffers[num].Column, v)
+}
+
+func (block *Block) appendColumnar(c column.Column, num int, v interface{}) error {
+	switch kind := reflect.ValueOf(v).Kind(); c.(type) {
+	case *column.Array:
+		if kind != reflect.Slice {
+			return fmt.Errorf("unsupported Array(T) type [%T]", v)
+		}
+	case *column.Nested:
+		if kind != reflect.Slice {
+			return fmt.Errorf("unsupported Nested type [%T]", v)
+		}
+	case *column.Map:
+		if kind != reflect.Map {
+			return fmt.Errorf("unsupported Map(K, V) type [%T]", v)
+		}
+	case *column.Point:
+		if _, ok := v.([2]float64); !ok {
+			return fmt.Errorf("unsupported Point type [%T]", v)
+		}
+	}
+	block.columnar[num] = append(block.columnar[num], v)
+	return nil
+}
+
 func (block *Block) Reserve() {
 	if len(block.buffers) == 0 {
 		block.buffers = make([]*buffer, len(block.Columns))
 		block.offsets = make([]offset, len(block.Columns))
+		block.columnar = make([][]interface{}, len(block.Columns))
 		for i := 0; i < len(block.Columns); i++ {
 			var (
 				offsetBuffer = wb.New(wb.InitialSize)
@@ -172,6 +313,20 @@ func (block *Block) Reserve() {
 	}
 }
 
+// Size returns the number of the bytes of the values appended to the block since it was reserved or written.
+// The values of the columns written at once (see column.NeedsColumnarWrite) are encoded as the block is written,
+// they are not counted.
+func (block *Block) Size() int {
+	var size int
+	for i, buffer := range block.buffers {
+		size += buffer.offsetBuffer.Len() + buffer.columnBuffer.Len()
+		for _, offsets := range block.offsets[i] {
+			size += 8 * len(offsets)
+		}
+	}
+	return size
+}
+
 func (block *Block) Reset() {
 	block.NumRows = 0
 	block.NumColumns = 0
@@ -184,6 +339,7 @@ func (block *Block) Reset() {
 	{
 		block.offsets = nil
 		block.buffers = nil
+		block.columnar = nil
 	}
 }
 
@@ -198,11 +354,20 @@ func (block *Block) Write(serverInfo *ServerInfo, encoder *binary.Encoder) error
 		for i := range block.offsets {
 			block.offsets[i] = offset{}
 		}
+		for i := range block.columnar {
+			block.columnar[i] = nil
+		}
 	}()
-	for i, column := range block.Columns {
-		encoder.String(column.Name())
-		encoder.String(column.CHType())
+	for i, c := range block.Columns {
+		encoder.String(c.Name())
+		encoder.String(c.CHType())
 		if len(block.buffers) == len(block.Columns) {
+			if column.NeedsColumnarWrite(c) {
+				if err := column.WriteColumn(c, encoder, block.columnar[i]); err != nil {
+					return err
+				}
+				continue
+			}
 			for _, offsets := range block.offsets[i] {
 				for _, offset := range offsets {
 					if err := encoder.UInt64(uint64(offset)); err != nil {
@@ -218,6 +383,16 @@ func (block *Block) Write(serverInfo *ServerInfo, encoder *binary.Encoder) error
 	return nil
 }
 
+// WriteNative writes the block info followed by r, a block in the Native format as is: the number of its columns
+// and of its rows, then each column by its name, its type and its values.
+func (block *Block) WriteNative(encoder *binary.Encoder, r io.Reader) error {
+	if err := block.info.write(encoder); err != nil {
+		return err
+	}
+	_, err := io.Copy(encoder, r)
+	return err
+}
+
 type blockInfo struct {
 	num1        uint64
 	isOverflows bool
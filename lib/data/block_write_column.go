@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
 )
 
 func (block *Block) WriteDate(c int, v time.Time) error {
@@ -104,3 +105,10 @@ func (block *Block) WriteArrayWithValue(c int, value Value) error {
 	}
 	return block.writeArray(block.Columns[c], value, c, 1)
 }
+
+func (block *Block) WriteMap(c int, v interface{}) error {
+	if _, ok := block.Columns[c].(*column.Map); !ok {
+		return fmt.Errorf("column %s is not Map(K, V)", block.Columns[c].Name())
+	}
+	return block.appendColumnar(block.Columns[c], c, v)
+}
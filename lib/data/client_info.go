@@ -14,16 +14,35 @@ const (
 	ClickHouseDBMSVersionMinor = 1
 )
 
-type ClientInfo struct{}
+// ClientInfo is the name and the version of the client sent with the hello and the queries, the server records them
+// in system.query_log. The empty name and the zero version are those of the driver.
+type ClientInfo struct {
+	Name         string
+	VersionMajor uint64
+	VersionMinor uint64
+}
 
-func (ClientInfo) Write(encoder *binary.Encoder) error {
-	encoder.String(ClientName)
-	encoder.Uvarint(ClickHouseDBMSVersionMajor)
-	encoder.Uvarint(ClickHouseDBMSVersionMinor)
+func (info ClientInfo) Write(encoder *binary.Encoder) error {
+	name, major, minor := info.values()
+	encoder.String(name)
+	encoder.Uvarint(major)
+	encoder.Uvarint(minor)
 	encoder.Uvarint(ClickHouseRevision)
 	return nil
 }
 
-func (ClientInfo) String() string {
-	return fmt.Sprintf("%s %d.%d.%d", ClientName, ClickHouseDBMSVersionMajor, ClickHouseDBMSVersionMinor, ClickHouseRevision)
+func (info ClientInfo) String() string {
+	name, major, minor := info.values()
+	return fmt.Sprintf("%s %d.%d.%d", name, major, minor, ClickHouseRevision)
+}
+
+func (info ClientInfo) values() (name string, major, minor uint64) {
+	name, major, minor = info.Name, info.VersionMajor, info.VersionMinor
+	if name == "" {
+		name = ClientName
+	}
+	if major == 0 && minor == 0 {
+		major, minor = ClickHouseDBMSVersionMajor, ClickHouseDBMSVersionMinor
+	}
+	return name, major, minor
 }
@@ -29,5 +29,10 @@ func newValue(v reflect.Value) Value {
 }
 
 func (v value) Index(i int) Value {
-	return newValue(v.Value.Index(i))
+	element := v.Value.Index(i)
+	if element.Kind() == reflect.Interface && !element.IsNil() {
+		// the elements of []interface{}, e.g. the inner slices of an Array(Array(T)) value
+		element = element.Elem()
+	}
+	return newValue(element)
 }
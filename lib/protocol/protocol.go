@@ -3,6 +3,7 @@ package protocol
 const (
 	DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE          = 54058
 	DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO = 54060
+	DBMS_MIN_REVISION_WITH_CLIENT_WRITE_INFO        = 54420
 )
 
 const (
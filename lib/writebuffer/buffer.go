@@ -54,7 +54,7 @@ func (wb *WriteBuffer) Bytes() []byte {
 	if len(wb.chunks) == 1 {
 		return wb.chunks[0]
 	}
-	bytes := make([]byte, 0, wb.len())
+	bytes := make([]byte, 0, wb.Len())
 	for _, chunk := range wb.chunks {
 		bytes = append(bytes, chunk...)
 	}
@@ -69,7 +69,8 @@ func (wb *WriteBuffer) addChunk(size, capacity int) {
 	wb.chunks = append(wb.chunks, chunk)
 }
 
-func (wb *WriteBuffer) len() int {
+// Len returns the number of the bytes written to the buffer.
+func (wb *WriteBuffer) Len() int {
 	var v int
 	for _, chunk := range wb.chunks {
 		v += len(chunk)
@@ -77,6 +78,21 @@ func (wb *WriteBuffer) len() int {
 	return v
 }
 
+// Truncate discards all but the first n bytes written to the buffer.
+func (wb *WriteBuffer) Truncate(n int) {
+	for i, chunk := range wb.chunks {
+		if n <= len(chunk) {
+			wb.chunks[i] = chunk[:n]
+			for _, dropped := range wb.chunks[i+1:] {
+				leakypool.PutBytes(dropped[:0])
+			}
+			wb.chunks = wb.chunks[:i+1]
+			return
+		}
+		n -= len(chunk)
+	}
+}
+
 func (wb *WriteBuffer) calcCap(dataSize int) int {
 	dataSize = max(dataSize, 64)
 	if len(wb.chunks) == 0 {
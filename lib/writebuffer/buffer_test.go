@@ -23,3 +23,17 @@ func Test_WriteBuffer_SafeWithLeakyPool(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func Test_WriteBuffer_Truncate(t *testing.T) {
+	wb := New(64)
+	wb.Write([]byte("head"))
+	wb.Write(make([]byte, 100))
+	wb.Write([]byte("tail"))
+	wb.Truncate(104)
+	assert.Equal(t, 104, wb.Len())
+	wb.Truncate(2)
+	wb.Write([]byte("ad"))
+	assert.Equal(t, []byte("head"), wb.Bytes())
+	wb.Truncate(10)
+	assert.Equal(t, []byte("head"), wb.Bytes())
+}
@@ -0,0 +1,76 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// the levels of the structured logs of the driver
+const (
+	LogDebug = "debug"
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// Logger receives the logs of the driver, e.g. an adapter of zap or zerolog. Custom loggers must be registered
+// with RegisterLogger, they receive the logs of the connections opened afterwards, whether debug of the DSN is set
+// or not: the dials, the read and write errors and the retries with their fields as the key-value pairs
+// of kv (host, ident, strategy, error...), the debug logs of the queries as messages of LogDebug.
+// The logs are printed as of debug of the DSN if none is registered.
+type Logger interface {
+	Log(level, msg string, kv ...interface{})
+}
+
+var (
+	customLoggerLock sync.RWMutex
+	customLogger     Logger
+)
+
+// RegisterLogger registers the custom logger.
+func RegisterLogger(logger Logger) {
+	customLoggerLock.Lock()
+	customLogger = logger
+	customLoggerLock.Unlock()
+}
+
+// DeregisterLogger deregisters the custom logger.
+func DeregisterLogger() {
+	customLoggerLock.Lock()
+	customLogger = nil
+	customLoggerLock.Unlock()
+}
+
+func getLogger() Logger {
+	customLoggerLock.RLock()
+	defer customLoggerLock.RUnlock()
+	return customLogger
+}
+
+// logEvent logs the structured log by the logger, or by the printf-style logf if the logger is nil:
+// the message is followed by the key=value pairs of kv.
+func logEvent(logger Logger, logf func(string, ...interface{}), level, msg string, kv ...interface{}) {
+	if logger != nil {
+		logger.Log(level, msg, kv...)
+		return
+	}
+	var line strings.Builder
+	line.WriteString(msg)
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i == 0 {
+			line.WriteString(" ")
+		} else {
+			line.WriteString(", ")
+		}
+		fmt.Fprintf(&line, "%v=%v", kv[i], kv[i+1])
+	}
+	logf("%s", line.String())
+}
+
+// loggerLogf returns the printf-style logf of the debug logs logged by the logger.
+func loggerLogf(logger Logger) func(string, ...interface{}) {
+	return func(format string, v ...interface{}) {
+		logger.Log(LogDebug, fmt.Sprintf(format, v...))
+	}
+}
@@ -0,0 +1,174 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type logRecord struct {
+	level, msg string
+	fields     map[string]interface{}
+}
+
+type recordedLogger struct {
+	sync.Mutex
+	records []logRecord
+}
+
+func (l *recordedLogger) Log(level, msg string, kv ...interface{}) {
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(kv); i += 2 {
+		fields[kv[i].(string)] = kv[i+1]
+	}
+	l.Lock()
+	l.records = append(l.records, logRecord{level: level, msg: msg, fields: fields})
+	l.Unlock()
+}
+
+func (l *recordedLogger) find(msg string) (logRecord, bool) {
+	l.Lock()
+	defer l.Unlock()
+	for _, record := range l.records {
+		if record.msg == msg {
+			return record, true
+		}
+	}
+	return logRecord{}, false
+}
+
+func Test_LoggerDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	defer breaker.success("127.0.0.1:1")
+	logger := &recordedLogger{}
+	conn, err := dial(context.Background(), connOptions{
+		hosts:        []string{"127.0.0.1:1", listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) { t.Error("logf is called with a logger") },
+		logger:       logger,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	if record, ok := logger.find("[dial err]"); assert.True(t, ok) {
+		assert.Equal(t, LogWarn, record.level)
+		assert.Equal(t, "127.0.0.1:1", record.fields["host"])
+		assert.Equal(t, "in_order", record.fields["strategy"])
+		assert.Error(t, record.fields["error"].(error))
+		assert.Contains(t, record.fields, "ident")
+	}
+	if record, ok := logger.find("[dial]"); assert.True(t, ok) {
+		assert.Equal(t, LogInfo, record.level)
+		assert.Equal(t, listener.Addr().String(), record.fields["host"])
+		assert.Equal(t, 1, record.fields["server"])
+	}
+
+	// a read error of the connection
+	stub := &stubConn{err: errors.New("connection reset")}
+	conn = newStubConnect(t, stub, connOptions{logger: logger})
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	if record, ok := logger.find("[connect] read error"); assert.True(t, ok) {
+		assert.Equal(t, LogError, record.level)
+		assert.Equal(t, "stub:9000", record.fields["host"])
+		assert.Equal(t, stub.err, record.fields["error"])
+	}
+}
+
+func Test_LoggerCloseAndHeartbeat(t *testing.T) {
+	logger := &recordedLogger{}
+	// the read in progress is cut once the timeout of CloseGracefully is over
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := newStubConnect(t, client, connOptions{logger: logger})
+	go conn.Read(make([]byte, 1))
+	for conn.inFlightIO() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, conn.CloseGracefully(10*time.Millisecond))
+	if record, ok := logger.find("[connect] I/O in progress not finished, closing"); assert.True(t, ok) {
+		assert.Equal(t, LogWarn, record.level)
+		assert.Equal(t, "stub:9000", record.fields["host"])
+		assert.Equal(t, 10*time.Millisecond, record.fields["timeout"])
+		assert.Contains(t, record.fields, "ident")
+	}
+
+	// the pong of the heartbeat is not read
+	stub := &stubConn{err: io.EOF}
+	conn = newStubConnect(t, stub, connOptions{logger: logger})
+	conn.startHeartbeat(time.Millisecond)
+	record, ok := logger.find("[heartbeat] ping failed")
+	for deadline := time.Now().Add(time.Second); !ok && time.Now().Before(deadline); record, ok = logger.find("[heartbeat] ping failed") {
+		time.Sleep(time.Millisecond)
+	}
+	if assert.True(t, ok) {
+		assert.Equal(t, LogWarn, record.level)
+		assert.Equal(t, "stub:9000", record.fields["host"])
+		assert.Error(t, record.fields["error"].(error))
+		assert.Contains(t, record.fields, "ident")
+	}
+}
+
+func Test_LoggerRegistered(t *testing.T) {
+	logger := &recordedLogger{}
+	RegisterLogger(logger)
+	defer DeregisterLogger()
+	defer breaker.success("127.0.0.1:1")
+	_, err := Open("tcp://127.0.0.1:1?timeout=1")
+	assert.Error(t, err)
+	// the debug logs are logged by the logger even without debug of the DSN
+	_, ok := logger.find("host(s)=127.0.0.1:1, database=default, username=default")
+	assert.True(t, ok)
+	if record, ok := logger.find("[dial err]"); assert.True(t, ok) {
+		assert.Equal(t, "127.0.0.1:1", record.fields["host"])
+	}
+}
+
+func Test_LogEventLogf(t *testing.T) {
+	var lines []string
+	logf := func(format string, v ...interface{}) { lines = append(lines, fmt.Sprintf(format, v...)) }
+	logEvent(nil, logf, LogWarn, "[dial err]", "host", "127.0.0.1:9000", "ident", 3, "error", errors.New("refused"))
+	logEvent(nil, logf, LogInfo, "[dial] retry")
+	assert.Equal(t, []string{"[dial err] host=127.0.0.1:9000, ident=3, error=refused", "[dial] retry"}, lines)
+}
+
+func Test_LoggerVerifyBlocks(t *testing.T) {
+	logger := &recordedLogger{}
+	RegisterLogger(logger)
+	defer DeregisterLogger()
+	defer breaker.success("127.0.0.1:1")
+	count := func() (n int) {
+		logger.Lock()
+		defer logger.Unlock()
+		for _, record := range logger.records {
+			if record.msg == "[verify blocks] the server sends no checksums of the uncompressed blocks, set compress to verify them" {
+				assert.Equal(t, LogWarn, record.level)
+				n++
+			}
+		}
+		return n
+	}
+	verifyBlocksWarning = sync.Once{}
+	// the compressed blocks are verified by the checksums of their frames
+	_, err := Open("tcp://127.0.0.1:1?timeout=1&verify_blocks=true&compress=true")
+	assert.Error(t, err)
+	assert.Equal(t, 0, count())
+	// the uncompressed blocks can't be verified, it is logged once
+	for i := 0; i < 2; i++ {
+		_, err := Open("tcp://127.0.0.1:1?timeout=1&verify_blocks=true")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, count())
+}
@@ -0,0 +1,64 @@
+package clickhouse
+
+import "sync"
+
+// Metrics receives the counters of the connections and of the queries of the driver, e.g. to export them to
+// Prometheus. Custom metrics must be registered with RegisterMetrics, they are passed to the connections opened
+// afterwards; the driver skips the calls if none is registered.
+// The methods are called from the goroutines of the queries and have to be safe for concurrent use.
+// NopMetrics may be embedded to implement a part of them only.
+type Metrics interface {
+	// ConnectionOpened is called by dial once the connection to the host is established.
+	ConnectionOpened(host string)
+	// DialFailed is called by dial for every failure to connect to the host, the retries included.
+	DialFailed(host string, err error)
+	// QueryExecuted is called once a query is sent to the server.
+	QueryExecuted()
+	// RowsRead is called by the block reader with the rows of every block of a result received.
+	RowsRead(rows int)
+	// BytesRead and BytesWritten are called with the bytes read from and written to the socket by the connection.
+	BytesRead(n int)
+	BytesWritten(n int)
+	// BlockCompressed is called once a block is compressed to be sent, BlockDecompressed once a block received
+	// is checked, with the uncompressed size and the compressed size on the wire: the compression ratio is
+	// size/compressedSize. They are called with compress of the DSN only.
+	BlockCompressed(size, compressedSize int)
+	BlockDecompressed(size, compressedSize int)
+}
+
+// NopMetrics implements Metrics by doing nothing.
+type NopMetrics struct{}
+
+func (NopMetrics) ConnectionOpened(string)    {}
+func (NopMetrics) DialFailed(string, error)   {}
+func (NopMetrics) QueryExecuted()             {}
+func (NopMetrics) RowsRead(int)               {}
+func (NopMetrics) BytesRead(int)              {}
+func (NopMetrics) BytesWritten(int)           {}
+func (NopMetrics) BlockCompressed(int, int)   {}
+func (NopMetrics) BlockDecompressed(int, int) {}
+
+var (
+	customMetricsLock sync.RWMutex
+	customMetrics     Metrics
+)
+
+// RegisterMetrics registers the custom metrics.
+func RegisterMetrics(metrics Metrics) {
+	customMetricsLock.Lock()
+	customMetrics = metrics
+	customMetricsLock.Unlock()
+}
+
+// DeregisterMetrics deregisters the custom metrics.
+func DeregisterMetrics() {
+	customMetricsLock.Lock()
+	customMetrics = nil
+	customMetricsLock.Unlock()
+}
+
+func getMetrics() Metrics {
+	customMetricsLock.RLock()
+	defer customMetricsLock.RUnlock()
+	return customMetrics
+}
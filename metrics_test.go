@@ -0,0 +1,126 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedMetrics struct {
+	NopMetrics
+	sync.Mutex
+	opened, failed          []string
+	queries, rows           int
+	bytesRead, bytesWritten int
+	compressed              [][2]int
+	decompressed            [][2]int
+}
+
+func (m *recordedMetrics) ConnectionOpened(host string) {
+	m.Lock()
+	m.opened = append(m.opened, host)
+	m.Unlock()
+}
+
+func (m *recordedMetrics) DialFailed(host string, err error) {
+	m.Lock()
+	m.failed = append(m.failed, host)
+	m.Unlock()
+}
+
+func (m *recordedMetrics) QueryExecuted()     { m.queries++ }
+func (m *recordedMetrics) RowsRead(n int)     { m.rows += n }
+func (m *recordedMetrics) BytesRead(n int)    { m.bytesRead += n }
+func (m *recordedMetrics) BytesWritten(n int) { m.bytesWritten += n }
+
+func (m *recordedMetrics) BlockCompressed(size, compressedSize int) {
+	m.compressed = append(m.compressed, [2]int{size, compressedSize})
+}
+
+func (m *recordedMetrics) BlockDecompressed(size, compressedSize int) {
+	m.decompressed = append(m.decompressed, [2]int{size, compressedSize})
+}
+
+func Test_MetricsDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	defer breaker.success("127.0.0.1:1")
+	metrics := &recordedMetrics{}
+	conn, err := dial(context.Background(), connOptions{
+		hosts:        []string{"127.0.0.1:1", listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		connTimeout:  time.Second,
+		logf:         func(string, ...interface{}) {},
+		metrics:      metrics,
+	})
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		assert.Equal(t, []string{"127.0.0.1:1"}, metrics.failed)
+		assert.Equal(t, []string{listener.Addr().String()}, metrics.opened)
+		assert.Equal(t, Metrics(metrics), conn.metrics)
+	}
+}
+
+func Test_MetricsQuery(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoderWithCompress(&buf)
+	)
+	c, _ := column.Factory("value", "String", time.UTC)
+	block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	for i := 0; i < 3; i++ {
+		if err := block.AppendRow([]driver.Value{"value"}); !assert.NoError(t, err) {
+			return
+		}
+	}
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	encoder.SelectCompress(true)
+	if err := block.Write(&data.ServerInfo{}, encoder); !assert.NoError(t, err) {
+		return
+	}
+	encoder.SelectCompress(false)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	var (
+		metrics = &recordedMetrics{}
+		stub    = &stubConn{data: buf.Bytes()}
+		conn    = newStubConnect(t, stub, connOptions{metrics: metrics})
+		ch      = &clickhouse{
+			conn:     conn,
+			logf:     func(string, ...interface{}) {},
+			settings: &querySettings{},
+			compress: true,
+			decoder:  binary.NewDecoderWithCompress(conn),
+			encoder:  binary.NewEncoderWithCompress(conn),
+		}
+	)
+	ch.decoder.OnCompressedBlock(metrics.BlockDecompressed)
+	ch.encoder.OnCompressedBlock(metrics.BlockCompressed)
+	if _, err := ch.ExecContext(context.Background(), "SELECT value", nil); !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, metrics.queries)
+	assert.Equal(t, 3, metrics.rows)
+	assert.Equal(t, buf.Len(), metrics.bytesRead)
+	assert.Equal(t, len(stub.written), metrics.bytesWritten)
+	// the empty block ending the query is compressed, the block of the rows is decompressed
+	if assert.Len(t, metrics.compressed, 1) && assert.Len(t, metrics.decompressed, 1) {
+		assert.True(t, metrics.compressed[0][1] > binary.HeaderSize)
+		// the frame follows the packet and the name of the table, and precedes the end of the stream
+		assert.Equal(t, buf.Len()-3, metrics.decompressed[0][1])
+	}
+}
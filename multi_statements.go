@@ -0,0 +1,86 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// execStatements runs the statements one after the other, it stops at the first failing one.
+// The arguments can't be bound since the statements are not told apart by their placeholders.
+// The rows affected of the result are the sum of the rows affected of the statements, the statements which don't
+// report any count for none.
+func (ch *clickhouse) execStatements(ctx context.Context, statements []string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("clickhouse: the arguments can't be bound to the %d statements of a multi-statement query", len(statements))
+	}
+	var rows int64
+	for i, statement := range statements {
+		ch.logf("[exec statement] %d of %d", i+1, len(statements))
+		result, err := ch.execStatement(ctx, statement)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			rows += affected
+		}
+	}
+	return &insertResult{rows: rows}, nil
+}
+
+func (ch *clickhouse) execStatement(ctx context.Context, statement string) (driver.Result, error) {
+	finish := ch.watchCancel(ctx)
+	defer finish()
+	stmt, err := ch.PrepareContext(ctx, statement)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(driver.StmtExecContext).ExecContext(ctx, nil)
+}
+
+// splitStatements splits the query at the semicolons ending its statements, the semicolons of the string literals,
+// the quoted identifiers and the comments (--, # and /* */) are skipped.
+// The statements made only of spaces and comments are dropped.
+func splitStatements(query string) []string {
+	var (
+		statements []string
+		start      int
+		code       bool // the statement holds more than spaces and comments
+	)
+	for i := 0; i < len(query); i++ {
+		switch char := query[i]; {
+		case char == '\'' || char == '"' || char == '`':
+			code = true
+			for i++; i < len(query) && query[i] != char; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case char == '-' && strings.HasPrefix(query[i:], "--"), char == '#' && (strings.HasPrefix(query[i:], "# ") || strings.HasPrefix(query[i:], "#!")):
+			// as the lexer of ClickHouse, # starts a comment when followed by a space or !
+			if end := strings.IndexByte(query[i:], '\n'); end != -1 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case char == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end != -1 {
+				i += end + 3
+			} else {
+				i = len(query)
+			}
+		case char == ';':
+			if code {
+				statements = append(statements, strings.TrimSpace(query[start:i]))
+			}
+			start, code = i+1, false
+		case char != ' ' && char != '\t' && char != '\n' && char != '\r':
+			code = true
+		}
+	}
+	if code {
+		statements = append(statements, strings.TrimSpace(query[start:]))
+	}
+	return statements
+}
@@ -0,0 +1,107 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	for query, expected := range map[string][]string{
+		"SELECT 1":           {"SELECT 1"},
+		"SELECT 1;":          {"SELECT 1"},
+		" ; ;\n":             nil,
+		"SELECT 1; SELECT 2": {"SELECT 1", "SELECT 2"},
+		"INSERT INTO t VALUES ('a;b', 'it\\'s;', 'c'';d'); SELECT 2":           {"INSERT INTO t VALUES ('a;b', 'it\\'s;', 'c'';d')", "SELECT 2"},
+		"CREATE TABLE `a;b` (\"c;d\" String) Engine=Memory;\nDROP TABLE `a;b`": {"CREATE TABLE `a;b` (\"c;d\" String) Engine=Memory", "DROP TABLE `a;b`"},
+		"-- the tables; first\nCREATE TABLE a (id UInt8) Engine=Memory; /* then; */ DROP TABLE a;\n-- done;": {
+			"-- the tables; first\nCREATE TABLE a (id UInt8) Engine=Memory",
+			"/* then; */ DROP TABLE a",
+		},
+		"# the table; first\nCREATE TABLE a (id UInt8) Engine=Memory;\n#!the end; of the script": {
+			"# the table; first\nCREATE TABLE a (id UInt8) Engine=Memory",
+		},
+		"SELECT '#'; SELECT 2":              {"SELECT '#'", "SELECT 2"},
+		"SELECT 'unterminated; string":      {"SELECT 'unterminated; string"},
+		"SELECT 1 /* unterminated; comment": {"SELECT 1 /* unterminated; comment"},
+	} {
+		assert.Equal(t, expected, splitStatements(query), query)
+	}
+}
+
+func Test_ExecMultiStatements(t *testing.T) {
+	newClickhouse := func(stub *stubConn, allowMultiStatements bool) *clickhouse {
+		conn := newStubConnect(t, stub, connOptions{})
+		return &clickhouse{
+			conn:                 conn,
+			logf:                 func(string, ...interface{}) {},
+			settings:             &querySettings{},
+			decoder:              binary.NewDecoder(conn),
+			encoder:              binary.NewEncoder(conn),
+			allowMultiStatements: allowMultiStatements,
+		}
+	}
+	const script = "CREATE TABLE t (s String) Engine=Memory;\nINSERT INTO t SELECT 'a;b';\n-- the end; of the script\nDROP TABLE t;"
+	{
+		var buf bytes.Buffer
+		for i := 0; i < 3; i++ {
+			binary.NewEncoder(&buf).Uvarint(protocol.ServerEndOfStream)
+		}
+		stub := &stubConn{data: buf.Bytes(), err: io.EOF}
+		ch := newClickhouse(stub, true)
+		if result, err := ch.ExecContext(context.Background(), script, nil); assert.NoError(t, err) {
+			// none of the statements report a count of rows
+			if rows, err := result.RowsAffected(); assert.NoError(t, err) {
+				assert.Equal(t, int64(0), rows)
+			}
+			written := string(stub.written)
+			create := strings.Index(written, "CREATE TABLE t (s String) Engine=Memory")
+			insert := strings.Index(written, "INSERT INTO t SELECT 'a;b'")
+			drop := strings.Index(written, "-- the end; of the script\nDROP TABLE t")
+			// the statements are sent one after the other, without their semicolons
+			assert.True(t, create != -1 && create < insert && insert < drop, "create=%d, insert=%d, drop=%d", create, insert, drop)
+			assert.NotContains(t, written, "Memory;")
+		}
+		_, err := ch.ExecContext(context.Background(), "SELECT 1; SELECT 2", []driver.NamedValue{{Ordinal: 1, Value: 1}})
+		if assert.Error(t, err) {
+			assert.Equal(t, "clickhouse: the arguments can't be bound to the 2 statements of a multi-statement query", err.Error())
+		}
+	}
+	{
+		// the statements following a failed one are not run
+		var buf bytes.Buffer
+		encoder := binary.NewEncoder(&buf)
+		encoder.Uvarint(protocol.ServerEndOfStream)
+		encoder.Uvarint(protocol.ServerException)
+		encoder.Int32(62)
+		encoder.String("DB::Exception")
+		encoder.String("Syntax error")
+		encoder.String("")
+		encoder.Bool(false)
+		stub := &stubConn{data: buf.Bytes(), err: io.EOF}
+		ch := newClickhouse(stub, true)
+		if _, err := ch.ExecContext(context.Background(), "SELECT 1; SELEC 2; SELECT 3", nil); assert.Error(t, err) {
+			if exception, ok := err.(*Exception); assert.True(t, ok) {
+				assert.Equal(t, int32(62), exception.Code)
+			}
+			assert.NotContains(t, string(stub.written), "SELECT 3")
+		}
+	}
+	{
+		// the script is sent as a whole unless allow_multi_statements is set
+		var buf bytes.Buffer
+		binary.NewEncoder(&buf).Uvarint(protocol.ServerEndOfStream)
+		stub := &stubConn{data: buf.Bytes(), err: io.EOF}
+		ch := newClickhouse(stub, false)
+		if _, err := ch.ExecContext(context.Background(), script, nil); assert.NoError(t, err) {
+			assert.Contains(t, string(stub.written), script)
+		}
+	}
+}
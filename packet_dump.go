@@ -0,0 +1,148 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+)
+
+// packetDumpSize is the size of the packets whose bytes are dumped in hex
+const packetDumpSize = 64
+
+var (
+	clientPacketNames = map[uint64]string{
+		protocol.ClientHello:  "Hello",
+		protocol.ClientQuery:  "Query",
+		protocol.ClientData:   "Data",
+		protocol.ClientCancel: "Cancel",
+		protocol.ClientPing:   "Ping",
+	}
+	serverPacketNames = map[uint64]string{
+		protocol.ServerHello:       "Hello",
+		protocol.ServerData:        "Data",
+		protocol.ServerException:   "Exception",
+		protocol.ServerProgress:    "Progress",
+		protocol.ServerPong:        "Pong",
+		protocol.ServerEndOfStream: "EndOfStream",
+		protocol.ServerProfileInfo: "ProfileInfo",
+		protocol.ServerTotals:      "Totals",
+		protocol.ServerExtremes:    "Extremes",
+	}
+)
+
+// packetDump logs the packets read and written by a connection with debug of the DSN: the name of their type,
+// their size and, up to packetDumpSize, their bytes, but those of the Hello of the client holding its credentials. The native protocol doesn't frame the packets, a packet
+// is made of the bytes from its type to the type of the next one: the packets are logged once the next one
+// begins, the ones ending the response once they are read.
+type packetDump struct {
+	sync.Mutex
+	logf          func(string, ...interface{})
+	read, written dumpedPacket
+}
+
+type dumpedPacket struct {
+	name   string
+	size   int
+	head   []byte
+	masked bool // the bytes of the packet are not dumped, e.g. the password of the Hello
+}
+
+func (p *dumpedPacket) add(b []byte) {
+	if p.name == "" {
+		return
+	}
+	p.size += len(b)
+	if p.masked {
+		return
+	}
+	if free := packetDumpSize + 1 - len(p.head); free > 0 {
+		if len(b) > free {
+			b = b[:free]
+		}
+		p.head = append(p.head, b...)
+	}
+}
+
+func (dump *packetDump) log(direction string, p *dumpedPacket) {
+	if p.name == "" || p.size <= 0 {
+		*p = dumpedPacket{head: p.head[:0]}
+		return
+	}
+	if p.size <= packetDumpSize && !p.masked {
+		dump.logf("[packet] %s %s: %d bytes [% x]", direction, p.name, p.size, p.head)
+	} else {
+		dump.logf("[packet] %s %s: %d bytes", direction, p.name, p.size)
+	}
+	*p = dumpedPacket{head: p.head[:0]}
+}
+
+// bytesRead adds the bytes read to the packet being read.
+func (dump *packetDump) bytesRead(b []byte) {
+	dump.Lock()
+	dump.read.add(b)
+	dump.Unlock()
+}
+
+// bytesWritten adds the bytes written to the packet being written.
+func (dump *packetDump) bytesWritten(b []byte) {
+	dump.Lock()
+	dump.written.add(b)
+	dump.Unlock()
+}
+
+// beginRead begins the packet of the server whose type has just been read, the previous one is logged.
+func (dump *packetDump) beginRead(packet uint64) {
+	dump.Lock()
+	defer dump.Unlock()
+	// the byte of the type has been added to the previous packet
+	dump.read.size--
+	if len(dump.read.head) > dump.read.size && dump.read.size >= 0 {
+		dump.read.head = dump.read.head[:dump.read.size]
+	}
+	dump.log("<-", &dump.read)
+	dump.read.name = packetName(serverPacketNames, packet)
+	dump.read.add([]byte{byte(packet)})
+	if packet == protocol.ServerEndOfStream || packet == protocol.ServerPong {
+		dump.log("<-", &dump.read)
+	}
+}
+
+// endRead logs the packet of the server read at last, it ends the response.
+func (dump *packetDump) endRead() {
+	dump.Lock()
+	dump.log("<-", &dump.read)
+	dump.Unlock()
+}
+
+// beginWrite begins the packet of the client whose type is about to be written, the previous one is logged
+// as well as the packet of the server read before.
+func (dump *packetDump) beginWrite(packet uint64) {
+	dump.Lock()
+	defer dump.Unlock()
+	dump.log("<-", &dump.read)
+	dump.log("->", &dump.written)
+	dump.written.name = packetName(clientPacketNames, packet)
+	dump.written.masked = packet == protocol.ClientHello
+}
+
+// flushed logs the packet of the client being written once it is sent, the bytes written
+// until the next packet begins are logged as its continuation.
+func (dump *packetDump) flushed() {
+	dump.Lock()
+	name, masked := dump.written.name, dump.written.masked
+	dump.log("->", &dump.written)
+	if name != "" {
+		dump.written.name = strings.TrimSuffix(name, " (continued)") + " (continued)"
+		dump.written.masked = masked
+	}
+	dump.Unlock()
+}
+
+func packetName(names map[uint64]string, packet uint64) string {
+	if name, ok := names[packet]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(%d)", packet)
+}
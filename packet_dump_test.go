@@ -0,0 +1,132 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PacketDump(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		lines   []string
+	)
+	c, _ := column.Factory("value", "UInt8", time.UTC)
+	block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	block.Write(&data.ServerInfo{}, encoder)
+	block = &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	block.AppendRow([]driver.Value{uint8(1)})
+	block.AppendRow([]driver.Value{uint8(2)})
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	block.Write(&data.ServerInfo{}, encoder)
+	encoder.Uvarint(protocol.ServerProgress)
+	encoder.Uvarint(2) // rows
+	encoder.Uvarint(2) // bytes
+	encoder.Uvarint(2) // total rows
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	read := buf.Len()
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	conn.dump = &packetDump{logf: func(format string, v ...interface{}) { lines = append(lines, fmt.Sprintf(format, v...)) }}
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	stmt, err := ch.PrepareContext(context.Background(), "SELECT value")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(context.Background(), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 1)
+	for rows.Next(dest) != io.EOF {
+	}
+	assert.NoError(t, rows.Close())
+
+	var sequence []string
+	for _, line := range lines {
+		sequence = append(sequence, strings.SplitN(line, ":", 2)[0])
+	}
+	assert.Equal(t, []string{
+		"[packet] -> Query",
+		"[packet] -> Data",
+		"[packet] <- Data",
+		"[packet] <- Data",
+		"[packet] <- Progress",
+		"[packet] <- EndOfStream",
+	}, sequence)
+	written := len(stub.written)
+	if assert.Len(t, lines, 6) {
+		// the sizes add up to the bytes written and read, the small packets are dumped
+		assert.Equal(t, "[packet] <- Progress: 4 bytes [03 02 02 02]", lines[4])
+		assert.Equal(t, "[packet] <- EndOfStream: 1 bytes [05]", lines[5])
+		var sizes [2]int
+		for i, line := range lines {
+			var size int
+			fmt.Sscanf(strings.SplitN(line, ": ", 2)[1], "%d bytes", &size)
+			if i < 2 {
+				sizes[0] += size
+			} else {
+				sizes[1] += size
+			}
+		}
+		assert.Equal(t, [2]int{written, read}, sizes)
+	}
+}
+
+func Test_PacketDumpHello(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		lines   []string
+	)
+	encoder.Uvarint(protocol.ServerHello)
+	encoder.String("ClickHouse")
+	encoder.Uvarint(21)
+	encoder.Uvarint(8)
+	encoder.Uvarint(protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE)
+	encoder.String("UTC")
+
+	stub := &stubConn{data: buf.Bytes()}
+	conn := newStubConnect(t, stub, connOptions{})
+	conn.dump = &packetDump{logf: func(format string, v ...interface{}) { lines = append(lines, fmt.Sprintf(format, v...)) }}
+	ch := &clickhouse{
+		conn:    conn,
+		logf:    func(string, ...interface{}) {},
+		decoder: binary.NewDecoder(conn),
+		encoder: binary.NewEncoder(conn),
+	}
+	if !assert.NoError(t, ch.hello("default", "default", "S3cretPw")) {
+		return
+	}
+	conn.dump.endRead()
+	// the Hello of the client holding the password is not dumped, only its size
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, fmt.Sprintf("[packet] -> Hello: %d bytes", len(stub.written)), lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "[packet] <- Hello: "), lines[1])
+	}
+	for _, line := range lines {
+		assert.NotContains(t, line, fmt.Sprintf("% x", "S3cretPw"))
+	}
+}
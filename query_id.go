@@ -0,0 +1,38 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// WithQueryIDFunc calls fn with the query id sent with the query run with the context: the id of WithQueryID,
+// or the UUID generated by the driver if none is set. The id is the query_id of the query in system.query_log.
+// fn is called before the query is sent to the server.
+func WithQueryIDFunc(ctx context.Context, fn func(queryID string)) context.Context {
+	return context.WithValue(ctx, queryIDFuncKey, fn)
+}
+
+// queryID returns the id of the query run with the context, a random (version 4) UUID if the context has none.
+func queryID(ctx context.Context) (string, error) {
+	if queryID, ok := ctx.Value(queryIDKey).(string); ok && queryID != "" {
+		return queryID, nil
+	}
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	var str [36]byte
+	hex.Encode(str[:], uuid[:4])
+	str[8] = '-'
+	hex.Encode(str[9:13], uuid[4:6])
+	str[13] = '-'
+	hex.Encode(str[14:18], uuid[6:8])
+	str[18] = '-'
+	hex.Encode(str[19:23], uuid[8:10])
+	str[23] = '-'
+	hex.Encode(str[24:], uuid[10:])
+	return string(str[:]), nil
+}
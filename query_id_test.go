@@ -0,0 +1,46 @@
+package clickhouse
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_QueryID(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	// exec returns the query id written in the query packet and the one reported to WithQueryIDFunc
+	exec := func(ctx context.Context) (written, reported string) {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(WithQueryIDFunc(ctx, func(queryID string) { reported = queryID }), "SELECT 1", nil)
+		if assert.NoError(t, err) && assert.Equal(t, byte(protocol.ClientQuery), stub.written[0]) {
+			// the id follows the type of the packet, prefixed by its length
+			written = string(stub.written[2 : 2+stub.written[1]])
+		}
+		return written, reported
+	}
+	written, reported := exec(WithQueryID(context.Background(), "query-1"))
+	assert.Equal(t, "query-1", written)
+	assert.Equal(t, "query-1", reported)
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, ctx := range []context.Context{context.Background(), WithQueryID(context.Background(), "")} {
+		written, reported := exec(ctx)
+		assert.Regexp(t, uuid, written)
+		assert.Equal(t, written, reported)
+	}
+	first, _ := exec(context.Background())
+	second, _ := exec(context.Background())
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,285 @@
+package clickhouse
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// placeholder is the {name:Type} placeholder of a query parameter, the type is empty for {name}.
+type placeholder struct {
+	name   string
+	chType string
+	start  int // the offset of the placeholder in the query
+	end    int // the offset following the placeholder
+}
+
+// placeholders returns the placeholders of the query parameters, the quoted strings and identifiers are skipped.
+func placeholders(query string) []placeholder {
+	var (
+		found                 []placeholder
+		quote, gravis, escape bool
+	)
+	for i := 0; i < len(query); i++ {
+		switch char := query[i]; {
+		case escape:
+			escape = false
+			continue
+		case char == '\\' && (quote || gravis):
+			escape = true
+			continue
+		case char == '\'' && !gravis:
+			quote = !quote
+			continue
+		case char == '`' && !quote:
+			gravis = !gravis
+			continue
+		case quote || gravis || char != '{':
+			continue
+		}
+		end := strings.IndexByte(query[i:], '}')
+		if end == -1 {
+			break
+		}
+		end += i
+		name, chType := query[i+1:end], ""
+		if colon := strings.IndexByte(name, ':'); colon != -1 {
+			name, chType = strings.TrimSpace(name[:colon]), strings.TrimSpace(name[colon+1:])
+			if chType == "" {
+				continue
+			}
+		}
+		if !isParameterName(name) {
+			continue
+		}
+		found = append(found, placeholder{name: name, chType: chType, start: i, end: end + 1})
+		i = end
+	}
+	return found
+}
+
+func isParameterName(name string) bool {
+	for i, char := range name {
+		if !(char == '_' || 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || i != 0 && '0' <= char && char <= '9') {
+			return false
+		}
+	}
+	return len(name) != 0
+}
+
+// bindParameters binds the placeholders of the query to the named arguments: the values are sent as the parameters
+// param_<name> of the HTTP interface, the query is returned with the types of the untyped placeholders inferred
+// from the values.
+func bindParameters(query string, args []driver.NamedValue) (string, map[string]interface{}, error) {
+	found := placeholders(query)
+	if len(found) == 0 {
+		return query, nil, nil
+	}
+	var (
+		bound    strings.Builder
+		last     int
+		settings = make(map[string]interface{}, len(found))
+	)
+	for _, p := range found {
+		arg, chType, err := parameterArg(p, args)
+		if err != nil {
+			return "", nil, err
+		}
+		if p.chType == "" {
+			bound.WriteString(query[last:p.start])
+			bound.WriteString("{" + p.name + ":" + chType + "}")
+			last = p.end
+		}
+		value, err := formatParameter(arg, chType, false)
+		if err != nil {
+			return "", nil, fmt.Errorf("clickhouse: query parameter %s: %v", p.name, err)
+		}
+		settings["param_"+p.name] = value
+	}
+	bound.WriteString(query[last:])
+	return bound.String(), settings, nil
+}
+
+// inlineParameters replaces the placeholders of the query by the literals of the named arguments cast to the types
+// of the placeholders, e.g. CAST([1,2] AS Array(UInt64)), and the Identifier placeholders by the quoted identifiers.
+// The native protocol of the revision of the client has no parameters: the server takes the param_<name> settings
+// for unknown settings.
+func inlineParameters(query string, args []driver.NamedValue) (string, error) {
+	found := placeholders(query)
+	if len(found) == 0 {
+		return query, nil
+	}
+	var (
+		bound strings.Builder
+		last  int
+	)
+	for _, p := range found {
+		arg, chType, err := parameterArg(p, args)
+		if err != nil {
+			return "", err
+		}
+		bound.WriteString(query[last:p.start])
+		last = p.end
+		if chType == "Identifier" {
+			name, ok := arg.(string)
+			if !ok {
+				return "", fmt.Errorf("clickhouse: query parameter %s: %T can't be the value of Identifier", p.name, arg)
+			}
+			bound.WriteString(quoteIdentifier(name))
+			continue
+		}
+		value, err := formatParameter(arg, chType, true)
+		if err != nil {
+			return "", fmt.Errorf("clickhouse: query parameter %s: %v", p.name, err)
+		}
+		bound.WriteString("CAST(" + value + " AS " + chType + ")")
+	}
+	bound.WriteString(query[last:])
+	return bound.String(), nil
+}
+
+// parameterArg returns the named argument of the placeholder and its type, the type of an untyped placeholder
+// is inferred from the value.
+func parameterArg(p placeholder, args []driver.NamedValue) (interface{}, string, error) {
+	arg, ok := namedArg(args, p.name)
+	if !ok {
+		return nil, "", fmt.Errorf("clickhouse: query parameter %s is not bound", p.name)
+	}
+	chType := p.chType
+	if chType == "" {
+		var err error
+		if chType, err = parameterType(arg); err != nil {
+			return nil, "", fmt.Errorf("clickhouse: query parameter %s: %v", p.name, err)
+		}
+	}
+	return arg, chType, nil
+}
+
+func namedArg(args []driver.NamedValue, name string) (interface{}, bool) {
+	for _, arg := range args {
+		if arg.Name == name {
+			return arg.Value, true
+		}
+	}
+	return nil, false
+}
+
+// parameterType returns the type of the placeholder of the value, e.g. DateTime for time.Time.
+func parameterType(v interface{}) (string, error) {
+	if _, ok := v.(time.Time); ok {
+		return "DateTime", nil
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.String:
+		return "String", nil
+	case reflect.Bool:
+		return "UInt8", nil
+	case reflect.Int8:
+		return "Int8", nil
+	case reflect.Int16:
+		return "Int16", nil
+	case reflect.Int32:
+		return "Int32", nil
+	case reflect.Int, reflect.Int64:
+		return "Int64", nil
+	case reflect.Uint8:
+		return "UInt8", nil
+	case reflect.Uint16:
+		return "UInt16", nil
+	case reflect.Uint32:
+		return "UInt32", nil
+	case reflect.Uint, reflect.Uint64:
+		return "UInt64", nil
+	case reflect.Float32:
+		return "Float32", nil
+	case reflect.Float64:
+		return "Float64", nil
+	case reflect.Slice, reflect.Array:
+		element, err := parameterType(reflect.Zero(value.Type().Elem()).Interface())
+		if err != nil {
+			return "", err
+		}
+		return "Array(" + element + ")", nil
+	}
+	return "", fmt.Errorf("the type of %T can't be inferred, set it in the placeholder {name:Type}", v)
+}
+
+var parameterEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`)
+
+// formatParameter returns the value as the server parses the text of the parameter of the type,
+// the strings and the dates are quoted in the elements of the arrays. The values of the string kinds,
+// e.g. of a named string type, are escaped as the strings, the values of the other kinds than the numbers,
+// the booleans, the slices and the pointers are rejected.
+func formatParameter(v interface{}, chType string, quoted bool) (string, error) {
+	var text string
+	switch v := v.(type) {
+	case nil:
+		if quoted {
+			return "NULL", nil
+		}
+		return `\N`, nil
+	case string:
+		if quoted {
+			return quote(v), nil
+		}
+		return parameterEscaper.Replace(v), nil
+	case time.Time:
+		for _, wrapper := range []string{"LowCardinality(", "Nullable("} {
+			if strings.HasPrefix(chType, wrapper) {
+				chType = chType[len(wrapper):]
+			}
+		}
+		switch {
+		case strings.HasPrefix(chType, "DateTime64"):
+			text = fmt.Sprintf("%d.%09d", v.Unix(), v.Nanosecond())
+		case strings.HasPrefix(chType, "DateTime"):
+			text = strconv.FormatInt(v.Unix(), 10)
+		default:
+			text = v.UTC().Format("2006-01-02")
+		}
+		if quoted {
+			return "'" + text + "'", nil
+		}
+		return text, nil
+	}
+	switch value := reflect.ValueOf(v); value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !strings.HasPrefix(chType, "Array(") || !strings.HasSuffix(chType, ")") {
+			return "", fmt.Errorf("%T can't be the value of %s", v, chType)
+		}
+		elements := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			element, err := formatParameter(value.Index(i).Interface(), chType[6:len(chType)-1], true)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, element)
+		}
+		return "[" + strings.Join(elements, ",") + "]", nil
+	case reflect.Ptr:
+		if value.IsNil() {
+			return formatParameter(nil, chType, quoted)
+		}
+		return formatParameter(value.Elem().Interface(), chType, quoted)
+	case reflect.String:
+		return formatParameter(value.String(), chType, quoted)
+	case reflect.Bool:
+		if value.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("%T can't be the value of %s", v, chType)
+}
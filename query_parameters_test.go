@@ -0,0 +1,166 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BindParameters(t *testing.T) {
+	date := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	dateTime := time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)
+	for _, test := range []struct {
+		query    string
+		args     []driver.NamedValue
+		expected string
+		settings map[string]interface{}
+	}{
+		{
+			query:    "SELECT * FROM example WHERE name = {name:String} AND id = {id:UInt32}",
+			args:     []driver.NamedValue{{Name: "id", Value: int64(42)}, {Name: "name", Value: "it's\ta \\ test"}},
+			expected: "SELECT * FROM example WHERE name = {name:String} AND id = {id:UInt32}",
+			settings: map[string]interface{}{"param_name": `it's\ta \\ test`, "param_id": "42"},
+		},
+		{
+			query:    "SELECT * FROM example WHERE tag IN {tags:Array(String)} AND id IN {ids:Array(Int64)}",
+			args:     []driver.NamedValue{{Name: "tags", Value: []string{"a", "b'c"}}, {Name: "ids", Value: []int64{1, -2}}},
+			expected: "SELECT * FROM example WHERE tag IN {tags:Array(String)} AND id IN {ids:Array(Int64)}",
+			settings: map[string]interface{}{"param_tags": `['a','b\'c']`, "param_ids": "[1,-2]"},
+		},
+		{
+			query:    "SELECT {date:Date}, {date_time:DateTime('UTC')}, {precise:DateTime64(9)}, {nullable:Nullable(DateTime)}",
+			args:     []driver.NamedValue{{Name: "date", Value: date}, {Name: "date_time", Value: dateTime}, {Name: "precise", Value: dateTime}, {Name: "nullable", Value: nil}},
+			expected: "SELECT {date:Date}, {date_time:DateTime('UTC')}, {precise:DateTime64(9)}, {nullable:Nullable(DateTime)}",
+			settings: map[string]interface{}{
+				"param_date":      "2021-03-04",
+				"param_date_time": "1614834367",
+				"param_precise":   "1614834367.000000008",
+				"param_nullable":  `\N`,
+			},
+		},
+		{
+			// the types of the untyped placeholders are inferred from the values
+			query:    "SELECT {time}, {name}, {ids}, {flag} FROM example WHERE s = '{quoted}'",
+			args:     []driver.NamedValue{{Name: "time", Value: dateTime}, {Name: "name", Value: "a"}, {Name: "ids", Value: []uint16{1}}, {Name: "flag", Value: true}},
+			expected: "SELECT {time:DateTime}, {name:String}, {ids:Array(UInt16)}, {flag:UInt8} FROM example WHERE s = '{quoted}'",
+			settings: map[string]interface{}{"param_time": "1614834367", "param_name": "a", "param_ids": "[1]", "param_flag": "1"},
+		},
+		{
+			// the slices of clickhouse.Array
+			query: "SELECT * FROM example WHERE id IN {ids:Array(UInt64)} AND name IN {names:Array(String)} AND day IN {days:Array(Date)}",
+			args: []driver.NamedValue{
+				{Name: "ids", Value: Array([]uint64{7, 42})},
+				{Name: "names", Value: Array([]string{"a", `b\'); DROP TABLE example; --`})},
+				{Name: "days", Value: Array([]time.Time{date, time.Date(1969, 7, 20, 0, 0, 0, 0, time.UTC)})},
+			},
+			expected: "SELECT * FROM example WHERE id IN {ids:Array(UInt64)} AND name IN {names:Array(String)} AND day IN {days:Array(Date)}",
+			settings: map[string]interface{}{
+				"param_ids":   "[7,42]",
+				"param_names": `['a','b\\\'); DROP TABLE example; --']`,
+				"param_days":  "['2021-03-04','1969-07-20']",
+			},
+		},
+		{
+			query:    "SELECT {ids}, {none:Array(Int32)}",
+			args:     []driver.NamedValue{{Name: "ids", Value: Array([]int32{-1})}, {Name: "none", Value: Array([]int32{})}},
+			expected: "SELECT {ids:Array(Int32)}, {none:Array(Int32)}",
+			settings: map[string]interface{}{"param_ids": "[-1]", "param_none": "[]"},
+		},
+		{
+			query:    "SELECT 1",
+			expected: "SELECT 1",
+		},
+	} {
+		query, settings, err := bindParameters(test.query, test.args)
+		if assert.NoError(t, err, test.query) {
+			assert.Equal(t, test.expected, query)
+			assert.Equal(t, test.settings, settings, test.query)
+		}
+	}
+	if _, _, err := bindParameters("SELECT {a:String}, {b:String}", []driver.NamedValue{{Name: "a", Value: "a"}}); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "query parameter b is not bound")
+	}
+	if _, _, err := bindParameters("SELECT {a}", []driver.NamedValue{{Name: "a", Value: struct{}{}}}); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "{name:Type}")
+	}
+	_, _, err := bindParameters("SELECT {a:String}", []driver.NamedValue{{Name: "a", Value: []string{"a"}}})
+	assert.Error(t, err)
+}
+
+func Test_InlineParameters(t *testing.T) {
+	dateTime := time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)
+	for _, test := range []struct {
+		query    string
+		args     []driver.NamedValue
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM example WHERE name = {name:String} AND id = {id}",
+			args:     []driver.NamedValue{{Name: "name", Value: "it's a \\ test"}, {Name: "id", Value: int64(42)}},
+			expected: `SELECT * FROM example WHERE name = CAST('it\'s a \\ test' AS String) AND id = CAST(42 AS Int64)`,
+		},
+		{
+			query:    "SELECT * FROM {table:Identifier} WHERE id IN {ids:Array(UInt64)} AND name IN {names}",
+			args:     []driver.NamedValue{{Name: "table", Value: "exa`mple"}, {Name: "ids", Value: Array([]uint64{1, 2})}, {Name: "names", Value: []string{"a", "b'); DROP TABLE example; --"}}},
+			expected: "SELECT * FROM `exa\\`mple` WHERE id IN CAST([1,2] AS Array(UInt64)) AND name IN CAST(['a','b\\'); DROP TABLE example; --'] AS Array(String))",
+		},
+		{
+			query:    "SELECT {time:DateTime}, {precise:DateTime64(9)}, {nullable:Nullable(String)}, '{quoted:String}'",
+			args:     []driver.NamedValue{{Name: "time", Value: dateTime}, {Name: "precise", Value: dateTime}, {Name: "nullable", Value: nil}},
+			expected: "SELECT CAST('1614834367' AS DateTime), CAST('1614834367.000000008' AS DateTime64(9)), CAST(NULL AS Nullable(String)), '{quoted:String}'",
+		},
+	} {
+		query, err := inlineParameters(test.query, test.args)
+		if assert.NoError(t, err, test.query) {
+			assert.Equal(t, test.expected, query)
+		}
+	}
+	if _, err := inlineParameters("SELECT * FROM {table:Identifier}", []driver.NamedValue{{Name: "table", Value: 1}}); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Identifier")
+	}
+	// the values of the named string types are escaped as the strings, the values of the other kinds are rejected
+	type name string
+	for _, test := range []struct {
+		arg      interface{}
+		expected string
+	}{
+		{name("a') OR 1=1 --"), `SELECT CAST('a\') OR 1=1 --' AS String)`},
+		{[]name{"a') OR 1=1 --"}, `SELECT CAST(['a\') OR 1=1 --'] AS Array(String))`},
+	} {
+		chType := "String"
+		if _, ok := test.arg.([]name); ok {
+			chType = "Array(String)"
+		}
+		value := driver.NamedValue{Name: "v", Value: test.arg}
+		if assert.NoError(t, checkNamedValue(&value)) {
+			query, err := inlineParameters("SELECT {v:"+chType+"}", []driver.NamedValue{value})
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, query)
+			}
+		}
+	}
+	if _, err := inlineParameters("SELECT {v:Array(String)}", []driver.NamedValue{{Name: "v", Value: []struct{}{{}}}}); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: query parameter v: struct {} can't be the value of String", err.Error())
+	}
+
+	// the parameters are not sent as settings over the native protocol
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	stub.data = []byte{protocol.ServerEndOfStream}
+	if _, err := ch.ExecContext(context.Background(), "SELECT {id:UInt64}", []driver.NamedValue{{Name: "id", Value: uint64(7)}}); assert.NoError(t, err) {
+		assert.Contains(t, string(stub.written), "SELECT CAST(7 AS UInt64)")
+		assert.NotContains(t, string(stub.written), "param_id")
+	}
+}
@@ -1,8 +1,12 @@
 package clickhouse
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
+	"sort"
 	"strconv"
 
 	"github.com/c3mb0/clickhouse-go/lib/binary"
@@ -16,6 +20,8 @@ const (
 	intQS
 	boolQS
 	timeQS
+	stringQS
+	floatQS
 )
 
 // description of single query setting
@@ -196,6 +202,11 @@ var querySettingList = []querySettingInfo{
 	{"allow_experimental_data_skipping_indices", boolQS},
 	{"allow_hyperscan", boolQS},
 	{"allow_simdjson", boolQS},
+	{"flatten_nested", boolQS},
+	{"allow_experimental_object_type", boolQS},
+	{"async_insert", boolQS},
+	{"wait_for_async_insert", boolQS},
+	{"async_insert_deduplicate", boolQS},
 
 	{"connect_timeout", timeQS},
 	{"connect_timeout_with_failover_ms", timeQS},
@@ -214,8 +225,26 @@ var querySettingList = []querySettingInfo{
 	{"http_receive_timeout", timeQS},
 	{"max_execution_time", timeQS},
 	{"timeout_before_checking_execution_speed", timeQS},
+
+	{"load_balancing", stringQS},
+	{"totals_mode", stringQS},
+	{"distributed_product_mode", stringQS},
+	{"count_distinct_implementation", stringQS},
+	{"network_compression_method", stringQS},
+	{"insert_deduplication_token", stringQS},
+
+	{"totals_auto_threshold", floatQS},
 }
 
+// querySettingTypes are the types of the settings of querySettingList by their names.
+var querySettingTypes = func() map[string]querySettingType {
+	types := make(map[string]querySettingType, len(querySettingList))
+	for _, info := range querySettingList {
+		types[info.name] = info.qsType
+	}
+	return types
+}()
+
 type querySettingValueEncoder func(enc *binary.Encoder) error
 
 type querySettings struct {
@@ -254,6 +283,17 @@ func makeQuerySettings(query url.Values) (*querySettings, error) {
 			}
 			qs.settings[info.name] = func(enc *binary.Encoder) error { return enc.Uvarint(value) }
 
+		case stringQS:
+			value := valueStr
+			qs.settings[info.name] = func(enc *binary.Encoder) error { return enc.String(value) }
+
+		case floatQS:
+			if _, err := strconv.ParseFloat(valueStr, 64); err != nil {
+				return nil, err
+			}
+			value := valueStr
+			qs.settings[info.name] = func(enc *binary.Encoder) error { return enc.String(value) }
+
 		default:
 			err := fmt.Errorf("query setting %s has unsupported data type", info.name)
 			return nil, err
@@ -268,6 +308,157 @@ func makeQuerySettings(query url.Values) (*querySettings, error) {
 	return qs, nil
 }
 
+// with returns the settings overridden by the settings of a query, see WithSettings.
+func (qs *querySettings) with(overrides map[string]interface{}) (*querySettings, error) {
+	if len(overrides) == 0 {
+		return qs, nil
+	}
+	merged := &querySettings{
+		settings: make(map[string]querySettingValueEncoder, len(qs.settings)+len(overrides)),
+	}
+	for name, fn := range qs.settings {
+		if _, overridden := overrides[name]; !overridden {
+			merged.settings[name] = fn
+		}
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	merged.settingsStr = qs.settingsStr
+	for _, name := range names {
+		fn, valueStr, err := encodeQuerySetting(name, overrides[name])
+		if err != nil {
+			return nil, fmt.Errorf("clickhouse: query setting %s: %v", name, err)
+		}
+		merged.settings[name] = fn
+		if merged.settingsStr != "" {
+			merged.settingsStr += "&"
+		}
+		merged.settingsStr += name + "=" + valueStr
+	}
+	return merged, nil
+}
+
+// encodeQuerySetting returns the encoder of the value of a setting as of the type of the setting on the server:
+// the settings of the revision of the client are written as varints for the numbers and the booleans, as strings
+// for the strings and the floats. The value is converted to the type of the setting, e.g. "1000" for max_block_size,
+// the unknown settings are rejected since the server would read their values as of its own types.
+func encodeQuerySetting(name string, v interface{}) (querySettingValueEncoder, string, error) {
+	qsType, ok := querySettingTypes[name]
+	if !ok {
+		return nil, "", errors.New("unknown setting")
+	}
+	value := reflect.ValueOf(v)
+	switch qsType {
+	case uintQS, intQS, timeQS:
+		number, err := settingUint(value)
+		if err != nil {
+			return nil, "", err
+		}
+		return func(enc *binary.Encoder) error { return enc.Uvarint(number) }, strconv.FormatUint(number, 10), nil
+	case boolQS:
+		flag, err := settingBool(value)
+		if err != nil {
+			return nil, "", err
+		}
+		number := uint64(0)
+		if flag {
+			number = 1
+		}
+		return func(enc *binary.Encoder) error { return enc.Uvarint(number) }, strconv.FormatBool(flag), nil
+	case floatQS:
+		var number float64
+		switch value.Kind() {
+		case reflect.Float32, reflect.Float64:
+			number = value.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			number = float64(value.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			number = float64(value.Uint())
+		case reflect.String:
+			var err error
+			if number, err = strconv.ParseFloat(value.String(), 64); err != nil {
+				return nil, "", fmt.Errorf("invalid value %q, the setting is a float", value.String())
+			}
+		default:
+			return nil, "", fmt.Errorf("unsupported value %T, the setting is a float", v)
+		}
+		str := strconv.FormatFloat(number, 'g', -1, 64)
+		return func(enc *binary.Encoder) error { return enc.String(str) }, str, nil
+	case stringQS:
+		if value.Kind() != reflect.String {
+			return nil, "", fmt.Errorf("unsupported value %T, the setting is a string", v)
+		}
+		str := value.String()
+		return func(enc *binary.Encoder) error { return enc.String(str) }, str, nil
+	}
+	return nil, "", fmt.Errorf("unsupported type of the setting")
+}
+
+// settingUint converts the value of a numeric setting, the booleans are 0 and 1.
+func settingUint(value reflect.Value) (uint64, error) {
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Int() < 0 {
+			return 0, fmt.Errorf("negative value %d", value.Int())
+		}
+		return uint64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), nil
+	case reflect.String:
+		number, err := strconv.ParseUint(value.String(), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q, the setting is a non-negative integer", value.String())
+		}
+		return number, nil
+	}
+	if !value.IsValid() {
+		return 0, errors.New("unsupported value <nil>")
+	}
+	return 0, fmt.Errorf("unsupported value %s, the setting is a non-negative integer", value.Type())
+}
+
+// settingBool converts the value of a boolean setting, the integers are 0 or 1.
+func settingBool(value reflect.Value) (bool, error) {
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.String:
+		flag, err := strconv.ParseBool(value.String())
+		if err != nil {
+			return false, fmt.Errorf("invalid value %q, the setting is a boolean", value.String())
+		}
+		return flag, nil
+	}
+	number, err := settingUint(value)
+	if err != nil || number > 1 {
+		return false, fmt.Errorf("invalid value %v, the setting is a boolean", value)
+	}
+	return number == 1, nil
+}
+
+// withQuerySettings returns the context of the queries run with the settings, in addition to the settings
+// of the context and of the DSN.
+func withQuerySettings(ctx context.Context, settings map[string]interface{}) context.Context {
+	merged := make(map[string]interface{}, len(settings))
+	if parent, ok := ctx.Value(querySettingsKey).(map[string]interface{}); ok {
+		for name, value := range parent {
+			merged[name] = value
+		}
+	}
+	for name, value := range settings {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, querySettingsKey, merged)
+}
+
 func (qs *querySettings) IsEmpty() bool {
 	return len(qs.settings) == 0
 }
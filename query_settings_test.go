@@ -0,0 +1,277 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithAsyncInsert(t *testing.T) {
+	exec := func(ctx context.Context) []byte {
+		settings, err := makeQuerySettings(url.Values{
+			"async_insert":   []string{"0"},
+			"max_block_size": []string{"42"},
+		})
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		stub := &stubConn{data: []byte{protocol.ServerEndOfStream}}
+		conn := newStubConnect(t, stub, connOptions{})
+		ch := &clickhouse{
+			conn:     conn,
+			logf:     func(string, ...interface{}) {},
+			settings: settings,
+			decoder:  binary.NewDecoder(conn),
+			encoder:  binary.NewEncoder(conn),
+		}
+		_, err = ch.ExecContext(ctx, "SELECT 1", nil)
+		assert.NoError(t, err)
+		return stub.written
+	}
+	// a setting is written as its name, prefixed by its length, and its value
+	var (
+		asyncInsertOff = []byte("\x0casync_insert\x00")
+		asyncInsertOn  = []byte("\x0casync_insert\x01")
+		waitOff        = []byte("\x15wait_for_async_insert\x00")
+		waitOn         = []byte("\x15wait_for_async_insert\x01")
+		maxBlockSize   = []byte("\x0emax_block_size\x2a")
+	)
+	written := exec(context.Background())
+	assert.True(t, bytes.Contains(written, asyncInsertOff))
+	assert.False(t, bytes.Contains(written, []byte("wait_for_async_insert")))
+	assert.True(t, bytes.Contains(written, maxBlockSize))
+
+	written = exec(WithAsyncInsert(context.Background(), false))
+	assert.Equal(t, 1, bytes.Count(written, []byte("\x0casync_insert")))
+	assert.True(t, bytes.Contains(written, asyncInsertOn))
+	assert.True(t, bytes.Contains(written, waitOff))
+	assert.True(t, bytes.Contains(written, maxBlockSize))
+
+	written = exec(WithAsyncInsert(WithAsyncInsert(context.Background(), false), true))
+	assert.True(t, bytes.Contains(written, asyncInsertOn))
+	assert.True(t, bytes.Contains(written, waitOn))
+	assert.False(t, bytes.Contains(written, waitOff))
+}
+
+func Test_WithMaxBlockSize(t *testing.T) {
+	settings, err := makeQuerySettings(url.Values{"max_block_size": []string{"42"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: settings,
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	exec := func(ctx context.Context) ([]byte, error) {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		return stub.written, err
+	}
+	if written, err := exec(WithMaxBlockSize(context.Background(), 1000)); assert.NoError(t, err) {
+		// the setting of the DSN is replaced, 1000 is written as the varint e8 07
+		assert.Equal(t, 1, bytes.Count(written, []byte("\x0emax_block_size")))
+		assert.True(t, bytes.Contains(written, []byte("\x0emax_block_size\xe8\x07")))
+	}
+	if written, err := exec(context.Background()); assert.NoError(t, err) {
+		assert.True(t, bytes.Contains(written, []byte("\x0emax_block_size\x2a")))
+	}
+	if written, err := exec(WithMaxBlockSize(context.Background(), -1)); assert.Error(t, err) {
+		assert.Empty(t, written)
+		assert.Contains(t, err.Error(), "max_block_size")
+	}
+}
+
+func Test_WithDeduplicationToken(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	exec := func(ctx context.Context) []byte {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		assert.NoError(t, err)
+		return stub.written
+	}
+	ctx := WithAsyncInsert(WithDeduplicationToken(context.Background(), "batch-42"), true)
+	written := exec(ctx)
+	assert.True(t, bytes.Contains(written, []byte("\x1ainsert_deduplication_token\x08batch-42")))
+	assert.True(t, bytes.Contains(written, []byte("\x0casync_insert\x01")))
+	// the token is not sent with the following queries of the connection
+	assert.False(t, bytes.Contains(exec(context.Background()), []byte("insert_deduplication_token")))
+}
+
+func Test_QuerySettingsWith(t *testing.T) {
+	settings, err := makeQuerySettings(url.Values{"max_block_size": []string{"42"}})
+	if assert.NoError(t, err) {
+		if unchanged, err := settings.with(nil); assert.NoError(t, err) {
+			assert.True(t, settings == unchanged)
+		}
+		merged, err := settings.with(map[string]interface{}{"wait_for_async_insert": false, "async_insert": true, "max_block_size": uint16(10)})
+		if assert.NoError(t, err) {
+			assert.Len(t, merged.settings, 3)
+			assert.Len(t, settings.settings, 1)
+			assert.Equal(t, "max_block_size=42&async_insert=true&max_block_size=10&wait_for_async_insert=false", merged.settingsStr)
+		}
+		for _, v := range []interface{}{-1, nil, []int{1}} {
+			_, err := settings.with(map[string]interface{}{"max_threads": v})
+			assert.Error(t, err)
+		}
+	}
+}
+
+func Test_QuerySettingsTypes(t *testing.T) {
+	// the values are written as of the types of the settings whatever their Go types
+	for _, test := range []struct {
+		name     string
+		values   []interface{}
+		expected string
+	}{
+		{"max_block_size", []interface{}{"1000", 1000, uint16(1000)}, "\x0emax_block_size\xe8\x07"},
+		{"async_insert", []interface{}{"true", true, 1}, "\x0casync_insert\x01"},
+		{"load_balancing", []interface{}{"in_order"}, "\x0eload_balancing\x08in_order"},
+		{"totals_auto_threshold", []interface{}{"0.5", 0.5, float32(0.5)}, "\x15totals_auto_threshold\x030.5"},
+	} {
+		for _, v := range test.values {
+			if settings, err := (&querySettings{}).with(map[string]interface{}{test.name: v}); assert.NoError(t, err, "%s=%v", test.name, v) {
+				var buf bytes.Buffer
+				if assert.NoError(t, settings.Serialize(binary.NewEncoder(&buf))) {
+					assert.Equal(t, test.expected, buf.String(), "%s=%v", test.name, v)
+				}
+			}
+		}
+	}
+	for name, v := range map[string]interface{}{
+		"max_block_size":        "1k",
+		"async_insert":          2,
+		"load_balancing":        1,
+		"totals_auto_threshold": "half",
+		"unknown_setting":       1,
+	} {
+		_, err := (&querySettings{}).with(map[string]interface{}{name: v})
+		if assert.Error(t, err, name) {
+			assert.Contains(t, err.Error(), "clickhouse: query setting "+name+": ")
+		}
+	}
+}
+
+func Test_WithSettings(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:     conn,
+		logf:     func(string, ...interface{}) {},
+		settings: &querySettings{},
+		decoder:  binary.NewDecoder(conn),
+		encoder:  binary.NewEncoder(conn),
+	}
+	exec := func(ctx context.Context) ([]byte, error) {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		return stub.written, err
+	}
+	ctx := WithSettings(context.Background(), map[string]interface{}{
+		"max_execution_time":    60,
+		"readonly":              true,
+		"load_balancing":        "in_order",
+		"totals_auto_threshold": 0.5,
+	})
+	if written, err := exec(ctx); assert.NoError(t, err) {
+		assert.True(t, bytes.Contains(written, []byte("\x12max_execution_time\x3c")))
+		assert.True(t, bytes.Contains(written, []byte("\x08readonly\x01")))
+		assert.True(t, bytes.Contains(written, []byte("\x0eload_balancing\x08in_order")))
+		assert.True(t, bytes.Contains(written, []byte("\x15totals_auto_threshold\x030.5")))
+	}
+	// the settings are the ones of the query only
+	if written, err := exec(context.Background()); assert.NoError(t, err) {
+		assert.False(t, bytes.Contains(written, []byte("max_execution_time")))
+	}
+	// the query is not written if a setting can't be
+	ctx = WithSettings(context.Background(), map[string]interface{}{"max_threads": -1})
+	if written, err := exec(ctx); assert.Error(t, err) {
+		assert.Empty(t, written)
+		assert.Contains(t, err.Error(), "max_threads")
+	}
+	if written, err := exec(context.Background()); assert.NoError(t, err) {
+		assert.True(t, bytes.HasPrefix(written, []byte{protocol.ClientQuery}))
+	}
+}
+
+func Test_WithQuotaKey(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		ServerInfo: data.ServerInfo{Revision: protocol.DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO},
+		conn:       conn,
+		logf:       func(string, ...interface{}) {},
+		settings:   &querySettings{},
+		decoder:    binary.NewDecoder(conn),
+		encoder:    binary.NewEncoder(conn),
+		quotaKey:   "default_key",
+	}
+	exec := func(ctx context.Context) []byte {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		assert.NoError(t, err)
+		return stub.written
+	}
+	// the quota key follows the client info, it is written as a string prefixed by its length
+	written := exec(WithQuotaKey(context.Background(), "tenant_a"))
+	assert.True(t, bytes.Contains(written, []byte("\x08tenant_a")))
+	assert.False(t, bytes.Contains(written, []byte("default_key")))
+
+	written = exec(WithQuotaKey(context.Background(), "tenant_b"))
+	assert.True(t, bytes.Contains(written, []byte("\x08tenant_b")))
+	assert.False(t, bytes.Contains(written, []byte("tenant_a")))
+
+	written = exec(context.Background())
+	assert.True(t, bytes.Contains(written, []byte("\x0bdefault_key")))
+	assert.False(t, bytes.Contains(written, []byte("tenant_")))
+}
+
+func Test_MaxQuerySize(t *testing.T) {
+	stub := &stubConn{}
+	conn := newStubConnect(t, stub, connOptions{})
+	ch := &clickhouse{
+		conn:         conn,
+		logf:         func(string, ...interface{}) {},
+		settings:     &querySettings{},
+		decoder:      binary.NewDecoder(conn),
+		encoder:      binary.NewEncoder(conn),
+		maxQuerySize: 16,
+	}
+	exec := func(ctx context.Context, query string) error {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, query, nil)
+		return err
+	}
+	ctx := context.Background()
+	assert.NoError(t, exec(ctx, "SELECT 12345678"))
+	err := exec(ctx, "SELECT 1234567890")
+	if assert.True(t, errors.Is(err, ErrQueryTooLarge), err) {
+		assert.Equal(t, "clickhouse: query too large: the query is 17 bytes, max_query_size is 16", err.Error())
+		assert.Empty(t, stub.written, "the query is not sent")
+	}
+	// the max_query_size of the settings of the query overrides the one of the DSN
+	assert.NoError(t, exec(WithSettings(ctx, map[string]interface{}{"max_query_size": 1024}), "SELECT 1234567890"))
+	assert.True(t, errors.Is(exec(WithSettings(ctx, map[string]interface{}{"max_query_size": uint32(8)}), "SELECT 1234"), ErrQueryTooLarge))
+	// the size is not checked without max_query_size
+	ch.maxQuerySize = 0
+	assert.NoError(t, exec(ctx, "SELECT 1234567890"))
+}
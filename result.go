@@ -6,3 +6,12 @@ type result struct{}
 
 func (*result) LastInsertId() (int64, error) { return 0, errors.New("LastInsertId is not supported") }
 func (*result) RowsAffected() (int64, error) { return 0, errors.New("RowsAffected is not supported") }
+
+// insertResult is the result of an INSERT, its rows are the rows sent as counted by the client
+// (the revision of the protocol of the driver is older than 54420, the server doesn't report the written rows).
+type insertResult struct {
+	rows int64
+}
+
+func (*insertResult) LastInsertId() (int64, error)   { return (&result{}).LastInsertId() }
+func (r *insertResult) RowsAffected() (int64, error) { return r.rows, nil }
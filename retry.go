@@ -0,0 +1,52 @@
+package clickhouse
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// retryQuery reports whether the query failed by err at the attempt is run again, on a fresh connection: the
+// exception of the server has to be one of the retry_on_codes, and the query an idempotent read. An INSERT is
+// never retried (its rows could be written twice), neither is a query of a session or of a transaction, whose
+// state is lost with the connection. The query is retried after the backoff of the attempt, unless the deadline
+// of the context is reached before.
+func (ch *clickhouse) retryQuery(ctx context.Context, query string, err error, attempt int) bool {
+	exception, ok := err.(*Exception)
+	if !ok || attempt >= ch.retryAttempts || ch.reconnect == nil || !ch.retryOnCode(exception.Code) {
+		return false
+	}
+	if _, inSession := ctx.Value(sessionKey).(session); inSession || ch.sessionID != "" || ch.inTransaction || hasExternalReaders(ctx) {
+		return false
+	}
+	if f := strings.Fields(query); len(f) != 0 && strings.EqualFold(f[0], "INSERT") {
+		return false
+	}
+	backoff := dialBackoff(0, attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+		return false
+	}
+	logEvent(ch.logger, ch.logf, LogInfo, "[retry]", "attempt", attempt+1, "code", exception.Code, "backoff", backoff, "host", ch.conn.host)
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+	}
+	ch.conn.Close()
+	if err := ch.reconnect(ctx); err != nil {
+		logEvent(ch.logger, ch.logf, LogWarn, "[retry] reconnect failed", "attempt", attempt+1, "error", err)
+		return false
+	}
+	return true
+}
+
+func (ch *clickhouse) retryOnCode(code int32) bool {
+	for _, c := range ch.retryOnCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RetryQuery(t *testing.T) {
+	exception := func(code int32) []byte {
+		var (
+			buf     bytes.Buffer
+			encoder = binary.NewEncoder(&buf)
+		)
+		encoder.Uvarint(protocol.ServerException)
+		encoder.Int32(code)
+		encoder.String("DB::Exception")
+		encoder.String("DB::Exception: failed")
+		encoder.String("")
+		encoder.Bool(false)
+		return buf.Bytes()
+	}
+	// query runs the query on a connection whose server fails it by the codes, a fresh connection by code;
+	// it returns the number of connections used and the error of the query
+	query := func(ctx context.Context, query string, ch *clickhouse, codes ...int32) (int, error) {
+		var used int
+		ch.reconnect = func(context.Context) error {
+			stub := &stubConn{data: exception(codes[used])}
+			used++
+			ch.conn = newStubConnect(t, stub, connOptions{})
+			ch.decoder, ch.encoder = binary.NewDecoder(ch.conn), binary.NewEncoder(ch.conn)
+			return nil
+		}
+		ch.reconnect(ctx)
+		ch.logf, ch.settings = func(string, ...interface{}) {}, &querySettings{}
+		stmt, err := ch.PrepareContext(ctx, query)
+		if !assert.NoError(t, err) {
+			return used, err
+		}
+		_, err = stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+		return used, err
+	}
+	code := func(err error) int32 {
+		if exception, ok := err.(*Exception); assert.True(t, ok, err) {
+			return exception.Code
+		}
+		return 0
+	}
+	ctx := context.Background()
+
+	// a retryable error, then a non-retryable one
+	used, err := query(ctx, "SELECT 1", &clickhouse{retryOnCodes: []int32{159, 202}, retryAttempts: 3}, 159, 60, 159)
+	assert.Equal(t, int32(60), code(err))
+	assert.Equal(t, 2, used)
+
+	// the attempts are exhausted
+	used, err = query(ctx, "SELECT 1", &clickhouse{retryOnCodes: []int32{159, 202}, retryAttempts: 2}, 202, 159, 202, 159)
+	assert.Equal(t, int32(202), code(err))
+	assert.Equal(t, 3, used)
+
+	// the code is not retryable, no retry is configured
+	used, err = query(ctx, "SELECT 1", &clickhouse{retryOnCodes: []int32{202}, retryAttempts: 1}, 159, 60)
+	assert.Equal(t, int32(159), code(err))
+	assert.Equal(t, 1, used)
+	used, err = query(ctx, "SELECT 1", &clickhouse{retryAttempts: 1}, 159, 60)
+	assert.Equal(t, int32(159), code(err))
+	assert.Equal(t, 1, used)
+
+	// an insert, or a query of a session, is not retried
+	used, err = query(ctx, "INSERT INTO t SELECT 1", &clickhouse{retryOnCodes: []int32{159}, retryAttempts: 1}, 159, 60)
+	assert.Equal(t, int32(159), code(err))
+	assert.Equal(t, 1, used)
+	used, err = query(WithSessionID(ctx, "s", false), "SELECT 1", &clickhouse{retryOnCodes: []int32{159}, retryAttempts: 1}, 159, 60)
+	assert.Equal(t, int32(159), code(err))
+	assert.Equal(t, 1, used)
+
+	// the deadline of the context is reached before the backoff
+	deadline, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	used, err = query(deadline, "SELECT 1", &clickhouse{retryOnCodes: []int32{159}, retryAttempts: 1}, 159, 60)
+	assert.Equal(t, int32(159), code(err))
+	assert.Equal(t, 1, used)
+}
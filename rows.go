@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,14 @@ type rows struct {
 	stream       chan *data.Block
 	columns      []string
 	blockColumns []column.Column
+	enumValues   bool           // scan the values of the enums instead of the names, see WithEnumValues
+	trimFixed    bool           // trim the padding of the fixed strings, see WithTrimmedFixedStrings
+	demand       chan struct{}  // the requests of the next block to decode, nil unless WithBlockStreaming
+	closed       bool           // the rows have been closed, Next returns io.EOF
+	span         Span           // the span of the query ended by Close, nil unless a Tracer is registered
+	current      []driver.Value // the values of the row returned by the last Next, see ScanStruct and ScanMap
+	structType   reflect.Type   // the struct scanned at last by ScanStruct
+	structFields [][]int        // the index of the field of structType of each column
 }
 
 func (rows *rows) Columns() []string {
@@ -32,30 +41,93 @@ func (rows *rows) Columns() []string {
 }
 
 func (rows *rows) ColumnTypeScanType(idx int) reflect.Type {
+	if enum := rows.enum(idx); enum != nil {
+		return enum.ValueType()
+	}
 	return rows.blockColumns[idx].ScanType()
 }
 
+// enum returns the Enum8 or Enum16 column, possibly Nullable, to scan the values of, nil if there is none.
+func (rows *rows) enum(idx int) *column.Enum {
+	if !rows.enumValues {
+		return nil
+	}
+	enum, _ := rows.column(idx).(*column.Enum)
+	return enum
+}
+
+// trimmed reports whether the values of the FixedString(N) column, possibly Nullable, are scanned without the padding.
+func (rows *rows) trimmed(idx int) bool {
+	if !rows.trimFixed {
+		return false
+	}
+	_, ok := rows.column(idx).(*column.FixedString)
+	return ok
+}
+
+// column returns the column of the values scanned, the column inside Nullable for a Nullable column.
+func (rows *rows) column(idx int) column.Column {
+	c := rows.blockColumns[idx]
+	if nullable, ok := c.(*column.Nullable); ok {
+		c = nullable.GetColumn()
+	}
+	return c
+}
+
 func (rows *rows) ColumnTypeDatabaseTypeName(idx int) string {
 	return rows.blockColumns[idx].CHType()
 }
 
 func (rows *rows) Next(dest []driver.Value) error {
+	if rows.closed {
+		// the demand of the blocks is closed, nothing is requested anymore
+		return io.EOF
+	}
 	if rows.block == nil || int(rows.block.NumRows) <= rows.offset {
-		switch block, ok := <-rows.stream; true {
-		case !ok:
-			if err := rows.error(); err != nil {
-				return err
-			}
-			return io.EOF
-		default:
-			rows.block = block
-			rows.offset = 0
+		if err := rows.nextBlock(); err != nil {
+			return err
 		}
 	}
 	for i := range dest {
-		dest[i] = rows.block.Values[i][rows.offset]
+		dest[i] = rows.block.Value(i, rows.offset)
+		if enum := rows.enum(i); enum != nil {
+			if ident, ok := dest[i].(string); ok {
+				dest[i], _ = enum.Value(ident)
+			}
+		}
+		if rows.trimmed(i) {
+			if str, ok := dest[i].(string); ok {
+				dest[i] = strings.TrimRight(str, "\x00")
+			}
+		}
+		if rows.demand != nil && rows.block.Values[i] != nil {
+			// the value is not referenced by the block anymore
+			rows.block.Values[i][rows.offset] = nil
+		}
 	}
 	rows.offset++
+	rows.current = dest
+	return nil
+}
+
+// nextBlock waits for the next block of the results, io.EOF at their end.
+func (rows *rows) nextBlock() error {
+	if rows.demand != nil {
+		rows.block = nil
+		select {
+		case rows.demand <- struct{}{}:
+		default:
+		}
+	}
+	block, ok := <-rows.stream
+	if !ok {
+		if err := rows.error(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	rows.block = block
+	rows.offset = 0
 	return nil
 }
 
@@ -81,15 +153,33 @@ func (rows *rows) NextResultSet() error {
 
 func (rows *rows) receiveData() error {
 	defer close(rows.stream)
+	defer rows.ch.conn.release()
+	defer rows.ch.reportQueryStats()
+	defer func() {
+		// the rows of a canceled query end with the error of its context
+		if err := rows.ch.endReading(); err != nil {
+			rows.setError(err)
+		}
+	}()
 	var (
 		err         error
 		packet      uint64
-		progress    *progress
-		profileInfo *profileInfo
+		progress    *Progress
+		profileInfo *ProfileInfo
+		demanded    bool // the next block has been requested, see WithBlockStreaming
+		delivered   bool // a block of rows has been sent to the stream
 	)
+	// the results end with the EndOfStream packet only: once rows have been returned, a connection closed before
+	// (driver.ErrBadConn) truncates them
+	fail := func(err error) error {
+		if err == driver.ErrBadConn && delivered {
+			err = &truncatedError{err: rows.ch.conn.ioErr}
+		}
+		return rows.setError(err)
+	}
 	for {
-		if packet, err = rows.ch.decoder.Uvarint(); err != nil {
-			return rows.setError(err)
+		if packet, err = rows.ch.readPacket(); err != nil {
+			return fail(err)
 		}
 		switch packet {
 		case protocol.ServerException:
@@ -97,25 +187,30 @@ func (rows *rows) receiveData() error {
 			return rows.setError(rows.ch.exception())
 		case protocol.ServerProgress:
 			if progress, err = rows.ch.progress(); err != nil {
-				return rows.setError(err)
+				return fail(err)
 			}
 			rows.ch.logf("[rows] <- progress: rows=%d, bytes=%d, total rows=%d",
-				progress.rows,
-				progress.bytes,
-				progress.totalRows,
+				progress.Rows,
+				progress.Bytes,
+				progress.TotalRows,
 			)
 		case protocol.ServerProfileInfo:
 			if profileInfo, err = rows.ch.profileInfo(); err != nil {
-				return rows.setError(err)
+				return fail(err)
 			}
-			rows.ch.logf("[rows] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.rows, profileInfo.bytes, profileInfo.blocks)
+			rows.ch.logf("[rows] <- profiling: rows=%d, bytes=%d, blocks=%d", profileInfo.Rows, profileInfo.Bytes, profileInfo.Blocks)
 		case protocol.ServerData, protocol.ServerTotals, protocol.ServerExtremes:
+			if packet == protocol.ServerData && rows.demand != nil && !demanded {
+				// the block is decoded once the rows of the previous one have been scanned
+				<-rows.demand
+				demanded = true
+			}
 			var (
 				block *data.Block
 				begin = time.Now()
 			)
 			if block, err = rows.ch.readBlock(); err != nil {
-				return rows.setError(err)
+				return fail(err)
 			}
 			rows.ch.logf("[rows] <- data: packet=%d, columns=%d, rows=%d, elapsed=%s", packet, block.NumColumns, block.NumRows, time.Since(begin))
 			if block.NumRows == 0 {
@@ -124,6 +219,7 @@ func (rows *rows) receiveData() error {
 			switch packet {
 			case protocol.ServerData:
 				rows.stream <- block
+				demanded, delivered = false, true
 			case protocol.ServerTotals:
 				rows.totals = block
 			case protocol.ServerExtremes:
@@ -140,12 +236,41 @@ func (rows *rows) receiveData() error {
 	}
 }
 
+// truncatedError is the error of the rows whose stream was cut off by the close of the connection, it unwraps
+// to the I/O error which closed it, if any.
+type truncatedError struct {
+	err error
+}
+
+func (e *truncatedError) Error() string {
+	if e.err == nil {
+		return ErrTruncatedResult.Error()
+	}
+	return ErrTruncatedResult.Error() + ": " + e.err.Error()
+}
+
+func (e *truncatedError) Unwrap() error {
+	return e.err
+}
+
+func (e *truncatedError) Is(target error) bool {
+	return target == ErrTruncatedResult || target == io.ErrUnexpectedEOF
+}
+
 func (rows *rows) Close() error {
+	if rows.closed {
+		return nil
+	}
 	rows.ch.logf("[rows] close")
-	rows.columns = nil
+	rows.closed, rows.columns = true, nil
+	if rows.demand != nil {
+		// the rest of the blocks are decoded without waiting for the requests
+		close(rows.demand)
+	}
 	for range rows.stream {
 	}
 	rows.finish()
+	endSpan(rows.span, rows.error())
 	return nil
 }
 
@@ -163,8 +288,13 @@ func (rows *rows) setError(err error) error {
 }
 
 func (rows *rows) ColumnTypeNullable(idx int) (nullable, ok bool) {
-	_, ok = rows.blockColumns[idx].(*column.Nullable)
-	return ok, true
+	switch c := rows.blockColumns[idx].(type) {
+	case *column.Nullable:
+		return true, true
+	case *column.LowCardinality:
+		return c.Nullable(), true
+	}
+	return false, true
 }
 
 func (rows *rows) ColumnTypePrecisionScale(idx int) (precision, scale int64, ok bool) {
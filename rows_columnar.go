@@ -0,0 +1,57 @@
+package clickhouse
+
+import (
+	"context"
+)
+
+// WithColumnarResults makes the blocks of the query run with the context decoded column by column into the slices
+// of the types of their columns, e.g. []int64 for Int64, []float64 for Float64, []string for String and []time.Time
+// for Date and DateTime, instead of a value boxed into an interface{} per row. The other columns, e.g. Nullable
+// or Array, are decoded as usual. The slices are read by ColumnarRows, Next still scans the rows of such blocks.
+func WithColumnarResults(ctx context.Context) context.Context {
+	return context.WithValue(ctx, columnarKey, true)
+}
+
+// ColumnarRows is implemented by the rows of the queries of the connections of OpenDirect, it reads the results
+// block by block, a column at once, e.g. for the analytics aggregating a few numeric columns over many rows:
+//
+//	rows, err := stmt.(driver.StmtQueryContext).QueryContext(clickhouse.WithColumnarResults(ctx), nil)
+//	columnar := rows.(clickhouse.ColumnarRows)
+//	for _, err = columnar.NextBlock(); err == nil; _, err = columnar.NextBlock() {
+//		for _, v := range columnar.ColumnValues(0).([]float64) {
+//			sum += v
+//		}
+//	}
+type ColumnarRows interface {
+	// NextBlock moves to the next block of the results, the rows of the current block not scanned by Next are skipped.
+	// It returns the number of the rows of the block, io.EOF at the end of the results. After NextResultSet the block
+	// of the totals or the extremes is the current block.
+	NextBlock() (int, error)
+	// ColumnValues returns the values of the column idx of the current block: a slice of the type of the column
+	// if the query is run with WithColumnarResults and the column is decoded so, a []interface{} of the values
+	// otherwise (as decoded, regardless of WithEnumValues and WithTrimmedFixedStrings). It returns nil before NextBlock.
+	// The slices are not reused by the next blocks, they can be kept.
+	ColumnValues(idx int) interface{}
+}
+
+// NextBlock implements ColumnarRows.
+func (rows *rows) NextBlock() (int, error) {
+	rows.current = nil
+	if err := rows.nextBlock(); err != nil {
+		return 0, err
+	}
+	// the rows of the block are read by ColumnValues, Next moves to the next block
+	rows.offset = int(rows.block.NumRows)
+	return int(rows.block.NumRows), nil
+}
+
+// ColumnValues implements ColumnarRows.
+func (rows *rows) ColumnValues(idx int) interface{} {
+	if rows.block == nil {
+		return nil
+	}
+	if rows.block.Slices != nil && rows.block.Slices[idx] != nil {
+		return rows.block.Slices[idx]
+	}
+	return rows.block.Values[idx]
+}
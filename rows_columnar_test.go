@@ -0,0 +1,166 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RowsColumnValues(t *testing.T) {
+	var (
+		columns = []string{"id", "value", "name", "time", "score"}
+		chTypes = []string{"UInt64", "Float64", "String", "DateTime", "Nullable(Float64)"}
+		now     = time.Unix(time.Now().Unix(), 0).UTC()
+		values  = [][]driver.Value{
+			{uint64(1), 1.5, "a", now, 0.5},
+			{uint64(2), -2.5, "b", now, nil},
+		}
+	)
+	query := func(ctx context.Context) driver.Rows {
+		prepared, err := newStubClickhouse(t, columns, chTypes, values...).Prepare("SELECT id, value, name, time, score FROM example")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		rows, err := prepared.(driver.StmtQueryContext).QueryContext(ctx, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return rows
+	}
+
+	rows := query(WithColumnarResults(context.Background()))
+	columnar := rows.(ColumnarRows)
+	assert.Nil(t, columnar.ColumnValues(0))
+	if n, err := columnar.NextBlock(); assert.NoError(t, err) && assert.Equal(t, 2, n) {
+		assert.Equal(t, []uint64{1, 2}, columnar.ColumnValues(0))
+		assert.Equal(t, []float64{1.5, -2.5}, columnar.ColumnValues(1))
+		assert.Equal(t, []string{"a", "b"}, columnar.ColumnValues(2))
+		assert.Equal(t, []time.Time{now, now}, columnar.ColumnValues(3))
+		// decoded as usual
+		assert.Equal(t, []interface{}{0.5, nil}, columnar.ColumnValues(4))
+	}
+	_, err := columnar.NextBlock()
+	assert.Equal(t, io.EOF, err)
+	rows.Close()
+
+	// the rows of the typed columns are still scanned by Next
+	rows = query(WithColumnarResults(context.Background()))
+	dest := make([]driver.Value, len(columns))
+	for _, row := range values {
+		if assert.NoError(t, rows.Next(dest)) {
+			assert.Equal(t, row, dest)
+		}
+	}
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	rows.Close()
+
+	// without WithColumnarResults the values are not decoded into slices
+	rows = query(context.Background())
+	columnar = rows.(ColumnarRows)
+	if _, err := columnar.NextBlock(); assert.NoError(t, err) {
+		assert.Equal(t, []interface{}{uint64(1), uint64(2)}, columnar.ColumnValues(0))
+	}
+	rows.Close()
+}
+
+// encodeNumericStream encodes the blocks of a query of the numeric columns id UInt64, value Float64 and count Int32.
+func encodeNumericStream(b *testing.B, blocks, rows int) ([]column.Column, []byte) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		columns []column.Column
+	)
+	for _, chType := range []string{"UInt64", "Float64", "Int32"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if err != nil {
+			b.Fatal(err)
+		}
+		columns = append(columns, c)
+	}
+	for i := 0; i < blocks; i++ {
+		block := &data.Block{Columns: columns, NumColumns: uint64(len(columns))}
+		for j := 0; j < rows; j++ {
+			if err := block.AppendRow([]driver.Value{uint64(j), float64(j) / 2, int32(j)}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		if err := block.Write(&data.ServerInfo{}, encoder); err != nil {
+			b.Fatal(err)
+		}
+	}
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	return columns, buf.Bytes()
+}
+
+// Benchmark_RowsColumnar sums the column value of the results scanned row by row by Next against its slices read
+// block by block by ColumnValues.
+func Benchmark_RowsColumnar(b *testing.B) {
+	const (
+		blocks       = 10
+		rowsPerBlock = 10000
+	)
+	columns, stream := encodeNumericStream(b, blocks, rowsPerBlock)
+	newRows := func(columnar bool) *rows {
+		return &rows{
+			ch: &clickhouse{
+				logf:     func(string, ...interface{}) {},
+				conn:     &connect{},
+				decoder:  binary.NewDecoder(bytes.NewReader(stream)),
+				columnar: columnar,
+			},
+			finish:       func() {},
+			stream:       make(chan *data.Block, 50),
+			columns:      []string{"id", "value", "count"},
+			blockColumns: columns,
+		}
+	}
+	want := float64(blocks) * float64(rowsPerBlock-1) * float64(rowsPerBlock) / 4
+	b.Run("rows", func(b *testing.B) {
+		b.ReportAllocs()
+		dest := make([]driver.Value, len(columns))
+		for i := 0; i < b.N; i++ {
+			var (
+				sum  float64
+				rows = newRows(false)
+			)
+			go rows.receiveData()
+			for rows.Next(dest) == nil {
+				sum += dest[1].(float64)
+			}
+			rows.Close()
+			if sum != want {
+				b.Fatalf("sum=%v, want %v", sum, want)
+			}
+		}
+	})
+	b.Run("columnar", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var (
+				sum  float64
+				rows = newRows(true)
+			)
+			go rows.receiveData()
+			for _, err := rows.NextBlock(); err == nil; _, err = rows.NextBlock() {
+				for _, v := range rows.ColumnValues(1).([]float64) {
+					sum += v
+				}
+			}
+			rows.Close()
+			if sum != want {
+				b.Fatalf("sum=%v, want %v", sum, want)
+			}
+		}
+	})
+}
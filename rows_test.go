@@ -0,0 +1,436 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RowsEnumValues(t *testing.T) {
+	var columns []column.Column
+	for _, chType := range []string{"Enum8('a' = 1, 'b' = -2)", "Nullable(Enum16('c' = 300))", "Array(Enum8('a' = 1))", "String"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		columns = append(columns, c)
+	}
+	newRows := func(enumValues bool) *rows {
+		return &rows{
+			blockColumns: columns,
+			enumValues:   enumValues,
+			block: &data.Block{
+				NumRows: 2,
+				Values: [][]interface{}{
+					{"a", "b"},
+					{"c", nil},
+					{[]string{"a"}, []string{}},
+					{"a", "b"},
+				},
+			},
+		}
+	}
+	rows := newRows(true)
+	dest := make([]driver.Value, len(columns))
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{int8(1), int16(300), []string{"a"}, "a"}, dest)
+	}
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{int8(-2), nil, []string{}, "b"}, dest)
+	}
+	assert.Equal(t, reflect.TypeOf(int8(0)), rows.ColumnTypeScanType(0))
+	assert.Equal(t, reflect.TypeOf(int16(0)), rows.ColumnTypeScanType(1))
+	assert.Equal(t, reflect.TypeOf(""), rows.ColumnTypeScanType(3))
+
+	rows = newRows(false)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{"a", "c", []string{"a"}, "a"}, dest)
+	}
+	assert.Equal(t, reflect.TypeOf(""), rows.ColumnTypeScanType(0))
+}
+
+func Test_RowsTrimmedFixedStrings(t *testing.T) {
+	var columns []column.Column
+	for _, chType := range []string{"FixedString(4)", "Nullable(FixedString(4))", "String"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		columns = append(columns, c)
+	}
+	newRows := func(trimFixed bool) *rows {
+		return &rows{
+			blockColumns: columns,
+			trimFixed:    trimFixed,
+			block: &data.Block{
+				NumRows: 2,
+				Values: [][]interface{}{
+					{"é\x00\x00", "\x00a\x00\x00"},
+					{"ab\x00\x00", nil},
+					{"a\x00", "b"},
+				},
+			},
+		}
+	}
+	rows := newRows(true)
+	dest := make([]driver.Value, len(columns))
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{"é", "ab", "a\x00"}, dest)
+	}
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{"\x00a", nil, "b"}, dest)
+	}
+
+	rows = newRows(false)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{"é\x00\x00", "ab\x00\x00", "a\x00"}, dest)
+	}
+}
+
+func Test_RowsColumnTypeNullable(t *testing.T) {
+	var rows rows
+	for _, chType := range []string{"Nullable(Int128)", "LowCardinality(Nullable(String))", "LowCardinality(String)", "Array(Nullable(UInt8))", "UInt8"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		rows.blockColumns = append(rows.blockColumns, c)
+	}
+	for idx, expected := range []bool{true, true, false, false, false} {
+		nullable, ok := rows.ColumnTypeNullable(idx)
+		if assert.True(t, ok) {
+			assert.Equal(t, expected, nullable, rows.blockColumns[idx].CHType())
+		}
+	}
+}
+
+// encodeStream returns the data packets of the blocks of the String rows, as sent by the server, followed by the end of the stream.
+func encodeStream(t testing.TB, blocks, rows int, value string) []byte {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+	)
+	c, err := column.Factory("value", "String", time.UTC)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for i := 0; i < blocks; i++ {
+		block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+		for j := 0; j < rows; j++ {
+			if err := block.AppendRow([]driver.Value{value}); !assert.NoError(t, err) {
+				t.FailNow()
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		if err := block.Write(&data.ServerInfo{}, encoder); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	return buf.Bytes()
+}
+
+// scanStream scans the rows of the stream and returns the peak of the heap allocated while the rows are scanned.
+func scanStream(t testing.TB, stream []byte, rowsPerBlock int, streaming bool) (count int, peak uint64) {
+	c, _ := column.Factory("value", "String", time.UTC)
+	rows := &rows{
+		ch: &clickhouse{
+			logf:    func(string, ...interface{}) {},
+			conn:    &connect{},
+			decoder: binary.NewDecoder(bytes.NewReader(stream)),
+		},
+		finish:       func() {},
+		stream:       make(chan *data.Block, 50),
+		columns:      []string{"value"},
+		blockColumns: []column.Column{c},
+	}
+	if streaming {
+		rows.stream, rows.demand = make(chan *data.Block), make(chan struct{}, 1)
+	}
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapAlloc
+	go rows.receiveData()
+	dest := make([]driver.Value, 1)
+	for ; ; count++ {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if count%rowsPerBlock == 0 {
+			// give the blocks time to be decoded ahead of the rows
+			time.Sleep(5 * time.Millisecond)
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > base && stats.HeapAlloc-base > peak {
+				peak = stats.HeapAlloc - base
+			}
+		}
+	}
+	rows.Close()
+	return count, peak
+}
+
+func Test_RowsBlockStreaming(t *testing.T) {
+	const (
+		blocks = 20
+		rows   = 1000
+	)
+	stream := encodeStream(t, blocks, rows, strings.Repeat("a", 1024))
+	count, buffered := scanStream(t, stream, rows, false)
+	assert.Equal(t, blocks*rows, count)
+	count, streamed := scanStream(t, stream, rows, true)
+	assert.Equal(t, blocks*rows, count)
+	// the blocks decoded ahead of the rows against about two blocks at most
+	t.Logf("peak heap: buffered=%d, streamed=%d", buffered, streamed)
+	assert.True(t, streamed*4 < buffered, "buffered=%d, streamed=%d", buffered, streamed)
+}
+
+func Test_RowsBlockStreamingClose(t *testing.T) {
+	stream := encodeStream(t, 5, 10, "a")
+	c, _ := column.Factory("value", "String", time.UTC)
+	rows := &rows{
+		ch: &clickhouse{
+			logf:    func(string, ...interface{}) {},
+			conn:    &connect{},
+			decoder: binary.NewDecoder(bytes.NewReader(stream)),
+		},
+		finish:       func() {},
+		stream:       make(chan *data.Block),
+		demand:       make(chan struct{}, 1),
+		blockColumns: []column.Column{c},
+	}
+	go rows.receiveData()
+	dest := make([]driver.Value, 1)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, "a", dest[0])
+	}
+	// the remaining blocks are drained without being requested
+	done := make(chan struct{})
+	go func() {
+		rows.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rows.Close did not return")
+	}
+	// the closed rows end without requesting a block
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	assert.NoError(t, rows.Close())
+}
+
+func Benchmark_RowsBlockStreaming(b *testing.B) {
+	const rows = 1000
+	stream := encodeStream(b, 20, rows, strings.Repeat("a", 1024))
+	for _, streaming := range []bool{false, true} {
+		name := "buffered"
+		if streaming {
+			name = "streamed"
+		}
+		b.Run(name, func(b *testing.B) {
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				if _, v := scanStream(b, stream, rows, streaming); v > peak {
+					peak = v
+				}
+			}
+			b.ReportMetric(float64(peak), "peak-heap-bytes")
+		})
+	}
+}
+
+func Test_RowsChecksumMismatch(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoderWithCompress(&buf)
+	)
+	c, _ := column.Factory("value", "String", time.UTC)
+	block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+	for i := 0; i < 100; i++ {
+		if err := block.AppendRow([]driver.Value{"value"}); !assert.NoError(t, err) {
+			return
+		}
+	}
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	encoder.SelectCompress(true)
+	if err := block.Write(&data.ServerInfo{}, encoder); !assert.NoError(t, err) {
+		return
+	}
+	encoder.SelectCompress(false)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	stream := buf.Bytes()
+	// a byte of the compressed data, following the packet, the name of the table and the header of the block
+	stream[2+binary.HeaderSize+4] ^= 0x01
+
+	conn := newStubConnect(t, &stubConn{data: stream}, connOptions{})
+	rows := &rows{
+		ch: &clickhouse{
+			logf:     func(string, ...interface{}) {},
+			conn:     conn,
+			compress: true,
+			decoder:  binary.NewDecoderWithCompress(conn),
+		},
+		finish:       func() {},
+		stream:       make(chan *data.Block, 1),
+		blockColumns: []column.Column{c},
+	}
+	go rows.receiveData()
+	assert.Equal(t, ErrChecksumMismatch, rows.Next(make([]driver.Value, 1)))
+	// the rest of the response can't be trusted, the connection is not reused
+	assert.True(t, conn.isClosed())
+}
+
+func Test_VerifyBlocksChecksumMismatch(t *testing.T) {
+	for compress, method := range map[string]binary.CompressionMethodByte{"lz4": binary.LZ4, "zstd": binary.ZSTD} {
+		if _, err := ParseDSN("tcp://127.0.0.1:9000?verify_blocks=true&compress=" + compress); !assert.NoError(t, err) {
+			return
+		}
+		var (
+			buf     bytes.Buffer
+			encoder, _ = binary.NewEncoderWithCompressMethod(&buf, method, 0, 0)
+		)
+		c, _ := column.Factory("value", "String", time.UTC)
+		block := &data.Block{Columns: []column.Column{c}, NumColumns: 1}
+		for i := 0; i < 100; i++ {
+			if err := block.AppendRow([]driver.Value{"value"}); !assert.NoError(t, err) {
+				return
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		encoder.SelectCompress(true)
+		if err := block.Write(&data.ServerInfo{}, encoder); !assert.NoError(t, err) {
+			return
+		}
+		encoder.SelectCompress(false)
+		encoder.Uvarint(protocol.ServerEndOfStream)
+		// a byte of the checksum, then a byte of the compressed data, following the packet and the name of the table
+		for _, idx := range []int{2 + 3, 2 + binary.HeaderSize + 4} {
+			stream := append([]byte(nil), buf.Bytes()...)
+			stream[idx] ^= 0x01
+			conn := newStubConnect(t, &stubConn{data: stream}, connOptions{})
+			rows := &rows{
+				ch: &clickhouse{
+					logf:     func(string, ...interface{}) {},
+					conn:     conn,
+					compress: true,
+					decoder:  binary.NewDecoderWithCompress(conn),
+				},
+				finish:       func() {},
+				stream:       make(chan *data.Block, 1),
+				blockColumns: []column.Column{c},
+			}
+			go rows.receiveData()
+			assert.Equal(t, ErrChecksumMismatch, rows.Next(make([]driver.Value, 1)), "compress=%s, byte %d", compress, idx)
+			assert.True(t, conn.isClosed())
+		}
+	}
+}
+
+func Test_RowsTruncated(t *testing.T) {
+	stream := encodeStream(t, 2, 10, "value")
+	c, _ := column.Factory("value", "String", time.UTC)
+	scan := func(stream []byte) (int, error) {
+		conn := newStubConnect(t, &stubConn{data: stream, err: io.EOF}, connOptions{})
+		rows := &rows{
+			ch: &clickhouse{
+				logf:    func(string, ...interface{}) {},
+				conn:    conn,
+				decoder: binary.NewDecoder(conn),
+			},
+			finish:       func() {},
+			stream:       make(chan *data.Block, 50),
+			blockColumns: []column.Column{c},
+		}
+		go rows.receiveData()
+		defer rows.Close()
+		dest := make([]driver.Value, 1)
+		for count := 0; ; count++ {
+			if err := rows.Next(dest); err != nil {
+				return count, err
+			}
+		}
+	}
+	count, err := scan(stream)
+	assert.Equal(t, 20, count)
+	assert.Equal(t, io.EOF, err)
+	// the stream is cut in the middle of the second block, and before the end of the stream
+	for cut, rows := range map[int]int{len(stream) - 30: 10, len(stream) - 1: 20} {
+		count, err := scan(stream[:cut])
+		assert.Equal(t, rows, count, "cut=%d", cut)
+		if assert.Error(t, err) {
+			assert.True(t, errors.Is(err, ErrTruncatedResult))
+			assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+			assert.Equal(t, io.EOF, errors.Unwrap(err))
+			assert.Equal(t, "clickhouse: the connection was closed before the end of the results: EOF", err.Error())
+		}
+	}
+	// the connection closed before any row is returned is a bad connection
+	_, err = scan(stream[:20])
+	assert.Equal(t, driver.ErrBadConn, err)
+}
+
+func Test_WithTimezone(t *testing.T) {
+	var (
+		columns = []string{"time", "time64", "nullable", "times"}
+		chTypes = []string{"DateTime", "DateTime64(3, 'UTC')", "Nullable(DateTime)", "Array(DateTime)"}
+		stored  = time.Date(2022, 3, 1, 12, 30, 0, 0, time.UTC)
+		tokyo   = time.FixedZone("Tokyo", 9*3600)
+		newYork = time.FixedZone("New_York", -5*3600)
+	)
+	scan := func(ctx context.Context) []driver.Value {
+		ch := newStubClickhouse(t, columns, chTypes, []driver.Value{stored, stored, stored, []time.Time{stored}})
+		prepared, err := ch.Prepare("SELECT time, time64, nullable, times FROM example")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		rows, err := prepared.(driver.StmtQueryContext).QueryContext(ctx, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer rows.Close()
+		dest := make([]driver.Value, len(columns))
+		if !assert.NoError(t, rows.Next(dest)) {
+			t.FailNow()
+		}
+		return dest
+	}
+	inTokyo := scan(WithTimezone(context.Background(), tokyo))
+	inNewYork := scan(WithTimezone(context.Background(), newYork))
+	for i, values := range [][]driver.Value{inTokyo, inNewYork} {
+		loc := []*time.Location{tokyo, newYork}[i]
+		for _, v := range []interface{}{values[0], values[1], values[2], values[3].([]time.Time)[0]} {
+			if value, ok := v.(time.Time); assert.True(t, ok, "%T", v) {
+				assert.True(t, stored.Equal(value), "%s", value)
+				assert.Equal(t, loc, value.Location())
+			}
+		}
+	}
+	assert.Equal(t, "2022-03-01 21:30:00", inTokyo[0].(time.Time).Format("2006-01-02 15:04:05"))
+	assert.Equal(t, "2022-03-01 07:30:00", inNewYork[0].(time.Time).Format("2006-01-02 15:04:05"))
+	// the timezone of the server, or of the column, without WithTimezone
+	values := scan(context.Background())
+	assert.Equal(t, time.UTC, values[0].(time.Time).Location())
+	assert.Equal(t, "UTC", values[1].(time.Time).Location().String())
+}
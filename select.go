@@ -0,0 +1,258 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Queryer runs the query of Select, it is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Select runs the query and appends its rows to dest, a pointer to a slice of structs (or of pointers to structs).
+// The columns are mapped to the exported fields by their ch tag, e.g. `ch:"column_name"`, or case-insensitively
+// by their names if untagged; the fields tagged `ch:"-"` are skipped and the fields of the embedded structs are mapped
+// as the fields of the struct. Every column has to be mapped to a field
+// and every field to a column. The values are scanned as by Scan: a nullable column is scanned into a pointer field
+// (nil for NULL) and an array into a slice field, e.g. Array(Array(Int32)) into [][]int32.
+//
+//	var dest []struct {
+//		ID   uint32   `ch:"id"`
+//		Tags []string `ch:"tags"`
+//	}
+//	err := clickhouse.Select(ctx, db, &dest, "SELECT id, tags FROM example WHERE id > ?", 10)
+func Select(ctx context.Context, db Queryer, dest interface{}, query string, args ...interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("clickhouse: Select expects a pointer to a slice of structs, got %T", dest)
+	}
+	var (
+		slice    = value.Elem()
+		elemType = slice.Type().Elem()
+		isPtr    = elemType.Kind() == reflect.Ptr
+	)
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("clickhouse: Select expects a pointer to a slice of structs, got %T", dest)
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fields, err := mapColumns(elemType, columns)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(fields))
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		for i, index := range fields {
+			values[i] = elem.Elem().FieldByIndex(index).Addr().Interface()
+		}
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// structField is an exported field of a struct mapped to a column.
+type structField struct {
+	name   string // the name of the column of the ch tag, the name of the field if untagged
+	tagged bool
+	field  string // the name of the field
+	index  []int
+}
+
+// structFields returns the fields of the struct that can be mapped to the columns, the fields of the embedded structs
+// included unless a field of the outer struct has the same name, as the promoted fields of Go.
+func structFields(t reflect.Type) []structField {
+	var (
+		fields   []structField
+		embedded [][]structField
+	)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("ch")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			inner := structFields(field.Type)
+			for j := range inner {
+				inner[j].index = append([]int{i}, inner[j].index...)
+			}
+			embedded = append(embedded, inner)
+			continue
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		if tag == "" {
+			fields = append(fields, structField{name: field.Name, field: field.Name, index: field.Index})
+		} else {
+			fields = append(fields, structField{name: tag, tagged: true, field: field.Name, index: field.Index})
+		}
+	}
+	outer := len(fields)
+	for _, inner := range embedded {
+	promoted:
+		for _, field := range inner {
+			for _, shadowing := range fields[:outer] {
+				if shadowing.field == field.field {
+					continue promoted
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// mapColumns returns the index of the field of each column, the fields tagged with its name take precedence over
+// the untagged fields of the same name regardless of the case.
+func mapColumns(t reflect.Type, columns []string) ([][]int, error) {
+	var (
+		fields   = structFields(t)
+		mappedBy = make([]string, len(fields)) // the column mapped to each field
+		indexes  = make([][]int, len(columns))
+	)
+	for i, name := range columns {
+		found := -1
+		for j, field := range fields {
+			if field.tagged && field.name == name {
+				found = j
+				break
+			}
+			if !field.tagged && found == -1 && strings.EqualFold(field.name, name) {
+				found = j
+			}
+		}
+		if found == -1 {
+			return nil, fmt.Errorf("clickhouse: column %s has no field in %s", name, t)
+		}
+		if other := mappedBy[found]; other != "" {
+			return nil, fmt.Errorf("clickhouse: columns %s and %s are mapped to the same field %s of %s", other, name, fields[found].field, t)
+		}
+		mappedBy[found] = name
+		indexes[i] = fields[found].index
+	}
+	for j, field := range fields {
+		if mappedBy[j] == "" {
+			return nil, fmt.Errorf("clickhouse: field %s of %s has no column in the results", field.field, t)
+		}
+	}
+	return indexes, nil
+}
+
+// StructScanner is implemented by the rows of the queries of the connections of OpenDirect, it scans the current row
+// into a struct instead of the values of its columns:
+//
+//	rows, err := stmt.Query(nil)
+//	for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+//		var row Row
+//		if err := rows.(clickhouse.StructScanner).ScanStruct(&row); err != nil {
+//			return err
+//		}
+//	}
+type StructScanner interface {
+	// ScanStruct sets the fields of dest, a pointer to a struct, to the values of the row returned by the last Next.
+	// The columns are mapped to the fields as by Select. The value of a column is set to a field of its type,
+	// a pointer to its type (nil for NULL) or a sql.Scanner.
+	ScanStruct(dest interface{}) error
+}
+
+// ScanStruct implements StructScanner.
+func (rows *rows) ScanStruct(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("clickhouse: ScanStruct expects a pointer to a struct, got %T", dest)
+	}
+	if rows.current == nil {
+		return fmt.Errorf("clickhouse: ScanStruct is called before Next")
+	}
+	t := value.Elem().Type()
+	if rows.structType != t {
+		fields, err := mapColumns(t, rows.columns)
+		if err != nil {
+			return err
+		}
+		rows.structType, rows.structFields = t, fields
+	}
+	for i, index := range rows.structFields {
+		field := value.Elem().FieldByIndex(index)
+		if err := setField(field, rows.current[i]); err != nil {
+			return fmt.Errorf("clickhouse: column %s of the type %s can't be scanned into the field %s of the type %s: %v",
+				rows.columns[i], rows.blockColumns[i].CHType(), t.FieldByIndex(index).Name, field.Type(), err)
+		}
+	}
+	return nil
+}
+
+// setField sets the field to the value of a column.
+func setField(field reflect.Value, v driver.Value) error {
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(v)
+	}
+	if v == nil {
+		switch field.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		return errors.New("the value is NULL")
+	}
+	value := reflect.ValueOf(v)
+	switch {
+	case value.Type().AssignableTo(field.Type()):
+		field.Set(value)
+	case field.Kind() == reflect.Ptr && value.Type().AssignableTo(field.Type().Elem()):
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(value)
+		field.Set(ptr)
+	default:
+		return fmt.Errorf("the value is a %T", v)
+	}
+	return nil
+}
+
+// MapScanner is implemented by the rows of the queries of the connections of OpenDirect, it scans the current row
+// into a map keyed by the names of the columns, e.g. for the results of the queries whose columns are not known in advance.
+type MapScanner interface {
+	// ScanMap sets dest[name] to the value of the column name of the row returned by the last Next.
+	// The values are of the types the columns are scanned as by Next, e.g. time.Time for DateTime, net.IP for IPv4
+	// or []uint8 for Array(UInt8), nil for NULL.
+	ScanMap(dest map[string]interface{}) error
+}
+
+// ScanMap implements MapScanner.
+func (rows *rows) ScanMap(dest map[string]interface{}) error {
+	if dest == nil {
+		return fmt.Errorf("clickhouse: ScanMap expects a non-nil map")
+	}
+	if rows.current == nil {
+		return fmt.Errorf("clickhouse: ScanMap is called before Next")
+	}
+	for i, name := range rows.columns {
+		dest[name] = rows.current[i]
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubConnector connects database/sql to the connection reading the stub.
+type stubConnector struct {
+	ch *clickhouse
+}
+
+func (c *stubConnector) Connect(context.Context) (driver.Conn, error) { return c.ch, nil }
+func (c *stubConnector) Driver() driver.Driver                        { return &bootstrap{} }
+
+// newStubClickhouse returns the connection reading the results of a query made of the block of the rows.
+func newStubClickhouse(t *testing.T, columns []string, chTypes []string, rows ...[]driver.Value) *clickhouse {
+	var (
+		buf     bytes.Buffer
+		encoder = binary.NewEncoder(&buf)
+		block   = &data.Block{NumColumns: uint64(len(columns))}
+	)
+	for i, name := range columns {
+		c, err := column.Factory(name, chTypes[i], time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Columns = append(block.Columns, c)
+	}
+	// the header of the results, the block of the rows and the end of the stream
+	for _, values := range [][][]driver.Value{nil, rows} {
+		block.Reserve()
+		block.NumRows = 0
+		for _, row := range values {
+			if err := block.AppendRow(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		encoder.Uvarint(protocol.ServerData)
+		encoder.String("")
+		if err := block.Write(&data.ServerInfo{}, encoder); err != nil {
+			t.Fatal(err)
+		}
+	}
+	encoder.Uvarint(protocol.ServerEndOfStream)
+
+	conn := newStubConnect(t, &stubConn{data: buf.Bytes()}, connOptions{})
+	return &clickhouse{
+		ServerInfo: data.ServerInfo{Timezone: time.UTC},
+		conn:       conn,
+		logf:       func(string, ...interface{}) {},
+		settings:   &querySettings{},
+		decoder:    binary.NewDecoder(conn),
+		encoder:    binary.NewEncoder(conn),
+	}
+}
+
+// newStubDB returns the database reading the results of a query made of the block of the rows.
+func newStubDB(t *testing.T, columns []string, chTypes []string, rows ...[]driver.Value) *sql.DB {
+	db := sql.OpenDB(&stubConnector{ch: newStubClickhouse(t, columns, chTypes, rows...)})
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func Test_SelectStructs(t *testing.T) {
+	type row struct {
+		ID      uint32
+		Name    string      `ch:"name"`
+		Score   *float64    `ch:"score"`
+		Tags    []string    `ch:"tags"`
+		Matrix  [][]int32   `ch:"matrix"`
+		Ignored interface{} `ch:"-"`
+		ignored int
+	}
+	var (
+		score   = 1.5
+		columns = []string{"id", "name", "score", "tags", "matrix"}
+		chTypes = []string{"UInt32", "String", "Nullable(Float64)", "Array(String)", "Array(Array(Int32))"}
+		values  = [][]driver.Value{
+			{uint32(1), "a", score, []string{"x", "y"}, [][]int32{{1, 2}, {3}}},
+			{uint32(2), "b", nil, []string{}, [][]int32{}},
+		}
+	)
+	db := newStubDB(t, columns, chTypes, values...)
+	defer db.Close()
+	var dest []row
+	if assert.NoError(t, Select(context.Background(), db, &dest, "SELECT id, name, score, tags, matrix FROM example")) {
+		assert.Equal(t, []row{
+			{ID: 1, Name: "a", Score: &score, Tags: []string{"x", "y"}, Matrix: [][]int32{{1, 2}, {3}}},
+			{ID: 2, Name: "b", Tags: []string{}, Matrix: [][]int32{}},
+		}, dest)
+	}
+
+	db = newStubDB(t, columns, chTypes, values...)
+	defer db.Close()
+	var ptrs []*row
+	if assert.NoError(t, Select(context.Background(), db, &ptrs, "SELECT id, name, score, tags, matrix FROM example")) && assert.Len(t, ptrs, 2) {
+		assert.Equal(t, [][]int32{{1, 2}, {3}}, ptrs[0].Matrix)
+	}
+}
+
+func Test_SelectStructsMapping(t *testing.T) {
+	var (
+		columns = []string{"id", "name"}
+		chTypes = []string{"UInt32", "String"}
+		values  = []driver.Value{uint32(1), "a"}
+	)
+	for _, test := range []struct {
+		dest interface{}
+		err  string
+	}{
+		{&[]struct{ ID uint32 }{}, "clickhouse: column name has no field in struct { ID uint32 }"},
+		{&[]struct {
+			ID   uint32
+			Name string
+			Age  uint8
+		}{}, "clickhouse: field Age of struct { ID uint32; Name string; Age uint8 } has no column in the results"},
+		{&[]struct {
+			ID   string
+			Name uint32
+		}{}, `sql: Scan error on column index 1, name "name": converting driver.Value type string ("a") to a uint32: invalid syntax`},
+		{[]struct{}{}, "clickhouse: Select expects a pointer to a slice of structs, got []struct {}"},
+		{&[]int{}, "clickhouse: Select expects a pointer to a slice of structs, got *[]int"},
+	} {
+		db := newStubDB(t, columns, chTypes, values)
+		if err := Select(context.Background(), db, test.dest, "SELECT id, name FROM example"); assert.Error(t, err) {
+			assert.Equal(t, test.err, err.Error())
+		}
+		db.Close()
+	}
+}
+
+func Test_RowsScanStruct(t *testing.T) {
+	var blockColumns []column.Column
+	for _, chType := range []string{"UInt32", "String", "Nullable(Float64)", "Array(String)"} {
+		c, err := column.Factory("column_name", chType, time.UTC)
+		if !assert.NoError(t, err) {
+			return
+		}
+		blockColumns = append(blockColumns, c)
+	}
+	type Base struct {
+		ID   uint32
+		Name string `ch:"name"`
+	}
+	type row struct {
+		Base
+		Name    string   `ch:"label"` // shadows the name of Base
+		Score   *float64 `ch:"score"`
+		Tags    []string `ch:"tags"`
+		Ignored Base     `ch:"-"`
+	}
+	var (
+		score = 1.5
+		rows  = &rows{
+			columns:      []string{"id", "label", "score", "tags"},
+			blockColumns: blockColumns,
+			block: &data.Block{
+				NumRows: 2,
+				Values: [][]interface{}{
+					{uint32(1), uint32(2)},
+					{"a", "b"},
+					{score, nil},
+					{[]string{"x"}, []string{}},
+				},
+			},
+		}
+		dest = make([]driver.Value, 4)
+		r    row
+	)
+	if err := rows.ScanStruct(&r); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: ScanStruct is called before Next", err.Error())
+	}
+	if assert.NoError(t, rows.Next(dest)) && assert.NoError(t, rows.ScanStruct(&r)) {
+		assert.Equal(t, row{Base: Base{ID: 1}, Name: "a", Score: &score, Tags: []string{"x"}}, r)
+	}
+	if assert.NoError(t, rows.Next(dest)) && assert.NoError(t, rows.ScanStruct(&r)) {
+		assert.Equal(t, row{Base: Base{ID: 2}, Name: "b", Tags: []string{}}, r)
+	}
+
+	var mismatch struct {
+		ID    uint32
+		Label uint32
+		Score float64
+		Tags  []string
+	}
+	if err := rows.ScanStruct(&mismatch); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: column label of the type String can't be scanned into the field Label of the type uint32: the value is a string", err.Error())
+	}
+	var null struct {
+		ID    uint32
+		Label string
+		Score float64
+		Tags  []string
+	}
+	if err := rows.ScanStruct(&null); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: column score of the type Nullable(Float64) can't be scanned into the field Score of the type float64: the value is NULL", err.Error())
+	}
+	if err := rows.ScanStruct(r); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: ScanStruct expects a pointer to a struct, got clickhouse.row", err.Error())
+	}
+}
+
+func Test_RowsScanMap(t *testing.T) {
+	var (
+		columns = []string{"time", "ip", "name", "bytes", "matrix", "enum"}
+		chTypes = []string{"DateTime", "IPv4", "Nullable(String)", "Array(UInt8)", "Array(Array(Nullable(String)))", "Enum8('a' = 1, 'b' = 2)"}
+		now     = time.Unix(time.Now().Unix(), 0).UTC()
+		name    = "name"
+		ch      = newStubClickhouse(t, columns, chTypes,
+			[]driver.Value{now, "127.0.0.1", name, []uint8{1, 2}, [][]*string{{&name, nil}}, "a"},
+			[]driver.Value{now, "10.0.0.1", nil, []uint8{}, [][]*string{}, "b"},
+		)
+	)
+	stmt, err := ch.Prepare("SELECT time, ip, name, bytes, matrix, enum FROM example")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.Query(nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer rows.Close()
+	dest := make(map[string]interface{})
+	if err := rows.(MapScanner).ScanMap(dest); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: ScanMap is called before Next", err.Error())
+	}
+	values := make([]driver.Value, len(columns))
+	if assert.NoError(t, rows.Next(values)) && assert.NoError(t, rows.(MapScanner).ScanMap(dest)) {
+		assert.Equal(t, map[string]interface{}{
+			"time":   now,
+			"ip":     net.IPv4(127, 0, 0, 1).To4(),
+			"name":   name,
+			"bytes":  []uint8{1, 2},
+			"matrix": [][]*string{{&name, nil}},
+			"enum":   "a",
+		}, dest)
+	}
+	if assert.NoError(t, rows.Next(values)) && assert.NoError(t, rows.(MapScanner).ScanMap(dest)) {
+		assert.Equal(t, map[string]interface{}{
+			"time":   now,
+			"ip":     net.IPv4(10, 0, 0, 1).To4(),
+			"name":   nil,
+			"bytes":  []uint8{},
+			"matrix": [][]*string{},
+			"enum":   "b",
+		}, dest)
+	}
+	assert.Equal(t, io.EOF, rows.Next(values))
+	if err := rows.(MapScanner).ScanMap(nil); assert.Error(t, err) {
+		assert.Equal(t, "clickhouse: ScanMap expects a non-nil map", err.Error())
+	}
+}
@@ -0,0 +1,42 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithSessionID runs the queries run with the context in the session id. The native protocol has no session_id
+// setting, unlike HTTP: the state of a session, e.g. its temporary tables, is the state of a connection and lasts
+// as long as the connection. The queries of a session have to be run on the same *sql.Conn (see sql.DB.Conn),
+// the driver checks that the connection is the one of the session: the query fails on a connection of another
+// session and, with check (as session_check=1), on a connection which has run no query of the session yet.
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	defer conn.Close()
+//	ctx = clickhouse.WithSessionID(ctx, "import", false)
+//	conn.ExecContext(ctx, "CREATE TEMPORARY TABLE staging (id UInt64)")
+//	conn.QueryContext(clickhouse.WithSessionID(ctx, "import", true), "SELECT count() FROM staging")
+func WithSessionID(ctx context.Context, id string, check bool) context.Context {
+	return context.WithValue(ctx, sessionKey, session{id: id, check: check})
+}
+
+type session struct {
+	id    string
+	check bool
+}
+
+// joinSession binds the connection to the session of the query, the connection of another session fails the query.
+func (ch *clickhouse) joinSession(s session) error {
+	switch {
+	case ch.sessionID == s.id:
+		return nil
+	case ch.sessionID != "":
+		return fmt.Errorf("clickhouse: the connection belongs to the session %s, not %s", ch.sessionID, s.id)
+	case s.check:
+		return fmt.Errorf("clickhouse: session %s is not found on the connection, pin a *sql.Conn to the session", s.id)
+	}
+	ch.logf("[session] %s", s.id)
+	ch.sessionID = s.id
+	return nil
+}
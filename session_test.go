@@ -0,0 +1,49 @@
+package clickhouse
+
+import (
+	"context"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WithSessionID(t *testing.T) {
+	newClickhouse := func() (*clickhouse, *stubConn) {
+		stub := &stubConn{}
+		conn := newStubConnect(t, stub, connOptions{})
+		return &clickhouse{
+			conn:     conn,
+			logf:     func(string, ...interface{}) {},
+			settings: &querySettings{},
+			decoder:  binary.NewDecoder(conn),
+			encoder:  binary.NewEncoder(conn),
+		}, stub
+	}
+	exec := func(ch *clickhouse, stub *stubConn, ctx context.Context) error {
+		stub.data, stub.written = []byte{protocol.ServerEndOfStream}, nil
+		_, err := ch.ExecContext(ctx, "SELECT 1", nil)
+		return err
+	}
+	var (
+		background = context.Background()
+		ch, stub   = newClickhouse()
+	)
+	assert.NoError(t, exec(ch, stub, WithSessionID(background, "a", false)))
+	assert.NoError(t, exec(ch, stub, WithSessionID(background, "a", true)))
+	// the queries out of the sessions are run on any connection
+	assert.NoError(t, exec(ch, stub, background))
+	if err := exec(ch, stub, WithSessionID(background, "b", false)); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "belongs to the session a")
+		// the query is not sent
+		assert.Empty(t, stub.written)
+	}
+	assert.False(t, ch.conn.isClosed())
+
+	ch, stub = newClickhouse()
+	if err := exec(ch, stub, WithSessionID(background, "a", true)); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "session a is not found")
+	}
+	assert.NoError(t, exec(ch, stub, WithSessionID(background, "b", false)))
+}
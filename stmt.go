@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"database/sql/driver"
+	"time"
 	"unicode"
 
 	"github.com/c3mb0/clickhouse-go/lib/data"
@@ -21,13 +22,119 @@ var emptyResult = &result{}
 
 type key string
 
-var queryIDKey key
+var (
+	queryIDKey        key
+	readTimeoutKey    key = "read_timeout"
+	enumValuesKey     key = "enum_values"
+	trimFixedKey      key = "trim_fixed_strings"
+	streamingKey      key = "block_streaming"
+	querySettingsKey  key = "query_settings"
+	batchBlockSizeKey key = "batch_block_size"
+	progressKey       key = "progress"
+	profileInfoKey    key = "profile_info"
+	sessionKey        key = "session"
+	quotaKeyKey       key = "quota_key"
+	queryIDFuncKey    key = "query_id_func"
+	querySpanKey      key = "query_span"
+	queryStatsKey     key = "query_stats"
+	databaseKey       key = "database"
+	columnarKey       key = "columnar_results"
+	queryDataKey      key = "query_data"
+	externalTablesKey key = "external_tables"
+	timezoneKey       key = "timezone"
+	credentialsKey    key = "credentials"
+)
 
-//Put query ID into context and use it in ExecContext or QueryContext
+// WithQueryID sets the id of the query run with the context, sent as the query_id of the query.
+// The driver generates a UUID for the queries of no id, or of an empty one, see WithQueryIDFunc.
 func WithQueryID(ctx context.Context, queryID string) context.Context {
 	return context.WithValue(ctx, queryIDKey, queryID)
 }
 
+// WithReadTimeout overrides the read timeout of the connection for the query run with the context,
+// the read timeout of the DSN is restored once the query is finished.
+func WithReadTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, readTimeoutKey, timeout)
+}
+
+// WithEnumValues makes the Enum8 and Enum16 columns of the query run with the context scanned
+// as their int8 and int16 values instead of their names.
+func WithEnumValues(ctx context.Context) context.Context {
+	return context.WithValue(ctx, enumValuesKey, true)
+}
+
+// WithTrimmedFixedStrings makes the FixedString(N) columns of the query run with the context scanned
+// without the trailing zero bytes of the padding.
+func WithTrimmedFixedStrings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trimFixedKey, true)
+}
+
+// WithTimezone makes the DateTime and DateTime64 values of the query run with the context returned in the location,
+// e.g. time.LoadLocation("America/New_York"), instead of the timezone of their columns or of the server:
+// the instants are the same, only the location of the time.Time differs. A nil location is the timezone of the server.
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, timezoneKey, loc)
+}
+
+// WithBlockStreaming bounds the memory used by the rows of the query run with the context: a block is decoded
+// only once the rows of the previous one have been scanned, and the values are released as the rows are scanned.
+// By default the blocks are decoded ahead of the rows, up to 50 of them. A block is decoded as a whole
+// (the native format lays its columns out one after another), the max_block_size setting bounds its size.
+func WithBlockStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingKey, true)
+}
+
+// WithMaxBlockSize sets max_block_size, the maximum rows of the blocks the server sends, for the query run with
+// the context instead of the setting of the DSN. Smaller blocks bound the memory of the values the driver decodes
+// at once, with WithBlockStreaming the memory of the rows read; but the server processes the query by blocks
+// of that size too, the more blocks the more overhead of each of them and the lower the throughput.
+// The default of the server is 65409 rows; n has to be positive.
+func WithMaxBlockSize(ctx context.Context, n int) context.Context {
+	return withQuerySettings(ctx, map[string]interface{}{"max_block_size": n})
+}
+
+// WithAsyncInsert makes the server buffer the rows of the INSERT prepared with the context (by PrepareContext
+// or PrepareBatch) and write them together with the rows of the other INSERTs (async_insert=1).
+// With wait, the commit returns once the rows are written (wait_for_async_insert=1). Without, it returns as soon
+// as the rows are buffered: they are lost if the server fails before writing them, and the rows of an INSERT
+// retried after an error may be written twice.
+func WithAsyncInsert(ctx context.Context, wait bool) context.Context {
+	return withQuerySettings(ctx, map[string]interface{}{
+		"async_insert":          true,
+		"wait_for_async_insert": wait,
+	})
+}
+
+// WithDeduplicationToken sets insert_deduplication_token for the INSERT prepared with the context: the server inserts
+// the rows of the blocks of an INSERT with the token only once, the retries of the same batch are ignored.
+// The blocks are deduplicated one by one (the token of the block n is the token suffixed with _n), a retried batch
+// has to be sent in the same blocks, see WithBatchBlockSize. The rows are deduplicated by the ReplicatedMergeTree
+// tables, or by the MergeTree tables with non_replicated_deduplication_window. The rows of the async inserts
+// (see WithAsyncInsert) are deduplicated only if async_insert_deduplicate is set, by the servers supporting it.
+// The token is sent with the queries of the context only, not with the other queries of the connection.
+func WithDeduplicationToken(ctx context.Context, token string) context.Context {
+	return withQuerySettings(ctx, map[string]interface{}{"insert_deduplication_token": token})
+}
+
+// WithSettings sets the settings of the queries run with the context, e.g. max_execution_time, in addition to
+// (or instead of) the settings of the DSN. The values are written as of the types of the settings on the server,
+// converted from their Go types, e.g. "1000" or 1000 for max_block_size. A value which can't be converted
+// or a setting unknown to the driver fails the query before it is sent.
+//
+//	ctx := clickhouse.WithSettings(ctx, map[string]interface{}{
+//		"max_execution_time": 60,
+//		"readonly":           1,
+//	})
+func WithSettings(ctx context.Context, settings map[string]interface{}) context.Context {
+	return withQuerySettings(ctx, settings)
+}
+
+// WithQuotaKey sets the quota key of the queries run with the context instead of quota_key of the DSN:
+// the server accounts the usage of the queries to the quota of the key, if the quota of the user is keyed by the client.
+func WithQuotaKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, quotaKeyKey, key)
+}
+
 func (stmt *stmt) NumInput() int {
 	switch {
 	case stmt.ch.block != nil:
@@ -39,21 +146,21 @@ func (stmt *stmt) NumInput() int {
 }
 
 func (stmt *stmt) Exec(args []driver.Value) (driver.Result, error) {
-	return stmt.execContext(context.Background(), args)
+	return stmt.execContext(context.Background(), convertOldArgs(args))
 }
 
 func (stmt *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	dargs := make([]driver.Value, len(args))
-	for i, nv := range args {
-		dargs[i] = nv.Value
-	}
-	return stmt.execContext(ctx, dargs)
+	return stmt.execContext(ctx, args)
 }
 
-func (stmt *stmt) execContext(ctx context.Context, args []driver.Value) (driver.Result, error) {
+func (stmt *stmt) execContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	if stmt.isInsert {
 		stmt.counter++
-		if err := stmt.ch.block.AppendRow(args); err != nil {
+		dargs := make([]driver.Value, len(args))
+		for i, nv := range args {
+			dargs[i] = nv.Value
+		}
+		if err := stmt.ch.block.AppendRow(dargs); err != nil {
 			return nil, err
 		}
 		if (stmt.counter % stmt.ch.blockSize) == 0 {
@@ -65,18 +172,35 @@ func (stmt *stmt) execContext(ctx context.Context, args []driver.Value) (driver.
 				return nil, err
 			}
 		}
-		return emptyResult, nil
-	}
-	query, externalTables := stmt.bind(convertOldArgs(args))
-	if err := stmt.ch.sendQuery(ctx, query, externalTables); err != nil {
-		return nil, err
+		// the row is sent with the block, on commit at the latest
+		return &insertResult{rows: 1}, nil
 	}
-	if err := stmt.ch.process(); err != nil {
+	query, externalTables := stmt.bind(args)
+	ctx, span := stmt.ch.startSpan(ctx, query)
+	err := stmt.runExec(ctx, query, externalTables, args)
+	endSpan(span, err)
+	if err != nil {
 		return nil, err
 	}
 	return emptyResult, nil
 }
 
+func (stmt *stmt) runExec(ctx context.Context, query string, externalTables []ExternalTable, args []driver.NamedValue) error {
+	query, err := inlineParameters(query, args)
+	if err != nil {
+		return err
+	}
+	if err := stmt.ch.sendQuery(ctx, query, externalTables); err != nil {
+		return err
+	}
+	stmt.ch.beginReading()
+	err = stmt.ch.process()
+	if cancelErr := stmt.ch.endReading(); cancelErr != nil {
+		return cancelErr
+	}
+	return err
+}
+
 func (stmt *stmt) Query(args []driver.Value) (driver.Rows, error) {
 	return stmt.queryContext(context.Background(), convertOldArgs(args))
 }
@@ -86,17 +210,45 @@ func (stmt *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (d
 }
 
 func (stmt *stmt) queryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	finish := stmt.ch.watchCancel(ctx)
 	query, externalTables := stmt.bind(args)
-	if err := stmt.ch.sendQuery(ctx, query, externalTables); err != nil {
-		finish()
+	ctx, span := stmt.ch.startSpan(ctx, query)
+	rows, err := stmt.runQuery(ctx, query, externalTables, args)
+	if err != nil {
+		endSpan(span, err)
 		return nil, err
 	}
-	meta, err := stmt.ch.readMeta()
+	rows.span = span
+	go rows.receiveData()
+	return rows, nil
+}
+
+func (stmt *stmt) runQuery(ctx context.Context, query string, externalTables []ExternalTable, args []driver.NamedValue) (*rows, error) {
+	query, err := inlineParameters(query, args)
 	if err != nil {
-		finish()
 		return nil, err
 	}
+	var (
+		meta   *data.Block
+		finish func()
+	)
+	for attempt := 0; ; attempt++ {
+		finish = stmt.ch.watchCancel(ctx)
+		if err := stmt.ch.sendQuery(ctx, query, externalTables); err != nil {
+			finish()
+			return nil, err
+		}
+		stmt.ch.beginReading()
+		if meta, err = stmt.ch.readMeta(); err == nil {
+			break
+		}
+		if cancelErr := stmt.ch.endReading(); cancelErr != nil {
+			err = cancelErr
+		}
+		finish()
+		if !stmt.ch.retryQuery(ctx, query, err, attempt) {
+			return nil, err
+		}
+	}
 	rows := rows{
 		ch:           stmt.ch,
 		finish:       finish,
@@ -104,7 +256,11 @@ func (stmt *stmt) queryContext(ctx context.Context, args []driver.NamedValue) (d
 		columns:      meta.ColumnNames(),
 		blockColumns: meta.Columns,
 	}
-	go rows.receiveData()
+	rows.enumValues, _ = ctx.Value(enumValuesKey).(bool)
+	rows.trimFixed, _ = ctx.Value(trimFixedKey).(bool)
+	if streaming, _ := ctx.Value(streamingKey).(bool); streaming {
+		rows.stream, rows.demand = make(chan *data.Block), make(chan struct{}, 1)
+	}
 	return &rows, nil
 }
 
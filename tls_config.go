@@ -1,8 +1,20 @@
 package clickhouse
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"sync"
+
+	"github.com/youmark/pkcs8"
+	"golang.org/x/crypto/ocsp"
 )
 
 // Based on the original implementation in the project go-sql-driver/mysql:
@@ -43,3 +55,203 @@ func getTLSConfigClone(key string) (config *tls.Config) {
 	return
 }
 
+// loadTLSFiles adds the client certificate and the CA of the server loaded from the files to the config.
+// A new config is created if config is nil and any of the files is given.
+// The key is decrypted with keyPassword if it is not empty.
+func loadTLSFiles(config *tls.Config, certFile, keyFile, keyPassword, caFile string) (*tls.Config, error) {
+	if len(certFile) == 0 && len(keyFile) == 0 && len(caFile) == 0 {
+		return config, nil
+	}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if len(certFile) != 0 || len(keyFile) != 0 {
+		if len(certFile) == 0 || len(keyFile) == 0 {
+			return nil, fmt.Errorf("invalid tls_cert/tls_key - both the certificate and the key are required")
+		}
+		cert, err := loadX509KeyPair(certFile, keyFile, keyPassword)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+	if len(caFile) != 0 {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls_ca - %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid tls_ca - no certificates found in %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// parseTLSVersion maps the tls_min_version DSN value to the TLS version, the empty value maps to 0.
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "":
+		return 0, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid tls_min_version - %s, supported versions are 1.2 and 1.3", version)
+}
+
+// parseFingerprints parses the comma separated hex encoded SHA-256 fingerprints of the tls_cert_fingerprint DSN value.
+func parseFingerprints(value string) ([][]byte, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	var fingerprints [][]byte
+	for _, v := range strings.Split(value, ",") {
+		fingerprint, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(v), ":", ""))
+		if err != nil || len(fingerprint) != sha256.Size {
+			return nil, fmt.Errorf("invalid tls_cert_fingerprint - %s is not a hex encoded SHA-256 fingerprint", v)
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints, nil
+}
+
+// pinCertificate installs the verification of the SHA-256 fingerprint of the server certificate,
+// the handshake fails unless it matches one of the fingerprints. The fingerprint replaces the CA
+// verification, so a self-signed certificate or one of an untrusted CA can be pinned whatever skip_verify.
+func pinCertificate(config *tls.Config, fingerprints [][]byte) {
+	// the certificate is trusted by its fingerprint, not by its chain
+	config.InsecureSkipVerify = true
+	next := config.VerifyPeerCertificate
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if next != nil {
+			if err := next(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		if len(rawCerts) == 0 {
+			return errors.New("tls: no peer certificates")
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		for _, fingerprint := range fingerprints {
+			if bytes.Equal(sum[:], fingerprint) {
+				return nil
+			}
+		}
+		return fmt.Errorf("tls: server certificate fingerprint %x does not match tls_cert_fingerprint", sum)
+	}
+}
+
+// verifyOCSP installs the verification of the OCSP response stapled by the server,
+// the handshake fails if the certificate is revoked or, unless allowMissing is set, not stapled.
+func verifyOCSP(config *tls.Config, allowMissing bool) {
+	next := config.VerifyConnection
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if next != nil {
+			if err := next(state); err != nil {
+				return err
+			}
+		}
+		if len(state.OCSPResponse) == 0 {
+			if allowMissing {
+				return nil
+			}
+			return errors.New("ocsp: server did not staple an OCSP response")
+		}
+		if len(state.PeerCertificates) == 0 {
+			return errors.New("ocsp: no peer certificates")
+		}
+		var issuer *x509.Certificate
+		switch {
+		case len(state.VerifiedChains) != 0 && len(state.VerifiedChains[0]) > 1:
+			issuer = state.VerifiedChains[0][1]
+		case len(state.PeerCertificates) > 1:
+			issuer = state.PeerCertificates[1]
+		default:
+			return errors.New("ocsp: issuer of the server certificate is unknown")
+		}
+		response, err := ocsp.ParseResponseForCert(state.OCSPResponse, state.PeerCertificates[0], issuer)
+		if err != nil {
+			return fmt.Errorf("ocsp: %v", err)
+		}
+		switch response.Status {
+		case ocsp.Good:
+			return nil
+		case ocsp.Revoked:
+			return fmt.Errorf("ocsp: server certificate was revoked at %s", response.RevokedAt)
+		}
+		return errors.New("ocsp: status of the server certificate is unknown")
+	}
+}
+
+func loadX509KeyPair(certFile, keyFile, keyPassword string) (tls.Certificate, error) {
+	if len(keyPassword) == 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("invalid tls_cert/tls_key - %v", err)
+		}
+		return cert, nil
+	}
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid tls_cert - %v", err)
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid tls_key - %v", err)
+	}
+	if keyPEM, err = decryptKey(keyPEM, keyPassword); err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid tls_cert/tls_key - %v", err)
+	}
+	return cert, nil
+}
+
+// decryptKey decrypts the PKCS#8 "ENCRYPTED PRIVATE KEY" or the legacy RFC 1423 encrypted
+// PEM private key and returns it as an unencrypted PKCS#8 PEM block.
+func decryptKey(keyPEM []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid tls_key - no PEM data found")
+	}
+	var (
+		key interface{}
+		err error
+	)
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		if key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password)); err != nil {
+			return nil, fmt.Errorf("invalid tls_key_password - could not decrypt the key: %v", err)
+		}
+	case x509.IsEncryptedPEMBlock(block):
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls_key_password - could not decrypt the key: %v", err)
+		}
+		if key, err = parsePrivateKey(block.Type, der); err != nil {
+			return nil, fmt.Errorf("invalid tls_key - %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid tls_key - tls_key_password is set but the key of type %s is not encrypted", block.Type)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tls_key - %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func parsePrivateKey(blockType string, der []byte) (interface{}, error) {
+	switch blockType {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(der)
+	}
+	return x509.ParsePKCS8PrivateKey(der)
+}
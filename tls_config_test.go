@@ -0,0 +1,528 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/youmark/pkcs8"
+	"golang.org/x/crypto/ocsp"
+)
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and localhost and its key to the dir.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func Test_loadTLSFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+
+	config, err := loadTLSFiles(nil, "", "", "", "")
+	if assert.NoError(t, err) {
+		assert.Nil(t, config)
+	}
+	config, err = loadTLSFiles(nil, certFile, keyFile, "", certFile)
+	if assert.NoError(t, err) {
+		assert.Len(t, config.Certificates, 1)
+		assert.NotNil(t, config.RootCAs)
+	}
+	base := &tls.Config{ServerName: "clickhouse"}
+	config, err = loadTLSFiles(base, "", "", "", certFile)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "clickhouse", config.ServerName)
+		assert.Len(t, config.Certificates, 0)
+		assert.NotNil(t, config.RootCAs)
+	}
+	_, err = loadTLSFiles(nil, certFile, "", "", "")
+	assert.Error(t, err)
+	_, err = loadTLSFiles(nil, "", "", "", keyFile)
+	assert.Error(t, err)
+	_, err = Open("tcp://127.0.0.1:9000?tls_ca=" + filepath.Join(dir, "missing.pem"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid tls_ca")
+	}
+}
+
+func Test_loadTLSFilesEncryptedKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	block, _ := pem.Decode(keyPEM)
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if !assert.NoError(t, err) {
+		return
+	}
+	der, err := pkcs8.MarshalPrivateKey(key, []byte("secret"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	encryptedFile := filepath.Join(dir, "encrypted.pem")
+	if err := ioutil.WriteFile(encryptedFile, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := loadTLSFiles(nil, certFile, encryptedFile, "secret", "")
+	if assert.NoError(t, err) {
+		assert.Len(t, config.Certificates, 1)
+	}
+	_, err = loadTLSFiles(nil, certFile, encryptedFile, "", "")
+	assert.Error(t, err)
+	_, err = loadTLSFiles(nil, certFile, encryptedFile, "wrong", "")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid tls_key_password")
+	}
+	_, err = loadTLSFiles(nil, certFile, keyFile, "secret", "")
+	assert.Error(t, err)
+	_, err = Open("tcp://127.0.0.1:9000?tls_cert=" + certFile + "&tls_key=" + encryptedFile + "&tls_key_password=wrong")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid tls_key_password")
+	}
+}
+
+func Test_DialTLSServerName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	serverNames := make(chan string, 1)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+			return nil, nil
+		},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	tlsConfig, err := loadTLSFiles(nil, "", "", "", certFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conn, err := dial(context.Background(), connOptions{
+		secure:        true,
+		tlsConfig:     tlsConfig,
+		tlsServerName: "localhost",
+		hosts:         []string{listener.Addr().String()},
+		openStrategy:  connOpenInOrder,
+		logf:          func(string, ...interface{}) {},
+	})
+	if assert.NoError(t, err) {
+		conn.Close()
+		assert.Equal(t, "localhost", <-serverNames)
+	}
+}
+
+func Test_DialTLSMinVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS10,
+		MaxVersion:   tls.VersionTLS10,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	defer breaker.success(listener.Addr().String())
+	_, err = dial(context.Background(), connOptions{
+		secure:       true,
+		skipVerify:   true,
+		tlsConfig:    tlsConfig,
+		hosts:        []string{listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	assert.Error(t, err)
+
+	for version, want := range map[string]uint16{"": 0, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		got, err := parseTLSVersion(version)
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, got)
+		}
+	}
+	_, err = parseTLSVersion("1.0")
+	assert.Error(t, err)
+}
+
+func Test_DialTLSConfigNotModified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clickhouse")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	tlsConfig := &tls.Config{
+		ServerName: "localhost",
+	}
+	options := connOptions{
+		secure:       true,
+		skipVerify:   true,
+		tlsConfig:    tlsConfig,
+		hosts:        []string{listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if conn, err := dial(context.Background(), options); assert.NoError(t, err) {
+				conn.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+	assert.Equal(t, uint16(0), tlsConfig.MinVersion)
+	assert.Equal(t, "localhost", tlsConfig.ServerName)
+}
+
+// newTestChain returns a CA and a server certificate for 127.0.0.1 issued by it.
+func newTestChain(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "clickhouse test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, &caTemplate, &caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, caKey, tls.Certificate{
+		Certificate: [][]byte{der, caDer},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+// serveTLS accepts connections and runs the TLS handshake until the listener is closed.
+func serveTLS(t *testing.T, config *tls.Config) net.Listener {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return listener
+}
+
+func Test_TLSConnectionState(t *testing.T) {
+	_, _, cert := newTestChain(t)
+	listener := serveTLS(t, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	})
+	defer listener.Close()
+	defer breaker.success(listener.Addr().String())
+	conn, err := dial(context.Background(), connOptions{
+		secure:       true,
+		skipVerify:   true,
+		hosts:        []string{listener.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	state, ok := (&clickhouse{conn: conn}).TLSConnectionState()
+	if assert.True(t, ok) {
+		assert.Equal(t, uint16(tls.VersionTLS13), state.Version)
+		assert.NotZero(t, state.CipherSuite)
+		if assert.NotEmpty(t, state.PeerCertificates) {
+			assert.Equal(t, cert.Leaf.SerialNumber, state.PeerCertificates[0].SerialNumber)
+		}
+	}
+
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer plain.Close()
+	conn, err = dial(context.Background(), connOptions{
+		hosts:        []string{plain.Addr().String()},
+		openStrategy: connOpenInOrder,
+		logf:         func(string, ...interface{}) {},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	_, ok = (&clickhouse{conn: conn}).TLSConnectionState()
+	assert.False(t, ok)
+}
+
+func Test_DialTLSCertFingerprint(t *testing.T) {
+	ca, _, cert := newTestChain(t)
+	listener := serveTLS(t, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	})
+	defer listener.Close()
+	defer breaker.success(listener.Addr().String())
+	sum := sha256.Sum256(cert.Certificate[0])
+	fingerprint := hex.EncodeToString(sum[:])
+	var tlsConfig *tls.Config
+	dialPinned := func(value string, skipVerify bool) error {
+		fingerprints, err := parseFingerprints(value)
+		if err != nil {
+			return err
+		}
+		conn, err := dial(context.Background(), connOptions{
+			secure:          true,
+			skipVerify:      skipVerify,
+			tlsConfig:       tlsConfig,
+			tlsFingerprints: fingerprints,
+			hosts:           []string{listener.Addr().String()},
+			openStrategy:    connOpenInOrder,
+			logf:            func(string, ...interface{}) {},
+		})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	assert.NoError(t, dialPinned(fingerprint, true))
+	assert.NoError(t, dialPinned(strings.Repeat("00", sha256.Size)+","+strings.ToUpper(fingerprint), true))
+	if err := dialPinned(strings.Repeat("00", sha256.Size), true); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "does not match tls_cert_fingerprint")
+	}
+	// the pinned certificate is trusted even though its CA is not
+	assert.NoError(t, dialPinned(fingerprint, false))
+	if err := dialPinned(strings.Repeat("00", sha256.Size), false); assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "does not match tls_cert_fingerprint")
+	}
+	tlsConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(ca)
+	assert.NoError(t, dialPinned(fingerprint, false))
+	assert.Error(t, dialPinned(strings.Repeat("00", sha256.Size), false))
+
+	for _, value := range []string{"00", "zz", fingerprint + ","} {
+		_, err := parseFingerprints(value)
+		assert.Error(t, err, value)
+	}
+	_, err := Open("tcp://127.0.0.1:9000?tls_cert_fingerprint=00")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid tls_cert_fingerprint")
+	}
+}
+
+func Test_DialVerifyOCSP(t *testing.T) {
+	ca, caKey, cert := newTestChain(t)
+	staple := func(status int) []byte {
+		response, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: cert.Leaf.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	tests := []struct {
+		name         string
+		staple       []byte
+		allowMissing bool
+		wantErr      bool
+	}{
+		{name: "good", staple: staple(ocsp.Good)},
+		{name: "revoked", staple: staple(ocsp.Revoked), wantErr: true},
+		{name: "missing", wantErr: true},
+		{name: "missing lenient", allowMissing: true},
+		{name: "revoked lenient", staple: staple(ocsp.Revoked), allowMissing: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverCert := cert
+			serverCert.OCSPStaple = tt.staple
+			listener := serveTLS(t, &tls.Config{Certificates: []tls.Certificate{serverCert}})
+			defer listener.Close()
+			defer breaker.success(listener.Addr().String())
+			conn, err := dial(context.Background(), connOptions{
+				secure:           true,
+				tlsConfig:        &tls.Config{RootCAs: roots},
+				verifyOCSP:       true,
+				allowMissingOCSP: tt.allowMissing,
+				hosts:            []string{listener.Addr().String()},
+				openStrategy:     connOpenInOrder,
+				logf:             func(string, ...interface{}) {},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Tracer starts the spans of the queries run by QueryContext and ExecContext, e.g. an adapter of an OpenTelemetry
+// trace.Tracer. Custom tracers must be registered with RegisterTracer, they trace the queries of the connections
+// opened afterwards; the driver starts no span if none is registered.
+type Tracer interface {
+	// StartSpan starts the span of the query, a child of the span of ctx, it returns the context of the span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the span of a query started by a Tracer. The attributes set by the driver are db.system, db.statement
+// (see trace_statement of the DSN), net.peer.name (the host the query is sent to) and db.clickhouse.query_id.
+// The span ends once the exec completes or the rows are closed, the error of the query is recorded before.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+var (
+	customTracerLock sync.RWMutex
+	customTracer     Tracer
+)
+
+// RegisterTracer registers the custom tracer.
+func RegisterTracer(tracer Tracer) {
+	customTracerLock.Lock()
+	customTracer = tracer
+	customTracerLock.Unlock()
+}
+
+// DeregisterTracer deregisters the custom tracer.
+func DeregisterTracer() {
+	customTracerLock.Lock()
+	customTracer = nil
+	customTracerLock.Unlock()
+}
+
+func getTracer() Tracer {
+	customTracerLock.RLock()
+	defer customTracerLock.RUnlock()
+	return customTracer
+}
+
+// startSpan starts the span of the query if a tracer is registered, the span is nil otherwise.
+// The context returned carries the span, sendQuery sets the host and the query id of the query on it.
+func (ch *clickhouse) startSpan(ctx context.Context, query string) (context.Context, Span) {
+	if ch.tracer == nil {
+		return ctx, nil
+	}
+	name := "clickhouse"
+	if f := strings.Fields(query); len(f) != 0 {
+		name = strings.ToUpper(f[0])
+	}
+	ctx, span := ch.tracer.StartSpan(ctx, name)
+	span.SetAttribute("db.system", "clickhouse")
+	if statement, ok := traceStatement(ch.traceStatement, query); ok {
+		span.SetAttribute("db.statement", statement)
+	}
+	return context.WithValue(ctx, querySpanKey, span), span
+}
+
+// endSpan records the error of the query, if any, and ends the span.
+func endSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && err != io.EOF {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// traceStatement returns the db.statement of the query as of trace_statement of the DSN: the query if full
+// (or unset), its SHA-256 if hash, the query truncated to the length in bytes if a number, none if none.
+func traceStatement(mode, query string) (string, bool) {
+	switch mode {
+	case "", "full":
+		return query, true
+	case "none":
+		return "", false
+	case "hash":
+		sum := sha256.Sum256([]byte(query))
+		return "sha256:" + hex.EncodeToString(sum[:]), true
+	}
+	length, _ := strconv.Atoi(mode)
+	if len(query) <= length {
+		return query, true
+	}
+	// the statement is cut at the start of a rune
+	for length > 0 && !utf8.RuneStart(query[length]) {
+		length--
+	}
+	return query[:length], true
+}
+
+func parseTraceStatement(mode string) (string, error) {
+	switch mode {
+	case "", "full", "hash", "none":
+		return mode, nil
+	}
+	if length, err := strconv.Atoi(mode); err == nil && length > 0 {
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid trace_statement - must be full, hash, none or a length in bytes, got %q", mode)
+}
@@ -0,0 +1,141 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/column"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedSpan struct {
+	parent     context.Context
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+type recordedTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordedTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	span := &recordedSpan{parent: ctx, name: name, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func Test_TraceSpans(t *testing.T) {
+	var (
+		tracer = &recordedTracer{}
+		stub   = &stubConn{}
+		conn   = newStubConnect(t, stub, connOptions{})
+		ch     = &clickhouse{
+			conn:     conn,
+			logf:     func(string, ...interface{}) {},
+			settings: &querySettings{},
+			decoder:  binary.NewDecoder(conn),
+			encoder:  binary.NewEncoder(conn),
+			tracer:   tracer,
+		}
+		ctx = WithQueryID(context.WithValue(context.Background(), key("parent"), "span"), "query-1")
+	)
+	stub.data = []byte{protocol.ServerEndOfStream}
+	if _, err := ch.ExecContext(ctx, "select 1", nil); !assert.NoError(t, err) || !assert.Len(t, tracer.spans, 1) {
+		return
+	}
+	span := tracer.spans[0]
+	assert.Equal(t, "span", span.parent.Value(key("parent")), "the span is a child of the span of the context")
+	assert.Equal(t, "SELECT", span.name)
+	assert.Equal(t, map[string]interface{}{
+		"db.system":              "clickhouse",
+		"db.statement":           "select 1",
+		"net.peer.name":          "stub:9000",
+		"db.clickhouse.query_id": "query-1",
+	}, span.attributes)
+	assert.True(t, span.ended)
+	assert.NoError(t, span.err)
+
+	// the error of the exec marks the span
+	var buf bytes.Buffer
+	encoder := binary.NewEncoder(&buf)
+	encoder.Uvarint(protocol.ServerException)
+	encoder.Int32(60)
+	encoder.String("DB::Exception")
+	encoder.String("DB::Exception: Table default.t doesn't exist")
+	encoder.String("")
+	encoder.Bool(false)
+	stub.data = buf.Bytes()
+	_, err := ch.ExecContext(ctx, "DROP TABLE t", nil)
+	if assert.Error(t, err) && assert.Len(t, tracer.spans, 2) {
+		assert.Equal(t, "DROP", tracer.spans[1].name)
+		assert.Equal(t, err, tracer.spans[1].err)
+		assert.True(t, tracer.spans[1].ended)
+	}
+
+	// the span of a query ends once its rows are closed
+	buf.Reset()
+	c, _ := column.Factory("value", "UInt8", time.UTC)
+	encoder.Uvarint(protocol.ServerData)
+	encoder.String("")
+	(&data.Block{Columns: []column.Column{c}, NumColumns: 1}).Write(&ch.ServerInfo, encoder)
+	encoder.Uvarint(protocol.ServerEndOfStream)
+	stub.data = buf.Bytes()
+	stmt, err := ch.PrepareContext(ctx, "SELECT value FROM t")
+	if !assert.NoError(t, err) {
+		return
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+	if assert.NoError(t, err) && assert.Len(t, tracer.spans, 3) {
+		assert.False(t, tracer.spans[2].ended)
+		assert.NoError(t, rows.Close())
+		assert.True(t, tracer.spans[2].ended)
+		assert.NoError(t, tracer.spans[2].err)
+	}
+
+	// no span is started without a tracer
+	ch.tracer = nil
+	stub.data = []byte{protocol.ServerEndOfStream}
+	if _, err := ch.ExecContext(ctx, "SELECT 1", nil); assert.NoError(t, err) {
+		assert.Len(t, tracer.spans, 3)
+	}
+}
+
+func Test_TraceStatement(t *testing.T) {
+	query := "SELECT 'héllo'"
+	for mode, expected := range map[string]string{
+		"":     query,
+		"full": query,
+		"hash": "sha256:9787f5cbb38f5452a43118417c9a835606e70e9a70463c07c7bc86c64bd950c7",
+		"9":    "SELECT 'h",
+		"10":   "SELECT 'h", // the rune é is not cut
+		"11":   "SELECT 'hé",
+		"64":   query,
+	} {
+		statement, ok := traceStatement(mode, query)
+		if assert.True(t, ok, mode) {
+			assert.Equal(t, expected, statement, mode)
+		}
+	}
+	_, ok := traceStatement("none", query)
+	assert.False(t, ok)
+	for _, mode := range []string{"full", "hash", "none", "128"} {
+		_, err := parseTraceStatement(mode)
+		assert.NoError(t, err, mode)
+	}
+	for _, mode := range []string{"0", "-1", "md5"} {
+		_, err := parseTraceStatement(mode)
+		assert.Error(t, err, mode)
+	}
+}
@@ -0,0 +1,104 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/c3mb0/clickhouse-go/lib/column"
+)
+
+// ScanTuple returns a sql.Scanner scanning the value of a Tuple column into the struct pointed to by dest:
+// the elements are assigned to the exported fields in the declared order, or by the `ch` tag (or the name of the field)
+// for a named tuple. The nested tuples are scanned into the struct fields the same way.
+//
+//	var user struct {
+//		ID   uint32 `ch:"id"`
+//		Name string `ch:"name"`
+//	}
+//	rows.Scan(clickhouse.ScanTuple(&user))
+func ScanTuple(dest interface{}) sql.Scanner {
+	return &tupleScanner{dest: dest}
+}
+
+type tupleScanner struct {
+	dest interface{}
+}
+
+func (scanner *tupleScanner) Scan(src interface{}) error {
+	value := reflect.ValueOf(scanner.dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("clickhouse: ScanTuple expects a pointer to a struct, got %T", scanner.dest)
+	}
+	return assignTuple(value.Elem(), src)
+}
+
+func assignTuple(dest reflect.Value, src interface{}) error {
+	fields := column.TupleFields(dest.Type())
+	switch src := src.(type) {
+	case []interface{}:
+		if len(fields) != len(src) {
+			return fmt.Errorf("clickhouse: %s has %d fields, the tuple has %d elements", dest.Type(), len(fields), len(src))
+		}
+		for i, v := range src {
+			if err := assignElement(dest.FieldByIndex(fields[i].Index), v); err != nil {
+				return fmt.Errorf("clickhouse: tuple element %d (field %s): %v", i, fields[i].Name, err)
+			}
+		}
+	case map[string]interface{}:
+		if len(fields) != len(src) {
+			return fmt.Errorf("clickhouse: %s has %d fields, the tuple has %d elements", dest.Type(), len(fields), len(src))
+		}
+		for name, v := range src {
+			field, found := column.TupleField(dest.Type(), name)
+			if !found {
+				return fmt.Errorf("clickhouse: tuple element %s has no field in %s", name, dest.Type())
+			}
+			if err := assignElement(dest.FieldByIndex(field.Index), v); err != nil {
+				return fmt.Errorf("clickhouse: tuple element %s (field %d %s): %v", name, field.Index[0], field.Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("clickhouse: can not scan %T into %s, the value is not a tuple", src, dest.Type())
+	}
+	return nil
+}
+
+func assignElement(dest reflect.Value, src interface{}) error {
+	if src == nil {
+		switch dest.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		return fmt.Errorf("NULL can not be assigned to %s", dest.Type())
+	}
+	switch src.(type) {
+	case []interface{}, map[string]interface{}:
+		// a nested tuple
+		switch {
+		case dest.Kind() == reflect.Struct && dest.Type() != reflect.TypeOf(time.Time{}):
+			return assignTuple(dest, src)
+		case dest.Kind() == reflect.Ptr && dest.Type().Elem().Kind() == reflect.Struct:
+			value := reflect.New(dest.Type().Elem())
+			if err := assignTuple(value.Elem(), src); err != nil {
+				return err
+			}
+			dest.Set(value)
+			return nil
+		}
+	}
+	value := reflect.ValueOf(src)
+	switch {
+	case value.Type().AssignableTo(dest.Type()):
+		dest.Set(value)
+	case dest.Kind() == reflect.Ptr && value.Type().AssignableTo(dest.Type().Elem()):
+		ptr := reflect.New(dest.Type().Elem())
+		ptr.Elem().Set(value)
+		dest.Set(ptr)
+	default:
+		return fmt.Errorf("%T can not be assigned to %s", src, dest.Type())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ScanTuple(t *testing.T) {
+	type point struct {
+		X, Y float64
+	}
+	type element struct {
+		ID       uint32
+		Name     *string
+		Point    point
+		Location *point
+		hidden   int
+	}
+	var value element
+	if err := ScanTuple(&value).Scan([]interface{}{uint32(1), "a", []interface{}{1.5, 2.5}, []interface{}{3.5, 4.5}}); assert.NoError(t, err) {
+		if assert.NotNil(t, value.Name) && assert.NotNil(t, value.Location) {
+			assert.Equal(t, "a", *value.Name)
+			assert.Equal(t, point{3.5, 4.5}, *value.Location)
+		}
+		assert.Equal(t, uint32(1), value.ID)
+		assert.Equal(t, point{1.5, 2.5}, value.Point)
+	}
+	if err := ScanTuple(&value).Scan([]interface{}{uint32(2), nil, []interface{}{1.5, 2.5}, nil}); assert.NoError(t, err) {
+		assert.Nil(t, value.Name)
+		assert.Nil(t, value.Location)
+	}
+
+	var named struct {
+		ID      uint32 `ch:"id"`
+		Name    string
+		Created time.Time `ch:"created_at"`
+	}
+	created := time.Unix(1600000000, 0)
+	if err := ScanTuple(&named).Scan(map[string]interface{}{"id": uint32(3), "name": "b", "created_at": created}); assert.NoError(t, err) {
+		assert.Equal(t, uint32(3), named.ID)
+		assert.Equal(t, "b", named.Name)
+		assert.Equal(t, created, named.Created)
+	}
+
+	for _, tc := range []struct {
+		dest  interface{}
+		src   interface{}
+		error string
+	}{
+		{&value, []interface{}{uint32(1), "a", []interface{}{1.5, 2.5}}, "has 4 fields, the tuple has 3 elements"},
+		{&value, []interface{}{"1", "a", []interface{}{1.5, 2.5}, nil}, "tuple element 0 (field ID)"},
+		{&value, []interface{}{uint32(1), "a", []interface{}{1.5, "2.5"}, nil}, "tuple element 2 (field Point)"},
+		{&value, []interface{}{nil, "a", []interface{}{1.5, 2.5}, nil}, "NULL can not be assigned to uint32"},
+		{&named, map[string]interface{}{"id": uint32(3), "title": "b", "created_at": created}, "tuple element title has no field"},
+		{&named, "a", "the value is not a tuple"},
+		{named, []interface{}{}, "expects a pointer to a struct"},
+	} {
+		if err := ScanTuple(tc.dest).Scan(tc.src); assert.Error(t, err) {
+			assert.Contains(t, err.Error(), tc.error)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// WarmupError is the error of Warmup if some of the connections could not be opened.
+type WarmupError struct {
+	Opened int     // the connections opened and pinged
+	Errors []error // the errors of the connections that could not be opened
+}
+
+func (e *WarmupError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("clickhouse: %d of %d connections could not be opened: %s",
+		len(e.Errors), e.Opened+len(e.Errors), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the error of the first connection that could not be opened, so that errors.Is(err, ErrConnectionFailed)
+// or errors.Is(err, ErrAuthFailed) tells why.
+func (e *WarmupError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// Warmup opens n connections of the pool of db and pings them, so that the first queries don't wait for their
+// connections to be dialed. The connections are opened concurrently, each one is dialed as by a query: to the host
+// chosen by the connection_open_strategy of the DSN, so they are spread over the servers.
+// n is capped at the MaxOpenConns of the pool; the pool keeps idle no more than its MaxIdleConns (2 by default,
+// see sql.DB.SetMaxIdleConns), the connections above are closed once warmed up.
+func Warmup(db *sql.DB, n int) error {
+	if max := db.Stats().MaxOpenConnections; max > 0 && n > max {
+		n = max
+	}
+	var (
+		wg    sync.WaitGroup
+		ctx   = context.Background()
+		conns = make([]*sql.Conn, n)
+		errs  = make([]error, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if err := conn.PingContext(ctx); err != nil {
+				conn.Close()
+				errs[i] = err
+				return
+			}
+			conns[i] = conn
+		}(i)
+	}
+	// the connections are held until all of them are open, so that none of them is reused by another
+	wg.Wait()
+	warmupErr := &WarmupError{}
+	for i, conn := range conns {
+		if conn == nil {
+			warmupErr.Errors = append(warmupErr.Errors, errs[i])
+			continue
+		}
+		conn.Close()
+		warmupErr.Opened++
+	}
+	if len(warmupErr.Errors) != 0 {
+		return warmupErr
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/c3mb0/clickhouse-go/lib/binary"
+	"github.com/c3mb0/clickhouse-go/lib/data"
+	"github.com/c3mb0/clickhouse-go/lib/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+// helloServer is a server answering the hello of the clients by the server info and their pings by pongs,
+// it counts the connections accepted and the pings answered, and records the clients and the users of the hellos.
+type helloServer struct {
+	listener net.Listener
+	addr     string
+	info     data.ServerInfo
+	timezone string
+	accepted int32
+	pings    int32
+	mutex    sync.Mutex
+	clients  []string
+	users    []string
+}
+
+func newHelloServer(t *testing.T, info data.ServerInfo, timezone string) *helloServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &helloServer{
+		listener: listener,
+		addr:     listener.Addr().String(),
+		info:     info,
+		timezone: timezone,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&server.accepted, 1)
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (server *helloServer) close() {
+	server.listener.Close()
+}
+
+func (server *helloServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var (
+		decoder = binary.NewDecoder(conn)
+		encoder = binary.NewEncoder(conn)
+	)
+	// the hello of the client is its info, the database and the credentials
+	if packet, err := decoder.Uvarint(); err != nil || packet != protocol.ClientHello {
+		return
+	}
+	client, err := decoder.String()
+	if err != nil {
+		return
+	}
+	var version [3]uint64 // the version and the revision
+	for i := range version {
+		if version[i], err = decoder.Uvarint(); err != nil {
+			return
+		}
+	}
+	var hello [3]string
+	for i := range hello {
+		if hello[i], err = decoder.String(); err != nil {
+			return
+		}
+	}
+	server.mutex.Lock()
+	server.clients = append(server.clients, fmt.Sprintf("%s %d.%d", client, version[0], version[1]))
+	server.users = append(server.users, hello[1])
+	server.mutex.Unlock()
+	encoder.Uvarint(protocol.ServerHello)
+	encoder.String(server.info.Name)
+	encoder.Uvarint(server.info.MajorVersion)
+	encoder.Uvarint(server.info.MinorVersion)
+	encoder.Uvarint(server.info.Revision)
+	if server.info.Revision >= protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE {
+		encoder.String(server.timezone)
+	}
+	encoder.Flush()
+	for {
+		packet, err := decoder.Uvarint()
+		if err != nil || packet != protocol.ClientPing {
+			return
+		}
+		atomic.AddInt32(&server.pings, 1)
+		encoder.Uvarint(protocol.ServerPong)
+		encoder.Flush()
+	}
+}
+
+func Test_Warmup(t *testing.T) {
+	server := newHelloServer(t, data.ServerInfo{Name: "ClickHouse", MajorVersion: 21, MinorVersion: 8, Revision: protocol.DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE}, "UTC")
+	defer server.close()
+	db, err := sql.Open("clickhouse", "tcp://"+server.addr+"?timeout=1&read_timeout=1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(3)
+	db.SetMaxIdleConns(3)
+	// the connections are capped at MaxOpenConns
+	if assert.NoError(t, Warmup(db, 5)) {
+		assert.Equal(t, int32(3), atomic.LoadInt32(&server.accepted))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&server.pings))
+		assert.Equal(t, 3, db.Stats().Idle)
+	}
+
+	defer breaker.success("127.0.0.1:1")
+	db, err = sql.Open("clickhouse", "tcp://127.0.0.1:1?timeout=1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+	err = Warmup(db, 2)
+	var warmupErr *WarmupError
+	if assert.True(t, errors.As(err, &warmupErr), err) {
+		assert.Equal(t, 0, warmupErr.Opened)
+		assert.Len(t, warmupErr.Errors, 2)
+		assert.True(t, errors.Is(err, ErrConnectionFailed), err)
+	}
+}
@@ -1,8 +1,10 @@
 package clickhouse
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"io"
 	"time"
 
 	"github.com/c3mb0/clickhouse-go/lib/data"
@@ -17,6 +19,8 @@ type Clickhouse interface {
 	Rollback() error
 	Close() error
 	WriteBlock(block *data.Block) error
+	PrepareBatch(ctx context.Context, query string) (Batch, error)
+	InsertReader(ctx context.Context, table, format string, r io.Reader) error
 }
 
 // Interface for Block allowing writes to individual columns
@@ -36,7 +40,7 @@ type ColumnWriter interface {
 }
 
 func OpenDirect(dsn string) (Clickhouse, error) {
-	return open(dsn)
+	return open(context.Background(), dsn)
 }
 
 func (ch *clickhouse) Block() (*data.Block, error) {